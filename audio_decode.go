@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/aiff"
+	"github.com/gopxl/beep/v2/ffmpeg"
+	"github.com/gopxl/beep/v2/flac"
+	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/vorbis"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// errUnsupportedFormat is returned by decodeAudioFile for files that are fine but cannot be
+// played here, e.g. an Opus file without ffmpeg installed. Such files are not marked as corrupted.
+//
+// errUnsupportedFormat 由 decodeAudioFile 返回，表示文件本身没有问题但无法在此播放，
+// 例如未安装 ffmpeg 时的 Opus 文件。这类文件不会被标记为已损坏。
+var errUnsupportedFormat = errors.New("unsupported audio format")
+
+// Audio container formats recognized by detectAudioFormat.
+//
+// detectAudioFormat 能识别的音频容器格式。
+const (
+	formatFLAC    = "flac"
+	formatMP3     = "mp3"
+	formatWAV     = "wav"
+	formatVorbis  = "ogg"
+	formatOpus    = "opus"
+	formatAIFF    = "aiff"
+	formatMP4     = "m4a"
+	formatAAC     = "aac"
+	formatWavPack = "wv"
+	formatAPE     = "ape"
+	formatOggFLAC = "oga"
+
+	// formatTagged is an ID3-tagged file whose data is not recognized. It is left to ffmpeg.
+	// formatTagged 是数据无法识别的带 ID3 标签的文件，交给 ffmpeg 处理。
+	formatTagged = "tagged"
+)
+
+// id3PaddingScan is how far past an ID3v2 tag detectAudioFormat skips zero padding that
+// some taggers leave after the tag's declared size.
+//
+// id3PaddingScan 是 detectAudioFormat 在 ID3v2 标签之后跳过零填充的最大范围，
+// 某些标签工具会在标签声明的大小之后留下这种填充。
+const id3PaddingScan = 4096
+
+// audioExtensions maps the file extensions BM plays to the format they usually contain.
+//
+// audioExtensions 将 BM 支持的文件扩展名映射到它们通常包含的格式。
+var audioExtensions = map[string]string{
+	".flac": formatFLAC,
+	".mp3":  formatMP3,
+	".wav":  formatWAV,
+	".ogg":  formatVorbis,
+	".oga":  formatVorbis,
+	".opus": formatOpus,
+	".aiff": formatAIFF,
+	".aif":  formatAIFF,
+	".aifc": formatAIFF,
+	".m4a":  formatMP4,
+	".m4b":  formatMP4,
+	".mp4":  formatMP4,
+	".alac": formatMP4,
+	".aac":  formatAAC,
+	".wv":   formatWavPack,
+	".ape":  formatAPE,
+}
+
+// detectAudioFormat identifies the format of an audio file from its first bytes, so that
+// misnamed files still play. It falls back to the extension when the content is not
+// recognized, except after an ID3v2 tag: as any format may carry one, such files are only
+// decoded natively when the data after the tag is recognized.
+//
+// detectAudioFormat 根据文件开头的字节识别音频格式，使扩展名错误的文件也能播放。
+// 无法识别内容时回退到扩展名，但 ID3v2 标签之后的数据除外：由于任何格式都可能带有该标签，
+// 只有在能识别标签之后的数据时才会使用原生解码器。
+func detectAudioFormat(r io.ReadSeeker, filePath string) string {
+	header := make([]byte, 64)
+	n, _ := io.ReadFull(r, header)
+	header = header[:n]
+
+	if len(header) >= 10 && string(header[0:3]) == "ID3" {
+		size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 | int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+		if header[5]&0x10 != 0 {
+			size += 10 // Footer. / 页脚。
+		}
+		if _, err := r.Seek(10+size, io.SeekStart); err != nil {
+			return formatTagged
+		}
+		next := make([]byte, id3PaddingScan)
+		m, _ := io.ReadFull(r, next)
+		next = bytes.TrimLeft(next[:m], "\x00")
+		if format := detectHeaderFormat(next); format != "" {
+			return format
+		}
+		return formatTagged
+	}
+
+	if format := detectHeaderFormat(header); format != "" {
+		return format
+	}
+	return audioExtensions[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// detectHeaderFormat identifies a format from the bytes it starts with, or returns "".
+//
+// detectHeaderFormat 根据开头的字节识别格式，无法识别时返回 ""。
+func detectHeaderFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return formatFLAC
+	case len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE":
+		return formatWAV
+	case len(header) >= 12 && string(header[0:4]) == "FORM" && (string(header[8:12]) == "AIFF" || string(header[8:12]) == "AIFC"):
+		return formatAIFF
+	case bytes.HasPrefix(header, []byte("OggS")):
+		// The first page holds the identification header of the codec.
+		// 第一页包含编解码器的标识头。
+		header = header[:min(len(header), 64)]
+		switch {
+		case bytes.Contains(header, []byte("OpusHead")):
+			return formatOpus
+		case bytes.Contains(header, []byte("\x01vorbis")):
+			return formatVorbis
+		case bytes.Contains(header, []byte("\x7fFLAC")):
+			return formatOggFLAC
+		}
+	case len(header) >= 8 && string(header[4:8]) == "ftyp":
+		return formatMP4
+	case bytes.HasPrefix(header, []byte("wvpk")):
+		return formatWavPack
+	case bytes.HasPrefix(header, []byte("MAC ")):
+		return formatAPE
+	case len(header) >= 2 && header[0] == 0xff && header[1]&0xf6 == 0xf0:
+		// ADTS frame sync with layer 0.
+		// ADTS 帧同步，layer 为 0。
+		return formatAAC
+	case len(header) >= 2 && header[0] == 0xff && header[1]&0xe0 == 0xe0 && header[1]&0x06 != 0:
+		// MPEG audio frame sync with a valid layer.
+		// 带有效 layer 的 MPEG 音频帧同步。
+		return formatMP3
+	}
+	return ""
+}
+
+// isAudioFile checks if a file, or the file holding a cue track, has a supported audio
+// extension.
+//
+// isAudioFile 检查文件或 cue 音轨所在的文件是否具有支持的音频扩展名。
+func isAudioFile(filename string) bool {
+	_, ok := audioExtensions[strings.ToLower(filepath.Ext(cueAudioPath(filename)))]
+	return ok
+}
+
+// audioSource is an open audio file: an *os.File or a readAheadFile.
+//
+// audioSource 是已打开的音频文件：*os.File 或 readAheadFile。
+type audioSource interface {
+	io.ReadSeekCloser
+	Name() string
+}
+
+// decodeAudioFile decodes a song from f, the open audio file holding it, and takes ownership
+// of f. It also returns the format detected from the content. Cue tracks are played from the
+// part of their audio file between the start and the end of the track.
+//
+// decodeAudioFile 从 f（存放歌曲的已打开音频文件）解码歌曲，并接管 f。它还返回根据内容检测到的
+// 格式。cue 音轨从其音频文件中音轨开始和结束之间的部分播放。
+func decodeAudioFile(f audioSource, songPath string) (beep.StreamSeekCloser, beep.Format, string, error) {
+	audioPath, number, isTrack := splitCueTrackPath(songPath)
+	if !isTrack {
+		return decodeAudioSource(f)
+	}
+
+	track, ok := findCueTrack(songPath)
+	if !ok {
+		f.Close()
+		return nil, beep.Format{}, "", fmt.Errorf("Track %d not found in the cue sheet of %s\n\n在 %s 的 cue 表中未找到音轨 %d", number, audioPath, audioPath, number)
+	}
+	streamer, format, container, err := decodeAudioSource(f)
+	if err != nil {
+		return nil, beep.Format{}, "", err
+	}
+	trackStreamer, err := newCueTrackStreamer(streamer, format, track)
+	if err != nil {
+		streamer.Close()
+		return nil, beep.Format{}, "", fmt.Errorf("Failed to seek to the start of the track: %w\n\n跳转到音轨开始位置失败: %v", err, err)
+	}
+	return trackStreamer, format, container, nil
+}
+
+// decodeAudioSource decodes the open audio file f from its start, detecting its format from
+// the content, which it returns too. Formats without a native decoder are decoded with ffmpeg; if it is not
+// installed the error wraps errUnsupportedFormat.
+//
+// decodeAudioSource 从头解码已打开的音频文件 f，并根据内容检测其格式（该格式也会被返回）。没有原生解码器的格式使用
+// ffmpeg 解码；如果未安装 ffmpeg，返回的错误会包装 errUnsupportedFormat。
+func decodeAudioSource(f audioSource) (beep.StreamSeekCloser, beep.Format, string, error) {
+	filePath := f.Name()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, beep.Format{}, "", err
+	}
+
+	format := detectAudioFormat(f, filePath)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, beep.Format{}, "", err
+	}
+
+	switch format {
+	case formatFLAC:
+		streamer, beepFormat, err := flac.Decode(f)
+		return streamer, beepFormat, format, err
+	case formatMP3:
+		streamer, beepFormat, err := mp3.Decode(f)
+		return streamer, beepFormat, format, err
+	case formatWAV:
+		streamer, beepFormat, err := wav.Decode(f)
+		return streamer, beepFormat, format, err
+	case formatVorbis:
+		streamer, beepFormat, err := vorbis.Decode(f)
+		return streamer, beepFormat, format, err
+	case formatAIFF:
+		streamer, beepFormat, err := aiff.Decode(f)
+		return streamer, beepFormat, format, err
+	case "":
+		f.Close()
+		ext := filepath.Ext(filePath)
+		return nil, beep.Format{}, "", fmt.Errorf("%w: %s\n\n不支持的音频格式: %s", errUnsupportedFormat, ext, ext)
+	}
+
+	f.Close()
+	streamer, beepFormat, err := ffmpeg.Decode(filePath)
+	if errors.Is(err, ffmpeg.ErrNotFound) {
+		if format == formatTagged {
+			format = filepath.Base(filePath)
+		}
+		return nil, beep.Format{}, "", fmt.Errorf("%w: %s needs ffmpeg and ffprobe to be installed\n\n不支持的音频格式: %s 需要安装 ffmpeg 和 ffprobe", errUnsupportedFormat, format, format)
+	}
+	return streamer, beepFormat, format, err
+}
+
+// isCorruptionError reports whether a decoding error means the file itself is broken,
+// as opposed to a format that cannot be played on this system.
+//
+// isCorruptionError 判断解码错误是否意味着文件本身已损坏，而不是该格式无法在此系统上播放。
+func isCorruptionError(err error) bool {
+	return err != nil && !errors.Is(err, errUnsupportedFormat)
+}
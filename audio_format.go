@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// lossyFormats are the formats whose decoded precision says nothing about the source file,
+// so no bit depth is shown for them.
+//
+// lossyFormats 是解码精度不能反映音源文件位深的格式，因此不显示它们的位深。
+var lossyFormats = map[string]bool{
+	formatMP3:    true,
+	formatVorbis: true,
+	formatOpus:   true,
+	formatAAC:    true,
+}
+
+// formatNames are the names of the formats shown in the format line.
+//
+// formatNames 是格式行中显示的格式名称。
+var formatNames = map[string]string{
+	formatFLAC:    "FLAC",
+	formatMP3:     "MP3",
+	formatWAV:     "WAV",
+	formatVorbis:  "Vorbis",
+	formatOpus:    "Opus",
+	formatAIFF:    "AIFF",
+	formatMP4:     "MP4",
+	formatAAC:     "AAC",
+	formatWavPack: "WavPack",
+	formatAPE:     "APE",
+	formatOggFLAC: "Ogg FLAC",
+}
+
+// channelLayouter is implemented by the decoders that know the speaker layout of their
+// source channels.
+//
+// channelLayouter 由知道音源声道扬声器布局的解码器实现。
+type channelLayouter interface {
+	ChannelLayout() beep.ChannelMask
+}
+
+// sourceLayout returns the channel layout of a decoded song.
+//
+// sourceLayout 返回已解码歌曲的声道布局。
+func sourceLayout(streamer beep.Streamer, format beep.Format) beep.ChannelMask {
+	if l, ok := streamer.(channelLayouter); ok && l.ChannelLayout() != 0 {
+		return l.ChannelLayout()
+	}
+	return beep.DefaultChannelMask(format.NumChannels)
+}
+
+// configureDownmix applies the downmix settings to the decoders.
+//
+// configureDownmix 将缩混设置应用到解码器。
+func configureDownmix() {
+	if GlobalConfig == nil {
+		return
+	}
+	beep.DefaultDownmix = beep.Downmix{
+		Center:    GlobalConfig.App.DownmixCenter,
+		Surround:  GlobalConfig.App.DownmixSurround,
+		LFE:       GlobalConfig.App.DownmixLFE,
+		Mono:      GlobalConfig.App.MonoGain,
+		Normalize: GlobalConfig.App.DownmixNormalize,
+	}
+}
+
+// playerOutput is the streamer handed to the speaker. In bit-perfect mode it reads the
+// song directly while the volume and the playback rate are neutral, skipping the
+// resampler and the volume control; otherwise it plays through them as usual.
+// When the read-ahead buffer or the output of ffmpeg runs dry it plays silence instead of
+// waiting, which would hold the speaker lock and freeze the UI.
+//
+// playerOutput 是交给扬声器的流。在比特完美模式下，当音量和播放速度为中性值时，
+// 它直接读取歌曲，跳过重采样器和音量控制；否则照常经过它们播放。
+// 当预读缓冲区或 ffmpeg 的输出耗尽时，它会播放静音而不是等待，因为等待会占用扬声器锁并使界面卡住。
+type playerOutput struct {
+	player    *audioPlayer
+	bypassed  atomic.Bool // True while the song is read directly. / 直接读取歌曲时为 true。
+	buffering atomic.Bool // True while waiting for the read-ahead. / 等待预读时为 true。
+}
+
+// Stream implements beep.Streamer. It runs with the speaker lock held.
+//
+// Stream 实现 beep.Streamer。调用时持有扬声器锁。
+func (o *playerOutput) Stream(samples [][2]float64) (int, bool) {
+	p := o.player
+	if !p.ctrl.Paused && !o.sourceReady(len(samples)) {
+		clear(samples)
+		return len(samples), true
+	}
+	if p.bitPerfect && p.resampler.Ratio() == 1 && p.volume.Volume == 0 && !p.volume.Silent {
+		o.bypassed.Store(true)
+		return p.ctrl.Stream(samples)
+	}
+	if o.bypassed.Load() {
+		// The resampler still holds samples from before the bypass; start a fresh one at
+		// the current position so nothing stale is played.
+		// 重采样器仍保存着旁路之前的采样；在当前位置重新创建一个，避免播放过期的数据。
+		p.resampler = beep.ResampleRatio(4, p.resampler.Ratio(), p.ctrl)
+		p.volume.Streamer = p.resampler
+		o.bypassed.Store(false)
+	}
+	return p.volume.Stream(samples)
+}
+
+// Err implements beep.Streamer.
+//
+// Err 实现 beep.Streamer。
+func (o *playerOutput) Err() error {
+	return o.player.volume.Err()
+}
+
+// decoderResume is how much audio a background decoder must have buffered after an underrun,
+// e.g. after a seek restarted ffmpeg, before playback resumes.
+//
+// decoderResume 是缓冲不足后（例如跳转重新启动 ffmpeg 之后）恢复播放前，
+// 后台解码器必须缓冲的音频时长。
+const decoderResume = time.Second / 4
+
+// backgroundDecoder is a decoder that decodes ahead in the background, like the ffmpeg
+// decoder, which restarts ffmpeg when seeking.
+//
+// backgroundDecoder 是在后台提前解码的解码器，例如跳转时会重新启动 ffmpeg 的 ffmpeg 解码器。
+type backgroundDecoder interface {
+	// Ready reports whether n samples can be streamed without waiting.
+	// Ready 判断是否可以无需等待地产生 n 个采样。
+	Ready(n int) bool
+}
+
+// sourceReady reports whether enough of the song file, or of the output of a background
+// decoder, is buffered for the decoder to produce n samples without waiting. After an
+// underrun, more is required before playback resumes.
+//
+// sourceReady 判断歌曲文件或后台解码器的输出是否已缓冲足够的数据，使解码器无需等待即可产生
+// n 个采样。缓冲不足后，需要缓冲更多数据才会恢复播放。
+func (o *playerOutput) sourceReady(n int) bool {
+	p := o.player
+	if p.source == nil && p.decoder == nil {
+		return true
+	}
+	samples := int(float64(n) * p.resampler.Ratio())
+	ready := true
+	if p.source != nil {
+		need := int(float64(samples)*p.bytesPerSample) + readAheadMargin
+		if o.buffering.Load() {
+			need += readAheadResume
+		}
+		ready = p.source.ready(need)
+	}
+	if ready && p.decoder != nil {
+		need := samples
+		if o.buffering.Load() {
+			need += p.sampleRate.N(decoderResume)
+		}
+		ready = p.decoder.Ready(need)
+	}
+	o.buffering.Store(!ready)
+	return ready
+}
+
+// outputBitDepth returns how many bits of a source the output format carries unchanged.
+// A float32 sample has a 24-bit mantissa.
+//
+// outputBitDepth 返回输出格式能原样保留的音源位数。float32 采样具有 24 位尾数。
+func outputBitDepth(f speaker.OutputFormat) int {
+	if f == speaker.OutputFloat32 {
+		return 24
+	}
+	return f.BitDepth()
+}
+
+// applyDither enables dither for a song when it has more bits than the integer output
+// format and dither is enabled in the config.
+//
+// applyDither 在歌曲位深高于整数输出格式且配置启用了抖动时为该歌曲开启抖动。
+func applyDither(format beep.Format) {
+	output := speaker.CurrentOutputFormat()
+	enabled := GlobalConfig != nil && GlobalConfig.App.Dither && output != speaker.OutputFloat32
+	speaker.SetDither(enabled && format.Precision*8 > output.BitDepth())
+}
+
+// formatSampleRate formats a sample rate in kHz, e.g. "44.1 kHz" or "96 kHz".
+//
+// formatSampleRate 以 kHz 格式化采样率，例如 "44.1 kHz" 或 "96 kHz"。
+func formatSampleRate(rate beep.SampleRate) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.1f", float64(rate)/1000), "0"), ".") + " kHz"
+}
+
+// sourceFormat returns the name of the format detected from the content of the playing song,
+// e.g. "FLAC".
+//
+// sourceFormat 返回根据正在播放歌曲的内容检测到的格式名称，例如 "FLAC"。
+func (a *audioPlayer) sourceFormat() string {
+	if name, ok := formatNames[a.container]; ok {
+		return name
+	}
+	// Data after an ID3 tag that was left to ffmpeg.
+	// 交给 ffmpeg 处理的 ID3 标签之后的数据。
+	return strings.ToUpper(strings.TrimPrefix(filepath.Ext(cueAudioPath(a.path)), "."))
+}
+
+// formatLine describes the source and output formats of the playing song,
+// e.g. "FLAC 24-bit 96 kHz 5.1 → 32-bit float 96 kHz".
+//
+// formatLine 描述正在播放歌曲的音源格式和输出格式，
+// 例如 "FLAC 24-bit 96 kHz 5.1 → 32-bit float 96 kHz"。
+func (a *audioPlayer) formatLine() string {
+	source := a.sourceFormat()
+	bits := a.format.Precision * 8
+	if !lossyFormats[a.container] && a.container != formatTagged && bits > 0 {
+		source += fmt.Sprintf(" %d-bit", bits)
+	}
+	layout := a.layout.String()
+	if a.layout == 0 {
+		layout = fmt.Sprintf("%d ch", a.format.NumChannels)
+	}
+	rate := formatSampleRate(a.sampleRate)
+	line := fmt.Sprintf("%s %s %s → %s %s", source, rate, layout, speaker.CurrentOutputFormat(), rate)
+	// Only stereo and unity-gain mono sources reach the output without being remixed.
+	// 只有立体声和单位增益的单声道音源在到达输出时不会被重新混音。
+	unmixed := a.layout == beep.LayoutStereo || (a.layout == beep.LayoutMono && beep.DefaultDownmix.Mono == 1)
+	if a.output.bypassed.Load() && unmixed && bits <= outputBitDepth(speaker.CurrentOutputFormat()) {
+		line += " · bit-perfect"
+	}
+	return line
+}
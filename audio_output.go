@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// Audio output selected on the command line; they override the config file when set.
+//
+// 命令行选择的音频输出；设置后会覆盖配置文件。
+var (
+	cliAudioBackend string
+	cliOutputDevice string
+)
+
+// parseAudioFlags removes --backend and --device (in "--flag value" or "--flag=value" form)
+// from args and returns the remaining arguments.
+//
+// parseAudioFlags 从 args 中移除 --backend 和 --device（支持 "--flag value" 或 "--flag=value" 形式），
+// 并返回剩余的参数。
+func parseAudioFlags(args []string) ([]string, error) {
+	flags := map[string]*string{
+		"--backend": &cliAudioBackend,
+		"--device":  &cliOutputDevice,
+	}
+
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		target, ok := flags[name]
+		if !ok {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("Missing value for %s\n\n%s 缺少参数值", name, name)
+			}
+			i++
+			value = args[i]
+		}
+		*target = value
+	}
+	return rest, nil
+}
+
+// resolveAudioOutput returns the backend and device to use, preferring the command line
+// over the config file.
+//
+// resolveAudioOutput 返回要使用的后端和设备，命令行优先于配置文件。
+func resolveAudioOutput() (speaker.Backend, string, error) {
+	name, device := "auto", ""
+	if GlobalConfig != nil {
+		if GlobalConfig.App.AudioBackend != "" {
+			name = GlobalConfig.App.AudioBackend
+		}
+		device = GlobalConfig.App.OutputDevice
+	}
+	if cliAudioBackend != "" {
+		name = cliAudioBackend
+	}
+	if cliOutputDevice != "" {
+		device = cliOutputDevice
+	}
+
+	var backend speaker.Backend
+	switch strings.ToLower(name) {
+	case "auto":
+		backend = speaker.BackendAuto
+	case "pulse", "pulseaudio", "pipewire":
+		backend = speaker.BackendPulse
+	case "alsa":
+		backend = speaker.BackendALSA
+	case "file", "pipe":
+		backend = speaker.BackendFile
+		// The terminal UI owns stdout, so the file backend needs a real path or FIFO.
+		if device == "" || device == "-" {
+			return "", "", fmt.Errorf("The file backend needs an output path (output_device or --device)\n\nfile 后端需要输出路径（output_device 或 --device）")
+		}
+		expanded, err := expandHomePath(device)
+		if err != nil {
+			return "", "", err
+		}
+		device = expanded
+	case "null", "none":
+		backend = speaker.BackendNull
+	default:
+		return "", "", fmt.Errorf("Unknown audio backend: %s (expected auto, pulse, alsa, file or null)\n\n未知的音频后端: %s（可选 auto、pulse、alsa、file 或 null）", name, name)
+	}
+	return backend, device, nil
+}
+
+// resolveOutputFormat returns the speaker sample format selected by output_format.
+//
+// resolveOutputFormat 返回 output_format 所选的扬声器采样格式。
+func resolveOutputFormat() (speaker.OutputFormat, error) {
+	name := "16"
+	if GlobalConfig != nil && GlobalConfig.App.OutputFormat != "" {
+		name = GlobalConfig.App.OutputFormat
+	}
+	switch strings.ToLower(name) {
+	case "16", "s16", "int16":
+		return speaker.OutputInt16, nil
+	case "24", "s24", "int24":
+		return speaker.OutputInt24, nil
+	case "float32", "float", "f32", "32":
+		return speaker.OutputFloat32, nil
+	default:
+		return 0, fmt.Errorf("Unknown output format: %s (expected 16, 24 or float32)\n\n未知的输出格式: %s（可选 16、24 或 float32）", name, name)
+	}
+}
+
+// initSpeaker initializes the speaker with the configured audio output backend and
+// returns the ID of the selected output device ("" for the backend's default).
+//
+// initSpeaker 使用配置的音频输出后端初始化扬声器，并返回所选输出设备的 ID（"" 表示后端默认设备）。
+func initSpeaker(sampleRate beep.SampleRate) (string, error) {
+	backend, device, err := resolveAudioOutput()
+	if err != nil {
+		return "", err
+	}
+	if err := speaker.SetBackend(backend, device); err != nil {
+		return "", err
+	}
+	format, err := resolveOutputFormat()
+	if err != nil {
+		return "", err
+	}
+	if err := speaker.SetOutputFormat(format); err != nil {
+		return "", err
+	}
+	if err := speaker.Init(sampleRate, sampleRate.N(time.Second/30)); err != nil {
+		return "", fmt.Errorf("Failed to initialize audio output: %v\n\n初始化音频输出失败: %v", err, err)
+	}
+	return device, nil
+}
@@ -0,0 +1,322 @@
+package aiff
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// Decode takes a Reader containing audio data in AIFF or AIFF-C format and returns a
+// StreamSeekCloser, which streams that audio. Uncompressed integer samples ("NONE" and
+// little-endian "sowt") of 8 to 32 bits and float samples ("fl32", "fl64") are supported.
+// The Seek method will return an error if r is not io.Seeker.
+//
+// Do not close the supplied Reader, instead, use the Close method of the returned
+// StreamSeekCloser when you want to release the resources.
+func Decode(r io.Reader) (s beep.StreamSeekCloser, format beep.Format, err error) {
+	d := decoder{r: r}
+	defer func() { // hacky way to always close r if an error occurred
+		if closer, ok := d.r.(io.Closer); ok {
+			if err != nil {
+				closer.Close()
+			}
+		}
+	}()
+
+	var form [12]byte
+	if _, err := io.ReadFull(r, form[:]); err != nil {
+		return nil, beep.Format{}, errors.Wrap(err, "aiff: missing FORM header")
+	}
+	if string(form[0:4]) != "FORM" {
+		return nil, beep.Format{}, fmt.Errorf("aiff: missing FORM at the beginning > %s", string(form[0:4]))
+	}
+	formType := string(form[8:12])
+	if formType != "AIFF" && formType != "AIFC" {
+		return nil, beep.Format{}, errors.New("aiff: unsupported file type")
+	}
+	d.compression = "NONE"
+
+	// Walk the chunks until both COMM and SSND have been seen. pos is the offset of
+	// the next chunk from the start of the file.
+	pos := int64(len(form))
+	var haveComm bool
+	var dataSize int64
+	for !haveComm || d.dataStart == 0 {
+		var head [8]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return nil, beep.Format{}, errors.Wrap(err, "aiff: missing COMM or SSND chunk")
+		}
+		id := string(head[0:4])
+		size := int64(binary.BigEndian.Uint32(head[4:8]))
+		padded := size + size%2
+		pos += 8
+
+		switch id {
+		case "COMM":
+			if err := d.readComm(io.LimitReader(r, size), formType); err != nil {
+				return nil, beep.Format{}, err
+			}
+			haveComm = true
+			if err := skip(r, padded-d.commRead); err != nil {
+				return nil, beep.Format{}, errors.Wrap(err, "aiff: missing COMM chunk body")
+			}
+		case "SSND":
+			var ssnd [8]byte
+			if _, err := io.ReadFull(r, ssnd[:]); err != nil {
+				return nil, beep.Format{}, errors.Wrap(err, "aiff: missing SSND chunk header")
+			}
+			offset := int64(binary.BigEndian.Uint32(ssnd[0:4]))
+			d.dataStart = pos + 8 + offset
+			dataSize = size - 8 - offset
+			if haveComm {
+				if err := skip(r, offset); err != nil {
+					return nil, beep.Format{}, errors.Wrap(err, "aiff: missing SSND chunk body")
+				}
+				pos += 8 + offset
+				break
+			}
+			// The sound data comes before the COMM chunk; skip it and seek back later.
+			if _, ok := r.(io.Seeker); !ok {
+				return nil, beep.Format{}, errors.New("aiff: SSND chunk before COMM chunk needs a seekable reader")
+			}
+			if err := skip(r, padded-8); err != nil {
+				return nil, beep.Format{}, errors.Wrap(err, "aiff: missing SSND chunk body")
+			}
+		default:
+			if err := skip(r, padded); err != nil {
+				return nil, beep.Format{}, errors.Wrap(err, "aiff: missing unknown chunk body")
+			}
+		}
+		if id != "SSND" || !haveComm {
+			pos += padded
+		}
+	}
+
+	if seeker, ok := r.(io.Seeker); ok && pos != d.dataStart {
+		if _, err := seeker.Seek(d.dataStart, io.SeekStart); err != nil {
+			return nil, beep.Format{}, errors.Wrap(err, "aiff: seek error")
+		}
+	}
+
+	d.frameSize = d.numChans * d.bytesPerSample
+	d.len = int(d.numFrames)
+	if dataSize >= 0 && int(dataSize)/d.frameSize < d.len {
+		// Truncated file; play what is there.
+		d.len = int(dataSize) / d.frameSize
+	}
+
+	format = beep.Format{
+		SampleRate:  beep.SampleRate(d.sampleRate),
+		NumChannels: d.numChans,
+		Precision:   d.bytesPerSample,
+	}
+	d.layout = beep.DefaultChannelMask(d.numChans)
+	d.matrix = beep.DefaultDownmix.Matrix(d.layout, d.numChans)
+	return &d, format, nil
+}
+
+// readComm parses the body of a COMM chunk.
+func (d *decoder) readComm(r io.Reader, formType string) error {
+	var comm struct {
+		NumChans     int16
+		NumFrames    uint32
+		SampleSize   int16
+		SampleRate80 [10]byte
+	}
+	if err := binary.Read(r, binary.BigEndian, &comm); err != nil {
+		return errors.Wrap(err, "aiff: missing COMM chunk body")
+	}
+	d.commRead = 18
+
+	if formType == "AIFC" {
+		var compression [4]byte
+		if err := binary.Read(r, binary.BigEndian, &compression); err != nil {
+			return errors.Wrap(err, "aiff: missing compression type")
+		}
+		d.commRead += 4
+		d.compression = string(compression[:])
+	}
+
+	if comm.NumChans <= 0 {
+		return errors.New("aiff: invalid number of channels (less than 1)")
+	}
+	d.numChans = int(comm.NumChans)
+	d.numFrames = comm.NumFrames
+	d.sampleRate = extendedToFloat(comm.SampleRate80)
+	if d.sampleRate <= 0 || math.IsInf(d.sampleRate, 0) || math.IsNaN(d.sampleRate) {
+		return errors.New("aiff: invalid sample rate")
+	}
+
+	switch d.compression {
+	case "NONE", "sowt", "twos":
+		if comm.SampleSize < 1 || comm.SampleSize > 32 {
+			return fmt.Errorf("aiff: unsupported number of bits per sample - %d", comm.SampleSize)
+		}
+		// Samples are left-aligned in whole bytes, so e.g. 20-bit audio decodes as 24-bit.
+		d.bytesPerSample = (int(comm.SampleSize) + 7) / 8
+	case "fl32", "FL32":
+		d.bytesPerSample = 4
+	case "fl64", "FL64":
+		d.bytesPerSample = 8
+	default:
+		return fmt.Errorf("aiff: unsupported compression type - %q", d.compression)
+	}
+	return nil
+}
+
+// extendedToFloat converts an 80-bit IEEE 754 extended precision number, which AIFF uses
+// for the sample rate, to a float64.
+func extendedToFloat(b [10]byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]))
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	sign := 1.0
+	if exponent&0x8000 != 0 {
+		sign = -1
+		exponent &= 0x7fff
+	}
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	if exponent == 0x7fff {
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(float64(mantissa), exponent-16383-63)
+}
+
+// skip discards n bytes from r.
+func skip(r io.Reader, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+type decoder struct {
+	r   io.Reader
+	err error
+
+	numChans       int
+	numFrames      uint32
+	sampleRate     float64
+	compression    string
+	bytesPerSample int
+	frameSize      int
+	commRead       int64 // Bytes of the COMM chunk consumed by readComm.
+
+	dataStart int64 // Offset of the first sample frame from the start of the file.
+	len       int
+	pos       int
+	buf       []byte
+
+	// layout and matrix fold the channels into stereo; see beep.DefaultDownmix.
+	layout   beep.ChannelMask
+	matrix   beep.DownmixMatrix
+	channels []float64
+}
+
+func (d *decoder) Stream(samples [][2]float64) (n int, ok bool) {
+	if d.err != nil || d.pos >= d.len {
+		return 0, false
+	}
+
+	frames := min(len(samples), d.len-d.pos)
+	if cap(d.buf) < frames*d.frameSize {
+		d.buf = make([]byte, frames*d.frameSize)
+	}
+	p := d.buf[:frames*d.frameSize]
+	read, err := io.ReadFull(d.r, p)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		d.err = errors.Wrap(err, "aiff")
+	}
+	n = read / d.frameSize
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		// The file ends before the COMM chunk says it does.
+		d.len = d.pos + n
+	}
+
+	if len(d.channels) != d.numChans {
+		d.channels = make([]float64, d.numChans)
+	}
+	for i := 0; i < n; i++ {
+		frame := p[i*d.frameSize:]
+		for c := range d.channels {
+			d.channels[c] = d.decodeSample(frame[c*d.bytesPerSample:])
+		}
+		if d.layout == beep.LayoutStereo {
+			samples[i] = [2]float64{d.channels[0], d.channels[1]}
+		} else {
+			samples[i] = d.matrix.Apply(d.channels)
+		}
+	}
+	d.pos += n
+	return n, n > 0
+}
+
+// decodeSample decodes a single sample of one channel.
+func (d *decoder) decodeSample(p []byte) float64 {
+	switch d.compression {
+	case "fl32", "FL32":
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(p)))
+	case "fl64", "FL64":
+		return math.Float64frombits(binary.BigEndian.Uint64(p))
+	}
+
+	// Assemble the sample in the top bits of an int32 so that the sign is kept.
+	var v int32
+	for i := 0; i < d.bytesPerSample; i++ {
+		b := p[i]
+		if d.compression == "sowt" {
+			b = p[d.bytesPerSample-1-i]
+		}
+		v |= int32(b) << (24 - 8*i)
+	}
+	return float64(v) / (1 << 31)
+}
+
+// ChannelLayout returns the speaker layout of the source channels.
+func (d *decoder) ChannelLayout() beep.ChannelMask {
+	return d.layout
+}
+
+func (d *decoder) Err() error {
+	return d.err
+}
+
+func (d *decoder) Len() int {
+	return d.len
+}
+
+func (d *decoder) Position() int {
+	return d.pos
+}
+
+func (d *decoder) Seek(p int) error {
+	seeker, ok := d.r.(io.Seeker)
+	if !ok {
+		return errors.New("aiff: seek: resource is not io.Seeker")
+	}
+	if p < 0 || d.len < p {
+		return fmt.Errorf("aiff: seek position %v out of range [%v, %v]", p, 0, d.len)
+	}
+	if _, err := seeker.Seek(d.dataStart+int64(p)*int64(d.frameSize), io.SeekStart); err != nil {
+		return errors.Wrap(err, "aiff: seek error")
+	}
+	d.pos = p
+	return nil
+}
+
+func (d *decoder) Close() error {
+	if closer, ok := d.r.(io.Closer); ok {
+		err := closer.Close()
+		if err != nil {
+			return errors.Wrap(err, "aiff")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package aiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// chunk returns an AIFF chunk with the given id and body, padded to an even size.
+func chunk(id string, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(id)
+	binary.Write(&b, binary.BigEndian, uint32(len(body)))
+	b.Write(body)
+	if len(body)%2 != 0 {
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+// comm returns a COMM chunk body. For AIFF-C files, compression is appended.
+func comm(numChans int16, numFrames uint32, sampleSize int16, sampleRate float64, compression string) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, numChans)
+	binary.Write(&b, binary.BigEndian, numFrames)
+	binary.Write(&b, binary.BigEndian, sampleSize)
+
+	// 80-bit extended float of an integer sample rate.
+	exp := 16383 + 63
+	mantissa := uint64(sampleRate)
+	for mantissa&(1<<63) == 0 {
+		mantissa <<= 1
+		exp--
+	}
+	binary.Write(&b, binary.BigEndian, uint16(exp))
+	binary.Write(&b, binary.BigEndian, mantissa)
+
+	if compression != "" {
+		b.WriteString(compression)
+		b.Write([]byte{0, 0}) // Empty compression name, padded.
+	}
+	return b.Bytes()
+}
+
+func ssnd(data []byte) []byte {
+	return append(make([]byte, 8), data...)
+}
+
+func form(formType string, chunks ...[]byte) []byte {
+	var body bytes.Buffer
+	body.WriteString(formType)
+	for _, c := range chunks {
+		body.Write(c)
+	}
+	return chunk("FORM", body.Bytes())
+}
+
+func TestDecode_Stereo16(t *testing.T) {
+	data := []byte{
+		0x40, 0x00, 0xc0, 0x00, // 0.5, -0.5
+		0x7f, 0xff, 0x80, 0x00, // ~1, -1
+		0x00, 0x00, 0x00, 0x01,
+	}
+	file := form("AIFF", chunk("COMM", comm(2, 3, 16, 44100, "")), chunk("SSND", ssnd(data)))
+
+	s, format, err := Decode(bytes.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}, format)
+	assert.Equal(t, 3, s.Len())
+
+	samples := make([][2]float64, 4)
+	n, ok := s.Stream(samples)
+	assert.True(t, ok)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, [2]float64{0.5, -0.5}, samples[0])
+	assert.Equal(t, [2]float64{32767.0 / 32768, -1}, samples[1])
+	assert.Equal(t, [2]float64{0, 1.0 / 32768}, samples[2])
+
+	_, ok = s.Stream(samples)
+	assert.False(t, ok)
+
+	require.NoError(t, s.Seek(1))
+	n, _ = s.Stream(samples[:1])
+	assert.Equal(t, 1, n)
+	assert.Equal(t, [2]float64{32767.0 / 32768, -1}, samples[0])
+	assert.Equal(t, 2, s.Position())
+}
+
+func TestDecode_AIFCLittleEndian24Mono(t *testing.T) {
+	// 0x400000 is 0.5 in 24-bit; "sowt" stores it little-endian.
+	data := []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xc0}
+	file := form("AIFC", chunk("FVER", make([]byte, 4)), chunk("COMM", comm(1, 2, 24, 96000, "sowt")), chunk("SSND", ssnd(data)))
+
+	s, format, err := Decode(bytes.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, beep.Format{SampleRate: 96000, NumChannels: 1, Precision: 3}, format)
+
+	samples := make([][2]float64, 2)
+	n, _ := s.Stream(samples)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, [2]float64{0.5, 0.5}, samples[0])
+	assert.Equal(t, [2]float64{-0.5, -0.5}, samples[1])
+}
+
+func TestDecode_Float32SoundBeforeComm(t *testing.T) {
+	var data bytes.Buffer
+	binary.Write(&data, binary.BigEndian, []float32{0.25, -0.75})
+	file := form("AIFC", chunk("SSND", ssnd(data.Bytes())), chunk("COMM", comm(2, 1, 32, 48000, "fl32")))
+
+	s, format, err := Decode(bytes.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, beep.SampleRate(48000), format.SampleRate)
+
+	samples := make([][2]float64, 1)
+	n, _ := s.Stream(samples)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, [2]float64{0.25, -0.75}, samples[0])
+}
+
+func TestDecode_UnsupportedCompression(t *testing.T) {
+	file := form("AIFC", chunk("COMM", comm(2, 1, 16, 44100, "ulaw")), chunk("SSND", ssnd(make([]byte, 4))))
+	_, _, err := Decode(bytes.NewReader(file))
+	assert.Error(t, err)
+}
+
+func TestExtendedToFloat(t *testing.T) {
+	// 44100 Hz as stored by most encoders.
+	b := [10]byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}
+	assert.Equal(t, 44100.0, extendedToFloat(b))
+	assert.Equal(t, 0.0, extendedToFloat([10]byte{}))
+	assert.True(t, math.IsInf(extendedToFloat([10]byte{0x7f, 0xff}), 1))
+}
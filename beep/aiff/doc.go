@@ -0,0 +1,2 @@
+// Package aiff implements audio data decoding in AIFF and AIFF-C format.
+package aiff
@@ -0,0 +1,219 @@
+package beep
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// ChannelMask is a set of speaker positions. The bits follow the dwChannelMask field of
+// WAVE_FORMAT_EXTENSIBLE, and the channels of an interleaved multichannel stream appear
+// in bit order.
+type ChannelMask uint32
+
+// Speaker positions of a ChannelMask.
+const (
+	FrontLeft ChannelMask = 1 << iota
+	FrontRight
+	FrontCenter
+	LowFrequency
+	BackLeft
+	BackRight
+	FrontLeftOfCenter
+	FrontRightOfCenter
+	BackCenter
+	SideLeft
+	SideRight
+	TopCenter
+	TopFrontLeft
+	TopFrontCenter
+	TopFrontRight
+	TopBackLeft
+	TopBackCenter
+	TopBackRight
+)
+
+// Common channel layouts.
+const (
+	LayoutMono         = FrontCenter
+	LayoutStereo       = FrontLeft | FrontRight
+	Layout2Point1      = LayoutStereo | LowFrequency
+	Layout3Point0      = LayoutStereo | FrontCenter
+	LayoutQuad         = LayoutStereo | BackLeft | BackRight
+	Layout5Point0      = Layout3Point0 | BackLeft | BackRight
+	Layout5Point0Side  = Layout3Point0 | SideLeft | SideRight
+	Layout5Point1      = Layout5Point0 | LowFrequency
+	Layout5Point1Side  = Layout5Point0Side | LowFrequency
+	Layout6Point1      = Layout5Point1Side | BackCenter
+	Layout7Point1      = Layout5Point1 | SideLeft | SideRight
+	Layout7Point1Front = Layout5Point1 | FrontLeftOfCenter | FrontRightOfCenter
+)
+
+var layoutNames = map[ChannelMask]string{
+	LayoutMono:         "mono",
+	LayoutStereo:       "stereo",
+	Layout2Point1:      "2.1",
+	Layout3Point0:      "3.0",
+	LayoutQuad:         "quad",
+	Layout5Point0:      "5.0",
+	Layout5Point0Side:  "5.0(side)",
+	Layout5Point1:      "5.1",
+	Layout5Point1Side:  "5.1(side)",
+	Layout6Point1:      "6.1",
+	Layout7Point1:      "7.1",
+	Layout7Point1Front: "7.1(wide)",
+}
+
+// DefaultChannelMask returns the layout assumed for numChannels channels when a file does
+// not say which speakers its channels belong to. It matches the FLAC channel assignments
+// and the usual WAVE conventions. It returns 0 for more than 8 channels.
+func DefaultChannelMask(numChannels int) ChannelMask {
+	switch numChannels {
+	case 1:
+		return LayoutMono
+	case 2:
+		return LayoutStereo
+	case 3:
+		return Layout3Point0
+	case 4:
+		return LayoutQuad
+	case 5:
+		return Layout5Point0
+	case 6:
+		return Layout5Point1
+	case 7:
+		return Layout6Point1
+	case 8:
+		return Layout7Point1
+	default:
+		return 0
+	}
+}
+
+// ParseChannelLayout returns the layout with the given common name, as returned by String.
+// The names match the ones used by FFmpeg.
+func ParseChannelLayout(name string) (ChannelMask, bool) {
+	for m, n := range layoutNames {
+		if n == name {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// Count returns the number of channels in m.
+func (m ChannelMask) Count() int {
+	return bits.OnesCount32(uint32(m))
+}
+
+// String returns the common name of the layout, e.g. "stereo" or "5.1", or the channel
+// count for other layouts.
+func (m ChannelMask) String() string {
+	if name, ok := layoutNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d ch", m.Count())
+}
+
+// Downmix holds the coefficients used to fold a source with any channel layout into
+// the stereo samples of a Streamer.
+type Downmix struct {
+	// Center is the gain of the front center channel in each of the front channels.
+	Center float64
+	// Surround is the gain of a surround channel in the front channel on its side.
+	Surround float64
+	// LFE is the gain of the low-frequency channel in each of the front channels.
+	LFE float64
+	// Mono is the gain of a mono source in each of the front channels.
+	Mono float64
+	// Normalize scales the whole matrix down when a full-scale signal on every channel
+	// would clip, so that the downmix never exceeds full scale.
+	Normalize bool
+}
+
+// DefaultDownmix is the downmix used by the decoders. The center and surround channels
+// are mixed at -3 dB as recommended by ITU-R BS.775, the LFE channel is dropped and mono
+// sources play at full level on both sides.
+var DefaultDownmix = Downmix{
+	Center:    math.Sqrt2 / 2,
+	Surround:  math.Sqrt2 / 2,
+	LFE:       0,
+	Mono:      1,
+	Normalize: true,
+}
+
+// Matrix returns the left and right gains of each channel of a source with numChannels
+// interleaved channels in the given layout. Channels not covered by the layout are
+// silent, and a source without a layout plays its first two channels as left and right.
+// Stereo sources get the identity matrix, so they are passed on unchanged.
+// Mono sources are not normalized, so Mono alone sets their level.
+func (d Downmix) Matrix(layout ChannelMask, numChannels int) DownmixMatrix {
+	matrix := make(DownmixMatrix, numChannels)
+	if layout == 0 {
+		layout = DefaultChannelMask(min(numChannels, 2))
+	}
+	if layout == LayoutMono && numChannels == 1 {
+		matrix[0] = [2]float64{d.Mono, d.Mono}
+		return matrix
+	}
+
+	c := 0
+	for bit := ChannelMask(1); bit != 0 && c < numChannels; bit <<= 1 {
+		if layout&bit == 0 {
+			continue
+		}
+		matrix[c] = d.gains(bit)
+		c++
+	}
+
+	if d.Normalize {
+		var left, right float64
+		for _, g := range matrix {
+			left += math.Abs(g[0])
+			right += math.Abs(g[1])
+		}
+		if peak := max(left, right); peak > 1 {
+			for i := range matrix {
+				matrix[i][0] /= peak
+				matrix[i][1] /= peak
+			}
+		}
+	}
+	return matrix
+}
+
+// gains returns the left and right gains of a single speaker position.
+func (d Downmix) gains(position ChannelMask) [2]float64 {
+	switch position {
+	case FrontLeft, FrontLeftOfCenter:
+		return [2]float64{1, 0}
+	case FrontRight, FrontRightOfCenter:
+		return [2]float64{0, 1}
+	case FrontCenter, TopCenter, TopFrontCenter:
+		return [2]float64{d.Center, d.Center}
+	case LowFrequency:
+		return [2]float64{d.LFE, d.LFE}
+	case BackLeft, SideLeft, TopFrontLeft, TopBackLeft:
+		return [2]float64{d.Surround, 0}
+	case BackRight, SideRight, TopFrontRight, TopBackRight:
+		return [2]float64{0, d.Surround}
+	case BackCenter, TopBackCenter:
+		g := d.Surround * math.Sqrt2 / 2
+		return [2]float64{g, g}
+	default:
+		return [2]float64{}
+	}
+}
+
+// DownmixMatrix holds the left and right gains of each channel of a source.
+type DownmixMatrix [][2]float64
+
+// Apply mixes one frame of channel values into a stereo sample.
+func (m DownmixMatrix) Apply(frame []float64) [2]float64 {
+	var out [2]float64
+	for c, v := range frame {
+		out[0] += m[c][0] * v
+		out[1] += m[c][1] * v
+	}
+	return out
+}
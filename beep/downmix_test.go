@@ -0,0 +1,61 @@
+package beep
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownmix_MatrixStereoIsIdentity(t *testing.T) {
+	m := DefaultDownmix.Matrix(LayoutStereo, 2)
+	assert.Equal(t, DownmixMatrix{{1, 0}, {0, 1}}, m)
+	assert.Equal(t, [2]float64{0.25, -0.5}, m.Apply([]float64{0.25, -0.5}))
+}
+
+func TestDownmix_MatrixMono(t *testing.T) {
+	d := DefaultDownmix
+	d.Mono = math.Sqrt2 / 2
+	assert.Equal(t, DownmixMatrix{{d.Mono, d.Mono}}, d.Matrix(LayoutMono, 1))
+}
+
+func TestDownmix_Matrix5Point1(t *testing.T) {
+	d := Downmix{Center: 0.5, Surround: 0.5, LFE: 0}
+	m := d.Matrix(Layout5Point1, 6)
+	assert.Equal(t, DownmixMatrix{{1, 0}, {0, 1}, {0.5, 0.5}, {0, 0}, {0.5, 0}, {0, 0.5}}, m)
+
+	// Normalizing scales the matrix so that the row sum of each side is at most 1.
+	d.Normalize = true
+	m = d.Matrix(Layout5Point1, 6)
+	var left, right float64
+	for _, g := range m {
+		left += g[0]
+		right += g[1]
+	}
+	assert.InDelta(t, 1, left, 1e-12)
+	assert.InDelta(t, 1, right, 1e-12)
+}
+
+func TestDownmix_MatrixWithoutLayout(t *testing.T) {
+	m := DefaultDownmix.Matrix(0, 10)
+	assert.Equal(t, [2]float64{1, 0}, m[0])
+	assert.Equal(t, [2]float64{0, 1}, m[1])
+	for _, g := range m[2:] {
+		assert.Equal(t, [2]float64{}, g)
+	}
+}
+
+func TestChannelMask_String(t *testing.T) {
+	assert.Equal(t, "5.1", Layout5Point1.String())
+	assert.Equal(t, "7.1", DefaultChannelMask(8).String())
+	assert.Equal(t, "2 ch", (FrontLeft | BackCenter).String())
+}
+
+func TestParseChannelLayout(t *testing.T) {
+	m, ok := ParseChannelLayout("5.1(side)")
+	assert.True(t, ok)
+	assert.Equal(t, Layout5Point1Side, m)
+
+	_, ok = ParseChannelLayout("22.2")
+	assert.False(t, ok)
+}
@@ -0,0 +1,384 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// ErrNotFound is returned by Decode when ffmpeg or ffprobe is not installed.
+var ErrNotFound = errors.New("ffmpeg: ffmpeg and ffprobe are needed to decode this format")
+
+// lossless lists the codecs whose bit depth is meaningful. Lossy codecs decode to float
+// samples and report a precision of 0.
+var lossless = map[string]bool{
+	"alac":    true,
+	"ape":     true,
+	"flac":    true,
+	"mlp":     true,
+	"truehd":  true,
+	"tta":     true,
+	"wavpack": true,
+}
+
+// Decode probes the audio file at path with ffprobe and returns a StreamSeekCloser, which
+// decodes the first audio stream of the file with ffmpeg. It takes a path instead of a
+// Reader because containers like MP4 may keep their index at the end of the file.
+//
+// ffmpeg runs in the background and its output is buffered, up to a second of audio.
+// Seeking discards the buffer and restarts ffmpeg at the new position without waiting for
+// it; Stream then waits for the new output, which callers holding a lock the audio callback
+// needs can avoid by checking Ready first.
+//
+// Use the Close method of the returned StreamSeekCloser to stop ffmpeg.
+func Decode(path string) (s beep.StreamSeekCloser, format beep.Format, err error) {
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, beep.Format{}, ErrNotFound
+	}
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, beep.Format{}, ErrNotFound
+	}
+
+	cmd := exec.Command(ffprobe,
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "stream=codec_name,sample_rate,channels,channel_layout,bits_per_raw_sample,bits_per_sample,duration:format=duration",
+		"-of", "default=noprint_wrappers=1",
+		path,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("ffmpeg: probing failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	info, err := parseProbe(string(out))
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+
+	d := &decoder{
+		ffmpeg:    ffmpeg,
+		path:      path,
+		info:      info,
+		frameSize: info.channels * 4,
+		limit:     info.sampleRate,
+		len:       int(math.Round(info.duration * float64(info.sampleRate))),
+		layout:    info.layout,
+		channels:  make([]float64, info.channels),
+	}
+	d.cond = sync.NewCond(&d.mu)
+	d.matrix = beep.DefaultDownmix.Matrix(d.layout, info.channels)
+	d.mu.Lock()
+	d.restart(0)
+	d.mu.Unlock()
+
+	format = beep.Format{
+		SampleRate:  beep.SampleRate(info.sampleRate),
+		NumChannels: info.channels,
+	}
+	if lossless[info.codec] {
+		format.Precision = (info.bits + 7) / 8
+	}
+	return d, format, nil
+}
+
+// probe is the part of the ffprobe output the decoder needs.
+type probe struct {
+	codec      string
+	sampleRate int
+	channels   int
+	layout     beep.ChannelMask
+	bits       int
+	duration   float64 // Seconds.
+}
+
+// parseProbe parses the key=value lines printed by ffprobe.
+func parseProbe(out string) (probe, error) {
+	var p probe
+	var streamDuration, formatDuration float64
+	seenDuration := false
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || value == "N/A" {
+			continue
+		}
+		switch key {
+		case "codec_name":
+			p.codec = value
+		case "sample_rate":
+			p.sampleRate, _ = strconv.Atoi(value)
+		case "channels":
+			p.channels, _ = strconv.Atoi(value)
+		case "channel_layout":
+			p.layout, _ = beep.ParseChannelLayout(value)
+		case "bits_per_raw_sample", "bits_per_sample":
+			if bits, err := strconv.Atoi(value); err == nil && bits > p.bits {
+				p.bits = bits
+			}
+		case "duration":
+			// The stream duration comes first, the container duration second.
+			v, _ := strconv.ParseFloat(value, 64)
+			if !seenDuration {
+				streamDuration = v
+				seenDuration = true
+			} else {
+				formatDuration = v
+			}
+		}
+	}
+
+	if p.sampleRate <= 0 || p.channels <= 0 {
+		return probe{}, errors.New("ffmpeg: no audio stream found")
+	}
+	if p.layout.Count() != p.channels {
+		p.layout = beep.DefaultChannelMask(p.channels)
+	}
+	p.duration = streamDuration
+	if p.duration <= 0 {
+		p.duration = formatDuration
+	}
+	return p, nil
+}
+
+type decoder struct {
+	ffmpeg    string
+	path      string
+	info      probe
+	frameSize int // Bytes per frame of ffmpeg's output.
+	limit     int // Frames buffered at most.
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte    // Output of ffmpeg from pos on.
+	cmd    *exec.Cmd // ffmpeg of the current generation, nil once it has exited.
+	gen    int       // Incremented when ffmpeg is restarted or stopped.
+	done   bool      // ffmpeg has exited and all of its output is in buf.
+	closed bool
+
+	len int
+	pos int
+	err error
+
+	// layout and matrix fold the channels into stereo; see beep.DefaultDownmix.
+	layout   beep.ChannelMask
+	matrix   beep.DownmixMatrix
+	channels []float64
+}
+
+// restart discards the buffered output, stops ffmpeg and starts it again from the given
+// frame in the background. d.mu must be held.
+func (d *decoder) restart(frame int) {
+	d.gen++
+	if d.cmd != nil {
+		d.cmd.Process.Kill()
+		d.cmd = nil
+	}
+	d.buf = nil
+	d.pos = frame
+	d.done = false
+	d.err = nil
+	d.cond.Broadcast()
+	go d.run(d.gen, frame)
+}
+
+// args returns the arguments that make ffmpeg write 32-bit float samples to its stdout,
+// from the given frame on.
+func (d *decoder) args(frame int) []string {
+	args := []string{"-v", "error", "-nostdin"}
+	if frame > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(float64(frame)/float64(d.info.sampleRate), 'f', 6, 64))
+	}
+	return append(args,
+		"-i", d.path,
+		"-map", "0:a:0",
+		"-f", "f32le",
+		"-acodec", "pcm_f32le",
+		"-ar", strconv.Itoa(d.info.sampleRate),
+		"-",
+	)
+}
+
+// run runs ffmpeg from the given frame and buffers its output until it exits or the
+// generation gen is over.
+func (d *decoder) run(gen, frame int) {
+	cmd := exec.Command(d.ffmpeg, d.args(frame)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+
+	d.mu.Lock()
+	if err != nil {
+		if d.gen == gen {
+			d.err = errors.Wrap(err, "ffmpeg")
+			d.done = true
+			d.cond.Broadcast()
+		}
+		d.mu.Unlock()
+		return
+	}
+	if d.gen != gen {
+		d.mu.Unlock()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return
+	}
+	d.cmd = cmd
+	d.mu.Unlock()
+
+	chunk := make([]byte, 64*1024)
+	for {
+		d.mu.Lock()
+		for d.gen == gen && len(d.buf) >= d.limit*d.frameSize {
+			d.cond.Wait()
+		}
+		stale := d.gen != gen
+		d.mu.Unlock()
+		if stale {
+			// restart or Close has killed ffmpeg.
+			cmd.Wait()
+			return
+		}
+
+		n, readErr := stdout.Read(chunk)
+
+		var waitErr error
+		if readErr != nil {
+			waitErr = cmd.Wait()
+		}
+		d.mu.Lock()
+		if d.gen != gen {
+			d.mu.Unlock()
+			if readErr == nil {
+				cmd.Wait()
+			}
+			return
+		}
+		d.buf = append(d.buf, chunk[:n]...)
+		if readErr != nil {
+			// ffmpeg has finished; a non-zero exit status means the file could not be decoded.
+			if waitErr != nil {
+				d.err = fmt.Errorf("ffmpeg: decoding failed: %v: %s", waitErr, strings.TrimSpace(stderr.String()))
+			} else if readErr != io.EOF {
+				d.err = errors.Wrap(readErr, "ffmpeg")
+			}
+			d.buf = d.buf[:len(d.buf)/d.frameSize*d.frameSize]
+			d.cmd = nil
+			d.done = true
+		}
+		d.cond.Broadcast()
+		d.mu.Unlock()
+		if readErr != nil {
+			return
+		}
+	}
+}
+
+// Ready reports whether n samples, or a second of audio if n is larger, can be streamed
+// without waiting for ffmpeg.
+func (d *decoder) Ready(n int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.done || d.closed || len(d.buf) >= min(n, d.limit)*d.frameSize
+}
+
+func (d *decoder) Stream(samples [][2]float64) (n int, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	want := min(len(samples), d.limit) * d.frameSize
+	for len(d.buf) < want && !d.done && !d.closed {
+		d.cond.Wait()
+	}
+	if d.closed || d.err != nil {
+		return 0, false
+	}
+
+	n = min(len(d.buf), want) / d.frameSize
+	for i := 0; i < n; i++ {
+		frame := d.buf[i*d.frameSize:]
+		for c := range d.channels {
+			d.channels[c] = float64(math.Float32frombits(binary.LittleEndian.Uint32(frame[c*4:])))
+		}
+		if d.layout == beep.LayoutStereo {
+			samples[i] = [2]float64{d.channels[0], d.channels[1]}
+		} else {
+			samples[i] = d.matrix.Apply(d.channels)
+		}
+	}
+	d.buf = d.buf[n*d.frameSize:]
+	d.pos += n
+	d.cond.Broadcast()
+
+	if d.done && len(d.buf) == 0 {
+		// The probed duration is an estimate; the end of the stream is the real length.
+		d.len = d.pos
+	}
+	return n, n > 0
+}
+
+func (d *decoder) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+func (d *decoder) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return max(d.len, d.pos)
+}
+
+func (d *decoder) Position() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pos
+}
+
+// Seek restarts ffmpeg at p in the background and returns without waiting for it.
+func (d *decoder) Seek(p int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if length := max(d.len, d.pos); p < 0 || length < p {
+		return fmt.Errorf("ffmpeg: seek position %v out of range [%v, %v]", p, 0, length)
+	}
+	d.restart(p)
+	return nil
+}
+
+// ChannelLayout returns the speaker layout of the source channels.
+func (d *decoder) ChannelLayout() beep.ChannelMask {
+	return d.layout
+}
+
+// Close stops ffmpeg. It does not wait for it to exit.
+func (d *decoder) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	d.gen++
+	if d.cmd != nil {
+		d.cmd.Process.Kill()
+		d.cmd = nil
+	}
+	d.cond.Broadcast()
+	return nil
+}
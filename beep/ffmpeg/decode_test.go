@@ -0,0 +1,130 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gopxl/beep/v2"
+)
+
+func TestParseProbe(t *testing.T) {
+	p, err := parseProbe(`codec_name=alac
+sample_rate=96000
+channels=6
+channel_layout=5.1(side)
+bits_per_sample=0
+bits_per_raw_sample=24
+duration=N/A
+duration=12.500000
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "alac", p.codec)
+	assert.Equal(t, 96000, p.sampleRate)
+	assert.Equal(t, 6, p.channels)
+	assert.Equal(t, beep.Layout5Point1Side, p.layout)
+	assert.Equal(t, 24, p.bits)
+	assert.Equal(t, 12.5, p.duration)
+}
+
+func TestParseProbe_UnknownLayout(t *testing.T) {
+	p, err := parseProbe("sample_rate=48000\nchannels=2\nchannel_layout=downmix\nduration=1.0\n")
+	require.NoError(t, err)
+	assert.Equal(t, beep.LayoutStereo, p.layout)
+}
+
+func TestParseProbe_NoAudio(t *testing.T) {
+	_, err := parseProbe("")
+	assert.Error(t, err)
+}
+
+func TestDecode_NotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, _, err := Decode("song.opus")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// installFakeTools puts ffprobe and ffmpeg scripts on PATH. ffmpeg runs the given shell
+// commands, then writes two stereo frames of 32-bit floats: (0.5, -0.5) and (0.25, 1).
+func installFakeTools(t *testing.T, before string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	ffprobe := "#!/bin/sh\nprintf 'codec_name=opus\\nsample_rate=48000\\nchannels=2\\nchannel_layout=stereo\\nduration=0.000042\\n'\n"
+	ffmpeg := "#!/bin/sh\n" + before + "\nprintf '\\000\\000\\000\\077\\000\\000\\000\\277\\000\\000\\200\\076\\000\\000\\200\\077'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(ffprobe), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(ffmpeg), 0o755))
+	t.Setenv("PATH", dir)
+}
+
+func TestDecode(t *testing.T) {
+	installFakeTools(t, "")
+
+	s, format, err := Decode("song.opus")
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, beep.Format{SampleRate: 48000, NumChannels: 2, Precision: 0}, format)
+	assert.Equal(t, 2, s.Len())
+
+	samples := make([][2]float64, 4)
+	n, ok := s.Stream(samples)
+	assert.True(t, ok)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, [2]float64{0.5, -0.5}, samples[0])
+	assert.Equal(t, [2]float64{0.25, 1}, samples[1])
+	assert.NoError(t, s.Err())
+
+	_, ok = s.Stream(samples)
+	assert.False(t, ok)
+
+	require.NoError(t, s.Seek(1))
+	assert.Equal(t, 1, s.Position())
+	n, _ = s.Stream(samples)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 3, s.Position())
+}
+
+func TestDecode_Ready(t *testing.T) {
+	installFakeTools(t, "exec sleep 10")
+
+	s, _, err := Decode("song.opus")
+	require.NoError(t, err)
+	defer s.Close()
+	d := s.(*decoder)
+
+	// ffmpeg sleeps instead of writing anything.
+	assert.False(t, d.Ready(1))
+
+	// Seeking does not wait for the new ffmpeg either.
+	require.NoError(t, s.Seek(0))
+	assert.False(t, d.Ready(1))
+	assert.Equal(t, 0, s.Position())
+
+	require.NoError(t, s.Close())
+	assert.True(t, d.Ready(1))
+	_, ok := s.Stream(make([][2]float64, 2))
+	assert.False(t, ok)
+}
+
+func TestDecode_ReadyAfterOutput(t *testing.T) {
+	installFakeTools(t, "")
+
+	s, _, err := Decode("song.opus")
+	require.NoError(t, err)
+	defer s.Close()
+	d := s.(*decoder)
+
+	assert.Eventually(t, func() bool { return d.Ready(2) }, 5*time.Second, 10*time.Millisecond)
+	samples := make([][2]float64, 2)
+	n, ok := s.Stream(samples)
+	assert.True(t, ok)
+	assert.Equal(t, 2, n)
+	assert.True(t, d.Ready(1), "a finished ffmpeg is always ready")
+}
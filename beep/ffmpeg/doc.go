@@ -0,0 +1,3 @@
+// Package ffmpeg implements audio data decoding of the formats beep has no native decoder
+// for (Opus, AAC, ALAC, WavPack, APE, ...) by running the ffmpeg and ffprobe tools.
+package ffmpeg
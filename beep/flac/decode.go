@@ -48,6 +48,8 @@ func Decode(r io.Reader) (s beep.StreamSeekCloser, format beep.Format, err error
 		NumChannels: int(d.stream.Info.NChannels),
 		Precision:   int(d.stream.Info.BitsPerSample / 8),
 	}
+	d.layout = beep.DefaultChannelMask(format.NumChannels)
+	d.matrix = beep.DefaultDownmix.Matrix(d.layout, format.NumChannels)
 	return &d, format, nil
 }
 
@@ -60,6 +62,11 @@ type decoder struct {
 	seekEnabled bool
 
 	hasFixedBlockSize bool
+
+	// layout and matrix fold the channels into stereo; see beep.DefaultDownmix.
+	layout   beep.ChannelMask
+	matrix   beep.DownmixMatrix
+	channels []float64
 }
 
 func (d *decoder) Stream(samples [][2]float64) (n int, ok bool) {
@@ -103,21 +110,31 @@ func (d *decoder) decodeFrameRangeInto(frame *frame.Frame, start, num int, into
 	s := 1 << (bps - 1)
 	q := 1 / float64(s)
 
-	if numChannels == 1 {
-		samples1 := frame.Subframes[0].Samples[start:]
-		for i := 0; i < num; i++ {
-			v := float64(samples1[i]) * q
-			into[i][0] = v
-			into[i][1] = v
-		}
-	} else {
+	if d.layout == beep.LayoutStereo {
 		samples1 := frame.Subframes[0].Samples[start:]
 		samples2 := frame.Subframes[1].Samples[start:]
 		for i := 0; i < num; i++ {
 			into[i][0] = float64(samples1[i]) * q
 			into[i][1] = float64(samples2[i]) * q
 		}
+		return
 	}
+
+	// Mono and multichannel sources go through the downmix matrix.
+	if len(d.channels) != int(numChannels) {
+		d.channels = make([]float64, numChannels)
+	}
+	for i := 0; i < num; i++ {
+		for c := range d.channels {
+			d.channels[c] = float64(frame.Subframes[c].Samples[start+i]) * q
+		}
+		into[i] = d.matrix.Apply(d.channels)
+	}
+}
+
+// ChannelLayout returns the speaker layout of the source channels.
+func (d *decoder) ChannelLayout() beep.ChannelMask {
+	return d.layout
 }
 
 func (d *decoder) Err() error {
@@ -165,9 +182,11 @@ func (d *decoder) Seek(p int) error {
 					return errors.Wrap(err, "flac")
 				}
 				// Calculate the frame start position manually, because this doesn't
-				// work for the last frame.
+				// work for the last frame. The last frame may be shorter than
+				// BlockSizeMax, so p can also be in the frame before it.
 				frameStart := d.frame.Num * uint64(d.stream.Info.BlockSizeMax)
-				if frameStart+uint64(d.frame.BlockSize) >= d.stream.Info.NSamples {
+				frameEnd := frameStart + uint64(d.frame.BlockSize)
+				if uint64(p) < frameEnd || frameEnd >= d.stream.Info.NSamples {
 					// Found the desired frame.
 					d.posInFrame = p - int(frameStart)
 					return nil
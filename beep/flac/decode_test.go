@@ -119,6 +119,21 @@ func TestDecoder_Seek(t *testing.T) {
 	flacSamples = testtools.CollectNum(100, flacStream)
 	testtools.AssertSamplesEqual(t, wavSamples, flacSamples)
 
+	// Test end of the frame before the last one, which is within BlockSizeMax
+	// of the end of the stream when the last frame is short. The start of the
+	// last frame is unreliable (see Seek), so use the fixed block size instead.
+	seekPos = int(frameStarts[len(frameStarts)-2]+frameStarts[1]) - 1
+	err = wavStream.Seek(seekPos)
+	assert.NoError(t, err)
+	assert.Equal(t, seekPos, wavStream.Position())
+	err = flacStream.Seek(seekPos)
+	assert.NoError(t, err)
+	assert.Equal(t, seekPos, flacStream.Position())
+
+	wavSamples = testtools.CollectNum(100, wavStream)
+	flacSamples = testtools.CollectNum(100, flacStream)
+	testtools.AssertSamplesEqual(t, wavSamples, flacSamples)
+
 	// Test end of stream.
 	seekPos = wavStream.Len() - 1
 	err = wavStream.Seek(seekPos)
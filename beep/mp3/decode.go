@@ -2,8 +2,11 @@
 package mp3
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"math"
+	"sync/atomic"
 
 	gomp3 "github.com/hajimehoshi/go-mp3"
 	"github.com/pkg/errors"
@@ -17,8 +20,23 @@ const (
 	gomp3BytesPerFrame = gomp3NumChannels * gomp3Precision
 )
 
+// seekPreroll is the number of frames decoded and dropped before the seek position. A
+// frame can use up to 511 bytes of the frames before it (the bit reservoir), which at
+// low bitrates spans several frames, and go-mp3 only primes one frame when it seeks.
+const seekPreroll = 10
+
 // Decode takes a ReadCloser containing audio data in MP3 format and returns a StreamSeekCloser,
-// which streams that audio. The Seek method will panic if rc is not io.Seeker.
+// which streams that audio. The Seek method will return an error if rc is not io.Seeker.
+//
+// If the file has a Xing/Info or VBRI header, its frame count gives the length, and the
+// encoder delay and padding from the LAME tag are trimmed so that gapless albums play
+// sample-accurately. Files without a header whose first frames have the same bitrate get a
+// length estimated from the file size, which is corrected when the end is reached. Either
+// way the file is not read in full before playback starts. If rc is an io.ReaderAt, an
+// index of the frames is built in the background, through ReadAt so that the stream is
+// not disturbed, and seeking is exact once it is ready. Until then, seeking uses the Xing
+// TOC, or the position in proportion to the file size for files without one, so Seek
+// never reads more than a few frames.
 //
 // Do not close the supplied ReadSeekCloser, instead, use the Close method of the returned
 // StreamSeekCloser when you want to release the resources.
@@ -28,48 +46,141 @@ func Decode(rc io.ReadCloser) (s beep.StreamSeekCloser, format beep.Format, err
 			err = errors.Wrap(err, "mp3")
 		}
 	}()
-	d, err := gomp3.NewDecoder(rc)
+
+	d := &decoder{rc: rc, len: -1}
+	var r io.Reader = rc
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		d.vbr, err = readVBRHeader(rs)
+		if err != nil {
+			return nil, beep.Format{}, err
+		}
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return nil, beep.Format{}, err
+		}
+		if d.vbr != nil && d.vbr.frames > 0 {
+			// The header gives the length, so go-mp3 does not need to read the whole
+			// file to count the frames; the index is built when it is needed.
+			r = readerOnly{rc}
+		}
+	}
+
+	d.d, err = gomp3.NewDecoder(r)
 	if err != nil {
 		return nil, beep.Format{}, err
 	}
-	format = beep.Format{
-		SampleRate:  beep.SampleRate(d.SampleRate()),
+	d.indexed = d.d.Length() >= 0
+
+	if d.vbr != nil {
+		d.skip = d.vbr.skip()
+		if d.vbr.frames > 0 {
+			d.len = d.vbr.samples()
+			d.estimated = d.vbr.estimated
+		}
+	}
+	if d.indexed {
+		d.clampLen()
+	} else if ra, ok := rc.(io.ReaderAt); ok && d.vbr != nil {
+		d.indexDone = make(chan struct{})
+		go d.indexFrames(ra, d.vbr.start)
+	}
+	d.discard = d.skip
+
+	d.f = beep.Format{
+		SampleRate:  beep.SampleRate(d.d.SampleRate()),
 		NumChannels: gomp3NumChannels,
 		Precision:   gomp3Precision,
 	}
-	return &decoder{rc, d, format, 0, nil}, format, nil
+	return d, d.f, nil
+}
+
+// readerOnly hides the Seek method of a reader from go-mp3.
+type readerOnly struct {
+	io.Reader
 }
 
 type decoder struct {
-	closer io.Closer
-	d      *gomp3.Decoder
-	f      beep.Format
-	pos    int
-	err    error
+	rc  io.ReadCloser
+	d   *gomp3.Decoder
+	f   beep.Format
+	vbr *vbrHeader
+
+	// indexed is true if go-mp3 has an index of the frames and can seek exactly, which it
+	// builds when the file has no header giving its length.
+	indexed bool
+
+	// frames holds the offsets of the frames once indexFrames has found them all, for
+	// exact seeking in files go-mp3 has no index of. indexDone is closed when it is done.
+	frames    atomic.Pointer[[]int64]
+	indexDone chan struct{}
+
+	skip    int // Samples of decoder output before the first sample of the audio.
+	discard int // Samples of decoder output to drop before the sample at pos.
+	len     int // Length in samples; -1 if unknown.
+	pos     int
+	buf     []byte
+	err     error
+
+	// estimated is set while len is estimated from the file size. The stream is not cut
+	// at an estimated length.
+	estimated bool
 }
 
 func (d *decoder) Stream(samples [][2]float64) (n int, ok bool) {
 	if d.err != nil {
 		return 0, false
 	}
-	var tmp [gomp3BytesPerFrame]byte
-	for i := range samples {
-		dn, err := d.d.Read(tmp[:])
-		if dn == len(tmp) {
-			samples[i], _ = d.f.DecodeSigned(tmp[:])
-			d.pos += dn
-			n++
-			ok = true
-		}
-		if err == io.EOF {
-			break
-		}
+
+	// Drop the header frame and the encoder and decoder delay, or the preroll of a seek.
+	for d.discard > 0 {
+		skipped, err := d.read(min(d.discard, 1152))
+		d.discard -= skipped
 		if err != nil {
-			d.err = errors.Wrap(err, "mp3")
-			break
+			d.handleReadError(err)
+			return 0, false
 		}
 	}
-	return n, ok
+
+	if d.len >= 0 && !d.estimated {
+		samples = samples[:min(len(samples), max(d.len-d.pos, 0))]
+	}
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	n, err := d.read(len(samples))
+	for i := 0; i < n; i++ {
+		samples[i], _ = d.f.DecodeSigned(d.buf[i*gomp3BytesPerFrame:])
+	}
+	d.pos += n
+	if d.estimated && d.pos > d.len {
+		d.len = d.pos
+	}
+	if err != nil {
+		d.handleReadError(err)
+	}
+	return n, n > 0
+}
+
+// read reads up to n samples of decoder output into d.buf.
+func (d *decoder) read(n int) (int, error) {
+	if cap(d.buf) < n*gomp3BytesPerFrame {
+		d.buf = make([]byte, n*gomp3BytesPerFrame)
+	}
+	read, err := io.ReadFull(d.d, d.buf[:n*gomp3BytesPerFrame])
+	return read / gomp3BytesPerFrame, err
+}
+
+// handleReadError records a decoding error. At the end of the data, the length is
+// corrected if the file is shorter than its header says.
+func (d *decoder) handleReadError(err error) {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if d.len < 0 || d.pos < d.len || d.estimated {
+			d.len = d.pos
+			d.estimated = false
+		}
+		return
+	}
+	d.err = errors.Wrap(err, "mp3")
 }
 
 func (d *decoder) Err() error {
@@ -77,27 +188,176 @@ func (d *decoder) Err() error {
 }
 
 func (d *decoder) Len() int {
-	return int(d.d.Length()) / gomp3BytesPerFrame
+	return max(d.len, 0)
 }
 
 func (d *decoder) Position() int {
-	return d.pos / gomp3BytesPerFrame
+	return d.pos
 }
 
 func (d *decoder) Seek(p int) error {
 	if p < 0 || d.Len() < p {
 		return fmt.Errorf("mp3: seek position %v out of range [%v, %v]", p, 0, d.Len())
 	}
-	_, err := d.d.Seek(int64(p)*gomp3BytesPerFrame, io.SeekStart)
+	rs, ok := d.rc.(io.ReadSeeker)
+	if !ok {
+		return errors.New("mp3: seek: resource is not io.Seeker")
+	}
+
+	if !d.indexed {
+		if frames := d.frames.Load(); frames != nil {
+			if ok, err := d.seekFrame(rs, *frames, p); ok || err != nil {
+				return err
+			}
+		}
+		return d.seekApprox(rs, p)
+	}
+
+	// go-mp3 cannot seek to the end of its output, so seeking to the end of the
+	// stream only moves the position.
+	raw := p + d.skip
+	start := max(raw-seekPreroll*d.samplesPerFrame(), 0)
+	if int64(start)*gomp3BytesPerFrame < d.d.Length() {
+		if _, err := d.d.Seek(int64(start)*gomp3BytesPerFrame, io.SeekStart); err != nil {
+			return errors.Wrap(err, "mp3")
+		}
+		d.discard = raw - start
+	}
+	d.pos = p
+	d.err = nil
+	return nil
+}
+
+// samplesPerFrame returns the number of samples in a frame: 1152 for MPEG-1 and 576 for
+// the lower sample rates of MPEG-2 and 2.5.
+func (d *decoder) samplesPerFrame() int {
+	if d.vbr != nil {
+		return d.vbr.samplesPerFrame
+	}
+	if d.d.SampleRate() < 32000 {
+		return 576
+	}
+	return 1152
+}
+
+// indexFrames finds the offsets of the frames from start to the end of the file, reading
+// it through ra in the background, and publishes them in d.frames. Only the frame headers
+// are parsed. It stops at the first byte that is not a frame, e.g. an ID3v1 tag.
+func (d *decoder) indexFrames(ra io.ReaderAt, start int64) {
+	defer close(d.indexDone)
+
+	r := bufio.NewReaderSize(io.NewSectionReader(ra, start, math.MaxInt64-start), 64<<10)
+	var frames []int64
+	offset := start
+	for {
+		b, err := r.Peek(4)
+		if err != nil {
+			break
+		}
+		h, ok := parseFrameHeader(b)
+		if !ok {
+			break
+		}
+		frames = append(frames, offset)
+		if _, err := r.Discard(h.size); err != nil {
+			break
+		}
+		offset += int64(h.size)
+	}
+	d.frames.Store(&frames)
+}
+
+// seekFrame seeks exactly with the offsets of the frames. It returns false if the frames
+// needed are not in the index, e.g. because it stopped at a damaged frame.
+func (d *decoder) seekFrame(rs io.ReadSeeker, frames []int64, p int) (bool, error) {
+	if p == d.len && !d.estimated {
+		// Nothing is left to decode.
+		d.pos = p
+		return true, nil
+	}
+
+	raw := p + d.skip
+	first := max(raw/d.samplesPerFrame()-seekPreroll, 0)
+	if first >= len(frames) {
+		return false, nil
+	}
+	if _, err := rs.Seek(frames[first], io.SeekStart); err != nil {
+		return true, errors.Wrap(err, "mp3")
+	}
+	dec, err := gomp3.NewDecoder(readerOnly{rs})
+	if err != nil {
+		return true, errors.Wrap(err, "mp3")
+	}
+	d.d = dec
+	d.discard = raw - first*d.samplesPerFrame()
+	d.pos = p
+	d.err = nil
+	return true, nil
+}
+
+// clampLen makes sure the length does not go beyond the decoder output, which can happen
+// when the padding is shorter than the decoder delay. An estimated length is replaced by
+// the exact one.
+func (d *decoder) clampLen() {
+	out := int(d.d.Length()/gomp3BytesPerFrame) - d.skip
+	if d.len < 0 || out < d.len || d.estimated {
+		d.len = max(out, 0)
+		d.estimated = false
+	}
+}
+
+// seekApprox seeks with the Xing TOC, which is accurate to about 1/256 of the file, or for
+// files without one, to the position in proportion to the size of the audio data, which is
+// close for CBR files.
+func (d *decoder) seekApprox(rs io.ReadSeeker, p int) error {
+	if d.vbr == nil || d.len <= 0 {
+		return errors.New("mp3: seek: the file has no frame index and no length")
+	}
+
+	size := d.vbr.bytes
+	if size <= 0 {
+		end, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return errors.Wrap(err, "mp3")
+		}
+		size = end - d.vbr.start
+	}
+	if p == d.len {
+		// Nothing is left to decode.
+		d.pos = p
+		return nil
+	}
+
+	preroll := min(p, seekPreroll*d.vbr.samplesPerFrame)
+	offset := d.vbr.byteOffset(float64(p-preroll)/float64(d.len), size)
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return errors.Wrap(err, "mp3")
+	}
+	buf := make([]byte, 8192)
+	n, err := io.ReadFull(rs, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return errors.Wrap(err, "mp3")
+	}
+	i := syncFrame(buf[:n])
+	if i < 0 {
+		return errors.New("mp3: seek: no frame found at the TOC position")
+	}
+	if _, err := rs.Seek(offset+int64(i), io.SeekStart); err != nil {
+		return errors.Wrap(err, "mp3")
+	}
+	dec, err := gomp3.NewDecoder(readerOnly{rs})
 	if err != nil {
 		return errors.Wrap(err, "mp3")
 	}
-	d.pos = p * gomp3BytesPerFrame
+	d.d = dec
+	d.discard = preroll
+	d.pos = p
+	d.err = nil
 	return nil
 }
 
 func (d *decoder) Close() error {
-	err := d.closer.Close()
+	err := d.rc.Close()
 	if err != nil {
 		return errors.Wrap(err, "mp3")
 	}
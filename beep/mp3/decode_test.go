@@ -1,13 +1,16 @@
 package mp3_test
 
 import (
+	"math"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/gopxl/beep/v2/internal/testtools"
 	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/wav"
 )
 
 func TestDecoder_ReturnBehaviour(t *testing.T) {
@@ -17,9 +20,62 @@ func TestDecoder_ReturnBehaviour(t *testing.T) {
 
 	s, _, err := mp3.Decode(f)
 	assert.NoError(t, err)
-	// The length of the streamer isn't tested because mp3 files have
-	// a different padding depending on the decoder used.
-	// https://superuser.com/a/1393775
 
 	testtools.AssertStreamerHasCorrectReturnBehaviour(t, s, s.Len())
 }
+
+func TestDecoder_GaplessLength(t *testing.T) {
+	f, err := os.Open(testtools.TestFilePath("valid_44100hz_x_padded_samples.mp3"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, _, err := mp3.Decode(f)
+	require.NoError(t, err)
+
+	// The encoder delay and padding from the LAME tag are trimmed, so the
+	// length matches the source of the file.
+	// https://superuser.com/a/1393775
+	assert.Equal(t, 22050, s.Len())
+	mp3Samples := testtools.Collect(s)
+	assert.Len(t, mp3Samples, 22050)
+	assert.Equal(t, 22050, s.Position())
+
+	// Compare with the lossless source to check that the audio is not shifted.
+	wavFile, err := os.Open(testtools.TestFilePath("valid_44100hz_22050_samples.wav"))
+	require.NoError(t, err)
+	defer wavFile.Close()
+	wavStream, _, err := wav.Decode(wavFile)
+	require.NoError(t, err)
+	wavSamples := testtools.Collect(wavStream)
+
+	var sum float64
+	for i := range wavSamples {
+		d := mp3Samples[i][0] - wavSamples[i][0]
+		sum += d * d
+	}
+	assert.Less(t, math.Sqrt(sum/float64(len(wavSamples))), 0.03)
+}
+
+func TestDecoder_Seek(t *testing.T) {
+	f, err := os.Open(testtools.TestFilePath("valid_44100hz_x_padded_samples.mp3"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, _, err := mp3.Decode(f)
+	require.NoError(t, err)
+	mp3.WaitIndex(s)
+	all := testtools.Collect(s)
+
+	// Seeking is sample-accurate, also right after the header frame and within the
+	// bit reservoir of earlier frames.
+	for _, p := range []int{0, 1, 1000, 10000, 12345, s.Len() - 100} {
+		require.NoError(t, s.Seek(p))
+		assert.Equal(t, p, s.Position())
+		samples := testtools.CollectNum(100, s)
+		assert.Equal(t, all[p:p+100], samples, "position %d", p)
+	}
+
+	require.NoError(t, s.Seek(s.Len()))
+	_, ok := s.Stream(make([][2]float64, 10))
+	assert.False(t, ok)
+}
@@ -0,0 +1,10 @@
+package mp3
+
+import "github.com/gopxl/beep/v2"
+
+// WaitIndex waits until the frames of a decoder returned by Decode are indexed.
+func WaitIndex(s beep.StreamSeekCloser) {
+	if d := s.(*decoder); d.indexDone != nil {
+		<-d.indexDone
+	}
+}
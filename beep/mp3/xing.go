@@ -0,0 +1,281 @@
+package mp3
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// decoderDelay is the number of samples an MP3 decoder outputs before the first sample of
+// the encoded audio, caused by the synthesis filterbank.
+const decoderDelay = 529
+
+// vbrHeader holds the information of the Xing/Info or VBRI header that most encoders
+// write in place of the first audio frame.
+type vbrHeader struct {
+	start           int64  // Offset of the frame holding the header.
+	frames          int    // Number of audio frames, not counting the header frame.
+	bytes           int64  // Size of the MP3 data including the header frame; 0 if unknown.
+	toc             []byte // Xing TOC: offsets at each percent of the duration in 1/256 of bytes.
+	samplesPerFrame int
+
+	// estimated is set for files without a header, whose frame count is estimated from
+	// the file size and the bitrate of the first frames.
+	estimated bool
+
+	// The LAME tag gives the encoder delay and the padding added to the last frame,
+	// which are trimmed for gapless playback.
+	hasLAME bool
+	delay   int
+	padding int
+}
+
+// readVBRHeader looks for a Xing/Info or VBRI header in the first frame of r. Files without
+// such a header are assumed to be CBR if their first frames have the same bitrate, and get
+// an estimated frame count. It returns nil if the length cannot be known without reading
+// the whole file.
+func readVBRHeader(r io.ReadSeeker) (*vbrHeader, error) {
+	var id3 [10]byte
+	n, err := io.ReadFull(r, id3[:])
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	var offset int64
+	if n == len(id3) && string(id3[0:3]) == "ID3" {
+		offset = 10 + int64(id3[6]&0x7f)<<21 | int64(id3[7]&0x7f)<<14 | int64(id3[8]&0x7f)<<7 | int64(id3[9]&0x7f)
+		if id3[5]&0x10 != 0 {
+			offset += 10 // Footer.
+		}
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Enough for the header and, for files without one, a dozen frames to check the
+	// bitrate of.
+	buf := make([]byte, 16384)
+	n, err = io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	buf = buf[:n]
+
+	i := syncFrame(buf)
+	if i < 0 {
+		return nil, nil
+	}
+	h, _ := parseFrameHeader(buf[i:])
+	if v := parseVBRFrame(buf[i:], h); v != nil {
+		v.start = offset + int64(i)
+		return v, nil
+	}
+	if !constantBitrate(buf[i:]) {
+		return nil, nil
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	return estimateCBR(h, offset+int64(i), end), nil
+}
+
+// constantBitrate reports whether all frames in b have the same bitrate as the first one.
+func constantBitrate(b []byte) bool {
+	first, _ := parseFrameHeader(b)
+	for i := 0; i+4 <= len(b); {
+		h, ok := parseFrameHeader(b[i:])
+		if !ok || h.bitrate != first.bitrate || h.sampleRate != first.sampleRate {
+			return false
+		}
+		i += h.size
+	}
+	return true
+}
+
+// estimateCBR returns a header with the estimated frame count of a CBR file whose audio
+// goes from start to end. Tags at the end of the file make the estimate slightly too long.
+func estimateCBR(h frameHeader, start, end int64) *vbrHeader {
+	v := &vbrHeader{start: start, samplesPerFrame: h.samplesPerFrame(), estimated: true}
+	// The average frame size; the padding bit makes single frames one byte longer.
+	frameSize := float64(v.samplesPerFrame) * 125 * float64(h.bitrate) / float64(h.sampleRate)
+	v.bytes = end - start
+	v.frames = int(math.Round(float64(v.bytes) / frameSize))
+	return v
+}
+
+// Layer III bitrates in kbit/s and sample rates in Hz, by index.
+var (
+	bitratesMPEG1    = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	bitratesMPEG2    = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	sampleRatesMPEG1 = [3]int{44100, 48000, 32000}
+)
+
+// frameHeader is the part of an MPEG audio frame header needed to find the VBR header
+// and the frame boundaries.
+type frameHeader struct {
+	mpeg1      bool
+	mono       bool
+	sampleRate int
+	bitrate    int // In kbit/s.
+	size       int // Size of the frame in bytes, including the header.
+}
+
+// parseFrameHeader parses the 4-byte header of a Layer III frame.
+func parseFrameHeader(b []byte) (frameHeader, bool) {
+	if b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return frameHeader{}, false
+	}
+	version := b[1] >> 3 & 3
+	layer := b[1] >> 1 & 3
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := b[2] >> 2 & 3
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return frameHeader{}, false
+	}
+
+	h := frameHeader{
+		mpeg1:      version == 3,
+		mono:       b[3]>>6 == 3,
+		sampleRate: sampleRatesMPEG1[sampleRateIndex],
+	}
+	padding := int(b[2] >> 1 & 1)
+	if h.mpeg1 {
+		h.bitrate = bitratesMPEG1[bitrateIndex]
+		h.size = 144000*h.bitrate/h.sampleRate + padding
+	} else {
+		// MPEG-2 halves the sample rates of MPEG-1 and MPEG-2.5 halves them again.
+		h.sampleRate /= 2
+		if version == 0 {
+			h.sampleRate /= 2
+		}
+		h.bitrate = bitratesMPEG2[bitrateIndex]
+		h.size = 72000*h.bitrate/h.sampleRate + padding
+	}
+	return h, true
+}
+
+// samplesPerFrame returns the number of samples in a frame: 1152 for MPEG-1 and 576 for
+// MPEG-2 and 2.5.
+func (h frameHeader) samplesPerFrame() int {
+	if h.mpeg1 {
+		return 1152
+	}
+	return 576
+}
+
+// syncFrame returns the offset of the first frame in b that is followed by another frame
+// with the same sample rate, or -1. Checking the next frame avoids false syncs in the
+// middle of the audio data.
+func syncFrame(b []byte) int {
+	for i := 0; i+4 <= len(b); i++ {
+		h, ok := parseFrameHeader(b[i:])
+		if !ok {
+			continue
+		}
+		next := i + h.size
+		if next+4 > len(b) {
+			// The last frame of the file has no frame after it.
+			if next == len(b) {
+				return i
+			}
+			return -1
+		}
+		if n, ok := parseFrameHeader(b[next:]); ok && n.sampleRate == h.sampleRate {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseVBRFrame parses the Xing/Info or VBRI header in a frame, if there is one.
+func parseVBRFrame(frame []byte, h frameHeader) *vbrHeader {
+	v := &vbrHeader{samplesPerFrame: h.samplesPerFrame()}
+
+	// The Xing header comes right after the side information.
+	xing := 4 + 32
+	switch {
+	case h.mpeg1 && h.mono, !h.mpeg1 && !h.mono:
+		xing = 4 + 17
+	case !h.mpeg1 && h.mono:
+		xing = 4 + 9
+	}
+	if len(frame) >= xing+8 && (string(frame[xing:xing+4]) == "Xing" || string(frame[xing:xing+4]) == "Info") {
+		p := frame[xing+4:]
+		flags := binary.BigEndian.Uint32(p)
+		p = p[4:]
+		if flags&1 != 0 && len(p) >= 4 {
+			v.frames = int(binary.BigEndian.Uint32(p))
+			p = p[4:]
+		}
+		if flags&2 != 0 && len(p) >= 4 {
+			v.bytes = int64(binary.BigEndian.Uint32(p))
+			p = p[4:]
+		}
+		if flags&4 != 0 && len(p) >= 100 {
+			v.toc = append([]byte(nil), p[:100]...)
+			p = p[100:]
+		}
+		if flags&8 != 0 && len(p) >= 4 {
+			p = p[4:] // Quality.
+		}
+
+		// The LAME tag starts with the encoder name; FFmpeg writes the same layout.
+		if len(p) >= 24 && (string(p[0:4]) == "LAME" || string(p[0:4]) == "Lavc" || string(p[0:4]) == "Lavf") {
+			v.hasLAME = true
+			v.delay = int(p[21])<<4 | int(p[22])>>4
+			v.padding = int(p[22]&0x0f)<<8 | int(p[23])
+		}
+		return v
+	}
+
+	// The VBRI header written by the Fraunhofer encoder is always 32 bytes after the
+	// frame header.
+	const vbri = 4 + 32
+	if len(frame) >= vbri+18 && string(frame[vbri:vbri+4]) == "VBRI" {
+		p := frame[vbri+4:]
+		v.bytes = int64(binary.BigEndian.Uint32(p[6:10]))
+		v.frames = int(binary.BigEndian.Uint32(p[10:14]))
+		return v
+	}
+	return nil
+}
+
+// samples returns the number of samples of the encoded audio, without the encoder delay
+// and padding.
+func (v *vbrHeader) samples() int {
+	n := v.frames * v.samplesPerFrame
+	if v.hasLAME {
+		n -= v.delay + v.padding
+	}
+	return max(n, 0)
+}
+
+// skip returns the number of samples the decoder outputs before the first sample of the
+// encoded audio: the silent header frame and, if known, the encoder and decoder delay.
+func (v *vbrHeader) skip() int {
+	if v.estimated {
+		return 0
+	}
+	n := v.samplesPerFrame
+	if v.hasLAME {
+		n += v.delay + decoderDelay
+	}
+	return n
+}
+
+// byteOffset returns the approximate byte offset of the given fraction of the duration
+// using the Xing TOC, or the same fraction of size if there is no TOC.
+func (v *vbrHeader) byteOffset(fraction float64, size int64) int64 {
+	percent := min(max(fraction*100, 0), 100)
+	if v.toc == nil {
+		return v.start + int64(percent/100*float64(size))
+	}
+	i := min(int(percent), 99)
+	a := float64(v.toc[i])
+	b := 256.0
+	if i < 99 {
+		b = float64(v.toc[i+1])
+	}
+	pos := (a + (b-a)*(percent-float64(i))) / 256
+	return v.start + int64(pos*float64(size))
+}
@@ -0,0 +1,192 @@
+package mp3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gopxl/beep/v2/internal/testtools"
+)
+
+func TestReadVBRHeader(t *testing.T) {
+	f, err := os.Open(testtools.TestFilePath("valid_44100hz_x_padded_samples.mp3"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	v, err := readVBRHeader(f)
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.Equal(t, int64(45), v.start) // After the ID3v2 tag.
+	assert.Equal(t, 21, v.frames)
+	assert.Equal(t, int64(4570), v.bytes)
+	assert.Len(t, v.toc, 100)
+	assert.Equal(t, 1152, v.samplesPerFrame)
+	assert.True(t, v.hasLAME)
+	assert.Equal(t, 576, v.delay)
+	assert.Equal(t, 1566, v.padding)
+	assert.Equal(t, 22050, v.samples())
+	assert.Equal(t, 1152+576+529, v.skip())
+}
+
+func TestParseVBRFrame_VBRI(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbit/s, 44.1 kHz, joint stereo.
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x44})
+	vbri := frame[36:]
+	copy(vbri, "VBRI")
+	binary.BigEndian.PutUint16(vbri[4:], 1)       // Version.
+	binary.BigEndian.PutUint32(vbri[10:], 123456) // Bytes.
+	binary.BigEndian.PutUint32(vbri[14:], 1000)   // Frames.
+
+	h, ok := parseFrameHeader(frame)
+	require.True(t, ok)
+	assert.Equal(t, 417, h.size)
+	assert.Equal(t, 44100, h.sampleRate)
+
+	v := parseVBRFrame(frame, h)
+	require.NotNil(t, v)
+	assert.Equal(t, 1000, v.frames)
+	assert.Equal(t, int64(123456), v.bytes)
+	assert.False(t, v.hasLAME)
+	assert.Nil(t, v.toc)
+	assert.Equal(t, 1152000, v.samples())
+	assert.Equal(t, 1152, v.skip())
+}
+
+func TestParseVBRFrame_None(t *testing.T) {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x44})
+	h, _ := parseFrameHeader(frame)
+	assert.Nil(t, parseVBRFrame(frame, h))
+}
+
+func TestSyncFrame(t *testing.T) {
+	header := []byte{0xff, 0xfb, 0x90, 0x44} // 417 bytes per frame.
+	var b bytes.Buffer
+	b.Write([]byte{0x00, 0xff, 0xfb, 0x90, 0x44, 0x00}) // A false sync.
+	b.Write(header)
+	b.Write(make([]byte, 413))
+	b.Write(header)
+	b.Write(make([]byte, 413))
+	assert.Equal(t, 6, syncFrame(b.Bytes()))
+	assert.Equal(t, -1, syncFrame(make([]byte, 100)))
+}
+
+func TestDecoder_SeekTOC(t *testing.T) {
+	f, err := os.Open(testtools.TestFilePath("valid_44100hz_x_padded_samples.mp3"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	// Without ReadAt the frames are not indexed, so the TOC is used.
+	s, _, err := Decode(nopSeekCloser{f})
+	require.NoError(t, err)
+	d := s.(*decoder)
+	assert.Nil(t, d.indexDone)
+
+	require.NoError(t, d.Seek(10000))
+	assert.Equal(t, 10000, d.Position())
+	samples := testtools.Collect(d)
+	assert.NoError(t, d.Err())
+	// The TOC is approximate, but the stream still ends at the end of the audio.
+	assert.InDelta(t, 22050-10000, len(samples), 2*1152)
+
+	require.NoError(t, d.Seek(d.Len()))
+	_, ok := d.Stream(make([][2]float64, 10))
+	assert.False(t, ok)
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// headerlessMP3 returns the test file without its Info frame, as a CBR file without a
+// header.
+func headerlessMP3(t *testing.T) []byte {
+	b, err := os.ReadFile(testtools.TestFilePath("valid_44100hz_x_padded_samples.mp3"))
+	require.NoError(t, err)
+	h, ok := parseFrameHeader(b[45:])
+	require.True(t, ok)
+	return append(b[:45:45], b[45+h.size:]...)
+}
+
+func TestReadVBRHeader_CBREstimate(t *testing.T) {
+	v, err := readVBRHeader(bytes.NewReader(headerlessMP3(t)))
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.True(t, v.estimated)
+	assert.Equal(t, 21, v.frames)
+	assert.Equal(t, 0, v.skip())
+}
+
+func TestConstantBitrate(t *testing.T) {
+	b := headerlessMP3(t)[45:]
+	assert.True(t, constantBitrate(b))
+	// A frame with a different bitrate, as in VBR files.
+	vbr := append([]byte(nil), b...)
+	vbr[2] = vbr[2]&0x0f | 0x90
+	assert.False(t, constantBitrate(vbr))
+}
+
+func TestDecoder_EstimatedLength(t *testing.T) {
+	s, _, err := Decode(nopSeekCloser{bytes.NewReader(headerlessMP3(t))})
+	require.NoError(t, err)
+	d := s.(*decoder)
+	assert.True(t, d.estimated)
+	assert.Equal(t, 21*1152, d.Len())
+
+	// Playing to the end gives the real length.
+	d.len = 10000
+	samples := testtools.Collect(d)
+	assert.Len(t, samples, 21*1152)
+	assert.Equal(t, 21*1152, d.Len())
+	assert.False(t, d.estimated)
+}
+
+func TestDecoder_EstimatedLengthSeek(t *testing.T) {
+	s, _, err := Decode(nopSeekCloser{bytes.NewReader(headerlessMP3(t))})
+	require.NoError(t, err)
+	d := s.(*decoder)
+	d.len = 10000
+
+	// Without a TOC, the seek goes to the same fraction of the file, and the length stays
+	// estimated until the end is reached.
+	require.NoError(t, d.Seek(5000))
+	assert.Equal(t, 5000, d.Position())
+	assert.True(t, d.estimated)
+	samples := testtools.Collect(d)
+	assert.NoError(t, d.Err())
+	assert.InDelta(t, 21*1152-5000, len(samples), 2*1152)
+	assert.False(t, d.estimated)
+}
+
+func TestDecoder_IndexFrames(t *testing.T) {
+	f, err := os.Open(testtools.TestFilePath("valid_44100hz_x_padded_samples.mp3"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	s, _, err := Decode(f)
+	require.NoError(t, err)
+	d := s.(*decoder)
+	require.NotNil(t, d.indexDone)
+	<-d.indexDone
+
+	// The Info frame and the 21 audio frames, back to back after the ID3v2 tag.
+	frames := *d.frames.Load()
+	require.Len(t, frames, 22)
+	assert.Equal(t, int64(45), frames[0])
+	var b [4]byte
+	for i := 1; i < len(frames); i++ {
+		_, err := f.ReadAt(b[:], frames[i-1])
+		require.NoError(t, err)
+		h, ok := parseFrameHeader(b[:])
+		require.True(t, ok)
+		assert.Equal(t, frames[i-1]+int64(h.size), frames[i])
+	}
+}
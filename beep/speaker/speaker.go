@@ -2,8 +2,12 @@
 package speaker
 
 import (
+	"encoding/binary"
 	"io"
+	"math"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ebitengine/oto/v3"
@@ -14,9 +18,66 @@ import (
 )
 
 const channelCount = 2
-const bitDepthInBytes = 2
-const bytesPerSample = bitDepthInBytes * channelCount
-const otoFormat = oto.FormatSignedInt16LE
+
+// OutputFormat is the sample format the speaker hands to the audio driver.
+type OutputFormat int
+
+const (
+	// OutputInt16 is 16-bit signed integer output. It is the default.
+	OutputInt16 OutputFormat = iota
+	// OutputInt24 is packed 24-bit signed integer output.
+	OutputInt24
+	// OutputFloat32 is 32-bit float output. It passes the mixed samples on without
+	// quantization, so sources of any bit depth up to 24 bits are reproduced exactly.
+	OutputFloat32
+)
+
+// String returns a human readable name of the format, like "24-bit" or "32-bit float".
+func (f OutputFormat) String() string {
+	switch f {
+	case OutputInt24:
+		return "24-bit"
+	case OutputFloat32:
+		return "32-bit float"
+	default:
+		return "16-bit"
+	}
+}
+
+// BitDepth returns the number of significant bits per sample: 16, 24, or 24 for
+// float32, whose mantissa holds 24 bits.
+func (f OutputFormat) BitDepth() int {
+	if f == OutputInt16 {
+		return 16
+	}
+	return 24
+}
+
+func (f OutputFormat) bitDepthInBytes() int {
+	switch f {
+	case OutputInt24:
+		return 3
+	case OutputFloat32:
+		return 4
+	default:
+		return 2
+	}
+}
+
+func (f OutputFormat) bytesPerSample() int {
+	return f.bitDepthInBytes() * channelCount
+}
+
+func (f OutputFormat) otoFormat() oto.Format {
+	switch f {
+	case OutputInt24:
+		return oto.FormatSignedInt24LE
+	case OutputFloat32:
+		return oto.FormatFloat32LE
+	default:
+		return oto.FormatSignedInt16LE
+	}
+}
 
 var (
 	mu      sync.Mutex
@@ -25,8 +86,77 @@ var (
 	player  *oto.Player
 
 	bufferDuration time.Duration
+
+	// currentSampleRate is the sample rate the speaker is running at.
+	currentSampleRate beep.SampleRate
+	// taps receive every buffer of mixed samples before it is sent to the device.
+	taps []*tapFunc
+	// muted replaces the device output with silence while streamers keep advancing.
+	muted bool
+
+	// backend and device select the audio output used by Init.
+	backend Backend
+	device  string
+
+	// outputFormat is the sample format sent to the driver, fixed by Init.
+	outputFormat OutputFormat
+	// dither enables TPDF dither when quantizing to an integer format.
+	dither atomic.Bool
 )
 
+// SetOutputFormat selects the sample format sent to the audio driver. It must be called before Init.
+func SetOutputFormat(f OutputFormat) error {
+	if context != nil {
+		return errors.New("speaker output format cannot be changed after initialization")
+	}
+	outputFormat = f
+	return nil
+}
+
+// CurrentOutputFormat returns the sample format sent to the audio driver.
+func CurrentOutputFormat() OutputFormat {
+	return outputFormat
+}
+
+// SetDither enables or disables triangular (TPDF) dither when the samples are quantized
+// to an integer output format. Dither only makes sense when the source has more
+// precision than the output; it has no effect on OutputFloat32.
+//
+// SetDither is concurrent-safe.
+func SetDither(enabled bool) {
+	dither.Store(enabled)
+}
+
+// Backend selects the audio output driver.
+type Backend = oto.Backend
+
+// Available audio output backends.
+const (
+	// BackendAuto uses PulseAudio and falls back to ALSA.
+	BackendAuto = oto.BackendAuto
+	// BackendPulse plays through a PulseAudio (or pipewire-pulse) server.
+	BackendPulse = oto.BackendPulse
+	// BackendALSA plays through an ALSA PCM device.
+	BackendALSA = oto.BackendALSA
+	// BackendFile writes raw PCM in the selected OutputFormat (OutputInt16, OutputInt24 or
+	// OutputFloat32), or WAV for a ".wav" path, to a file or FIFO in real time.
+	BackendFile = oto.BackendFile
+	// BackendNull discards the audio in real time.
+	BackendNull = oto.BackendNull
+)
+
+// SetBackend selects the audio output backend used by Init. The meaning of device
+// depends on the backend: the ALSA PCM name, or the path written by the file backend.
+// It must be called before Init.
+func SetBackend(b Backend, dev string) error {
+	if context != nil {
+		return errors.New("speaker backend cannot be changed after initialization")
+	}
+	backend = b
+	device = dev
+	return nil
+}
+
 // Init initializes audio playback through speaker. Must be called before using this package.
 //
 // The bufferSize argument specifies the number of samples of the speaker's buffer. Bigger
@@ -52,8 +182,10 @@ func Init(sampleRate beep.SampleRate, bufferSize int) error {
 	context, readyChan, err = oto.NewContext(&oto.NewContextOptions{
 		SampleRate:   int(sampleRate),
 		ChannelCount: channelCount,
-		Format:       otoFormat,
+		Format:       outputFormat.otoFormat(),
 		BufferSize:   sampleRate.D(driverBufferSize),
+		Backend:      backend,
+		Device:       device,
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to initialize speaker")
@@ -61,10 +193,11 @@ func Init(sampleRate beep.SampleRate, bufferSize int) error {
 	<-readyChan
 
 	player = context.NewPlayer(newReaderFromStreamer(&mixer))
-	player.SetBufferSize(playerBufferSize * bytesPerSample)
+	player.SetBufferSize(playerBufferSize * outputFormat.bytesPerSample())
 	player.Play()
 
 	bufferDuration = sampleRate.D(bufferSize)
+	currentSampleRate = sampleRate
 
 	return nil
 }
@@ -105,16 +238,53 @@ func ReInit(sampleRate beep.SampleRate, bufferSize int) error {
 
 	playerBufferSize := bufferSize / 2
 	newPlayer := context.NewPlayer(newReaderFromStreamer(&mixer))
-	newPlayer.SetBufferSize(playerBufferSize * bytesPerSample)
+	newPlayer.SetBufferSize(playerBufferSize * outputFormat.bytesPerSample())
 	newPlayer.Play()
 
 	mu.Lock()
 	player = newPlayer
 	bufferDuration = sampleRate.D(bufferSize)
+	currentSampleRate = sampleRate
 	mu.Unlock()
 	return nil
 }
 
+// Device describes an output of the audio backend.
+type Device = oto.Device
+
+// Devices lists the outputs of the backend the speaker plays to.
+func Devices() ([]Device, error) {
+	if context == nil {
+		return nil, errors.New("speaker is not initialized")
+	}
+	devices, err := context.Devices()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list output devices")
+	}
+	return devices, nil
+}
+
+// SetDevice switches playback to another output of the backend, as returned by Devices.
+// An empty id selects the backend's default output. The playing Streamers are not
+// touched, so their position and paused state are kept; only the audio buffered in
+// the old device is lost. If the speaker is not yet initialized, the device is used by Init.
+//
+// SetDevice is concurrent-safe.
+func SetDevice(id string) error {
+	reInitMu.Lock()
+	defer reInitMu.Unlock()
+
+	if context == nil {
+		device = id
+		return nil
+	}
+	if err := context.SetDevice(id); err != nil {
+		return errors.Wrap(err, "failed to switch output device")
+	}
+	device = id
+	return nil
+}
+
 // Close closes audio playback. However, the underlying driver context keeps existing, because
 // closing it isn't supported (https://github.com/hajimehoshi/oto/issues/149). In most cases,
 // there is certainly no need to call Close even when the program doesn't play anymore, because
@@ -140,6 +310,42 @@ func Unlock() {
 	mu.Unlock()
 }
 
+// tapFunc is a registered tap. Taps are kept by pointer so that each can be removed even
+// if the same function was added twice.
+type tapFunc struct {
+	fn func(samples [][2]float64, sampleRate beep.SampleRate)
+}
+
+// AddTap registers fn to receive the final mixed samples of every buffer pulled by the
+// device, together with the current sample rate, and returns a function that removes it.
+// Taps are called in the order they were added.
+//
+// fn is called from the audio thread while the speaker is locked, so it must return
+// quickly, must not call Lock, and must not retain or modify the samples slice.
+func AddTap(fn func(samples [][2]float64, sampleRate beep.SampleRate)) (remove func()) {
+	t := &tapFunc{fn: fn}
+	mu.Lock()
+	taps = append(taps, t)
+	mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			mu.Lock()
+			taps = slices.DeleteFunc(taps, func(other *tapFunc) bool { return other == t })
+			mu.Unlock()
+		})
+	}
+}
+
+// SetMuted silences the device output without pausing playback. Streamers keep being
+// pulled at the device's pace, so taps registered with AddTap still receive the audio.
+func SetMuted(m bool) {
+	mu.Lock()
+	muted = m
+	mu.Unlock()
+}
+
 // Play starts playing all provided Streamers through the speaker.
 func Play(s ...beep.Streamer) {
 	mu.Lock()
@@ -197,13 +403,17 @@ func Clear() {
 
 // sampleReader is a wrapper for beep.Streamer to implement io.Reader.
 type sampleReader struct {
-	s   beep.Streamer
-	buf [][2]float64
+	s      beep.Streamer
+	buf    [][2]float64
+	format OutputFormat
+	rng    uint64 // xorshift state for dither noise.
 }
 
 func newReaderFromStreamer(s beep.Streamer) *sampleReader {
 	return &sampleReader{
-		s: s,
+		s:      s,
+		format: outputFormat,
+		rng:    0x9e3779b97f4a7c15,
 	}
 }
 
@@ -211,6 +421,9 @@ func newReaderFromStreamer(s beep.Streamer) *sampleReader {
 // samples. Read expects the size of buf be divisible by the length
 // of a sample (= channel count * bit depth in bytes).
 func (s *sampleReader) Read(buf []byte) (n int, err error) {
+	bytesPerSample := s.format.bytesPerSample()
+	bitDepthInBytes := s.format.bitDepthInBytes()
+
 	// Read samples from streamer
 	if len(buf)%bytesPerSample != 0 {
 		return 0, errors.New("requested number of bytes do not align with the samples")
@@ -230,25 +443,64 @@ func (s *sampleReader) Read(buf []byte) (n int, err error) {
 	}
 
 	// Convert samples to bytes
+	useDither := dither.Load()
 	for i := range s.buf[:ns] {
 		for c := range s.buf[i] {
-			val := s.buf[i][c]
-			val = util.Clamp(val, -1, 1)
-			valInt16 := int16(val * (1<<15 - 1))
-			low := byte(valInt16)
-			high := byte(valInt16 >> 8)
-			buf[i*bytesPerSample+c*bitDepthInBytes+0] = low
-			buf[i*bytesPerSample+c*bitDepthInBytes+1] = high
+			val := util.Clamp(s.buf[i][c], -1, 1)
+			out := buf[i*bytesPerSample+c*bitDepthInBytes:]
+			switch s.format {
+			case OutputFloat32:
+				binary.LittleEndian.PutUint32(out, math.Float32bits(float32(val)))
+			case OutputInt24:
+				v := s.quantize(val, 24, useDither)
+				out[0] = byte(v)
+				out[1] = byte(v >> 8)
+				out[2] = byte(v >> 16)
+			default:
+				v := s.quantize(val, 16, useDither)
+				out[0] = byte(v)
+				out[1] = byte(v >> 8)
+			}
 		}
 	}
 
 	return ns * bytesPerSample, nil
 }
 
+// quantize converts val in [-1, 1] to a signed integer of the given bit depth. Samples
+// decoded from a source of the same or lower bit depth map back to their exact
+// original values unless dither is enabled.
+func (s *sampleReader) quantize(val float64, bits int, useDither bool) int32 {
+	scale := float64(int64(1) << (bits - 1))
+	v := val * scale
+	if useDither {
+		v += s.random() - s.random()
+	}
+	return int32(util.Clamp(math.Round(v), -scale, scale-1))
+}
+
+// random returns a uniformly distributed number in [0, 1).
+func (s *sampleReader) random() float64 {
+	s.rng ^= s.rng << 13
+	s.rng ^= s.rng >> 7
+	s.rng ^= s.rng << 17
+	return float64(s.rng>>11) / (1 << 53)
+}
+
 // stream pull samples from the streamer while preventing concurrency
-// problems by locking the global mixer.
+// problems by locking the global mixer. The samples are passed to the taps
+// and silenced afterwards if the speaker is muted.
 func (s *sampleReader) stream(samples [][2]float64) (n int, ok bool) {
 	mu.Lock()
 	defer mu.Unlock()
-	return s.s.Stream(samples)
+	n, ok = s.s.Stream(samples)
+	if n > 0 {
+		for _, t := range taps {
+			t.fn(samples[:n], currentSampleRate)
+		}
+	}
+	if muted {
+		clear(samples[:n])
+	}
+	return n, ok
 }
@@ -1,13 +1,109 @@
 package speaker
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/internal/testtools"
 )
 
+func TestSampleReader_TapAndMute(t *testing.T) {
+	s, data := testtools.RandomDataStreamer(100)
+	r := newReaderFromStreamer(s)
+
+	var tapped [][2]float64
+	var tappedRate beep.SampleRate
+	currentSampleRate = 44100
+	var second int
+	remove := AddTap(func(samples [][2]float64, sampleRate beep.SampleRate) {
+		tapped = append(tapped, samples...)
+		tappedRate = sampleRate
+	})
+	removeSecond := AddTap(func(samples [][2]float64, sampleRate beep.SampleRate) {
+		second += len(samples)
+	})
+	defer remove()
+	defer removeSecond()
+	SetMuted(true)
+	defer func() {
+		SetMuted(false)
+		currentSampleRate = 0
+	}()
+
+	buf := make([]byte, 100*outputFormat.bytesPerSample())
+	n, err := r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(buf), n)
+	assert.Equal(t, make([]byte, len(buf)), buf, "muted output must be silent")
+	assert.Equal(t, data, tapped)
+	assert.Equal(t, beep.SampleRate(44100), tappedRate)
+	assert.Equal(t, 100, second)
+
+	removeSecond()
+	removeSecond()
+	assert.Len(t, taps, 1)
+}
+
+func TestSampleReader_BitExact(t *testing.T) {
+	for _, tt := range []struct {
+		format OutputFormat
+		bits   int
+	}{
+		{OutputInt16, 16},
+		{OutputInt24, 24},
+	} {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			scale := float64(int64(1) << (tt.bits - 1))
+			values := []int32{-int32(scale), -12345, -1, 0, 1, 12345, int32(scale) - 1}
+			data := make([][2]float64, len(values))
+			for i, v := range values {
+				data[i] = [2]float64{float64(v) / scale, float64(v) / scale}
+			}
+
+			r := newReaderFromStreamer(testtools.NewDataStreamer(data))
+			r.format = tt.format
+			bytesPerChannel := tt.format.bitDepthInBytes()
+			buf := make([]byte, len(values)*tt.format.bytesPerSample())
+			_, err := r.Read(buf)
+			assert.NoError(t, err)
+
+			for i, want := range values {
+				for c := 0; c < 2; c++ {
+					var got int32
+					b := buf[(i*2+c)*bytesPerChannel:]
+					for k := 0; k < bytesPerChannel; k++ {
+						got |= int32(b[k]) << (8 * k)
+					}
+					got = got << (32 - tt.bits) >> (32 - tt.bits)
+					assert.Equal(t, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleReader_Float32(t *testing.T) {
+	data := [][2]float64{{0.5, -0.25}, {1, -1}}
+	r := newReaderFromStreamer(testtools.NewDataStreamer(data))
+	r.format = OutputFloat32
+	buf := make([]byte, len(data)*OutputFloat32.bytesPerSample())
+	_, err := r.Read(buf)
+	assert.NoError(t, err)
+
+	for i := range data {
+		for c := 0; c < 2; c++ {
+			got := math.Float32frombits(binary.LittleEndian.Uint32(buf[(i*2+c)*4:]))
+			assert.Equal(t, float32(data[i][c]), got)
+		}
+	}
+}
+
 func BenchmarkSampleReader_Read(b *testing.B) {
 	// note: must be multiples of bytesPerSample
 	bufferSizes := []int{64, 512, 8192, 32768}
@@ -79,6 +79,7 @@ func Decode(r io.Reader) (s beep.StreamSeekCloser, format beep.Format, err error
 				d.h.ByteRate = fmtchunk.ByteRate
 				d.h.BytesPerFrame = fmtchunk.BytesPerFrame
 				d.h.BitsPerSample = fmtchunk.BitsPerSample
+				d.layout = beep.ChannelMask(fmtchunk.ChannelMask)
 
 				// SubFormat is represented by GUID. Plain PCM is KSDATAFORMAT_SUBTYPE_PCM GUID.
 				// See https://docs.microsoft.com/en-us/windows-hardware/drivers/ddi/content/ksmedia/ns-ksmedia-waveformatextensible
@@ -154,6 +155,11 @@ func Decode(r io.Reader) (s beep.StreamSeekCloser, format beep.Format, err error
 		NumChannels: int(d.h.NumChans),
 		Precision:   int(d.h.BitsPerSample / 8),
 	}
+	if d.layout.Count() != format.NumChannels {
+		// No channel mask, or one that doesn't match the channels in the file.
+		d.layout = beep.DefaultChannelMask(format.NumChannels)
+	}
+	d.matrix = beep.DefaultDownmix.Matrix(d.layout, format.NumChannels)
 	return &d, format, nil
 }
 
@@ -202,6 +208,11 @@ type decoder struct {
 	hsz int32
 	pos int32
 	err error
+
+	// layout and matrix fold the channels into stereo; see beep.DefaultDownmix.
+	layout   beep.ChannelMask
+	matrix   beep.DownmixMatrix
+	channels []float64
 }
 
 func (d *decoder) Stream(samples [][2]float64) (n int, ok bool) {
@@ -217,47 +228,28 @@ func (d *decoder) Stream(samples [][2]float64) (n int, ok bool) {
 	if err != nil && err != io.EOF {
 		d.err = err
 	}
+	if d.layout != beep.LayoutStereo {
+		d.downmixInto(samples, p[:n])
+		d.pos += int32(n)
+		return n / bytesPerFrame, true
+	}
 	switch {
-	case d.h.BitsPerSample == 8 && d.h.NumChans == 1:
-		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
-			val := float64(p[i])/(1<<8)*2 - 1
-			samples[j][0] = val
-			samples[j][1] = val
-		}
-	case d.h.BitsPerSample == 8 && d.h.NumChans >= 2:
+	case d.h.BitsPerSample == 8:
 		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
 			samples[j][0] = float64(p[i+0])/(1<<8)*2 - 1
 			samples[j][1] = float64(p[i+1])/(1<<8)*2 - 1
 		}
-	case d.h.BitsPerSample == 16 && d.h.NumChans == 1:
-		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
-			val := float64(int16(p[i+0])+int16(p[i+1])*(1<<8)) / (1 << 15)
-			samples[j][0] = val
-			samples[j][1] = val
-		}
-	case d.h.BitsPerSample == 16 && d.h.NumChans >= 2:
+	case d.h.BitsPerSample == 16:
 		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
 			samples[j][0] = float64(int16(p[i+0])+int16(p[i+1])*(1<<8)) / (1 << 15)
 			samples[j][1] = float64(int16(p[i+2])+int16(p[i+3])*(1<<8)) / (1 << 15)
 		}
-	case d.h.BitsPerSample == 24 && d.h.NumChans == 1:
-		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
-			val := float64((int32(p[i+0])<<8)+(int32(p[i+1])<<16)+(int32(p[i+2])<<24)) / (1 << 8) / (1 << 23)
-			samples[j][0] = val
-			samples[j][1] = val
-		}
-	case d.h.BitsPerSample == 24 && d.h.NumChans >= 2:
+	case d.h.BitsPerSample == 24:
 		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
 			samples[j][0] = float64((int32(p[i+0])<<8)+(int32(p[i+1])<<16)+(int32(p[i+2])<<24)) / (1 << 8) / (1 << 23)
 			samples[j][1] = float64((int32(p[i+3])<<8)+(int32(p[i+4])<<16)+(int32(p[i+5])<<24)) / (1 << 8) / (1 << 23)
 		}
-	case d.h.FormatType == 3 && d.h.BitsPerSample == 32 && d.h.NumChans == 1:
-		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
-			val := math.Float32frombits(binary.LittleEndian.Uint32(p[i : i+4]))
-			samples[j][0] = float64(val)
-			samples[j][1] = float64(val)
-		}
-	case d.h.FormatType == 3 && d.h.BitsPerSample == 32 && d.h.NumChans >= 2:
+	case d.h.FormatType == 3 && d.h.BitsPerSample == 32:
 		for i, j := 0, 0; i <= n-bytesPerFrame; i, j = i+bytesPerFrame, j+1 {
 			left := math.Float32frombits(binary.LittleEndian.Uint32(p[i : i+4]))
 			right := math.Float32frombits(binary.LittleEndian.Uint32(p[i+4 : i+8]))
@@ -269,6 +261,43 @@ func (d *decoder) Stream(samples [][2]float64) (n int, ok bool) {
 	return n / bytesPerFrame, true
 }
 
+// downmixInto decodes the whole frames in p and mixes them into samples with the
+// downmix matrix. It handles mono and multichannel sources.
+func (d *decoder) downmixInto(samples [][2]float64, p []byte) {
+	numChans := int(d.h.NumChans)
+	bytesPerSample := int(d.h.BitsPerSample / 8)
+	if len(d.channels) != numChans {
+		d.channels = make([]float64, numChans)
+	}
+	for i, j := 0, 0; i <= len(p)-int(d.h.BytesPerFrame); i, j = i+int(d.h.BytesPerFrame), j+1 {
+		for c := range d.channels {
+			d.channels[c] = d.decodeSample(p[i+c*bytesPerSample:])
+		}
+		samples[j] = d.matrix.Apply(d.channels)
+	}
+}
+
+// decodeSample decodes a single sample of one channel.
+func (d *decoder) decodeSample(p []byte) float64 {
+	switch {
+	case d.h.BitsPerSample == 8:
+		return float64(p[0])/(1<<8)*2 - 1
+	case d.h.BitsPerSample == 16:
+		return float64(int16(p[0])+int16(p[1])*(1<<8)) / (1 << 15)
+	case d.h.BitsPerSample == 24:
+		return float64((int32(p[0])<<8)+(int32(p[1])<<16)+(int32(p[2])<<24)) / (1 << 8) / (1 << 23)
+	case d.h.FormatType == 3 && d.h.BitsPerSample == 32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(p[0:4])))
+	default:
+		return 0
+	}
+}
+
+// ChannelLayout returns the speaker layout of the source channels.
+func (d *decoder) ChannelLayout() beep.ChannelMask {
+	return d.layout
+}
+
 func (d *decoder) Err() error {
 	return d.err
 }
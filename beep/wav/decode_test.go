@@ -2,6 +2,7 @@ package wav
 
 import (
 	"bytes"
+	"encoding/binary"
 	"os"
 	"testing"
 
@@ -215,3 +216,41 @@ func TestDecoder_ReturnBehaviour(t *testing.T) {
 
 	testtools.AssertStreamerHasCorrectReturnBehaviour(t, s, s.Len())
 }
+
+func TestDecode_Multichannel(t *testing.T) {
+	// One frame of 16-bit 5.1 audio: FL, FR, FC, LFE, BL, BR.
+	frame := []int16{8192, -8192, 16384, 32767, 4096, 0}
+
+	var wav bytes.Buffer
+	wav.WriteString("RIFF")
+	binary.Write(&wav, binary.LittleEndian, int32(4+8+16+8+len(frame)*2))
+	wav.WriteString("WAVE")
+	wav.WriteString("fmt ")
+	binary.Write(&wav, binary.LittleEndian, int32(16))
+	binary.Write(&wav, binary.LittleEndian, int16(1)) // PCM
+	binary.Write(&wav, binary.LittleEndian, formatchunk{6, 44100, 44100 * 12, 12, 16})
+	wav.WriteString("data")
+	binary.Write(&wav, binary.LittleEndian, int32(len(frame)*2))
+	binary.Write(&wav, binary.LittleEndian, frame)
+
+	s, f, err := Decode(bytes.NewReader(wav.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to decode the WAV file: %v", err)
+	}
+	assert.Equal(t, 6, f.NumChannels)
+	assert.Equal(t, beep.Layout5Point1, s.(*decoder).ChannelLayout())
+
+	samples := make([][2]float64, 1)
+	n, ok := s.Stream(samples)
+	assert.Equal(t, 1, n)
+	assert.True(t, ok)
+
+	var want [2]float64
+	matrix := beep.DefaultDownmix.Matrix(beep.Layout5Point1, 6)
+	for c, v := range frame {
+		want[0] += matrix[c][0] * float64(v) / (1 << 15)
+		want[1] += matrix[c][1] * float64(v) / (1 << 15)
+	}
+	assert.InDelta(t, want[0], samples[0][0], 1e-12)
+	assert.InDelta(t, want[1], samples[0][1], 1e-12)
+}
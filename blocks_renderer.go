@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nfnt/resize"
+)
+
+// colorDepth is how many colors the terminal can show in text.
+//
+// colorDepth 是终端在文本中可以显示的颜色数量。
+type colorDepth int
+
+const (
+	colorDepth16 colorDepth = iota
+	colorDepth256
+	colorDepthTrue
+)
+
+// detectColorDepth returns how many colors the terminal can show in text.
+//
+// detectColorDepth 返回终端在文本中可以显示的颜色数量。
+func detectColorDepth() colorDepth {
+	if SupportsTrueColor() {
+		return colorDepthTrue
+	}
+	if strings.Contains(os.Getenv("TERM"), "256") {
+		return colorDepth256
+	}
+	return colorDepth16
+}
+
+// ansi16Palette is the usual xterm palette of the 16 basic colors.
+//
+// ansi16Palette 是 16 种基本颜色的常用 xterm 调色板。
+var ansi16Palette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// renderBlocksImage draws an image at the given cell with text: every cell is an upper half
+// block whose foreground and background colors are two pixels of the image. It works in any
+// terminal, multiplexer or SSH session that shows colored text.
+//
+// renderBlocksImage 使用文本在给定单元格处绘制图像：每个单元格是一个上半块字符，
+// 其前景色和背景色是图像的两个像素。它适用于任何能显示彩色文本的终端、复用器或 SSH 会话。
+func renderBlocksImage(img image.Image, row, col, widthChars, heightChars int) error {
+	if widthChars <= 0 || heightChars <= 0 {
+		return nil
+	}
+	scaled := resize.Resize(uint(widthChars), uint(heightChars*2), img, resize.Bilinear)
+	bounds := scaled.Bounds()
+	depth := detectColorDepth()
+
+	var sb strings.Builder
+	for y := 0; y < heightChars; y++ {
+		fmt.Fprintf(&sb, "\x1b[%d;%dH", row+y, col)
+		var lastFg, lastBg string
+		for x := 0; x < widthChars; x++ {
+			top := scaled.At(bounds.Min.X+x, bounds.Min.Y+2*y)
+			bottom := scaled.At(bounds.Min.X+x, bounds.Min.Y+2*y+1)
+			if fg := blocksColor(top, depth, false); fg != lastFg {
+				sb.WriteString(fg)
+				lastFg = fg
+			}
+			if bg := blocksColor(bottom, depth, true); bg != lastBg {
+				sb.WriteString(bg)
+				lastBg = bg
+			}
+			sb.WriteString("▀")
+		}
+		sb.WriteString("\x1b[0m")
+	}
+
+	if _, err := io.WriteString(os.Stdout, sb.String()); err != nil {
+		return fmt.Errorf("failed to write block image: %v\n\n无法写入色块图像: %v", err, err)
+	}
+	return nil
+}
+
+// blocksColor returns the escape sequence that sets the foreground or background color
+// closest to c that the terminal can show.
+//
+// blocksColor 返回将前景色或背景色设置为终端可显示的最接近 c 的颜色的转义序列。
+func blocksColor(c color.Color, depth colorDepth, background bool) string {
+	r32, g32, b32, _ := c.RGBA()
+	r, g, b := int(r32>>8), int(g32>>8), int(b32>>8)
+
+	switch depth {
+	case colorDepthTrue:
+		if background {
+			return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", r, g, b)
+		}
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, b)
+	case colorDepth256:
+		if background {
+			return fmt.Sprintf("\x1b[48;5;%dm", nearest256(r, g, b))
+		}
+		return fmt.Sprintf("\x1b[38;5;%dm", nearest256(r, g, b))
+	default:
+		index := nearest16(r, g, b)
+		code := 30 + index
+		if index >= 8 {
+			code = 90 + index - 8
+		}
+		if background {
+			code += 10
+		}
+		return fmt.Sprintf("\x1b[%dm", code)
+	}
+}
+
+// nearest256 returns the index of the color of the 256-color palette's color cube or
+// grayscale ramp closest to the given one.
+//
+// nearest256 返回 256 色调色板的颜色立方体或灰阶中最接近给定颜色的颜色索引。
+func nearest256(r, g, b int) int {
+	levels := [6]int{0, 95, 135, 175, 215, 255}
+	cubeIndex := func(v int) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	ri, gi, bi := cubeIndex(r), cubeIndex(g), cubeIndex(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := colorDistance(r, g, b, levels[ri], levels[gi], levels[bi])
+
+	gray := (r + g + b) / 3
+	grayIndex := 23
+	if gray < 238 {
+		grayIndex = max((gray-3)/10, 0)
+	}
+	grayLevel := 8 + 10*grayIndex
+	if colorDistance(r, g, b, grayLevel, grayLevel, grayLevel) < cubeDist {
+		return 232 + grayIndex
+	}
+	return cube
+}
+
+// nearest16 returns the index of the basic color closest to the given one.
+//
+// nearest16 返回最接近给定颜色的基本颜色索引。
+func nearest16(r, g, b int) int {
+	best, bestDist := 0, -1
+	for i, c := range ansi16Palette {
+		if d := colorDistance(r, g, b, c[0], c[1], c[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func colorDistance(r1, g1, b1, r2, g2, b2 int) int {
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return dr*dr + dg*dg + db*db
+}
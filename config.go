@@ -62,6 +62,9 @@ type Config struct {
 	App         AppConfig              `toml:"app"`
 	Icons       map[string]IconsConfig `toml:"icons"`
 	ActiveIcons *IconsConfig           `toml:"-"`
+	Theme       toml.Primitive         `toml:"theme"` // Decoded by resolveTheme. / 由 resolveTheme 解析。
+	ActiveTheme *Theme                 `toml:"-"`
+	Layouts     []LayoutTemplate       `toml:"layouts"`
 }
 
 // AppConfig holds application-level configuration settings.
@@ -92,6 +95,28 @@ type AppConfig struct {
 	Icons                string `toml:"icons"`
 	ShuffleHistoryWindow int    `toml:"shuffle_history_window"`
 	MaxSearchDirs        int    `toml:"max_search_dirs"`
+	PlaylistsDir         string `toml:"playlists_dir"`
+	NotificationTimeoutMs int    `toml:"notification_timeout_ms"`
+	NotificationUrgency  string `toml:"notification_urgency"`
+	WebRemoteAddr        string `toml:"web_remote_addr"`
+	WebRemoteToken       string `toml:"web_remote_token"`
+	StreamAddr           string `toml:"stream_addr"`
+	StreamSampleRate     int `toml:"stream_sample_rate"`
+	StreamMuteLocal      bool `toml:"stream_mute_local"`
+	AudioBackend         string `toml:"audio_backend"`
+	OutputDevice         string `toml:"output_device"`
+	OutputFormat         string `toml:"output_format"`
+	Dither               bool `toml:"dither"`
+	BitPerfect           bool `toml:"bit_perfect"`
+	DownmixCenter        float64 `toml:"downmix_center"`
+	DownmixSurround      float64 `toml:"downmix_surround"`
+	DownmixLFE           float64 `toml:"downmix_lfe"`
+	DownmixNormalize     bool `toml:"downmix_normalize"`
+	MonoGain             float64 `toml:"mono_gain"`
+	ReadAheadKB          int `toml:"read_ahead_kb"`
+	CoverCacheMB         int `toml:"cover_cache_mb"`
+	StatusFormat         string `toml:"status_format"`
+	WindowTitleFormat    string `toml:"window_title_format"`
 }
 
 // Keymap defines all the keybindings for the application, organized by page.
@@ -102,6 +127,7 @@ type Keymap struct {
 	Player   PlayerKeymap   `toml:"player"`
 	Library  LibraryKeymap  `toml:"library"`
 	Playlist PlaylistKeymap `toml:"playlist"`
+	Split    SplitKeymap    `toml:"split"`
 }
 
 // GlobalKeymap holds keybindings that work across all pages.
@@ -113,6 +139,7 @@ type GlobalKeymap struct {
 	SwitchToPlayer   Key `toml:"SwitchToPlayer"`
 	SwitchToPlayList Key `toml:"SwitchToPlayList"`
 	SwitchToLibrary  Key `toml:"SwitchToLibrary"`
+	SwitchToSplit    Key `toml:"SwitchToSplit"`
 }
 
 // PlayerKeymap holds keybindings specific to the Player page.
@@ -132,6 +159,7 @@ type PlayerKeymap struct {
 	ToggleTextColor Key `toml:"ToggleTextColor"`
 	Reset           Key `toml:"Reset"`
 	ToggleLayout    Key `toml:"ToggleLayout"`
+	SelectDevice    Key `toml:"SelectDevice"`
 }
 
 // LibraryKeymap holds keybindings for the Library page.
@@ -179,6 +207,13 @@ type PlaylistKeymap struct {
 	SearchMode SearchModeKeymap `toml:"SearchMode"`
 }
 
+// SplitKeymap holds keybindings for the split view. All other keys go to its focused pane.
+//
+// SplitKeymap 保存分屏视图的按键绑定。其他按键都会传给获得焦点的窗格。
+type SplitKeymap struct {
+	FocusNext Key `toml:"FocusNext"`
+}
+
 // GlobalConfig is the global configuration instance.
 //
 // GlobalConfig 是全局配置实例。
@@ -244,12 +279,14 @@ func LoadConfig() error {
 
 	configFile := filepath.Join(configPath, "config.toml")
 
+	var md toml.MetaData
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		if err := os.WriteFile(configFile, []byte(defaultConfigContent), 0644); err != nil {
 			return fmt.Errorf("could not write default config file: %v\n\n无法写入默认配置文件: %v", err, err)
 		}
 		var config Config
-		if _, err := toml.Decode(defaultConfigContent, &config); err != nil {
+		var err error
+		if md, err = toml.Decode(defaultConfigContent, &config); err != nil {
 			return fmt.Errorf("could not decode default config: %v\n\n无法解析默认配置: %v", err, err)
 		}
 		GlobalConfig = &config
@@ -258,7 +295,8 @@ func LoadConfig() error {
 			return fmt.Errorf("could not update config file: %v\n\n无法更新配置文件: %v", err, err)
 		}
 		var config Config
-		if _, err := toml.DecodeFile(configFile, &config); err != nil {
+		var err error
+		if md, err = toml.DecodeFile(configFile, &config); err != nil {
 			return fmt.Errorf("could not decode config file: %v\n\n无法解析配置文件: %v", err, err)
 		}
 		GlobalConfig = &config
@@ -270,17 +308,45 @@ func LoadConfig() error {
 	if GlobalConfig.App.LayoutDebounceMs <= 0 {
 		GlobalConfig.App.LayoutDebounceMs = 200
 	}
+	if GlobalConfig.App.CoverCacheMB <= 0 {
+		GlobalConfig.App.CoverCacheMB = 64
+	}
 	if GlobalConfig.App.AutostartLastPlayed && (!GlobalConfig.App.RememberLibraryPath || !GlobalConfig.App.PlaylistHistory) {
 		return fmt.Errorf("autostart_last_played can only be enabled when both remember_library_path and playlist_history are also enabled\n\nautostart_last_played 只能在 remember_library_path 和 playlist_history 同时开启时才能开启")
 	}
+	if GlobalConfig.App.DefaultPage < 0 || GlobalConfig.App.DefaultPage > defaultPageSplit {
+		GlobalConfig.App.DefaultPage = 0
+	}
 	if GlobalConfig.App.DefaultLayoutNarrow < 0 || GlobalConfig.App.DefaultLayoutNarrow > 3 {
 		GlobalConfig.App.DefaultLayoutNarrow = 0
 	}
 	if GlobalConfig.App.DefaultLayoutWide < 0 || GlobalConfig.App.DefaultLayoutWide > 4 {
 		GlobalConfig.App.DefaultLayoutWide = 0
 	}
+	if len(GlobalConfig.Keymap.Split.FocusNext) == 0 {
+		// Configs written before the split view have no [keymap.split] section.
+		// 分屏视图出现之前写入的配置没有 [keymap.split] 节。
+		GlobalConfig.Keymap.Split.FocusNext = Key{"tab"}
+	}
+	if GlobalConfig.App.ReadAheadKB > 0 && GlobalConfig.App.ReadAheadKB < 1024 {
+		// The buffer must hold more than what is needed to resume after an underrun.
+		// 缓冲区必须大于缓冲不足后恢复播放所需的数据量。
+		GlobalConfig.App.ReadAheadKB = 1024
+	}
 
 	resolveIconSet(GlobalConfig)
+	if err := resolveTheme(GlobalConfig, md); err != nil {
+		return err
+	}
+	if err := validateLayouts(GlobalConfig.Layouts); err != nil {
+		return err
+	}
+	if err := validateFormat("status_format", GlobalConfig.App.StatusFormat); err != nil {
+		return err
+	}
+	if err := validateFormat("window_title_format", GlobalConfig.App.WindowTitleFormat); err != nil {
+		return err
+	}
 
 	return validateKeymap(GlobalConfig.Keymap)
 }
@@ -307,9 +373,11 @@ func updateConfigFile(configPath string) error {
 		comment string
 	}{
 		{"[keymap.player]", "ToggleLayout", "    ToggleLayout = [\"o\"]", "    # Toggle layout mode (only works in wide/narrow mode).\n    #\n    # 切换布局模式（仅在宽/窄模式下有效）。"},
+		{"[keymap.global]", "SwitchToSplit", "    SwitchToSplit = [\"4\"]", "    # Switch to the split view: Library, PlayList and player side by side.\n    #\n    # 切换到分屏视图：媒体库、播放列表和播放器并排显示。"},
+		{"[keymap.player]", "SelectDevice", "    SelectDevice = [\"v\"]", "    # Open the output device picker.\n    #\n    # 打开输出设备选择器。"},
 		{"[app]", "max_history_size", "max_history_size = 100", "# Maximum number of history entries - limits the maximum number of playback history records.\n#\n# 最大历史记录数量 - 限制播放历史记录的最大条数"},
 		{"[app]", "switch_debounce_ms", "switch_debounce_ms = 50", "# Song switching debounce time (milliseconds) - prevents rapid continuous song switching, avoiding misoperation.\n#\n# 切歌防抖时间（毫秒）- 防止快速连续切歌，避免误操作"},
-		{"[app]", "default_page", "default_page = 3", "# Default starting page - the page displayed when the program starts.\n# 0 = Player page, 1 = PlayList page, 2 = Library page, 3 = memory (use saved page from last session).\n#\n# 默认启动页面 - 程序启动时显示的页面。\n# 0 = 播放器页面, 1 = 播放列表页面, 2 = 媒体库页面, 3 = 记忆（使用上次保存的页面）。"},
+		{"[app]", "default_page", "default_page = 3", "# Default starting page - the page displayed when the program starts.\n# 0 = Player page, 1 = PlayList page, 2 = Library page, 3 = memory (use saved page from last session),\n# 4 = Split view. Other values fall back to 0.\n#\n# 默认启动页面 - 程序启动时显示的页面。\n# 0 = 播放器页面, 1 = 播放列表页面, 2 = 媒体库页面, 3 = 记忆（使用上次保存的页面），\n# 4 = 分屏视图。其他值回退为 0。"},
 		{"[app]", "default_play_mode", "default_play_mode = 3", "# Default play mode - the playback mode when the program starts.\n# 0 = repeat one, 1 = repeat all, 2 = random, 3 = memory (use saved play mode from last session).\n#\n# 默认播放模式 - 程序启动时的播放模式。\n# 0 = 单曲循环, 1 = 列表循环, 2 = 随机播放, 3 = 记忆（使用上次保存的播放模式）。"},
 		{"[app]", "remember_library_path", "remember_library_path = true", "# Whether to remember the music library path - if true, the program will remember the last used music library path.\n# If no path parameter is specified next time the program starts, the saved path will be used automatically.\n#\n# 是否记录音乐库路径 - 如果为true，程序会记住上次使用的音乐库路径。\n# 下次启动时如果不指定路径参数，会自动使用保存的路径。"},
 		{"[app]", "playlist_history", "playlist_history = true", "# Whether to record the playlist - if true, the program will record the playlist and load it next time it starts.\n# Note: 'remember_library_path' must also be true for this to take effect.\n#\n# 是否记录播放列表 - 如果为true，程序会记录播放列表并在下次启动时加载。\n# 注意: 'remember_library_path' 也必须为 true 才能生效。"},
@@ -319,7 +387,7 @@ func updateConfigFile(configPath string) error {
 		{"[app]", "default_color_r", "default_color_r = 100", "# Default text color - the color used for text display when no suitable color is found from album art.\n# RGB values range from 0 to 255.\n#\n# 默认文字颜色 - 当从专辑封面中找不到合适的颜色时，用于文字显示的颜色。\n# RGB 值的范围是 0 到 255。"},
 		{"[app]", "default_color_g", "default_color_g = 149", ""},
 		{"[app]", "default_color_b", "default_color_b = 237", ""},
-		{"[app]", "image_protocol", "image_protocol = \"auto\"", "# Image rendering protocol - determines which terminal protocol to use for displaying album art.\n# Available options: \"auto\", \"kitty\", \"sixel\", \"iterm2\"\n# \"auto\" will automatically detect the best available protocol.\n#\n# 图像渲染协议 - 决定使用哪种终端协议来显示专辑封面。\n# 可用选项: \"auto\", \"kitty\", \"sixel\", \"iterm2\"\n# \"auto\" 会自动检测最佳可用协议。"},
+		{"[app]", "image_protocol", "image_protocol = \"auto\"", "# Image rendering protocol - determines which terminal protocol to use for displaying album art.\n# Available options: \"auto\", \"kitty\", \"sixel\", \"iterm2\", \"blocks\"\n# \"auto\" will automatically detect the best available protocol.\n# \"blocks\" draws the cover with colored half-block characters and works in any terminal.\n#\n# 图像渲染协议 - 决定使用哪种终端协议来显示专辑封面。\n# 可用选项: \"auto\", \"kitty\", \"sixel\", \"iterm2\", \"blocks\"\n# \"auto\" 会自动检测最佳可用协议。\n# \"blocks\" 使用彩色半块字符绘制封面，适用于任何终端。"},
 		{"[app]", "enable_notifications", "enable_notifications = true", "# Enable desktop notifications - whether to show desktop notifications when songs change.\n# If true, the program will send desktop notifications using the freedesktop.org standard.\n#\n# 启用桌面通知 - 是否在歌曲切换时显示桌面通知。\n# 如果为true，程序将使用freedesktop.org标准发送桌面通知。"},
 		{"[app]", "storage", "storage = \"~/.config/BM/storage.json\"", "# Storage file path - used to save full path information entered by the user.\n#\n# 存储文件路径 - 用于保存用户输入的完整路径信息。"},
 		{"[app]", "default_cover_path", "default_cover_path = \"~/.config/BM/default.jpg\"", "# Default cover art path - the image file to use when a song has no embedded cover art.\n# Supports JPG and PNG formats. If empty or the file doesn't exist, no cover will be shown.\n#\n# 默认封面图片路径 - 当歌曲没有内嵌封面时使用的图片文件。\n# 支持 JPG 和 PNG 格式。如果为空或文件不存在，则不显示封面。"},
@@ -330,6 +398,28 @@ func updateConfigFile(configPath string) error {
 		{"[app]", "layout_debounce_ms", "layout_debounce_ms = 200", "# Layout switching debounce time (milliseconds) - prevents rapid layout switching.\n#\n# 布局切换防抖时间（毫秒）- 防止快速连续切换布局。"},
 		{"[app]", "default_layout_narrow", "default_layout_narrow = 0", "# Default layout for narrow terminal - the layout displayed when the program starts in a narrow terminal.\n# 0 = auto, 1 = text only, 2 = image only, 3 = memory (use saved layout from last session).\n#\n# 窄终端默认布局 - 程序在窄终端启动时显示的布局。\n# 0 = 自动, 1 = 仅文本, 2 = 仅封面, 3 = 记忆（使用上次保存的布局）。"},
 		{"[app]", "default_layout_wide", "default_layout_wide = 0", "# Default layout for wide terminal - the layout displayed when the program starts in a wide terminal.\n# 0 = auto, 1 = narrow mode, 2 = text only, 3 = image only, 4 = memory (use saved layout from last session).\n#\n# 宽终端默认布局 - 程序在宽终端启动时显示的布局。\n# 0 = 自动, 1 = 窄终端模式, 2 = 仅文本, 3 = 仅封面, 4 = 记忆（使用上次保存的布局）。"},
+		{"[app]", "playlists_dir", "playlists_dir = \"~/.config/BM/playlists\"", "# Named playlists directory - M3U/M3U8/PLS files in this folder are offered to desktop\n# applets through the MPRIS Playlists interface, next to the built-in smart playlists.\n# Leave empty to only offer the smart playlists.\n#\n# 命名播放列表目录 - 该目录中的 M3U/M3U8/PLS 文件会与内置的智能播放列表一起，\n# 通过 MPRIS Playlists 接口提供给桌面小部件。\n# 留空则只提供智能播放列表。"},
+		{"[app]", "notification_timeout_ms", "notification_timeout_ms = -1", "# Notification timeout (milliseconds) - how long the song notification stays on screen.\n# -1 = use the notification server's default, 0 = never expire.\n#\n# 通知显示时长（毫秒）- 歌曲通知在屏幕上停留的时间。\n# -1 = 使用通知服务器的默认值，0 = 永不过期。"},
+		{"[app]", "notification_urgency", "notification_urgency = \"low\"", "# Notification urgency - \"low\", \"normal\" or \"critical\".\n# Some notification servers keep \"critical\" notifications until they are dismissed.\n#\n# 通知紧急程度 - \"low\"、\"normal\" 或 \"critical\"。\n# 部分通知服务器会一直保留 \"critical\" 级别的通知直到被手动关闭。"},
+		{"[app]", "web_remote_addr", "web_remote_addr = \"\"", "# Web remote address - when set (e.g. \"127.0.0.1:8080\" or \":8080\"), BM serves a small web page\n# and a REST/SSE API for controlling playback from a browser or phone.\n# Empty = disabled. Only requests from the page itself can control playback. Without web_remote_token\n# anyone who can reach the address can read the status, playlist and library, so set a token or only\n# bind it to trusted networks. Without a token, the page must also be opened by an IP address of this machine, as\n# localhost or as the host in web_remote_addr.\n#\n# 网页遥控地址 - 设置后（例如 \"127.0.0.1:8080\" 或 \":8080\"），BM 会提供一个小型网页\n# 以及 REST/SSE API，可以通过浏览器或手机控制播放。\n# 留空 = 禁用。只有来自该网页本身的请求可以控制播放。未设置 web_remote_token 时，任何能访问该地址的人\n# 都可以读取状态、播放列表和媒体库，因此请设置令牌或只绑定到可信网络。未设置令牌时，\n# 还必须通过本机的 IP 地址、localhost 或 web_remote_addr 中的主机名打开网页。"},
+		{"[app]", "web_remote_token", "web_remote_token = \"\"", "# Web remote token - when set, the web remote API, both reading the player state and controlling playback,\n# needs this token. Open the page once as http://host:port/?token=<token>; the browser remembers it. API\n# clients send it as an \"Authorization: Bearer <token>\" header or a ?token= parameter. Empty = no token.\n#\n# 网页遥控令牌 - 设置后，网页遥控 API（包括读取播放器状态和控制播放）都需要此令牌。\n# 以 http://host:port/?token=<令牌> 打开一次网页，浏览器会记住它。API 客户端以\n# \"Authorization: Bearer <令牌>\" 请求头或 ?token= 参数发送它。留空 = 不使用令牌。"},
+		{"[app]", "stream_addr", "stream_addr = \"\"", "# Audio stream address - when set (e.g. \"0.0.0.0:8000\"), BM serves the audio it is playing as an\n# endless 16-bit PCM WAV stream over HTTP, so other devices can listen along\n# (e.g. `curl http://host:8000/ | mpv -` or open the URL in VLC). Multiple listeners are supported.\n# Empty = disabled. The stream has no authentication, so only bind it to trusted networks.\n#\n# 音频流地址 - 设置后（例如 \"0.0.0.0:8000\"），BM 会通过 HTTP 将正在播放的音频作为\n# 无限长的 16 位 PCM WAV 流提供，其他设备可以同步收听\n# （例如 `curl http://host:8000/ | mpv -`，或在 VLC 中打开该地址）。支持多个收听者。\n# 留空 = 禁用。音频流没有身份验证，请只绑定到可信网络。"},
+		{"[app]", "stream_sample_rate", "stream_sample_rate = 44100", "# Audio stream sample rate (Hz) - songs are resampled to this rate so the stream format never changes.\n#\n# 音频流采样率（Hz）- 歌曲会被重采样到该采样率，使音频流格式保持不变。"},
+		{"[app]", "stream_mute_local", "stream_mute_local = false", "# Mute local output while streaming - when true, the speaker plays silence and the audio is only\n# heard through the stream. The sound card still sets the playback pace.\n#\n# 推流时静音本地输出 - 设为 true 时扬声器输出静音，音频只通过音频流收听。\n# 播放节奏仍由声卡控制。"},
+		{"[app]", "audio_backend", "audio_backend = \"auto\"", "# Audio output backend - \"auto\", \"pulse\", \"alsa\", \"file\" or \"null\".\n# auto = PulseAudio (also works with pipewire-pulse), falling back to ALSA when no server is running.\n# alsa = talk to ALSA directly through libasound, for systems without a sound server.\n# file = write the audio to the output device path in real time: a path ending in \".wav\" gets a WAV header,\n#        anything else receives raw little-endian PCM, both in the output_format. A FIFO works too.\n# null = discard the audio (useful for headless machines and testing).\n# Can be overridden with the --backend command line flag.\n#\n# 音频输出后端 - \"auto\"、\"pulse\"、\"alsa\"、\"file\" 或 \"null\"。\n# auto = 使用 PulseAudio（也支持 pipewire-pulse），没有运行声音服务器时回退到 ALSA。\n# alsa = 通过 libasound 直接使用 ALSA，适用于没有声音服务器的系统。\n# file = 实时将音频写入输出设备路径：以 \".wav\" 结尾的路径会带有 WAV 头，\n#        其他路径写入原始小端 PCM，两者均使用 output_format 的格式。也可以使用 FIFO。\n# null = 丢弃音频（适用于无头机器和测试）。\n# 可以通过命令行参数 --backend 覆盖。"},
+		{"[app]", "output_device", "output_device = \"\"", "# Output device - the device BM plays to at startup. Empty = the backend's default.\n# pulse: the sink name (see `pactl list short sinks`), alsa: the PCM name (e.g. \"plughw:0,0\"),\n# file: the output path (required). The null backend ignores it.\n# Can be overridden with the --device command line flag, and switched live with the\n# SelectDevice key on the player page.\n#\n# 输出设备 - BM 启动时使用的播放设备。留空 = 后端的默认设备。\n# pulse：sink 名称（见 `pactl list short sinks`），alsa：PCM 名称（例如 \"plughw:0,0\"），\n# file：输出路径（必填）。null 后端会忽略此项。\n# 可以通过命令行参数 --device 覆盖，也可以在播放器页面通过 SelectDevice 按键实时切换。"},
+		{"[app]", "output_format", "output_format = \"16\"", "# Output sample format - \"16\", \"24\" or \"float32\".\n# 16 = 16-bit integers, 24 = 24-bit integers, float32 = 32-bit float (keeps 24-bit sources intact).\n# Lower depths lose precision on high-resolution files.\n#\n# 输出采样格式 - \"16\"、\"24\" 或 \"float32\"。\n# 16 = 16 位整数，24 = 24 位整数，float32 = 32 位浮点（可完整保留 24 位音源）。\n# 较低的位深会损失高解析度文件的精度。"},
+		{"[app]", "dither", "dither = true", "# Dither - add TPDF dither when a song has more bits than the integer output format,\n# e.g. a 24-bit FLAC played with output_format = \"16\". Has no effect on float32 output.\n#\n# 抖动 - 当歌曲的位深高于整数输出格式时加入 TPDF 抖动，\n# 例如使用 output_format = \"16\" 播放 24 位 FLAC。对 float32 输出无效。"},
+		{"[app]", "bit_perfect", "bit_perfect = false", "# Bit-perfect mode - skip the volume control and the resampler while the volume is 100%\n# and the playback rate is 1.00x, so samples reach the output unchanged.\n# The output runs at each song's own sample rate; pick an output_format deep enough for\n# your files (24 or float32 for high-resolution music).\n# For exclusive output, use audio_backend = \"alsa\" with a hardware device such as\n# output_device = \"hw:0,0\", which bypasses the sound server and its mixer.\n#\n# 比特完美模式 - 当音量为 100% 且播放速度为 1.00x 时跳过音量控制和重采样器，\n# 使采样原样到达输出。\n# 输出始终使用每首歌曲自身的采样率；请选择足够的 output_format 位深\n# （高解析度音乐请使用 24 或 float32）。\n# 如需独占输出，请使用 audio_backend = \"alsa\" 并指定硬件设备，例如\n# output_device = \"hw:0,0\"，以绕过声音服务器及其混音器。"},
+		{"[app]", "downmix_center", "downmix_center = 0.707", "# Downmix center - surround files (5.1, 7.1, ...) are folded into stereo. This is the gain\n# of the center channel in the left and right speakers (0.707 = -3 dB).\n#\n# 缩混中置 - 环绕声文件（5.1、7.1 等）会缩混为立体声。此项为中置声道在左右扬声器中的增益\n# （0.707 = -3 dB）。"},
+		{"[app]", "downmix_surround", "downmix_surround = 0.707", "# Downmix surround - gain of each surround/back channel on its own side (0.707 = -3 dB).\n#\n# 缩混环绕 - 每个环绕/后置声道在同侧扬声器中的增益（0.707 = -3 dB）。"},
+		{"[app]", "downmix_lfe", "downmix_lfe = 0.0", "# Downmix LFE - gain of the subwoofer channel in both speakers (0 = dropped).\n#\n# 缩混低音 - 低音炮声道在两个扬声器中的增益（0 = 丢弃）。"},
+		{"[app]", "downmix_normalize", "downmix_normalize = true", "# Normalize the downmix - scale the coefficients down so that a loud surround mix never clips.\n#\n# 缩混归一化 - 按比例降低系数，使响亮的环绕声缩混不会削波。"},
+		{"[app]", "mono_gain", "mono_gain = 1.0", "# Mono gain - level of a mono file in each speaker.\n# 1.0 plays it at full level on both sides, 0.707 keeps the same loudness as a centered stereo source.\n#\n# 单声道增益 - 单声道文件在每个扬声器中的音量。\n# 1.0 表示在两侧以原始音量播放，0.707 表示与居中的立体声音源保持相同响度。"},
+		{"[app]", "read_ahead_kb", "read_ahead_kb = 4096", "# Read-ahead buffer (KiB) - songs are read ahead into a buffer of this size in the background, so that\n# slow storage such as NFS or SMB mounts does not make the audio stutter. When the buffer runs dry,\n# BM plays silence and shows \"buffering\" until enough data has arrived. 0 = read the files directly.\n#\n# 预读缓冲区（KiB）- 歌曲会在后台被预读到该大小的缓冲区中，使 NFS 或 SMB 挂载等\n# 慢速存储不会导致音频卡顿。缓冲区耗尽时，BM 会播放静音并显示 \"buffering\"，直到收到足够的数据。\n# 0 = 直接读取文件。"},
+		{"[app]", "cover_cache_mb", "cover_cache_mb = 64", "# Cover cache size (MiB) - covers are kept resized for the terminal, with their color palette and the\n# image for notifications and MPRIS, under ~/.cache/BM/covers, so that switching songs does not decode\n# and resize them again. The least recently used covers are removed when the cache grows past this size.\n#\n# 封面缓存大小（MiB）- 封面会按终端尺寸缩放后，连同其调色板和用于通知及 MPRIS 的图片一起保存在\n# ~/.cache/BM/covers 中，使切换歌曲时无需再次解码和缩放。缓存超过该大小时会删除最近最少使用的封面。"},
+		{"[app]", "status_format", "status_format = \"\"", "# Status format - what the player page shows in place of the title, artist and album lines. Fields are\n# written as {name}: {title}, {artist}, {album}, {year}, {track}, {genre}, {file}, {elapsed}, {duration},\n# {remaining}, {codec}, {bitrate} (kbps), {samplerate}, {bits}, {channels}, {volume}, {rate}, {mode} and\n# {state}. \"\\n\" starts a new line, up to three. Empty = title, artist and album.\n# Example: \"{artist} — {title}\\n{album} {year}\\n{elapsed}/{duration} {bitrate}kbps {samplerate}\"\n#\n# 状态格式 - 播放器页面中代替标题、艺术家和专辑行显示的内容。字段写作 {name}：{title}、{artist}、\n# {album}、{year}、{track}、{genre}、{file}、{elapsed}、{duration}、{remaining}、{codec}、{bitrate}（kbps）、\n# {samplerate}、{bits}、{channels}、{volume}、{rate}、{mode} 和 {state}。\"\\n\" 开始新的一行，最多三行。\n# 留空 = 标题、艺术家和专辑。"},
+		{"[app]", "window_title_format", "window_title_format = \"\"", "# Window title format - when set, the terminal's window title is set to this format on every song change\n# (OSC 2), so tiling window manager title bars show the song. Uses the same fields as status_format.\n# The previous title is restored on exit. Empty = leave the window title alone.\n# Example: \"{artist} - {title}\"\n#\n# 窗口标题格式 - 设置后，每次切换歌曲时都会将终端窗口标题设置为该格式（OSC 2），使平铺式窗口管理器的\n# 标题栏显示当前歌曲。字段与 status_format 相同。退出时恢复原来的标题。留空 = 不修改窗口标题。"},
 	}
 
 	for _, missing := range missingKeys {
@@ -375,8 +465,8 @@ func updateConfigFile(configPath string) error {
 }
 
 func validateKeymap(keymap Keymap) error {
-	pages := []any{keymap.Global, keymap.Player, keymap.Library, keymap.Playlist}
-	pageNames := []string{"Global", "Player", "Library", "Playlist"}
+	pages := []any{keymap.Global, keymap.Player, keymap.Library, keymap.Playlist, keymap.Split}
+	pageNames := []string{"Global", "Player", "Library", "Playlist", "Split"}
 
 	for i, page := range pages {
 		normalModeKeys := make(map[rune]string)
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLoadConfigDefaultPage(t *testing.T) {
+	old := GlobalConfig
+	t.Cleanup(func() { GlobalConfig = old })
+
+	tests := []struct {
+		in, want int
+	}{
+		{0, 0},
+		{2, 2},
+		{defaultPageMemory, defaultPageMemory},
+		{defaultPageSplit, defaultPageSplit},
+		{5, 0},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		dir := filepath.Join(home, ".config", "BM")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		config := "[app]\ndefault_page = " + strconv.Itoa(tt.in) + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := LoadConfig(); err != nil {
+			t.Fatalf("LoadConfig with default_page = %d: %v", tt.in, err)
+		}
+		if got := GlobalConfig.App.DefaultPage; got != tt.want {
+			t.Errorf("LoadConfig with default_page = %d: DefaultPage = %d; want %d", tt.in, got, tt.want)
+		}
+		if got := GlobalConfig.Keymap.Split.FocusNext; len(got) != 1 || got[0] != "tab" {
+			t.Errorf("LoadConfig with default_page = %d: Split.FocusNext = %v; want [tab]", tt.in, got)
+		}
+	}
+}
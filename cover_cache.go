@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nfnt/resize"
+)
+
+// coverFileSize is the largest width and height of the PNG given to notifications, MPRIS
+// clients and the web remote.
+//
+// coverFileSize 是提供给通知、MPRIS 客户端和网页遥控的 PNG 的最大宽度和高度。
+const coverFileSize = 512
+
+// coverCache keeps what is derived from cover images on disk, under
+// $XDG_CACHE_HOME/BM/covers, so that it is not computed again the next time a song with the
+// same cover is played. Entries are directories named after a hash of the encoded image and
+// hold its color palette, a PNG for notifications and MPRIS, and the cover resized to each
+// size the player has drawn it at. When the cache grows past its limit, the least recently
+// used entries are removed.
+//
+// coverCache 将从封面图片派生的数据保存在磁盘上的 $XDG_CACHE_HOME/BM/covers 中，
+// 使下次播放具有相同封面的歌曲时无需重新计算。每个条目是以编码图片的哈希命名的目录，
+// 保存其调色板、用于通知和 MPRIS 的 PNG，以及播放器绘制过的各尺寸缩放封面。
+// 缓存超过上限时，会删除最近最少使用的条目。
+type coverCache struct {
+	dir   string
+	limit int64
+
+	mu sync.Mutex // Serializes eviction. / 串行化淘汰操作。
+}
+
+var (
+	coverCacheOnce   sync.Once
+	sharedCoverCache *coverCache
+)
+
+// getCoverCache returns the cover cache, or nil if there is no cache directory.
+//
+// getCoverCache 返回封面缓存；没有缓存目录时返回 nil。
+func getCoverCache() *coverCache {
+	coverCacheOnce.Do(func() {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return
+		}
+		limit := 64
+		if GlobalConfig != nil {
+			limit = GlobalConfig.App.CoverCacheMB
+		}
+		sharedCoverCache = &coverCache{
+			dir:   filepath.Join(dir, "BM", "covers"),
+			limit: int64(limit) << 20,
+		}
+	})
+	return sharedCoverCache
+}
+
+// coverKey returns the cache key of an encoded cover image.
+//
+// coverKey 返回编码封面图片的缓存键。
+func coverKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// entry returns the directory of a cache entry and marks it as used.
+//
+// entry 返回缓存条目的目录，并将其标记为已使用。
+func (c *coverCache) entry(key string) string {
+	dir := filepath.Join(c.dir, key)
+	now := time.Now()
+	os.Chtimes(dir, now, now)
+	return dir
+}
+
+// palette returns the cached palette of a cover.
+//
+// palette 返回封面的缓存调色板。
+func (c *coverCache) palette(key string) (coverPalette, bool) {
+	var palette coverPalette
+	if c == nil {
+		return palette, false
+	}
+	data, err := os.ReadFile(filepath.Join(c.entry(key), "palette.json"))
+	if err != nil {
+		return palette, false
+	}
+	return palette, json.Unmarshal(data, &palette) == nil
+}
+
+// storePalette caches the palette of a cover.
+//
+// storePalette 缓存封面的调色板。
+func (c *coverCache) storePalette(key string, palette coverPalette) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(palette)
+	if err != nil {
+		return
+	}
+	c.write(key, "palette.json", func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	})
+}
+
+// coverFile returns the path of the cached PNG of a cover for notifications and MPRIS,
+// writing it from img first if needed, or "" if it cannot be written.
+//
+// coverFile 返回封面用于通知和 MPRIS 的缓存 PNG 路径，必要时先由 img 写入；无法写入时返回 ""。
+func (c *coverCache) coverFile(key string, img image.Image) string {
+	if c == nil {
+		return ""
+	}
+	path := filepath.Join(c.entry(key), "cover.png")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	small := resize.Thumbnail(coverFileSize, coverFileSize, img, resize.Lanczos3)
+	if !c.write(key, "cover.png", func(f *os.File) error { return png.Encode(f, small) }) {
+		return ""
+	}
+	return path
+}
+
+// variant returns the cover resized to fit the given pixel size, if it is cached.
+//
+// variant 返回缩放到给定像素尺寸的封面（如果已缓存）。
+func (c *coverCache) variant(key string, width, height int) image.Image {
+	if c == nil {
+		return nil
+	}
+	img, err := loadImageFile(filepath.Join(c.entry(key), variantName(width, height)))
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// storeVariant caches the cover resized to fit the given pixel size.
+//
+// storeVariant 缓存缩放到给定像素尺寸的封面。
+func (c *coverCache) storeVariant(key string, width, height int, img image.Image) {
+	if c == nil {
+		return
+	}
+	c.write(key, variantName(width, height), func(f *os.File) error { return png.Encode(f, img) })
+}
+
+func variantName(width, height int) string {
+	return fmt.Sprintf("%dx%d.png", width, height)
+}
+
+// write writes a file of a cache entry through a temporary file, so that readers never see
+// it half written, and then evicts old entries if the cache is too large.
+//
+// write 通过临时文件写入缓存条目中的文件，使读取方不会看到写了一半的文件，
+// 然后在缓存过大时淘汰旧条目。
+func (c *coverCache) write(key, name string, encode func(*os.File) error) bool {
+	dir := filepath.Join(c.dir, key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false
+	}
+	f, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return false
+	}
+	err = encode(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return false
+	}
+
+	c.evict()
+	return true
+}
+
+// evict removes the least recently used entries until the cache fits its limit.
+//
+// evict 删除最近最少使用的条目，直到缓存符合其上限。
+func (c *coverCache) evict() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	type entryInfo struct {
+		path string
+		used time.Time
+		size int64
+	}
+	var entries []entryInfo
+	var total int64
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		e := entryInfo{path: filepath.Join(c.dir, d.Name()), used: info.ModTime()}
+		files, _ := os.ReadDir(e.path)
+		for _, file := range files {
+			if fi, err := file.Info(); err == nil {
+				e.size += fi.Size()
+			}
+		}
+		entries = append(entries, e)
+		total += e.size
+	}
+	if total <= c.limit {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	// The most recently used entry is the current song's and is kept.
+	// 最近使用的条目属于当前歌曲，予以保留。
+	for _, e := range entries[:len(entries)-1] {
+		if total <= c.limit {
+			break
+		}
+		if os.RemoveAll(e.path) == nil {
+			total -= e.size
+		}
+	}
+}
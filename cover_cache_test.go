@@ -0,0 +1,151 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// makeCacheEntry creates an entry of size bytes last used at used.
+func makeCacheEntry(t *testing.T, c *coverCache, key string, size int, used time.Time) {
+	t.Helper()
+	dir := filepath.Join(c.dir, key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cover.png"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir, used, used); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func cacheEntries(t *testing.T, c *coverCache) []string {
+	t.Helper()
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, d := range dirs {
+		keys = append(keys, d.Name())
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestCoverCacheEvict(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int64
+		sizes []int // Entry sizes, from least to most recently used. / 条目大小，按最近使用时间从早到晚排列。
+		want  []string
+	}{
+		{"under limit", 1000, []int{300, 300, 300}, []string{"a", "b", "c"}},
+		{"at limit", 900, []int{300, 300, 300}, []string{"a", "b", "c"}},
+		{"oldest removed", 800, []int{300, 300, 300}, []string{"b", "c"}},
+		{"several removed", 500, []int{300, 100, 300, 200}, []string{"c", "d"}},
+		{"latest kept", 100, []int{300, 300, 300}, []string{"c"}},
+	}
+	base := time.Now().Add(-time.Hour)
+	for _, tt := range tests {
+		c := &coverCache{dir: t.TempDir(), limit: tt.limit}
+		for i, size := range tt.sizes {
+			makeCacheEntry(t, c, string(rune('a'+i)), size, base.Add(time.Duration(i)*time.Minute))
+		}
+		c.evict()
+		if got := cacheEntries(t, c); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: entries after evict = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCoverCacheEntryMarksUsed(t *testing.T) {
+	c := &coverCache{dir: t.TempDir(), limit: 500}
+	base := time.Now().Add(-time.Hour)
+	makeCacheEntry(t, c, "a", 300, base)
+	makeCacheEntry(t, c, "b", 300, base.Add(time.Minute))
+
+	c.entry("a")
+	c.evict()
+	if got, want := cacheEntries(t, c), []string{"a"}; !slices.Equal(got, want) {
+		t.Errorf("entries after using a = %v; want %v", got, want)
+	}
+}
+
+func TestCoverCacheWriteEvicts(t *testing.T) {
+	c := &coverCache{dir: t.TempDir(), limit: 500}
+	makeCacheEntry(t, c, "a", 490, time.Now().Add(-time.Hour))
+
+	palette := coverPalette{Primary: &[3]int{1, 2, 3}}
+	c.storePalette("b", palette)
+	got, ok := c.palette("b")
+	if !ok || got.Primary == nil || *got.Primary != *palette.Primary {
+		t.Errorf("palette(b) = %+v, %v; want %+v, true", got, ok, palette)
+	}
+	c.storeVariant("b", 400, 1, image.NewGray(image.Rect(0, 0, 400, 1)))
+	if got, want := cacheEntries(t, c), []string{"b"}; !slices.Equal(got, want) {
+		t.Errorf("entries after writing b = %v; want %v", got, want)
+	}
+	if img := c.variant("b", 400, 1); img == nil || img.Bounds().Dx() != 400 {
+		t.Errorf("variant(b, 400, 1) = %v; want a 400x1 image", img)
+	}
+	if img := c.variant("b", 200, 1); img != nil {
+		t.Errorf("variant(b, 200, 1) = %v; want nil", img)
+	}
+}
+
+func TestCoverCacheCoverFile(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int
+	}{
+		{100, 50, 100, 50},
+		{1024, 1024, coverFileSize, coverFileSize},
+		{2000, 1000, coverFileSize, coverFileSize / 2},
+	}
+	for _, tt := range tests {
+		c := &coverCache{dir: t.TempDir(), limit: 64 << 20}
+		path := c.coverFile("k", image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)))
+		if path == "" {
+			t.Errorf("coverFile(%dx%d) = \"\"", tt.w, tt.h)
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := png.DecodeConfig(f)
+		f.Close()
+		if err != nil || cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+			t.Errorf("coverFile(%dx%d) is %dx%d, %v; want %dx%d", tt.w, tt.h, cfg.Width, cfg.Height, err, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestCoverCacheNil(t *testing.T) {
+	var c *coverCache
+	if _, ok := c.palette("k"); ok {
+		t.Error("nil cache palette(k) ok = true; want false")
+	}
+	if path := c.coverFile("k", image.NewRGBA(image.Rect(0, 0, 1, 1))); path != "" {
+		t.Errorf("nil cache coverFile(k) = %q; want \"\"", path)
+	}
+	if img := c.variant("k", 1, 1); img != nil {
+		t.Errorf("nil cache variant(k) = %v; want nil", img)
+	}
+	c.storePalette("k", coverPalette{})
+	c.storeVariant("k", 1, 1, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+}
+
+func TestCoverKey(t *testing.T) {
+	a, b := coverKey([]byte("cover a")), coverKey([]byte("cover b"))
+	if len(a) != 32 || a == b || a != coverKey([]byte("cover a")) {
+		t.Errorf("coverKey = %q, %q; want distinct stable 32-character keys", a, b)
+	}
+}
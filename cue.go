@@ -0,0 +1,741 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// cueTrackMarker separates the audio file from the track number in the path of a virtual
+// cue track, e.g. "/music/album.flac#track03".
+//
+// cueTrackMarker 在虚拟 cue 音轨的路径中分隔音频文件和音轨编号，例如 "/music/album.flac#track03"。
+const cueTrackMarker = "#track"
+
+// cueCheckInterval is how long cached cue sheets are used before the files are checked for
+// changes again, so that drawing the library does not stat every sheet each time.
+//
+// cueCheckInterval 是缓存的 cue 表在再次检查文件是否改变之前的使用时长，
+// 使绘制媒体库时不必每次都对每个 cue 表执行 stat。
+const cueCheckInterval = 2 * time.Second
+
+// cueTrack is one track of a cue sheet. Start and End are offsets into the audio file;
+// an End of 0 means the track lasts until the end of the file.
+//
+// cueTrack 是 cue 表中的一条音轨。Start 和 End 是相对音频文件的偏移；
+// End 为 0 表示音轨持续到文件末尾。
+type cueTrack struct {
+	Number    int
+	Title     string
+	Performer string
+	Album     string
+	Start     time.Duration
+	End       time.Duration
+
+	file string // The FILE the track belongs to, as written in the sheet. / 音轨所属的 FILE，与 cue 表中的写法一致。
+}
+
+// cueDirIndex holds the tracks of the cue sheets in one directory, keyed by audio file path.
+//
+// cueDirIndex 保存一个目录中 cue 表的音轨，以音频文件路径为键。
+type cueDirIndex struct {
+	modTime time.Time
+	checked time.Time            // When the files were last checked for changes. / 最近一次检查文件是否改变的时间。
+	sheets  map[string]time.Time // Cue sheet path to its modification time. / cue 表路径到其修改时间。
+	tracks  map[string][]cueTrack
+}
+
+// cueEmbedded holds the tracks of a cue sheet embedded in a FLAC file.
+//
+// cueEmbedded 保存嵌入在 FLAC 文件中的 cue 表音轨。
+type cueEmbedded struct {
+	modTime time.Time
+	size    int64
+	checked time.Time // When the file was last checked for changes. / 最近一次检查文件是否改变的时间。
+	tracks  []cueTrack
+}
+
+// cueCache avoids parsing cue sheets again every time the library is drawn.
+//
+// cueCache 避免每次绘制媒体库时都重新解析 cue 表。
+var cueCache = struct {
+	sync.Mutex
+	dirs     map[string]cueDirIndex
+	embedded map[string]cueEmbedded
+}{
+	dirs:     make(map[string]cueDirIndex),
+	embedded: make(map[string]cueEmbedded),
+}
+
+// cueTrackPath returns the path of a virtual cue track.
+//
+// cueTrackPath 返回虚拟 cue 音轨的路径。
+func cueTrackPath(audioPath string, number int) string {
+	return fmt.Sprintf("%s%s%02d", audioPath, cueTrackMarker, number)
+}
+
+// splitCueTrackPath splits the path of a virtual cue track into the audio file and the
+// track number. ok is false for the paths of ordinary files.
+//
+// splitCueTrackPath 将虚拟 cue 音轨的路径拆分为音频文件和音轨编号。对于普通文件的路径，ok 为 false。
+func splitCueTrackPath(path string) (audioPath string, number int, ok bool) {
+	i := strings.LastIndex(path, cueTrackMarker)
+	if i <= 0 {
+		return path, 0, false
+	}
+	number, err := strconv.Atoi(path[i+len(cueTrackMarker):])
+	if err != nil || number <= 0 {
+		return path, 0, false
+	}
+	return path[:i], number, true
+}
+
+// cueAudioPath returns the audio file a song is stored in, which is the path itself for
+// ordinary files.
+//
+// cueAudioPath 返回歌曲所在的音频文件，对于普通文件就是路径本身。
+func cueAudioPath(path string) string {
+	audioPath, _, _ := splitCueTrackPath(path)
+	return audioPath
+}
+
+// findCueTrack returns the cue track a virtual path refers to.
+//
+// findCueTrack 返回虚拟路径所指的 cue 音轨。
+func findCueTrack(path string) (cueTrack, bool) {
+	audioPath, number, ok := splitCueTrackPath(path)
+	if !ok {
+		return cueTrack{}, false
+	}
+	for _, track := range cueTracks(audioPath) {
+		if track.Number == number {
+			return track, true
+		}
+	}
+	return cueTrack{}, false
+}
+
+// songPaths returns the songs stored in an audio file: one virtual track for each entry of
+// its cue sheet, or just the file itself.
+//
+// songPaths 返回音频文件中存储的歌曲：cue 表中的每个条目对应一条虚拟音轨，否则就是文件本身。
+func songPaths(filePath string) []string {
+	tracks := cueTracks(filePath)
+	if len(tracks) == 0 {
+		return []string{filePath}
+	}
+	paths := make([]string, len(tracks))
+	for i, track := range tracks {
+		paths[i] = cueTrackPath(filePath, track.Number)
+	}
+	return paths
+}
+
+// songDisplayName returns the name shown for a song in the library and the playlist:
+// "03. Title" for cue tracks and the file name otherwise.
+//
+// songDisplayName 返回歌曲在媒体库和播放列表中显示的名称：cue 音轨为 "03. 标题"，否则为文件名。
+func songDisplayName(path string) string {
+	if track, ok := findCueTrack(path); ok {
+		return fmt.Sprintf("%02d. %s", track.Number, track.Title)
+	}
+	return filepath.Base(path)
+}
+
+// songSearchTarget returns the string the search engine matches a song against. For cue
+// tracks it is the file name followed by the track title and performer.
+//
+// songSearchTarget 返回搜索引擎用于匹配歌曲的字符串。对于 cue 音轨，它是文件名后接音轨标题和演唱者。
+func songSearchTarget(path string) string {
+	track, ok := findCueTrack(path)
+	if !ok {
+		return path
+	}
+	name := strings.Join([]string{filepath.Base(cueAudioPath(path)), songDisplayName(path), track.Performer}, " ")
+	// The engine works on the base name, so the title must not contain separators.
+	// 搜索引擎基于文件名工作，因此标题中不能包含路径分隔符。
+	name = strings.NewReplacer("/", " ", string(filepath.Separator), " ").Replace(name)
+	return filepath.Join(filepath.Dir(path), name)
+}
+
+// cueTracks returns the tracks of an audio file described by a cue sheet next to it or
+// embedded in it. Files with fewer than two tracks are played as a whole and return nil.
+//
+// cueTracks 返回由同目录的 cue 表或嵌入的 cue 表描述的音频文件音轨。
+// 少于两条音轨的文件会被整体播放，并返回 nil。
+func cueTracks(audioPath string) []cueTrack {
+	tracks, found := cueDirTracks(audioPath)
+	if !found && strings.EqualFold(filepath.Ext(audioPath), ".flac") {
+		tracks = cueEmbeddedTracks(audioPath)
+	}
+	if len(tracks) < 2 {
+		return nil
+	}
+	return tracks
+}
+
+// cueDirTracks looks the audio file up in the cue sheets of its directory. The sheets are
+// parsed once per directory and parsed again when the modification time of the directory
+// or of a sheet changes, which is checked at most every cueCheckInterval.
+//
+// cueDirTracks 在音频文件所在目录的 cue 表中查找该文件。每个目录的 cue 表只解析一次，
+// 当目录或某个 cue 表的修改时间改变时重新解析，最多每隔 cueCheckInterval 检查一次。
+func cueDirTracks(audioPath string) ([]cueTrack, bool) {
+	dir := filepath.Dir(audioPath)
+
+	cueCache.Lock()
+	defer cueCache.Unlock()
+
+	index, cached := cueCache.dirs[dir]
+	if !cached || time.Since(index.checked) >= cueCheckInterval {
+		info, err := os.Stat(dir)
+		if err != nil {
+			delete(cueCache.dirs, dir)
+			return nil, false
+		}
+
+		// Editing a sheet does not change the modification time of the directory, so the
+		// sheets are checked as well.
+		// 编辑 cue 表不会改变目录的修改时间，因此也要检查各个 cue 表。
+		valid := cached && index.modTime.Equal(info.ModTime())
+		for sheet, modTime := range index.sheets {
+			if !valid {
+				break
+			}
+			if sheetInfo, err := os.Stat(sheet); err != nil || !sheetInfo.ModTime().Equal(modTime) {
+				valid = false
+			}
+		}
+		if !valid {
+			index = buildCueDirIndex(dir, info.ModTime())
+		}
+		index.checked = time.Now()
+		cueCache.dirs[dir] = index
+	}
+
+	tracks, found := index.tracks[audioPath]
+	return tracks, found
+}
+
+// buildCueDirIndex parses every cue sheet in a directory.
+//
+// buildCueDirIndex 解析目录中的所有 cue 表。
+func buildCueDirIndex(dir string, modTime time.Time) cueDirIndex {
+	index := cueDirIndex{
+		modTime: modTime,
+		sheets:  make(map[string]time.Time),
+		tracks:  make(map[string][]cueTrack),
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return index
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.EqualFold(filepath.Ext(file.Name()), ".cue") {
+			continue
+		}
+		sheetPath := filepath.Join(dir, file.Name())
+		info, err := os.Stat(sheetPath)
+		if err != nil {
+			continue
+		}
+		index.sheets[sheetPath] = info.ModTime()
+
+		data, err := os.ReadFile(sheetPath)
+		if err != nil {
+			l.Warnf("failed to read cue sheet %s: %v\n\n警告: 读取 cue 表 %s 失败: %v", sheetPath, err, sheetPath, err)
+			continue
+		}
+		for file, tracks := range groupCueTracks(parseCueSheet(bytes.NewReader(data))) {
+			audioPath := resolveCueFile(dir, file)
+			if audioPath == "" {
+				continue
+			}
+			// The first sheet wins if several describe the same file.
+			// 如果多个 cue 表描述同一个文件，以第一个为准。
+			if _, exists := index.tracks[audioPath]; !exists {
+				index.tracks[audioPath] = tracks
+			}
+		}
+	}
+	return index
+}
+
+// resolveCueFile finds the audio file a FILE command refers to. Rips are often re-encoded
+// without updating the sheet, so a file with the same name and another audio extension is
+// accepted too.
+//
+// resolveCueFile 查找 FILE 命令所指的音频文件。音轨常在重新编码后未更新 cue 表，
+// 因此同名但扩展名不同的音频文件也会被接受。
+func resolveCueFile(dir, name string) string {
+	name = filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+	candidate := filepath.Join(dir, filepath.Base(name))
+	if info, err := os.Stat(candidate); err == nil && !info.IsDir() && isAudioFile(candidate) {
+		return candidate
+	}
+
+	stem := strings.TrimSuffix(candidate, filepath.Ext(candidate))
+	for ext := range audioExtensions {
+		if info, err := os.Stat(stem + ext); err == nil && !info.IsDir() {
+			return stem + ext
+		}
+	}
+	return ""
+}
+
+// groupCueTracks groups the tracks of a sheet by FILE and sets the end of each track to
+// the start of the next one in the same file.
+//
+// groupCueTracks 按 FILE 对 cue 表的音轨分组，并将每条音轨的结束位置设为同一文件中下一条音轨的开始位置。
+func groupCueTracks(tracks []cueTrack) map[string][]cueTrack {
+	files := make(map[string][]cueTrack)
+	for _, track := range tracks {
+		files[track.file] = append(files[track.file], track)
+	}
+	for _, fileTracks := range files {
+		for i := 0; i+1 < len(fileTracks); i++ {
+			fileTracks[i].End = fileTracks[i+1].Start
+		}
+	}
+	return files
+}
+
+// parseCueSheet parses the tracks of a cue sheet. Track titles and performers default to
+// those of the album.
+//
+// parseCueSheet 解析 cue 表中的音轨。音轨标题和演唱者默认为专辑的标题和演唱者。
+func parseCueSheet(r io.Reader) []cueTrack {
+	var (
+		tracks         []cueTrack
+		album          string
+		albumPerformer string
+		file           string
+		current        *cueTrack
+		hasIndex01     bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToValidUTF8(strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff")), "")
+		fields := splitCueLine(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		arg := func(i int) string {
+			if i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "TITLE":
+			if current == nil {
+				album = arg(1)
+			} else {
+				current.Title = arg(1)
+			}
+		case "PERFORMER":
+			if current == nil {
+				albumPerformer = arg(1)
+			} else {
+				current.Performer = arg(1)
+			}
+		case "FILE":
+			file = arg(1)
+			current = nil
+		case "TRACK":
+			number, err := strconv.Atoi(arg(1))
+			if err != nil || !strings.EqualFold(arg(2), "AUDIO") {
+				current = nil
+				continue
+			}
+			tracks = append(tracks, cueTrack{Number: number, Start: -1, file: file})
+			current = &tracks[len(tracks)-1]
+			hasIndex01 = false
+		case "INDEX":
+			if current == nil {
+				continue
+			}
+			number, err := strconv.Atoi(arg(1))
+			if err != nil {
+				continue
+			}
+			offset, ok := parseCueTime(arg(2))
+			if !ok {
+				continue
+			}
+			// INDEX 01 is where the track starts; INDEX 00 marks the pregap and is only
+			// used when there is no INDEX 01.
+			// INDEX 01 是音轨的开始位置；INDEX 00 标记前间隙，仅在没有 INDEX 01 时使用。
+			if number == 1 || (number == 0 && !hasIndex01) {
+				current.Start = offset
+				hasIndex01 = number == 1
+			}
+		}
+	}
+
+	result := tracks[:0]
+	for _, track := range tracks {
+		if track.Start < 0 {
+			continue
+		}
+		if track.Title == "" {
+			track.Title = fmt.Sprintf("Track %02d", track.Number)
+		}
+		if track.Performer == "" {
+			track.Performer = albumPerformer
+		}
+		track.Album = album
+		result = append(result, track)
+	}
+	return result
+}
+
+// splitCueLine splits a cue sheet line into its command and arguments, keeping quoted
+// arguments together.
+//
+// splitCueLine 将 cue 表的一行拆分为命令和参数，带引号的参数保持完整。
+func splitCueLine(line string) []string {
+	var fields []string
+	for line != "" {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			break
+		}
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				fields = append(fields, line[1:])
+				break
+			}
+			fields = append(fields, line[1:end+1])
+			line = line[end+2:]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			fields = append(fields, line)
+			break
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+	return fields
+}
+
+// parseCueTime parses an mm:ss:ff timestamp, where ff counts CD frames of 1/75 second.
+//
+// parseCueTime 解析 mm:ss:ff 时间戳，其中 ff 以 1/75 秒的 CD 帧为单位。
+func parseCueTime(s string) (time.Duration, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var values [3]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		values[i] = v
+	}
+	frames := (values[0]*60+values[1])*75 + values[2]
+	return time.Duration(frames) * time.Second / 75, true
+}
+
+// cueEmbeddedTracks returns the tracks of the cue sheet embedded in a FLAC file. Like the
+// cue sheets of a directory, they are read again when the file changes, which is checked at
+// most every cueCheckInterval.
+//
+// cueEmbeddedTracks 返回嵌入在 FLAC 文件中的 cue 表音轨。与目录中的 cue 表一样，
+// 文件改变时会重新读取，最多每隔 cueCheckInterval 检查一次。
+func cueEmbeddedTracks(audioPath string) []cueTrack {
+	cueCache.Lock()
+	defer cueCache.Unlock()
+
+	cached, ok := cueCache.embedded[audioPath]
+	if ok && time.Since(cached.checked) < cueCheckInterval {
+		return cached.tracks
+	}
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		delete(cueCache.embedded, audioPath)
+		return nil
+	}
+	if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+		cached = cueEmbedded{modTime: info.ModTime(), size: info.Size(), tracks: readFLACCueSheet(audioPath)}
+	}
+	cached.checked = time.Now()
+	cueCache.embedded[audioPath] = cached
+	return cached.tracks
+}
+
+// readFLACCueSheet reads the cue sheet of a FLAC file from its metadata blocks. A
+// CUESHEET Vorbis comment is preferred because it carries the track titles; otherwise the
+// CUESHEET block is used, with the album tags for all tracks.
+//
+// readFLACCueSheet 从 FLAC 文件的元数据块中读取 cue 表。优先使用 CUESHEET Vorbis 注释，
+// 因为它包含音轨标题；否则使用 CUESHEET 块，并为所有音轨使用专辑标签。
+func readFLACCueSheet(audioPath string) []cueTrack {
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || string(magic[:]) != "fLaC" {
+		return nil
+	}
+
+	const (
+		blockStreamInfo    = 0
+		blockVorbisComment = 4
+		blockCueSheet      = 5
+		maxBlockSize       = 1 << 20
+	)
+
+	var (
+		sampleRate int
+		cueBlock   []byte
+		comments   map[string]string
+	)
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7f
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		switch {
+		case (blockType == blockStreamInfo || blockType == blockVorbisComment || blockType == blockCueSheet) && size <= maxBlockSize:
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil
+			}
+			switch blockType {
+			case blockStreamInfo:
+				if len(body) >= 13 {
+					sampleRate = int(body[10])<<12 | int(body[11])<<4 | int(body[12])>>4
+				}
+			case blockVorbisComment:
+				comments = parseVorbisComments(body)
+			case blockCueSheet:
+				cueBlock = body
+			}
+		default:
+			if _, err := r.Discard(size); err != nil {
+				return nil
+			}
+		}
+		if last {
+			break
+		}
+	}
+
+	if sheet, ok := comments["CUESHEET"]; ok {
+		if tracks := embeddedCueTracks(parseCueSheet(strings.NewReader(sheet))); len(tracks) > 0 {
+			return tracks
+		}
+	}
+	if cueBlock == nil || sampleRate <= 0 {
+		return nil
+	}
+	return parseFLACCueSheetBlock(cueBlock, sampleRate, comments["ALBUM"], comments["ARTIST"])
+}
+
+// embeddedCueTracks returns the tracks of a cue sheet embedded in a FLAC file in the order
+// of the sheet. All tracks belong to this file, whatever the sheet calls it.
+//
+// embeddedCueTracks 按 cue 表中的顺序返回嵌入在 FLAC 文件中的 cue 表音轨。无论 cue 表中
+// 如何命名，所有音轨都属于此文件。
+func embeddedCueTracks(tracks []cueTrack) []cueTrack {
+	for i := range tracks {
+		tracks[i].file = ""
+	}
+	return groupCueTracks(tracks)[""]
+}
+
+// parseVorbisComments parses the body of a VORBIS_COMMENT block into upper-case keys and
+// their first values.
+//
+// parseVorbisComments 将 VORBIS_COMMENT 块的内容解析为大写的键及其第一个值。
+func parseVorbisComments(body []byte) map[string]string {
+	comments := make(map[string]string)
+	next := func() (string, bool) {
+		if len(body) < 4 {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(body))
+		body = body[4:]
+		if n < 0 || n > len(body) {
+			return "", false
+		}
+		s := string(body[:n])
+		body = body[n:]
+		return s, true
+	}
+
+	if _, ok := next(); !ok { // Vendor string. / 供应商字符串。
+		return comments
+	}
+	if len(body) < 4 {
+		return comments
+	}
+	count := int(binary.LittleEndian.Uint32(body))
+	body = body[4:]
+	for i := 0; i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			break
+		}
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(key)
+		if _, exists := comments[key]; !exists {
+			comments[key] = value
+		}
+	}
+	return comments
+}
+
+// parseFLACCueSheetBlock parses a FLAC CUESHEET metadata block, whose offsets are given
+// in samples.
+//
+// parseFLACCueSheetBlock 解析 FLAC CUESHEET 元数据块，其中的偏移量以采样为单位。
+func parseFLACCueSheetBlock(body []byte, sampleRate int, album, performer string) []cueTrack {
+	// Media catalog number, lead-in samples, flags and reserved bytes.
+	// 媒体目录号、导入采样数、标志和保留字节。
+	const headerSize = 128 + 8 + 1 + 258
+	if len(body) < headerSize+1 {
+		return nil
+	}
+	numTracks := int(body[headerSize])
+	p := body[headerSize+1:]
+
+	var tracks []cueTrack
+	for i := 0; i < numTracks; i++ {
+		// Offset, number, ISRC, flags, reserved bytes and the number of indexes.
+		// 偏移量、编号、ISRC、标志、保留字节和索引数量。
+		if len(p) < 36 {
+			return nil
+		}
+		offset := binary.BigEndian.Uint64(p[0:8])
+		number := int(p[8])
+		isAudio := p[21]&0x80 == 0
+		numIndexes := int(p[35])
+		p = p[36:]
+
+		start := uint64(math.MaxUint64)
+		for j := 0; j < numIndexes; j++ {
+			if len(p) < 12 {
+				return nil
+			}
+			indexOffset := binary.BigEndian.Uint64(p[0:8])
+			indexNumber := p[8]
+			if indexNumber == 1 || (indexNumber == 0 && start == math.MaxUint64) {
+				start = offset + indexOffset
+			}
+			p = p[12:]
+		}
+
+		// 170 and 255 are the lead-out tracks of CD and non-CD sheets.
+		// 170 和 255 分别是 CD 和非 CD cue 表的导出音轨。
+		if number == 170 || number == 255 || !isAudio || start == math.MaxUint64 {
+			continue
+		}
+		tracks = append(tracks, cueTrack{
+			Number:    number,
+			Title:     fmt.Sprintf("Track %02d", number),
+			Performer: performer,
+			Album:     album,
+			Start:     time.Duration(float64(start) / float64(sampleRate) * float64(time.Second)),
+		})
+	}
+	return groupCueTracks(tracks)[""]
+}
+
+// cueTrackStreamer plays one track of a larger audio file. Positions and lengths are
+// relative to the start of the track, so the progress bar and seeking work as for
+// ordinary files, and the stream ends where the track does.
+//
+// cueTrackStreamer 播放较大音频文件中的一条音轨。位置和长度相对于音轨开始处，
+// 因此进度条和跳转与普通文件一样工作，并且流在音轨结束处结束。
+type cueTrackStreamer struct {
+	beep.StreamSeekCloser
+	start int
+	end   int // -1 for the end of the file. / -1 表示文件末尾。
+}
+
+// newCueTrackStreamer seeks the decoded audio file to the start of the track.
+//
+// newCueTrackStreamer 将解码后的音频文件跳转到音轨的开始位置。
+func newCueTrackStreamer(s beep.StreamSeekCloser, format beep.Format, track cueTrack) (*cueTrackStreamer, error) {
+	toSamples := func(d time.Duration) int {
+		return int(math.Round(d.Seconds() * float64(format.SampleRate)))
+	}
+
+	t := &cueTrackStreamer{StreamSeekCloser: s, start: min(toSamples(track.Start), s.Len()), end: -1}
+	if track.End > 0 {
+		t.end = max(t.start, min(toSamples(track.End), s.Len()))
+	}
+	if err := s.Seek(t.start); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *cueTrackStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	if t.end >= 0 {
+		remaining := t.end - t.StreamSeekCloser.Position()
+		if remaining <= 0 {
+			return 0, false
+		}
+		samples = samples[:min(len(samples), remaining)]
+	}
+	return t.StreamSeekCloser.Stream(samples)
+}
+
+func (t *cueTrackStreamer) Len() int {
+	if t.end >= 0 {
+		return t.end - t.start
+	}
+	return max(0, t.StreamSeekCloser.Len()-t.start)
+}
+
+func (t *cueTrackStreamer) Position() int {
+	return max(0, t.StreamSeekCloser.Position()-t.start)
+}
+
+func (t *cueTrackStreamer) Seek(p int) error {
+	return t.StreamSeekCloser.Seek(t.start + p)
+}
+
+// ChannelLayout returns the channel layout of the underlying decoder.
+//
+// ChannelLayout 返回底层解码器的声道布局。
+func (t *cueTrackStreamer) ChannelLayout() beep.ChannelMask {
+	if l, ok := t.StreamSeekCloser.(channelLayouter); ok {
+		return l.ChannelLayout()
+	}
+	return 0
+}
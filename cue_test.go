@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCueTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"00:00:00", 0, true},
+		{"01:02:00", 62 * time.Second, true},
+		{"00:00:75", time.Second, true},
+		{"00:01:15", time.Second + 200*time.Millisecond, true},
+		{"99:59:74", 99*time.Minute + 59*time.Second + 74*time.Second/75, true},
+		{"00:00", 0, false},
+		{"00:-1:00", 0, false},
+		{"aa:00:00", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseCueTime(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseCueTime(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSplitCueLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`TRACK 01 AUDIO`, []string{"TRACK", "01", "AUDIO"}},
+		{`TITLE "Two  Words"`, []string{"TITLE", "Two  Words"}},
+		{"FILE\t\"a b.flac\"\tWAVE", []string{"FILE", "a b.flac", "WAVE"}},
+		{`PERFORMER "Unclosed`, []string{"PERFORMER", "Unclosed"}},
+		{`TITLE ""`, []string{"TITLE", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitCueLine(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCueLine(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseCueSheet(t *testing.T) {
+	sheet := "\ufeff" + `PERFORMER "Album Artist"
+TITLE "Album"
+FILE "disc1.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Intro"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    PERFORMER "Guest"
+    INDEX 00 03:00:00
+    INDEX 01 03:02:00
+  TRACK 03 AUDIO
+    INDEX 00 05:00:00
+  TRACK 04 DATA
+    INDEX 01 06:00:00
+  TRACK 05 AUDIO
+    TITLE "No index"
+FILE "disc2.flac" WAVE
+  TRACK 06 AUDIO
+    INDEX 01 00:00:00
+  TRACK 07 AUDIO
+    INDEX 01 04:00:00
+`
+	tracks := parseCueSheet(strings.NewReader(sheet))
+	want := []cueTrack{
+		{Number: 1, Title: "Intro", Performer: "Album Artist", Album: "Album", Start: 0, file: "disc1.flac"},
+		// INDEX 01 wins over the pregap of INDEX 00.
+		{Number: 2, Title: "Track 02", Performer: "Guest", Album: "Album", Start: 3*time.Minute + 2*time.Second, file: "disc1.flac"},
+		// Without INDEX 01 the track starts at INDEX 00.
+		{Number: 3, Title: "Track 03", Performer: "Album Artist", Album: "Album", Start: 5 * time.Minute, file: "disc1.flac"},
+		{Number: 6, Title: "Track 06", Performer: "Album Artist", Album: "Album", Start: 0, file: "disc2.flac"},
+		{Number: 7, Title: "Track 07", Performer: "Album Artist", Album: "Album", Start: 4 * time.Minute, file: "disc2.flac"},
+	}
+	if !reflect.DeepEqual(tracks, want) {
+		t.Fatalf("parseCueSheet() =\n%+v\nwant\n%+v", tracks, want)
+	}
+
+	files := groupCueTracks(tracks)
+	if len(files) != 2 {
+		t.Fatalf("groupCueTracks() has %d files; want 2", len(files))
+	}
+	var ends []time.Duration
+	for _, track := range files["disc1.flac"] {
+		ends = append(ends, track.End)
+	}
+	if want := []time.Duration{3*time.Minute + 2*time.Second, 5 * time.Minute, 0}; !reflect.DeepEqual(ends, want) {
+		t.Errorf("disc1.flac ends = %v; want %v", ends, want)
+	}
+	if end := files["disc2.flac"][0].End; end != 4*time.Minute {
+		t.Errorf("disc2.flac track 6 ends at %v; want 4m0s", end)
+	}
+}
+
+func TestEmbeddedCueTracks(t *testing.T) {
+	sheet := `FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+FILE "b.wav" WAVE
+  TRACK 02 AUDIO
+    INDEX 01 01:00:00
+  TRACK 03 AUDIO
+    INDEX 01 02:00:00
+`
+	// The sheet's FILEs are ignored: the tracks keep its order, every time, and each one
+	// ends where the next begins.
+	for i := 0; i < 20; i++ {
+		tracks := embeddedCueTracks(parseCueSheet(strings.NewReader(sheet)))
+		var numbers []int
+		var ends []time.Duration
+		for _, track := range tracks {
+			numbers = append(numbers, track.Number)
+			ends = append(ends, track.End)
+		}
+		if !reflect.DeepEqual(numbers, []int{1, 2, 3}) || !reflect.DeepEqual(ends, []time.Duration{time.Minute, 2 * time.Minute, 0}) {
+			t.Fatalf("embeddedCueTracks() numbers %v, ends %v", numbers, ends)
+		}
+	}
+}
+
+// flacCueSheetTrack describes a track of a FLAC CUESHEET block for buildFLACCueSheet.
+type flacCueSheetTrack struct {
+	offset  uint64
+	number  byte
+	data    bool
+	indexes [][2]uint64 // Index number and offset relative to the track.
+}
+
+// buildFLACCueSheet encodes a FLAC CUESHEET metadata block.
+func buildFLACCueSheet(tracks []flacCueSheetTrack) []byte {
+	body := make([]byte, 128+8+1+258)
+	body = append(body, byte(len(tracks)))
+	for _, track := range tracks {
+		var header [36]byte
+		binary.BigEndian.PutUint64(header[0:8], track.offset)
+		header[8] = track.number
+		if track.data {
+			header[21] = 0x80
+		}
+		header[35] = byte(len(track.indexes))
+		body = append(body, header[:]...)
+		for _, index := range track.indexes {
+			var entry [12]byte
+			binary.BigEndian.PutUint64(entry[0:8], index[1])
+			entry[8] = byte(index[0])
+			body = append(body, entry[:]...)
+		}
+	}
+	return body
+}
+
+func TestParseFLACCueSheetBlock(t *testing.T) {
+	const rate = 44100
+	block := buildFLACCueSheet([]flacCueSheetTrack{
+		{offset: 0, number: 1, indexes: [][2]uint64{{1, 0}}},
+		// A pregap: the track starts at INDEX 01, two seconds after the track offset.
+		{offset: 60 * rate, number: 2, indexes: [][2]uint64{{0, 0}, {1, 2 * rate}}},
+		// Only INDEX 00.
+		{offset: 120 * rate, number: 3, indexes: [][2]uint64{{0, rate / 2}}},
+		{offset: 150 * rate, number: 4, data: true, indexes: [][2]uint64{{1, 0}}},
+		{offset: 180 * rate, number: 170},
+	})
+
+	tracks := parseFLACCueSheetBlock(block, rate, "Album", "Artist")
+	want := []cueTrack{
+		{Number: 1, Title: "Track 01", Performer: "Artist", Album: "Album", Start: 0, End: 62 * time.Second},
+		{Number: 2, Title: "Track 02", Performer: "Artist", Album: "Album", Start: 62 * time.Second, End: 120*time.Second + 500*time.Millisecond},
+		{Number: 3, Title: "Track 03", Performer: "Artist", Album: "Album", Start: 120*time.Second + 500*time.Millisecond},
+	}
+	if !reflect.DeepEqual(tracks, want) {
+		t.Fatalf("parseFLACCueSheetBlock() =\n%+v\nwant\n%+v", tracks, want)
+	}
+
+	if got := parseFLACCueSheetBlock(block[:len(block)-5], rate, "", ""); got != nil {
+		t.Errorf("truncated block gave %+v; want nil", got)
+	}
+}
+
+func TestParseVorbisComments(t *testing.T) {
+	var body []byte
+	put := func(s string) {
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(s)))
+		body = append(body, s...)
+	}
+	put("vendor")
+	body = binary.LittleEndian.AppendUint32(body, 4)
+	put("album=First")
+	put("ALBUM=Second")
+	put("no separator")
+	put("CueSheet=FILE \"x\" WAVE")
+
+	want := map[string]string{"ALBUM": "First", "CUESHEET": `FILE "x" WAVE`}
+	if got := parseVorbisComments(body); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseVorbisComments() = %q; want %q", got, want)
+	}
+}
+
+func TestCueTrackPath(t *testing.T) {
+	path := cueTrackPath("/music/album.flac", 3)
+	audioPath, number, ok := splitCueTrackPath(path)
+	if audioPath != "/music/album.flac" || number != 3 || !ok {
+		t.Errorf("splitCueTrackPath(%q) = %q, %d, %v", path, audioPath, number, ok)
+	}
+	if _, _, ok := splitCueTrackPath("/music/song.flac"); ok {
+		t.Errorf("splitCueTrackPath accepted a plain file")
+	}
+}
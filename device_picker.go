@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// devicePicker is the output device overlay of the player page. While it is open it
+// takes over the screen and receives all keys; selecting a device switches the speaker
+// live without touching the playing song.
+//
+// devicePicker 是播放器页面的输出设备浮层。打开时它会接管屏幕并接收所有按键；
+// 选择设备后会实时切换扬声器，不影响正在播放的歌曲。
+type devicePicker struct {
+	devices []speaker.Device
+	cursor  int
+	message string // Error shown below the list. / 显示在列表下方的错误信息。
+}
+
+// openDevicePicker lists the devices of the active backend and opens the picker on the
+// current device.
+//
+// openDevicePicker 列出当前后端的设备，并打开选择器，光标位于当前设备。
+func (p *PlayerPage) openDevicePicker() {
+	picker := &devicePicker{}
+	devices, err := speaker.Devices()
+	if err != nil {
+		picker.message = err.Error()
+	}
+	picker.devices = devices
+	for i, device := range devices {
+		if device.ID == p.app.outputDevice {
+			picker.cursor = i
+		}
+	}
+	p.devicePicker = picker
+	p.View()
+}
+
+// closeDevicePicker closes the picker and redraws the player.
+//
+// closeDevicePicker 关闭选择器并重新绘制播放器。
+func (p *PlayerPage) closeDevicePicker() {
+	p.devicePicker = nil
+	p.clearArea()
+	p.View()
+}
+
+// handleDevicePickerKey handles a key while the picker is open.
+//
+// handleDevicePickerKey 处理选择器打开时的按键。
+func (p *PlayerPage) handleDevicePickerKey(key rune) {
+	picker := p.devicePicker
+	switch {
+	case IsKey(key, GlobalConfig.Keymap.Global.Quit) || IsKey(key, GlobalConfig.Keymap.Player.SelectDevice):
+		p.closeDevicePicker()
+		return
+	case IsKey(key, GlobalConfig.Keymap.Playlist.NavUp):
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+	case IsKey(key, GlobalConfig.Keymap.Playlist.NavDown):
+		if picker.cursor < len(picker.devices)-1 {
+			picker.cursor++
+		}
+	case IsKey(key, GlobalConfig.Keymap.Playlist.PlaySong):
+		if len(picker.devices) == 0 {
+			return
+		}
+		if err := p.app.SetOutputDevice(picker.devices[picker.cursor].ID); err != nil {
+			picker.message = err.Error()
+			break
+		}
+		p.closeDevicePicker()
+		return
+	default:
+		return
+	}
+	p.renderDevicePicker()
+}
+
+// renderDevicePicker draws the picker as a centered list, over the whole player pane in the
+// split view.
+//
+// renderDevicePicker 以居中列表的形式绘制选择器，在分屏视图中覆盖整个播放器窗格。
+func (p *PlayerPage) renderDevicePicker() {
+	picker := p.devicePicker
+	r := p.viewArea()
+	w, h := r.w, r.h
+
+	p.clearArea()
+	if p.area != nil {
+		// The cover would hide the list.
+		// 封面会遮住列表。
+		deleteKittyPlacement()
+	}
+
+	title := "Output Device"
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(1, max(1, (w-len(title))/2)), p.app.titleStyle(), title)
+
+	if len(picker.devices) == 0 {
+		msg := runewidth.Truncate("No output devices available", w, "...")
+		fmt.Printf("%s%s%s\x1b[0m", r.moveTo(h/2, max(1, (w-len(msg))/2)), GlobalConfig.ActiveTheme.Footer.sgr(), msg)
+	}
+
+	listHeight := max(1, h-4)
+	offset := max(0, picker.cursor-listHeight+1)
+	rowStyle, playingStyle, cursorStyle := GlobalConfig.ActiveTheme.Selected.sgr(), p.app.playingStyle(), p.app.cursorStyle()
+	for i := range listHeight {
+		index := offset + i
+		if index >= len(picker.devices) {
+			break
+		}
+		device := picker.devices[index]
+
+		style := rowStyle
+		prefix := " "
+		if device.ID == p.app.outputDevice {
+			style = playingStyle
+			prefix = "●"
+		}
+		if index == picker.cursor {
+			style += cursorStyle
+		}
+
+		line := fmt.Sprintf("%s %s (%s)", prefix, device.Description, device.ID)
+		line = runewidth.Truncate(line, max(1, w-4), "...")
+		fmt.Printf("%s%s%s\x1b[0m", r.moveTo(i+3, 3), style, line)
+	}
+
+	if picker.message != "" {
+		msg := runewidth.Truncate(picker.message, w-2, "...")
+		fmt.Printf("%s\x1b[31m%s\x1b[0m", r.moveTo(h, max(1, (w-runewidth.StringWidth(msg))/2)), msg)
+	}
+}
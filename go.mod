@@ -9,6 +9,7 @@ require (
 	github.com/mattn/go-runewidth v0.0.19
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/soniakeys/quant v1.0.0
+	golang.org/x/sys v0.45.0
 	golang.org/x/term v0.36.0
 )
 
@@ -23,7 +24,6 @@ require (
 	github.com/mewkiz/flac v1.0.12 // indirect
 	github.com/mewkiz/pkg v0.0.0-20230226050401-4010bf0fec14 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	golang.org/x/sys v0.45.0 // indirect
 )
 
 replace github.com/blacktop/go-termimg => ./kitty_test/go-termimg
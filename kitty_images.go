@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/nfnt/resize"
+)
+
+const (
+	// kittyMaxImages is how many covers are kept in the terminal for reuse, e.g. when the
+	// songs of an album share its cover or a song is played again.
+	//
+	// kittyMaxImages 是保留在终端中以供复用的封面数量，例如专辑中的歌曲共用封面或再次播放
+	// 某首歌曲时。
+	kittyMaxImages = 8
+
+	// kittyMaxImageSize is the largest width and height a cover is transmitted at. The
+	// terminal scales it to the cells it is placed on, so it is sent once for all sizes.
+	//
+	// kittyMaxImageSize 是传输封面时的最大宽度和高度。终端会将其缩放到所放置的单元格，
+	// 因此对所有尺寸只需发送一次。
+	kittyMaxImageSize = 960
+)
+
+// kittyImages remembers the covers transmitted to the terminal with the kitty graphics
+// protocol, oldest first, so that redraws and resizes only place them again. It is only
+// used from the main loop.
+//
+// kittyImages 按从旧到新的顺序记录使用 kitty 图形协议传输到终端的封面，
+// 使重绘和调整大小时只需重新放置它们。它只在主循环中使用。
+var kittyImages []kittyImage
+
+// kittyPlacedID is the image whose placement is on screen, or 0.
+//
+// kittyPlacedID 是当前屏幕上有放置的图像，没有时为 0。
+var kittyPlacedID uint32
+
+// kittyImage is a cover transmitted to the terminal.
+//
+// kittyImage 是已传输到终端的封面。
+type kittyImage struct {
+	key string // Cover cache key of the cover. / 封面的封面缓存键。
+	id  uint32
+}
+
+// renderKittyCover places a cover at the given cell with the kitty graphics protocol,
+// transmitting it first if the terminal does not have a cover with its key yet. The previous cover's
+// placement is deleted, but its data is kept for reuse. Inside tmux the cover is shown
+// with Unicode placeholders.
+//
+// renderKittyCover 使用 kitty 图形协议在给定单元格处放置封面；如果终端中还没有该键的封面，
+// 则先传输它。上一个封面的放置会被删除，但其数据会保留以供复用。在 tmux 中使用 Unicode
+// 占位符显示封面。
+func renderKittyCover(cover image.Image, key string, row, col, widthChars, heightChars int) error {
+	inTmux := detectMultiplexer() == muxTmux
+	if inTmux {
+		widthChars = min(widthChars, len(kittyDiacritics))
+		heightChars = min(heightChars, len(kittyDiacritics))
+	}
+
+	imageID, err := kittyCoverID(cover, key, inTmux)
+	if err != nil {
+		return err
+	}
+
+	if kittyPlacedID != imageID {
+		deleteKittyPlacement()
+	}
+	kittyPlacedID = imageID
+
+	// Placing again with the same placement ID replaces the previous placement.
+	// 使用相同的放置 ID 再次放置会替换之前的放置。
+	if inTmux {
+		if err := writeKittyCommand(fmt.Sprintf("a=p,U=1,i=%d,p=1,c=%d,r=%d,q=2", imageID, widthChars, heightChars)); err != nil {
+			return err
+		}
+		return writeKittyPlaceholders(imageID, row, col, widthChars, heightChars)
+	}
+	return writeKittyCommand(fmt.Sprintf("a=p,i=%d,p=1,c=%d,r=%d,q=2", imageID, widthChars, heightChars))
+}
+
+// kittyCoverID returns the ID of a cover in the terminal, looked up by its cover cache key,
+// transmitting it if needed and freeing the oldest cover when too many are kept.
+//
+// kittyCoverID 返回按封面缓存键查找到的封面在终端中的 ID，必要时传输该封面，并在保留的
+// 封面过多时释放最旧的封面。
+func kittyCoverID(cover image.Image, key string, placeholders bool) (uint32, error) {
+	for _, img := range kittyImages {
+		if img.key == key {
+			return img.id, nil
+		}
+	}
+
+	var imageID uint32
+	if placeholders {
+		imageID = nextKittyPlaceholderID()
+	} else {
+		imageID = atomic.AddUint32(&kittyImageID, 1)
+	}
+
+	scaled := resize.Thumbnail(kittyMaxImageSize, kittyMaxImageSize, cover, resize.Lanczos3)
+	if err := transmitKittyImage(scaled, fmt.Sprintf("a=t,i=%d", imageID)); err != nil {
+		return 0, err
+	}
+
+	if len(kittyImages) == kittyMaxImages {
+		oldest := kittyImages[0]
+		kittyImages = kittyImages[1:]
+		if oldest.id == kittyPlacedID {
+			kittyPlacedID = 0
+		}
+		// Uppercase I frees the image data as well as its placements.
+		// 大写的 I 会同时释放图像数据及其放置。
+		if err := writeKittyCommand(fmt.Sprintf("a=d,d=I,i=%d,q=2", oldest.id)); err != nil {
+			return 0, err
+		}
+	}
+	kittyImages = append(kittyImages, kittyImage{key: key, id: imageID})
+	return imageID, nil
+}
+
+// writeKittyCommand writes a kitty graphics command without payload.
+//
+// writeKittyCommand 写入不带数据的 kitty 图形命令。
+func writeKittyCommand(control string) error {
+	if _, err := io.WriteString(os.Stdout, wrapPassthrough("\x1b_G"+control+"\x1b\\")); err != nil {
+		return fmt.Errorf("failed to write kitty command: %v\n\n无法写入 kitty 命令: %v", err, err)
+	}
+	return nil
+}
+
+// deleteKittyPlacement removes the cover placed with kitty from the screen, keeping its
+// data in the terminal.
+//
+// deleteKittyPlacement 从屏幕上移除使用 kitty 放置的封面，同时将其数据保留在终端中。
+func deleteKittyPlacement() {
+	if kittyPlacedID == 0 {
+		return
+	}
+	_ = writeKittyCommand(fmt.Sprintf("a=d,d=i,i=%d,q=2", kittyPlacedID))
+	kittyPlacedID = 0
+}
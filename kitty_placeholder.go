@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// kittyPlaceholder is the character that the kitty graphics protocol replaces with a cell
+// of an image placed with U=1.
+//
+// kittyPlaceholder 是 kitty 图形协议用以 U=1 放置的图像单元格替换的字符。
+const kittyPlaceholder = '\U0010EEEE'
+
+// kittyPlaceholderID counts the images drawn with placeholders. Their IDs are kept within
+// the 256-color palette plus the most significant byte, since tmux passes 256-color
+// foregrounds through unchanged but may reduce 24-bit ones.
+//
+// kittyPlaceholderID 为使用占位符绘制的图像计数。其 ID 限制在 256 色调色板加最高字节的范围内，
+// 因为 tmux 会原样传递 256 色前景色，但可能会降低 24 位色的精度。
+var kittyPlaceholderID = uint32(os.Getpid()) + uint32(time.Now().UnixMicro()&0xFFFF)
+
+// kittyDiacritics are the combining characters that encode row and column numbers, and the
+// most significant byte of the image ID, on placeholder cells.
+//
+// kittyDiacritics 是在占位符单元格上编码行号、列号以及图像 ID 最高字节的组合字符。
+var kittyDiacritics = expandRuneRanges([][2]rune{
+	{0x0305, 0x0305}, {0x030D, 0x030E}, {0x0310, 0x0310}, {0x0312, 0x0312},
+	{0x033D, 0x033F}, {0x0346, 0x0346}, {0x034A, 0x034C}, {0x0350, 0x0352},
+	{0x0357, 0x0357}, {0x035B, 0x035B}, {0x0363, 0x036F}, {0x0483, 0x0487},
+	{0x0592, 0x0595}, {0x0597, 0x0599}, {0x059C, 0x05A1}, {0x05A8, 0x05A9},
+	{0x05AB, 0x05AC}, {0x05AF, 0x05AF}, {0x05C4, 0x05C4}, {0x0610, 0x0617},
+	{0x0657, 0x065B}, {0x065D, 0x065E}, {0x06D6, 0x06DC}, {0x06DF, 0x06E2},
+	{0x06E4, 0x06E4}, {0x06E7, 0x06E8}, {0x06EB, 0x06EC}, {0x0730, 0x0730},
+	{0x0732, 0x0733}, {0x0735, 0x0736}, {0x073A, 0x073A}, {0x073D, 0x073D},
+	{0x073F, 0x0741}, {0x0743, 0x0743}, {0x0745, 0x0745}, {0x0747, 0x0747},
+	{0x0749, 0x074A}, {0x07EB, 0x07F1}, {0x07F3, 0x07F3}, {0x0816, 0x0819},
+	{0x081B, 0x0823}, {0x0825, 0x0827}, {0x0829, 0x082D}, {0x0951, 0x0951},
+	{0x0953, 0x0954}, {0x0F82, 0x0F83}, {0x0F86, 0x0F87}, {0x135D, 0x135F},
+	{0x17DD, 0x17DD}, {0x193A, 0x193A}, {0x1A17, 0x1A17}, {0x1A75, 0x1A7C},
+	{0x1B6B, 0x1B6B}, {0x1B6D, 0x1B73}, {0x1DC0, 0x1DC1}, {0x1DC3, 0x1DC9},
+	{0x1DCB, 0x1DCC}, {0x1DD1, 0x1DE6}, {0x1DFE, 0x1DFE}, {0x20D0, 0x20D1},
+	{0x20D4, 0x20D7}, {0x20DB, 0x20DC}, {0x20E1, 0x20E1}, {0x20E7, 0x20E7},
+	{0x20E9, 0x20E9}, {0x20F0, 0x20F0}, {0x2CEF, 0x2CF1}, {0x2DE0, 0x2DFF},
+	{0xA66F, 0xA66F}, {0xA67C, 0xA67D}, {0xA6F0, 0xA6F1}, {0xA8E0, 0xA8F1},
+	{0xAAB0, 0xAAB0}, {0xAAB2, 0xAAB3}, {0xAAB7, 0xAAB8}, {0xAABE, 0xAABF},
+	{0xAAC1, 0xAAC1}, {0xFE20, 0xFE26}, {0x10A0F, 0x10A0F}, {0x10A38, 0x10A38},
+	{0x1D185, 0x1D189}, {0x1D1AA, 0x1D1AD}, {0x1D242, 0x1D244},
+})
+
+func expandRuneRanges(ranges [][2]rune) []rune {
+	var runes []rune
+	for _, r := range ranges {
+		for c := r[0]; c <= r[1]; c++ {
+			runes = append(runes, c)
+		}
+	}
+	return runes
+}
+
+// nextKittyPlaceholderID returns a new image ID for placeholders. Its low byte is a
+// 256-color index above the 16 basic colors, which terminals and tmux may remap; the next
+// two bytes are zero, and the most significant byte is kept small.
+//
+// nextKittyPlaceholderID 返回一个新的占位符图像 ID。其低字节是 16 种基本颜色之外的 256 色索引
+// （基本颜色可能被终端和 tmux 重新映射）；接下来的两个字节为零，最高字节保持较小的值。
+func nextKittyPlaceholderID() uint32 {
+	n := atomic.AddUint32(&kittyPlaceholderID, 1)
+	return (n/240%32)<<24 | (n%240 + 16)
+}
+
+// writeKittyPlaceholders draws a transmitted image at the given cell with kitty Unicode
+// placeholders: the image has a virtual placement, and the cells it covers are filled with
+// placeholder characters whose foreground color and diacritics tell the terminal which
+// image, row and column to show. Being text, they work inside tmux.
+//
+// writeKittyPlaceholders 使用 kitty Unicode 占位符在给定单元格处绘制已传输的图像：
+// 图像具有虚拟放置，其覆盖的单元格中填入占位符字符，
+// 由其前景色和变音符号告诉终端要显示哪个图像及其行列。
+// 由于它们是文本，因此可以在 tmux 中使用。
+func writeKittyPlaceholders(imageID uint32, row, col, widthChars, heightChars int) error {
+	// Only the first cell of a row needs diacritics; the following ones continue it.
+	// 只有每行的第一个单元格需要变音符号；后续单元格沿用它。
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\x1b[38;5;%dm", imageID&0xFF)
+	for y := 0; y < heightChars; y++ {
+		fmt.Fprintf(&sb, "\x1b[%d;%dH", row+y, col)
+		sb.WriteRune(kittyPlaceholder)
+		sb.WriteRune(kittyDiacritics[y])
+		sb.WriteRune(kittyDiacritics[0])
+		sb.WriteRune(kittyDiacritics[imageID>>24])
+		for x := 1; x < widthChars; x++ {
+			sb.WriteRune(kittyPlaceholder)
+		}
+	}
+	sb.WriteString("\x1b[39m")
+
+	if _, err := io.WriteString(os.Stdout, sb.String()); err != nil {
+		return fmt.Errorf("failed to write kitty placeholders: %v\n\n无法写入 kitty 占位符: %v", err, err)
+	}
+	return nil
+}
@@ -133,8 +133,11 @@ type LayoutPosition struct {
 //
 // collectMetrics 收集布局判断所需的所有指标。
 func (p *PlayerPage) collectMetrics(w, h int) LayoutMetrics {
-	title, artist, album := getSongMetadata(p.flacPath)
-	maxTextLength := max(max(len(title), len(artist)), len(album))
+	title, artist, album := p.app.songMetadata(p.flacPath)
+	maxTextLength := 0
+	for _, line := range p.statusLines() {
+		maxTextLength = max(maxTextLength, len(line))
+	}
 
 	showNothing := w < 23 || h < 5
 	showTextOnly := h < 13
@@ -396,8 +399,7 @@ func (p *PlayerPage) renderAlbumArt(coverImg image.Image, layout LayoutType, pos
 		}
 	}
 
-	fmt.Printf("\x1b[%d;%dH", pos.StartRow, pos.StartCol)
-	if err := RenderImage(scaledImg, imageWidthInChars, imageHeightInChars); err != nil {
+	if err := RenderImage(coverImg, p.coverKey(), scaledImg, pos.StartRow, pos.StartCol, imageWidthInChars, imageHeightInChars); err != nil {
 		_ = NewEncoder(os.Stdout).Encode(scaledImg)
 	}
 
@@ -489,26 +491,19 @@ func (p *PlayerPage) renderWithLayout() {
 
 	w, h, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil {
-		fmt.Print("\x1b[2J\x1b[H")
+		clearScreen()
 		l.Warnf("Unable to get terminal size\n\n无法获取终端尺寸")
 		return
 	}
 
-	fmt.Print("\x1b[2J\x1b[3J\x1b[H")
+	// The cover is redrawn over its previous placement, if any, without clearing it.
+	// 封面在其之前的放置（如果有）上重绘，而不先清除它。
+	fmt.Print("\x1b[H\x1b[J")
 
-	coverImg := p.loadCoverImage()
+	coverImg, palette := p.loadCoverImage()
 	metrics := p.collectMetrics(w, h)
 	layout := p.determineLayout(&metrics)
 
-	var coverColorR, coverColorG, coverColorB int
-
-	if coverImg != nil {
-		r, g, b := analyzeCoverColor(coverImg)
-		coverColorR, coverColorG, coverColorB = r, g, b
-	} else {
-		coverColorR, coverColorG, coverColorB = 255, 255, 255
-	}
-
 	var imageWidthInChars, imageHeightInChars int
 	var startCol, startRow int
 
@@ -521,8 +516,7 @@ func (p *PlayerPage) renderWithLayout() {
 			pixelH = 10
 		}
 
-		normalizedImg := resize.Resize(960, 960, coverImg, resize.Lanczos3)
-		scaledImg := resize.Thumbnail(uint(pixelW), uint(pixelH), normalizedImg, resize.Lanczos3)
+		scaledImg := p.app.song.scaledCover(pixelW, pixelH)
 		finalImgW, finalImgH := scaledImg.Bounds().Dx(), scaledImg.Bounds().Dy()
 
 		if p.cellW == 0 {
@@ -567,24 +561,7 @@ func (p *PlayerPage) renderWithLayout() {
 			imageHeightInChars = h - startRow
 		}
 
-		targetPixelW := imageWidthInChars * p.cellW
-		targetPixelH := imageHeightInChars * p.cellH
-		if targetPixelW > 0 && targetPixelH > 0 {
-			sb := scaledImg.Bounds()
-			if sb.Dx() >= targetPixelW && sb.Dy() >= targetPixelH &&
-				(sb.Dx() != targetPixelW || sb.Dy() != targetPixelH) {
-				offsetX := (sb.Dx() - targetPixelW) / 2
-				offsetY := (sb.Dy() - targetPixelH) / 2
-				aligned := image.NewRGBA(image.Rect(0, 0, targetPixelW, targetPixelH))
-				draw.Draw(aligned, aligned.Bounds(), scaledImg, image.Point{X: offsetX, Y: offsetY}, draw.Src)
-				scaledImg = aligned
-			}
-		}
-
-		fmt.Printf("\x1b[%d;%dH", startRow, startCol)
-		if err := RenderImage(scaledImg, imageWidthInChars, imageHeightInChars); err != nil {
-			_ = NewEncoder(os.Stdout).Encode(scaledImg)
-		}
+		p.drawCover(coverImg, scaledImg, startRow, startCol, imageWidthInChars, imageHeightInChars)
 
 		if imageWidthInChars > 0 && startCol+imageWidthInChars <= w {
 			fillStartCol := startCol + imageWidthInChars
@@ -600,6 +577,7 @@ func (p *PlayerPage) renderWithLayout() {
 		p.imageHeight = imageHeightInChars
 		p.imageRightEdge = startCol + imageWidthInChars
 	} else {
+		deleteKittyPlacement()
 		p.imageTop = 0
 		p.imageHeight = 0
 		p.imageRightEdge = 0
@@ -609,29 +587,62 @@ func (p *PlayerPage) renderWithLayout() {
 	metrics.ImageHeightInChars = imageHeightInChars
 	p.renderTextByLayout(layout, &metrics)
 
-	p.coverColorR = coverColorR
-	p.coverColorG = coverColorG
-	p.coverColorB = coverColorB
+	p.palette = palette
 }
 
-// loadCoverImage loads the cover image from audio file or fallbacks.
+// drawCover draws the scaled cover on the given cells, cropping it to whole cells so that it
+// lines up with the text around it.
 //
-// loadCoverImage 从音频文件加载封面图片或使用备用图片。
-func (p *PlayerPage) loadCoverImage() image.Image {
-	coverImg := getCoverFromAudioFile(p.flacPath)
+// drawCover 在给定的单元格上绘制缩放后的封面，并将其裁剪为整数个单元格，使其与周围的文本对齐。
+func (p *PlayerPage) drawCover(coverImg, scaledImg image.Image, row, col, widthChars, heightChars int) {
+	targetPixelW := widthChars * p.cellW
+	targetPixelH := heightChars * p.cellH
+	if targetPixelW > 0 && targetPixelH > 0 {
+		sb := scaledImg.Bounds()
+		if sb.Dx() >= targetPixelW && sb.Dy() >= targetPixelH &&
+			(sb.Dx() != targetPixelW || sb.Dy() != targetPixelH) {
+			offsetX := (sb.Dx() - targetPixelW) / 2
+			offsetY := (sb.Dy() - targetPixelH) / 2
+			aligned := image.NewRGBA(image.Rect(0, 0, targetPixelW, targetPixelH))
+			draw.Draw(aligned, aligned.Bounds(), scaledImg, image.Point{X: offsetX, Y: offsetY}, draw.Src)
+			scaledImg = aligned
+		}
+	}
 
-	if coverImg == nil && GlobalConfig != nil && GlobalConfig.App.EnableFolderCovers {
-		coverImg = getFolderCoverImage(p.flacPath)
+	if err := RenderImage(coverImg, p.coverKey(), scaledImg, row, col, widthChars, heightChars); err != nil {
+		_ = NewEncoder(os.Stdout).Encode(scaledImg)
 	}
+}
 
-	if coverImg == nil {
-		defaultCoverPath := getDefaultCoverPath()
-		if defaultCoverPath != "" {
-			if img, err := loadImageFile(defaultCoverPath); err == nil {
-				coverImg = img
-			}
-		}
+// loadCoverImage returns the cover image and its palette for the current song. While the
+// cover is still loading in the background, it returns no image and the page is redrawn
+// when the cover is ready.
+//
+// loadCoverImage 返回当前歌曲的封面图片及其调色板。封面仍在后台加载时不返回图片，
+// 封面就绪后会重绘页面。
+func (p *PlayerPage) loadCoverImage() (image.Image, uiPalette) {
+	info := p.app.song
+	if info == nil || info.path != p.flacPath {
+		return nil, plainPalette()
+	}
+	if !info.coverReady() {
+		p.waitingForCover = info
+		return nil, plainPalette()
+	}
+	if info.cover == nil {
+		return nil, plainPalette()
 	}
+	return info.cover, info.palette.resolve(terminalBackground())
+}
 
-	return coverImg
+// coverKey returns the cover cache key of the current song's cover, which identifies the
+// cover by its content, so that songs sharing a cover share it in the terminal too.
+//
+// coverKey 返回当前歌曲封面在封面缓存中的键。该键按内容标识封面，因此共用同一封面的歌曲
+// 在终端中也共用它。
+func (p *PlayerPage) coverKey() string {
+	if p.app.song == nil {
+		return ""
+	}
+	return p.app.song.coverKey
 }
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// LayoutTemplate is a player page layout defined in the config with [[layouts]]. The page is
+// split into panes, side by side or stacked, sized in proportion to their size. Each pane
+// shows a list of lines, one widget per line or several separated by spaces sharing a line.
+// The cover and the queue take the rows left over by the other lines of their pane, which
+// are otherwise centered vertically. A template is only used when the terminal is within its
+// breakpoints; 0 means no limit.
+//
+// LayoutTemplate 是在配置中通过 [[layouts]] 定义的播放器页面布局。页面被分为并排或上下堆叠的
+// 窗格，其大小与 size 成比例。每个窗格显示若干行，每行一个部件，或用空格分隔的多个部件共用
+// 一行。封面和队列占用所在窗格中其他行剩下的行，否则这些行垂直居中。只有当终端尺寸在模板的
+// 断点范围内时才会使用该模板；0 表示不限制。
+type LayoutTemplate struct {
+	Name      string       `toml:"name"`
+	Direction string       `toml:"direction"` // "columns" (default) or "rows". / "columns"（默认）或 "rows"。
+	MinWidth  int          `toml:"min_width"`
+	MaxWidth  int          `toml:"max_width"`
+	MinHeight int          `toml:"min_height"`
+	MaxHeight int          `toml:"max_height"`
+	Panes     []LayoutPane `toml:"panes"`
+}
+
+// LayoutPane is a pane of a layout template.
+//
+// LayoutPane 是布局模板中的一个窗格。
+type LayoutPane struct {
+	Size    int      `toml:"size"`    // Share of the page, 1 by default. / 占页面的比例，默认为 1。
+	Widgets []string `toml:"widgets"` // One entry per line, "" for a blank line. / 每项为一行，"" 表示空行。
+}
+
+// layoutWidgets are the widgets of layout templates, and whether they take the rows left
+// over in their pane instead of a single line.
+//
+// layoutWidgets 是布局模板中的部件，以及它们是否占用所在窗格剩下的行而不是单独一行。
+var layoutWidgets = map[string]bool{
+	"cover":      true,
+	"queue":      true,
+	"lyrics":     true,
+	"visualizer": true,
+	"title":      false,
+	"artist":     false,
+	"album":      false,
+	"progress":   false,
+	"time":       false,
+	"volume":     false,
+	"rate":       false,
+	"mode":       false,
+	"format":     false,
+	"status":     false,
+}
+
+// validateLayouts checks the layout templates of the config and fills in default pane sizes.
+//
+// validateLayouts 检查配置中的布局模板，并填充默认的窗格大小。
+func validateLayouts(layouts []LayoutTemplate) error {
+	names := make(map[string]bool)
+	for i := range layouts {
+		t := &layouts[i]
+		if t.Name == "" {
+			return fmt.Errorf("layout %d has no name\n\n第 %d 个布局没有名称", i+1, i+1)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("layout %q is defined twice\n\n布局 %q 被重复定义", t.Name, t.Name)
+		}
+		names[t.Name] = true
+
+		if t.Direction != "" && t.Direction != "columns" && t.Direction != "rows" {
+			return fmt.Errorf("invalid direction %q in layout %q, use \"columns\" or \"rows\"\n\n布局 %q 中的方向 %q 无效，请使用 \"columns\" 或 \"rows\"", t.Direction, t.Name, t.Name, t.Direction)
+		}
+		if t.MinWidth < 0 || t.MaxWidth < 0 || t.MinHeight < 0 || t.MaxHeight < 0 ||
+			(t.MaxWidth > 0 && t.MaxWidth < t.MinWidth) || (t.MaxHeight > 0 && t.MaxHeight < t.MinHeight) {
+			return fmt.Errorf("invalid breakpoints in layout %q\n\n布局 %q 中的断点无效", t.Name, t.Name)
+		}
+		if len(t.Panes) == 0 {
+			return fmt.Errorf("layout %q has no panes\n\n布局 %q 没有窗格", t.Name, t.Name)
+		}
+
+		covers := 0
+		for j := range t.Panes {
+			pane := &t.Panes[j]
+			if pane.Size < 0 {
+				return fmt.Errorf("invalid pane size %d in layout %q\n\n布局 %q 中的窗格大小 %d 无效", pane.Size, t.Name, t.Name, pane.Size)
+			}
+			if pane.Size == 0 {
+				pane.Size = 1
+			}
+			for _, line := range pane.Widgets {
+				widgets := strings.Fields(line)
+				for _, widget := range widgets {
+					fills, ok := layoutWidgets[widget]
+					if !ok {
+						return fmt.Errorf("unknown widget %q in layout %q\n\n布局 %q 中的部件 %q 未知", widget, t.Name, t.Name, widget)
+					}
+					if fills && len(widgets) > 1 {
+						return fmt.Errorf("widget %q must be alone on its line in layout %q\n\n布局 %q 中的部件 %q 必须单独占一行", widget, t.Name, t.Name, widget)
+					}
+					if widget == "cover" {
+						covers++
+					}
+				}
+			}
+		}
+		if covers > 1 {
+			return fmt.Errorf("layout %q shows the cover more than once\n\n布局 %q 中的封面出现了不止一次", t.Name, t.Name)
+		}
+	}
+	return nil
+}
+
+// fits reports whether the terminal size is within the template's breakpoints.
+//
+// fits 报告终端尺寸是否在模板的断点范围内。
+func (t *LayoutTemplate) fits(w, h int) bool {
+	return w >= t.MinWidth && h >= t.MinHeight &&
+		(t.MaxWidth == 0 || w <= t.MaxWidth) && (t.MaxHeight == 0 || h <= t.MaxHeight)
+}
+
+// templateLine is a line of a layout template placed on the screen. Lines of the cover and
+// the queue span several rows.
+//
+// templateLine 是布局模板中放置到屏幕上的一行。封面和队列所在的行跨越多行。
+type templateLine struct {
+	widgets []string
+	row     int
+	col     int
+	width   int
+	height  int
+}
+
+// place lays the template out in a rect of the terminal.
+//
+// place 在终端的一个区域中排布模板。
+func (t *LayoutTemplate) place(r viewRect) []templateLine {
+	w, h := r.w, r.h
+	total := 0
+	for _, pane := range t.Panes {
+		total += pane.Size
+	}
+	extent := w
+	if t.Direction == "rows" {
+		extent = h
+	}
+
+	var lines []templateLine
+	offset, used := 0, 0
+	for i, pane := range t.Panes {
+		used += pane.Size
+		end := extent * used / total
+		if i == len(t.Panes)-1 {
+			end = extent
+		}
+		col, row, pw, ph := r.col+offset, r.row, end-offset, h
+		if t.Direction == "rows" {
+			col, row, pw, ph = r.col, r.row+offset, w, end-offset
+		}
+		offset = end
+		lines = append(lines, placePane(pane, col, row, pw, ph)...)
+	}
+	return lines
+}
+
+// placePane lays the lines of a pane out in its rectangle. The rows left over by the other
+// lines are shared by the cover and the queue; when they do not divide evenly, the first ones
+// take a row more.
+//
+// placePane 在窗格的矩形区域中排布其各行。其他行剩下的行由封面和队列分享；不能整除时，
+// 排在前面的各多占一行。
+func placePane(pane LayoutPane, col, row, w, h int) []templateLine {
+	fixed, filling := 0, 0
+	for _, line := range pane.Widgets {
+		if widgets := strings.Fields(line); len(widgets) == 1 && layoutWidgets[widgets[0]] {
+			filling++
+		} else {
+			fixed++
+		}
+	}
+
+	fillRows, extraRows := 0, 0
+	if filling > 0 {
+		fillRows = max(h-fixed, 0) / filling
+		extraRows = max(h-fixed, 0) % filling
+	} else {
+		row += max(h-fixed, 0) / 2
+	}
+
+	var lines []templateLine
+	bottom := row + h
+	for _, line := range pane.Widgets {
+		widgets := strings.Fields(line)
+		height := 1
+		if len(widgets) == 1 && layoutWidgets[widgets[0]] {
+			height = fillRows
+			if extraRows > 0 {
+				height++
+				extraRows--
+			}
+		}
+		if row+height > bottom {
+			break
+		}
+		if height > 0 && len(widgets) > 0 {
+			l := templateLine{widgets: widgets, row: row, col: col, width: w, height: height}
+			// Text keeps a column of margin from the neighboring panes.
+			// 文本与相邻窗格保持一列的间距。
+			if widgets[0] != "cover" && w > 2 {
+				l.col, l.width = col+1, w-2
+			}
+			lines = append(lines, l)
+		}
+		row += height
+	}
+	return lines
+}
+
+// nowPlayingPanel is the layout of the compact player in the split view.
+//
+// nowPlayingPanel 是分屏视图中紧凑播放器的布局。
+var nowPlayingPanel = LayoutTemplate{
+	Name: "split",
+	Panes: []LayoutPane{{
+		Size:    1,
+		Widgets: []string{"cover", "", "title", "artist", "album", "", "progress", "time mode", "volume rate"},
+	}},
+}
+
+// resolveInitialTemplate returns the layout template chosen in the last session.
+//
+// resolveInitialTemplate 返回上次会话中选择的布局模板。
+func resolveInitialTemplate() string {
+	if len(GlobalConfig.Layouts) == 0 {
+		return ""
+	}
+	name, err := LoadLayoutTemplate()
+	if err != nil {
+		l.Warnf("Could not load saved layout: %v\n\n无法加载已保存的布局: %v", err, err)
+	}
+	return name
+}
+
+// activeTemplate returns the layout template to draw the page with: the one chosen with
+// ToggleLayout if it fits the terminal, or else the first that fits. It returns nil when no
+// template fits and the built-in layouts are used.
+//
+// activeTemplate 返回用于绘制页面的布局模板：通过 ToggleLayout 选择的模板（如果适合终端），
+// 否则为第一个适合的模板。没有适合的模板时返回 nil，并使用内置布局。
+func (p *PlayerPage) activeTemplate(w, h int) *LayoutTemplate {
+	var first *LayoutTemplate
+	for i := range GlobalConfig.Layouts {
+		t := &GlobalConfig.Layouts[i]
+		if !t.fits(w, h) {
+			continue
+		}
+		if t.Name == p.layoutTemplate {
+			return t
+		}
+		if first == nil {
+			first = t
+		}
+	}
+	return first
+}
+
+// cycleTemplate switches to the next layout template that fits the terminal, and back to
+// automatic choice after the last. It reports false when no template fits.
+//
+// cycleTemplate 切换到下一个适合终端的布局模板，最后一个之后回到自动选择。
+// 没有适合的模板时返回 false。
+func (p *PlayerPage) cycleTemplate(w, h int) bool {
+	var names []string
+	for _, t := range GlobalConfig.Layouts {
+		if t.fits(w, h) {
+			names = append(names, t.Name)
+		}
+	}
+	if len(names) == 0 {
+		return false
+	}
+
+	next := names[0]
+	for i, name := range names {
+		if name == p.layoutTemplate {
+			next = ""
+			if i+1 < len(names) {
+				next = names[i+1]
+			}
+			break
+		}
+	}
+
+	p.layoutTemplate = next
+	if err := SaveLayoutTemplate(next); err != nil {
+		l.Warnf("Could not save layout: %v\n\n无法保存布局: %v", err, err)
+	}
+	return true
+}
+
+// renderTemplate draws the page with a layout template in a rect of the terminal. The text is
+// drawn by updateStatus.
+//
+// renderTemplate 使用布局模板在终端的一个区域中绘制页面。文本由 updateStatus 绘制。
+func (p *PlayerPage) renderTemplate(t *LayoutTemplate, r viewRect) {
+	time.Sleep(50 * time.Millisecond)
+	p.refreshCellSize()
+
+	if p.area == nil {
+		fmt.Print("\x1b[H\x1b[J")
+	} else {
+		p.clearArea()
+	}
+	p.imageTop, p.imageHeight, p.imageRightEdge = 0, 0, 0
+
+	coverImg, palette := p.loadCoverImage()
+	p.palette = palette
+
+	for _, line := range t.place(r) {
+		if line.widgets[0] != "cover" {
+			continue
+		}
+		if coverImg == nil {
+			break
+		}
+		scaledImg := p.app.song.scaledCover(max(line.width*p.cellW, 10), max(line.height*p.cellH, 10))
+		widthChars := min(max(scaledImg.Bounds().Dx()/p.cellW, 1), line.width)
+		heightChars := min(max(scaledImg.Bounds().Dy()/p.cellH, 1), line.height)
+		row := line.row + (line.height-heightChars)/2
+		col := line.col + (line.width-widthChars)/2
+		p.drawCover(coverImg, scaledImg, row, col, widthChars, heightChars)
+		return
+	}
+	deleteKittyPlacement()
+}
+
+// updateTemplateText draws the text widgets of a layout template in a rect of the terminal.
+//
+// updateTemplateText 在终端的一个区域中绘制布局模板中的文本部件。
+func (p *PlayerPage) updateTemplateText(t *LayoutTemplate, r viewRect) {
+	colorCode := p.getColorCode()
+	for _, line := range t.place(r) {
+		switch line.widgets[0] {
+		case "cover":
+		case "queue":
+			p.drawQueue(line)
+		case "lyrics":
+			p.drawLyrics(line, colorCode)
+		case "visualizer":
+			p.drawVisualizer(line, colorCode)
+		default:
+			blank := strings.Repeat(" ", line.width)
+			fmt.Printf("\x1b[%d;%dH%s", line.row, line.col, blank)
+
+			// The widgets of a line share its width: the first is aligned left, the last
+			// right and the others centered. A single widget is centered.
+			// 一行中的部件平分其宽度：第一个左对齐，最后一个右对齐，其余居中。单个部件居中。
+			n := len(line.widgets)
+			for i, widget := range line.widgets {
+				segCol := line.col + line.width*i/n
+				segWidth := line.col + line.width*(i+1)/n - segCol
+				text, width := p.widgetText(widget, segWidth, colorCode)
+				if width == 0 {
+					continue
+				}
+				col := segCol + (segWidth-width)/2
+				if n > 1 && i == 0 {
+					col = segCol
+				} else if n > 1 && i == n-1 {
+					col = segCol + segWidth - width
+				}
+				fmt.Printf("\x1b[%d;%dH%s\x1b[0m", line.row, col, text)
+			}
+		}
+	}
+}
+
+// widgetText returns a one-line widget drawn in at most the given width, and its width on
+// screen.
+//
+// widgetText 返回在给定宽度内绘制的单行部件，以及它在屏幕上的宽度。
+func (p *PlayerPage) widgetText(widget string, width int, colorCode string) (string, int) {
+	title, artist, album := p.app.songMetadata(p.flacPath)
+	player := p.app.player
+	icons := GlobalConfig.ActiveIcons
+
+	var text, style string
+	switch widget {
+	case "title":
+		text, style = title, colorCode+"\x1b[1m"
+	case "artist":
+		text, style = artist, colorCode
+	case "album":
+		text, style = album, colorCode
+	case "volume":
+		text, style = fmt.Sprintf("vol %d%%", int(math.Round(p.app.linearVolume*100))), colorCode
+	case "status":
+		var lines []string
+		for _, line := range p.statusLines() {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		text, style = strings.Join(lines, " - "), colorCode
+	case "mode":
+		text, style = icons.RepeatOne, colorCode
+		switch p.app.playMode {
+		case 1:
+			text = icons.RepeatAll
+		case 2:
+			text = icons.Shuffle
+		}
+	}
+	if player != nil {
+		switch widget {
+		case "progress":
+			icon := icons.Pause
+			if player.ctrl.Paused {
+				icon = icons.Play
+			}
+			iconWidth := runewidth.StringWidth(icon)
+			barWidth := width - iconWidth - 1
+			if barWidth < 1 {
+				return "", 0
+			}
+			return colorCode + icon + " " + p.progressBar(barWidth), width
+		case "time":
+			elapsed := player.sampleRate.D(player.streamer.Position())
+			total := player.sampleRate.D(player.streamer.Len())
+			text, style = formatPlayTime(elapsed)+" / "+formatPlayTime(total), colorCode
+		case "rate":
+			text, style = fmt.Sprintf("%.2fx", player.resampler.Ratio()), colorCode
+		case "format":
+			text, style = player.formatLine(), GlobalConfig.ActiveTheme.Footer.sgr()
+		}
+	}
+
+	text = runewidth.Truncate(text, width, "...")
+	return style + text, runewidth.StringWidth(text)
+}
+
+// drawQueue draws the songs after the current one in the playlist.
+//
+// drawQueue 绘制播放列表中当前歌曲之后的歌曲。
+func (p *PlayerPage) drawQueue(line templateLine) {
+	current := -1
+	for i, song := range p.app.Playlist {
+		if song == p.flacPath {
+			current = i
+			break
+		}
+	}
+
+	footerStyle := GlobalConfig.ActiveTheme.Footer.sgr()
+	blank := strings.Repeat(" ", line.width)
+	for i := range line.height {
+		fmt.Printf("\x1b[%d;%dH%s", line.row+i, line.col, blank)
+		index := current + 1 + i
+		if current < 0 || index >= len(p.app.Playlist) {
+			continue
+		}
+		name := runewidth.Truncate(songDisplayName(p.app.Playlist[index]), line.width, "...")
+		fmt.Printf("\x1b[%d;%dH%s%s\x1b[0m", line.row+i, line.col, footerStyle, name)
+	}
+}
+
+// formatPlayTime formats a position in a song as m:ss, or h:mm:ss for long songs.
+//
+// formatPlayTime 将歌曲中的位置格式化为 m:ss，较长的歌曲为 h:mm:ss。
+func formatPlayTime(d time.Duration) string {
+	s := int(d / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateLayouts(t *testing.T) {
+	pane := func(widgets ...string) LayoutPane { return LayoutPane{Widgets: widgets} }
+	tests := []struct {
+		name    string
+		layouts []LayoutTemplate
+		err     string // Part of the error, "" if valid. / 错误的一部分，有效时为 ""。
+	}{
+		{"none", nil, ""},
+		{"valid", []LayoutTemplate{
+			{Name: "wide", Direction: "columns", MinWidth: 120, Panes: []LayoutPane{pane("cover"), pane("title", "", "time mode", "queue")}},
+			{Name: "tall", Direction: "rows", MaxWidth: 119, Panes: []LayoutPane{pane("cover"), pane("status", "progress")}},
+		}, ""},
+		{"no name", []LayoutTemplate{{Panes: []LayoutPane{pane("cover")}}}, "layout 1 has no name"},
+		{"duplicate name", []LayoutTemplate{
+			{Name: "a", Panes: []LayoutPane{pane("cover")}},
+			{Name: "a", Panes: []LayoutPane{pane("title")}},
+		}, `layout "a" is defined twice`},
+		{"bad direction", []LayoutTemplate{{Name: "a", Direction: "diagonal", Panes: []LayoutPane{pane("cover")}}}, `invalid direction "diagonal"`},
+		{"negative breakpoint", []LayoutTemplate{{Name: "a", MinWidth: -1, Panes: []LayoutPane{pane("cover")}}}, "invalid breakpoints"},
+		{"max below min width", []LayoutTemplate{{Name: "a", MinWidth: 100, MaxWidth: 80, Panes: []LayoutPane{pane("cover")}}}, "invalid breakpoints"},
+		{"max below min height", []LayoutTemplate{{Name: "a", MinHeight: 30, MaxHeight: 20, Panes: []LayoutPane{pane("cover")}}}, "invalid breakpoints"},
+		{"no panes", []LayoutTemplate{{Name: "a"}}, `layout "a" has no panes`},
+		{"negative size", []LayoutTemplate{{Name: "a", Panes: []LayoutPane{{Size: -2, Widgets: []string{"cover"}}}}}, "invalid pane size -2"},
+		{"unknown widget", []LayoutTemplate{{Name: "a", Panes: []LayoutPane{pane("title", "clock")}}}, `unknown widget "clock"`},
+		{"unknown widget in a shared line", []LayoutTemplate{{Name: "a", Panes: []LayoutPane{pane("time  clock")}}}, `unknown widget "clock"`},
+		{"filling widget sharing a line", []LayoutTemplate{{Name: "a", Panes: []LayoutPane{pane("queue time")}}}, `widget "queue" must be alone`},
+		{"two covers", []LayoutTemplate{{Name: "a", Panes: []LayoutPane{pane("cover"), pane("cover")}}}, "shows the cover more than once"},
+	}
+	for _, tt := range tests {
+		err := validateLayouts(tt.layouts)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: validateLayouts = %v; want nil", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: validateLayouts = %v; want an error containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestValidateLayoutsDefaultSize(t *testing.T) {
+	layouts := []LayoutTemplate{{Name: "a", Panes: []LayoutPane{{Widgets: []string{"cover"}}, {Size: 3, Widgets: []string{"title"}}}}}
+	if err := validateLayouts(layouts); err != nil {
+		t.Fatal(err)
+	}
+	if got := []int{layouts[0].Panes[0].Size, layouts[0].Panes[1].Size}; !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("pane sizes after validateLayouts = %v; want [1 3]", got)
+	}
+}
+
+func TestLayoutTemplateFits(t *testing.T) {
+	tests := []struct {
+		t    LayoutTemplate
+		w, h int
+		want bool
+	}{
+		{LayoutTemplate{}, 1, 1, true},
+		{LayoutTemplate{MinWidth: 100}, 99, 40, false},
+		{LayoutTemplate{MinWidth: 100}, 100, 40, true},
+		{LayoutTemplate{MaxWidth: 100}, 100, 40, true},
+		{LayoutTemplate{MaxWidth: 100}, 101, 40, false},
+		{LayoutTemplate{MinHeight: 30}, 200, 29, false},
+		{LayoutTemplate{MaxHeight: 30}, 200, 31, false},
+		{LayoutTemplate{MinWidth: 80, MaxWidth: 120, MinHeight: 20, MaxHeight: 40}, 100, 30, true},
+		{LayoutTemplate{MinWidth: 80, MaxWidth: 120, MinHeight: 20, MaxHeight: 40}, 100, 41, false},
+	}
+	for _, tt := range tests {
+		if got := tt.t.fits(tt.w, tt.h); got != tt.want {
+			t.Errorf("%+v.fits(%d, %d) = %v; want %v", tt.t, tt.w, tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestActiveTemplate(t *testing.T) {
+	saved := GlobalConfig
+	t.Cleanup(func() { GlobalConfig = saved })
+	GlobalConfig = &Config{Layouts: []LayoutTemplate{
+		{Name: "wide", MinWidth: 120},
+		{Name: "narrow", MaxWidth: 119},
+		{Name: "any"},
+	}}
+
+	tests := []struct {
+		chosen string
+		w, h   int
+		want   string
+	}{
+		{"", 160, 40, "wide"},
+		{"", 80, 40, "narrow"},
+		{"any", 160, 40, "any"},
+		{"narrow", 80, 40, "narrow"},
+		// A chosen template that does not fit falls back to the first that does.
+		{"narrow", 160, 40, "wide"},
+		{"gone", 80, 40, "narrow"},
+	}
+	for _, tt := range tests {
+		p := &PlayerPage{layoutTemplate: tt.chosen}
+		got := p.activeTemplate(tt.w, tt.h)
+		if got == nil || got.Name != tt.want {
+			t.Errorf("activeTemplate(%d, %d) with %q chosen = %v; want %q", tt.w, tt.h, tt.chosen, got, tt.want)
+		}
+	}
+
+	GlobalConfig.Layouts = GlobalConfig.Layouts[:1]
+	if got := (&PlayerPage{}).activeTemplate(80, 40); got != nil {
+		t.Errorf("activeTemplate(80, 40) = %q; want nil", got.Name)
+	}
+}
+
+func TestLayoutTemplatePlacePanes(t *testing.T) {
+	tests := []struct {
+		direction string
+		sizes     []int
+		r         viewRect
+		want      [][2]int // Start and length of each pane. / 每个窗格的起点和长度。
+	}{
+		{"columns", []int{1, 1}, viewRect{col: 1, row: 1, w: 80, h: 24}, [][2]int{{1, 40}, {41, 40}}},
+		{"columns", []int{1, 1}, viewRect{col: 1, row: 1, w: 81, h: 24}, [][2]int{{1, 40}, {41, 41}}},
+		{"columns", []int{1, 2}, viewRect{col: 1, row: 1, w: 100, h: 24}, [][2]int{{1, 33}, {34, 67}}},
+		{"columns", []int{1, 1, 1}, viewRect{col: 11, row: 3, w: 100, h: 24}, [][2]int{{11, 33}, {44, 33}, {77, 34}}},
+		{"columns", []int{3, 1, 3}, viewRect{col: 1, row: 1, w: 50, h: 24}, [][2]int{{1, 21}, {22, 7}, {29, 22}}},
+		{"rows", []int{1, 1}, viewRect{col: 1, row: 1, w: 80, h: 25}, [][2]int{{1, 12}, {13, 13}}},
+		{"rows", []int{2, 1}, viewRect{col: 5, row: 4, w: 80, h: 20}, [][2]int{{4, 13}, {17, 7}}},
+	}
+	for _, tt := range tests {
+		tmpl := LayoutTemplate{Direction: tt.direction}
+		for _, size := range tt.sizes {
+			// A cover alone spans the whole pane.
+			tmpl.Panes = append(tmpl.Panes, LayoutPane{Size: size, Widgets: []string{"cover"}})
+		}
+		lines := tmpl.place(tt.r)
+
+		var got [][2]int
+		for _, l := range lines {
+			if tt.direction == "rows" {
+				got = append(got, [2]int{l.row, l.height})
+				if l.col != tt.r.col || l.width != tt.r.w {
+					t.Errorf("%s %v in %+v: pane at col %d width %d; want the full width", tt.direction, tt.sizes, tt.r, l.col, l.width)
+				}
+			} else {
+				got = append(got, [2]int{l.col, l.width})
+				if l.row != tt.r.row || l.height != tt.r.h {
+					t.Errorf("%s %v in %+v: pane at row %d height %d; want the full height", tt.direction, tt.sizes, tt.r, l.row, l.height)
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %v in %+v: panes = %v; want %v", tt.direction, tt.sizes, tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestPlacePane(t *testing.T) {
+	type line struct {
+		widget           string
+		row, col, height int
+	}
+	tests := []struct {
+		name    string
+		widgets []string
+		h       int
+		want    []line
+	}{
+		{"centered text", []string{"title", "artist"}, 10, []line{{"title", 5, 2, 1}, {"artist", 6, 2, 1}}},
+		{"blank lines count", []string{"title", "", "time"}, 7, []line{{"title", 3, 2, 1}, {"time", 5, 2, 1}}},
+		{"cover fills", []string{"cover", "", "title", "progress"}, 20, []line{{"cover", 1, 1, 17}, {"title", 19, 2, 1}, {"progress", 20, 2, 1}}},
+		{"fill rows share evenly", []string{"cover", "title", "queue"}, 21, []line{{"cover", 1, 1, 10}, {"title", 11, 2, 1}, {"queue", 12, 2, 10}}},
+		{"fill remainder goes first", []string{"cover", "title", "lyrics", "visualizer"}, 12, []line{{"cover", 1, 1, 4}, {"title", 5, 2, 1}, {"lyrics", 6, 2, 4}, {"visualizer", 10, 2, 3}}},
+		{"too short", []string{"title", "artist", "album"}, 2, []line{{"title", 1, 2, 1}, {"artist", 2, 2, 1}}},
+		{"no room to fill", []string{"title", "cover", "album"}, 2, []line{{"title", 1, 2, 1}, {"album", 2, 2, 1}}},
+	}
+	for _, tt := range tests {
+		lines := placePane(LayoutPane{Size: 1, Widgets: tt.widgets}, 1, 1, 40, tt.h)
+		var got []line
+		for _, l := range lines {
+			got = append(got, line{strings.Join(l.widgets, " "), l.row, l.col, l.height})
+			if l.row+l.height > 1+tt.h {
+				t.Errorf("%s: %v ends below the pane", tt.name, l.widgets)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: placePane = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlacePaneFillsHeight(t *testing.T) {
+	widgets := []string{"cover", "", "title", "queue", "time mode", "lyrics"}
+	for h := 4; h <= 60; h++ {
+		rows := 0
+		for _, l := range placePane(LayoutPane{Size: 1, Widgets: widgets}, 1, 1, 40, h) {
+			rows += l.height
+		}
+		// Everything but the blank line is drawn.
+		if rows != h-1 {
+			t.Errorf("placePane in %d rows covers %d rows; want %d", h, rows, h-1)
+		}
+	}
+}
@@ -15,7 +15,6 @@ import (
 
 	"github.com/gopxl/beep/v2/speaker"
 	"github.com/mattn/go-runewidth"
-	"golang.org/x/term"
 )
 
 // LibraryEntry holds enriched information about a file or directory in the library.
@@ -27,10 +26,26 @@ type LibraryEntry struct {
 	isDir bool // True if it's a directory or a symlink to a directory. / 如果是目录或指向目录的符号链接，则为true。
 }
 
+// cueTrackEntry stands for one track of an audio file with a cue sheet. Its name is the
+// base name of the track's virtual path, so joining it with the directory gives that path.
+//
+// cueTrackEntry 代表带 cue 表的音频文件中的一条音轨。它的名称是音轨虚拟路径的文件名部分，
+// 因此与目录拼接即可得到该路径。
+type cueTrackEntry struct {
+	os.DirEntry
+	name string
+}
+
+func (e cueTrackEntry) Name() string {
+	return e.name
+}
+
 // Library browses the music directory and adds songs to the playlist.
 //
 // Library 浏览音乐目录并将歌曲添加到播放列表。
 type Library struct {
+	pageArea
+
 	app *App
 
 	entries           []LibraryEntry // All entries in the current directory. / 当前目录中的所有条目。
@@ -131,12 +146,25 @@ func (p *Library) scanDirectory(path string) {
 			isValidAudio = isAudioFile(targetInfo.Name())
 		}
 
-		if isDir || isValidAudio {
+		if isDir {
 			p.entries = append(p.entries, LibraryEntry{
 				entry: file,
 				info:  info,
 				isDir: isDir,
 			})
+		} else if isValidAudio {
+			// Files with a cue sheet are listed as their tracks.
+			// 带 cue 表的文件以其音轨列出。
+			for _, songPath := range songPaths(filepath.Join(path, file.Name())) {
+				var entry os.DirEntry = file
+				if name := filepath.Base(songPath); name != file.Name() {
+					entry = cueTrackEntry{DirEntry: file, name: name}
+				}
+				p.entries = append(p.entries, LibraryEntry{
+					entry: entry,
+					info:  info,
+				})
+			}
 		}
 	}
 
@@ -197,7 +225,9 @@ func (p *Library) ensureGlobalCache() {
 			if isDir {
 				walk(entryPath)
 			} else if isAudioFile(file.Name()) {
-				allAudioFiles[entryPath] = true
+				for _, songPath := range songPaths(entryPath) {
+					allAudioFiles[songPath] = true
+				}
 				tempPath := entryPath
 				for {
 					tempPath = filepath.Dir(tempPath)
@@ -225,7 +255,12 @@ func (p *Library) ensureGlobalCache() {
 		cache = append(cache, path)
 	}
 	p.globalFileCache = cache
-	p.searchEngine.BuildFromPaths(cache)
+
+	targets := make([]string, len(cache))
+	for i, path := range cache {
+		targets[i] = songSearchTarget(path)
+	}
+	p.searchEngine.BuildFromPaths(targets)
 }
 
 // filterSongs updates filteredSongPaths based on the searchQuery.
@@ -248,7 +283,7 @@ func (p *Library) filterSongs() {
 	var scoredItems []scoredItem
 
 	for _, path := range p.globalFileCache {
-		score := p.searchEngine.Match(p.searchQuery, path)
+		score := p.searchEngine.Match(p.searchQuery, songSearchTarget(path))
 		if score > 0 {
 			info, err := os.Stat(path)
 			isDir := err == nil && info.IsDir()
@@ -257,7 +292,7 @@ func (p *Library) filterSongs() {
 				path:     path,
 				score:    score,
 				isDir:    isDir,
-				itemName: filepath.Base(path),
+				itemName: songDisplayName(path),
 			})
 		}
 	}
@@ -292,6 +327,46 @@ func (p *Library) filterSongs() {
 	p.offset = 0
 }
 
+// searchSongs returns up to limit audio files matching query, best matches first.
+//
+// searchSongs 返回最多 limit 个与 query 匹配的音频文件，最佳匹配优先。
+func (p *Library) searchSongs(query string, limit int) []string {
+	if query == "" {
+		return nil
+	}
+
+	p.ensureGlobalCache()
+	type scoredSong struct {
+		path  string
+		score float64
+	}
+	var scored []scoredSong
+	for _, path := range p.globalFileCache {
+		if !isAudioFile(path) {
+			continue
+		}
+		if score := p.searchEngine.Match(query, songSearchTarget(path)); score > 0 {
+			scored = append(scored, scoredSong{path: path, score: score})
+		}
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		if math.Abs(scored[i].score-scored[j].score) > 0.0001 {
+			return scored[i].score > scored[j].score
+		}
+		return strings.ToLower(filepath.Base(scored[i].path)) < strings.ToLower(filepath.Base(scored[j].path))
+	})
+
+	results := make([]string, 0, min(len(scored), limit))
+	for _, song := range scored {
+		if len(results) >= limit {
+			break
+		}
+		results = append(results, song.path)
+	}
+	return results
+}
+
 // Init initializes the library by scanning the starting directory.
 //
 // Init 通过扫描起始目录来初始化媒体库。
@@ -421,7 +496,7 @@ func (p *Library) handleSearchViewInput(key rune) (Page, bool, error) {
 						if isDir {
 							collectSongs(entryPath)
 						} else if isAudioFile(file.Name()) {
-							songsInDir = append(songsInDir, entryPath)
+							songsInDir = append(songsInDir, songPaths(entryPath)...)
 						}
 					}
 				}
@@ -513,7 +588,7 @@ func (p *Library) toggleSelectionForEntry(libEntry LibraryEntry) {
 				if isDir {
 					collectSongs(entryPath)
 				} else if isAudioFile(file.Name()) {
-					songsInDir = append(songsInDir, entryPath)
+					songsInDir = append(songsInDir, songPaths(entryPath)...)
 				}
 			}
 		}
@@ -582,7 +657,7 @@ func (p *Library) toggleSelectAll(isSearchView bool) {
 						if isDir {
 							collectSongs(entryPath)
 						} else if isAudioFile(file.Name()) {
-							allSongs = append(allSongs, entryPath)
+							allSongs = append(allSongs, songPaths(entryPath)...)
 						}
 					}
 				}
@@ -633,8 +708,7 @@ func (p *Library) toggleSelectAll(isSearchView bool) {
 		// 清空当前播放状态
 		p.app.setCurrentSong("")
 		if p.app.mprisServer != nil {
-			p.app.mprisServer.StopService()
-			p.app.mprisServer = nil
+			p.app.mprisServer.TrackChanged()
 		}
 
 		// 更新播放器页面
@@ -687,6 +761,15 @@ func (p *Library) toggleSelection(path string) {
 	}
 }
 
+// isFileSelected reports whether an audio file, or any of its cue tracks, is selected.
+//
+// isFileSelected 判断音频文件或其任一 cue 音轨是否被选中。
+func (p *Library) isFileSelected(filePath string) bool {
+	return slices.ContainsFunc(songPaths(filePath), func(songPath string) bool {
+		return p.selected[songPath]
+	})
+}
+
 // removeSongFromPlaylist removes a song path from the app's playlist.
 //
 // removeSongFromPlaylist 从应用的播放列表中删除一个歌曲路径。
@@ -716,18 +799,15 @@ func (p *Library) removeSongFromPlaylist(songPath string) {
 			p.app.removeFromPlayHistory(songPath)
 
 			if len(p.app.Playlist) == 0 {
-				if p.app.player != nil {
-					speaker.Lock()
-					if p.app.player.ctrl != nil {
-						p.app.player.ctrl.Paused = true
-					}
-					speaker.Unlock()
+				speaker.Lock()
+				if p.app.player != nil && p.app.player.ctrl != nil {
+					p.app.player.ctrl.Paused = true
 				}
 				p.app.player = nil
+				speaker.Unlock()
 				p.app.setCurrentSong("")
 				if p.app.mprisServer != nil {
-					p.app.mprisServer.StopService()
-					p.app.mprisServer = nil
+					p.app.mprisServer.TrackChanged()
 				}
 				if playerPage, ok := p.app.pages[0].(*PlayerPage); ok {
 					playerPage.UpdateSong("")
@@ -760,18 +840,15 @@ func (p *Library) HandleSignal(sig os.Signal) error {
 //
 // View 根据当前模式渲染媒体库页面。
 func (p *Library) View() {
-	w, h, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil {
-		w, h = 80, 24
-	}
+	r := p.viewArea()
 
-	fmt.Print("\x1b[2J\x1b[3J\x1b[H")
+	p.clearArea()
 
 	title := "Library"
-	titleX := (w - len(title)) / 2
-	fmt.Printf("\x1b[1;%dH\x1b[1m%s\x1b[0m", titleX, title)
+	titleX := max(1, (r.w-len(title))/2)
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(1, titleX), p.app.titleStyle(), title)
 
-	listHeight := h - 4
+	listHeight := r.h - 4
 
 	var currentListLength int
 	var currentCursor int
@@ -799,24 +876,25 @@ func (p *Library) View() {
 	p.offset = currentOffset
 
 	if p.isSearching || p.searchQuery != "" {
-		p.drawSearchFooter(w, h, fmt.Sprintf("Search: %s", p.searchQuery))
+		p.drawSearchFooter(r, fmt.Sprintf("Search: %s", p.searchQuery))
 	} else {
-		p.drawPathFooter(w, h, fmt.Sprintf("Path: %s", filepath.Base(p.currentPath)))
+		p.drawPathFooter(r, fmt.Sprintf("Path: %s", filepath.Base(p.currentPath)))
 	}
 
 	if p.searchQuery != "" {
-		p.renderFilteredListContent(w, h, listHeight, currentOffset)
+		p.renderFilteredListContent(r, listHeight, currentOffset)
 	} else {
-		p.renderDirectoryListContent(w, h, listHeight, currentOffset)
+		p.renderDirectoryListContent(r, listHeight, currentOffset)
 	}
 
-	p.drawScrollbar(h, listHeight, currentListLength, currentOffset)
+	p.drawScrollbar(r, listHeight, currentListLength, currentOffset)
 }
 
 // drawSearchFooter is a helper for drawing the search footer with cursor positioning.
 //
 // drawSearchFooter 是一个用于绘制带有光标定位的搜索页脚的辅助函数。
-func (p *Library) drawSearchFooter(w, h int, footerText string) {
+func (p *Library) drawSearchFooter(r viewRect, footerText string) {
+	w, h := r.w, r.h
 	if len(footerText) > w {
 		footerText = "..." + footerText[len(footerText)-w+3:]
 	}
@@ -824,11 +902,11 @@ func (p *Library) drawSearchFooter(w, h int, footerText string) {
 	if footerX < 1 {
 		footerX = 1
 	}
-	fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", h, footerX, footerText)
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(h, footerX), GlobalConfig.ActiveTheme.Search.sgr(), footerText)
 	if p.isSearching {
 		cursorX := footerX + len("Search: ") + len(p.searchQuery)
 		if cursorX <= w {
-			fmt.Printf("\x1b[%d;%dH█", h, cursorX)
+			fmt.Printf("%s█", r.moveTo(h, cursorX))
 		}
 	}
 }
@@ -836,7 +914,8 @@ func (p *Library) drawSearchFooter(w, h int, footerText string) {
 // drawPathFooter is a helper for drawing the path footer.
 //
 // drawPathFooter 是一个用于绘制路径页脚的辅助函数。
-func (p *Library) drawPathFooter(w, h int, footerText string) {
+func (p *Library) drawPathFooter(r viewRect, footerText string) {
+	w, h := r.w, r.h
 	if len(footerText) > w {
 		footerText = "..." + footerText[len(footerText)-w+3:]
 	}
@@ -844,14 +923,15 @@ func (p *Library) drawPathFooter(w, h int, footerText string) {
 	if footerX < 1 {
 		footerX = 1
 	}
-	fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", h, footerX, footerText)
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(h, footerX), GlobalConfig.ActiveTheme.Footer.sgr(), footerText)
 }
 
 // renderFilteredListContent renders the search results with directories on top,
 // files on bottom, separated by a gray dashed line.
 //
 // renderFilteredListContent 渲染搜索结果，目录在上，歌曲在下，中间用灰色虚线分隔。
-func (p *Library) renderFilteredListContent(w, h, listHeight, currentOffset int) {
+func (p *Library) renderFilteredListContent(r viewRect, listHeight, currentOffset int) {
+	w := r.w
 	dirCount := p.searchDirCount
 	totalCount := len(p.filteredSongPaths)
 	hasSep := dirCount > 0 && dirCount < totalCount
@@ -873,7 +953,7 @@ func (p *Library) renderFilteredListContent(w, h, listHeight, currentOffset int)
 				sepWidth = 1
 			}
 			sepText := strings.Repeat("─", sepWidth)
-			fmt.Printf("\x1b[%d;1H\x1b[K\x1b[90m%s\x1b[0m", i+3, sepText)
+			fmt.Printf("%s%s%s\x1b[0m", r.clearRow(i+3), GlobalConfig.ActiveTheme.Separator.sgr(), sepText)
 			continue
 		}
 
@@ -908,7 +988,7 @@ func (p *Library) renderFilteredListContent(w, h, listHeight, currentOffset int)
 				}
 			}
 		} else {
-			displayPath = filepath.Base(fullPath)
+			displayPath = songDisplayName(fullPath)
 		}
 
 		isSelected := p.selected[fullPath]
@@ -943,7 +1023,7 @@ func (p *Library) renderFilteredListContent(w, h, listHeight, currentOffset int)
 							if checkSelected(entryPath) {
 								return true
 							}
-						} else if p.selected[entryPath] {
+						} else if p.isFileSelected(entryPath) {
 							return true
 						}
 					}
@@ -957,7 +1037,7 @@ func (p *Library) renderFilteredListContent(w, h, listHeight, currentOffset int)
 		line := ""
 		style := "\x1b[0m"
 		if isSelected {
-			style += "\x1b[32m"
+			style += GlobalConfig.ActiveTheme.Selected.sgr()
 			if isDir {
 				line = "✓ " + displayPath + "/"
 			} else {
@@ -965,6 +1045,7 @@ func (p *Library) renderFilteredListContent(w, h, listHeight, currentOffset int)
 			}
 		} else {
 			if isDir {
+				style += GlobalConfig.ActiveTheme.Directory.sgr()
 				line = "▸ " + displayPath + "/"
 			} else {
 				line = "  " + displayPath
@@ -972,21 +1053,22 @@ func (p *Library) renderFilteredListContent(w, h, listHeight, currentOffset int)
 		}
 
 		if itemIdx == p.cursor {
-			style += "\x1b[7m"
+			style += p.app.cursorStyle()
 		}
 		if runewidth.StringWidth(line) > w-1 {
 			for runewidth.StringWidth(line) > w-1 && len(line) > 0 {
 				line = line[:len(line)-1]
 			}
 		}
-		fmt.Printf("\x1b[%d;1H\x1b[K%s%s\x1b[0m", i+3, style, line)
+		fmt.Printf("%s%s%s\x1b[0m", r.clearRow(i+3), style, line)
 	}
 }
 
 // renderDirectoryListContent is a helper for rendering the directory list content.
 //
 // renderDirectoryListContent 是一个用于渲染目录列表内容的辅助函数。
-func (p *Library) renderDirectoryListContent(w, h, listHeight, currentOffset int) {
+func (p *Library) renderDirectoryListContent(r viewRect, listHeight, currentOffset int) {
+	w := r.w
 	for i := range listHeight {
 		entryIndex := currentOffset + i
 		if entryIndex >= len(p.entries) {
@@ -1001,7 +1083,7 @@ func (p *Library) renderDirectoryListContent(w, h, listHeight, currentOffset int
 				line = line[:len(line)-1]
 			}
 		}
-		fmt.Printf("\x1b[%d;1H\x1b[K%s%s\x1b[0m", i+3, style, line)
+		fmt.Printf("%s%s%s\x1b[0m", r.clearRow(i+3), style, line)
 	}
 }
 
@@ -1013,6 +1095,9 @@ func (p *Library) getDirEntryLine(libEntry LibraryEntry, fullPath string, isCurs
 	style := "\x1b[0m"
 	isLink := libEntry.info.Mode()&os.ModeSymlink != 0
 	name := libEntry.entry.Name()
+	if !libEntry.isDir {
+		name = songDisplayName(fullPath)
+	}
 
 	if isLink {
 		name += "@"
@@ -1050,7 +1135,7 @@ func (p *Library) getDirEntryLine(libEntry LibraryEntry, fullPath string, isCurs
 						if checkSelected(entryPath) {
 							return true
 						}
-					} else if p.selected[entryPath] {
+					} else if p.isFileSelected(entryPath) {
 						return true
 					}
 				}
@@ -1061,33 +1146,33 @@ func (p *Library) getDirEntryLine(libEntry LibraryEntry, fullPath string, isCurs
 		}
 		if isDirPartiallySelected {
 			line = "✓ " + name + "/"
-			style += "\x1b[32m"
+			style += GlobalConfig.ActiveTheme.Selected.sgr()
 		} else {
 			line = "▸ " + name + "/"
+			style += GlobalConfig.ActiveTheme.Directory.sgr()
 		}
 	} else {
 		if p.selected[fullPath] {
 			line = "✓ " + name
-			style += "\x1b[32m"
+			style += GlobalConfig.ActiveTheme.Selected.sgr()
 		} else {
 			line = "  " + name
 		}
 	}
 	if isCursor {
-		style += "\x1b[7m"
+		style += p.app.cursorStyle()
 	}
 	return line, style
 }
 
-// drawScrollbar draws a scrollbar on the right side of the screen.
+// drawScrollbar draws a scrollbar on the right side of the page.
 //
-// drawScrollbar 在屏幕右侧绘制一个滚动条。
-func (p *Library) drawScrollbar(h, listHeight, totalItems, currentOffset int) {
+// drawScrollbar 在页面右侧绘制一个滚动条。
+func (p *Library) drawScrollbar(r viewRect, listHeight, totalItems, currentOffset int) {
 	if totalItems <= listHeight {
 		return
 	}
 
-	w, _, _ := term.GetSize(int(os.Stdout.Fd()))
 	thumbSize := listHeight * listHeight / totalItems
 	if thumbSize < 1 {
 		thumbSize = 1
@@ -1101,11 +1186,12 @@ func (p *Library) drawScrollbar(h, listHeight, totalItems, currentOffset int) {
 		thumbStart = currentOffset * thumbRange / scrollRange
 	}
 
+	scrollbarStyle := GlobalConfig.ActiveTheme.Scrollbar.sgr()
 	for i := range listHeight {
 		if i >= thumbStart && i < thumbStart+thumbSize {
-			fmt.Printf("\x1b[%d;%dH┃", i+3, w)
+			fmt.Printf("%s%s┃\x1b[0m", r.moveTo(i+3, r.w), scrollbarStyle)
 		} else {
-			fmt.Printf("\x1b[%d;%dH│", i+3, w)
+			fmt.Printf("%s%s│\x1b[0m", r.moveTo(i+3, r.w), scrollbarStyle)
 		}
 	}
 }
@@ -1114,11 +1200,3 @@ func (p *Library) drawScrollbar(h, listHeight, totalItems, currentOffset int) {
 //
 // Library的Tick方法不执行任何操作，因为它是事件驱动的。
 func (p *Library) Tick() {}
-
-// isAudioFile checks if a file has a supported audio extension.
-//
-// isAudioFile 检查文件是否具有支持的音频扩展名。
-func isAudioFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	return ext == ".flac" || ext == ".mp3" || ext == ".wav" || ext == ".ogg"
-}
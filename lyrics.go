@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// lyricLine is a line of lyrics and, for synced lyrics, the time it is sung at.
+//
+// lyricLine 是一行歌词，对于同步歌词还包括演唱这一行的时间。
+type lyricLine struct {
+	at   time.Duration
+	text string
+}
+
+// songLyrics are the lyrics of a song. Synced lyrics are sorted by time.
+//
+// songLyrics 是歌曲的歌词。同步歌词按时间排序。
+type songLyrics struct {
+	lines  []lyricLine
+	synced bool
+}
+
+// loadLyrics returns the lyrics of a song from the .lrc file next to it, or else from its
+// embedded lyrics. Cue tracks have neither, as both would be those of the whole file.
+//
+// loadLyrics 从歌曲旁边的 .lrc 文件读取歌词，否则使用其内嵌歌词。cue 音轨两者都不使用，
+// 因为它们都属于整个文件。
+func loadLyrics(songPath, embedded string) songLyrics {
+	if _, _, ok := splitCueTrackPath(songPath); !ok {
+		lrcPath := strings.TrimSuffix(songPath, filepath.Ext(songPath)) + ".lrc"
+		if data, err := os.ReadFile(lrcPath); err == nil {
+			return parseLyrics(string(data))
+		}
+	}
+	return parseLyrics(embedded)
+}
+
+// parseLyrics parses LRC lyrics: lines start with one or more [mm:ss.xx] time tags, an
+// [offset:ms] tag shifts them, and other [key:value] tags are ignored, as are the
+// <mm:ss.xx> tags of enhanced LRC. Text without any time tags is kept as plain lyrics.
+//
+// parseLyrics 解析 LRC 歌词：每行以一个或多个 [mm:ss.xx] 时间标签开头，[offset:ms] 标签
+// 用于整体平移时间，其他 [key:value] 标签以及增强 LRC 的 <mm:ss.xx> 标签会被忽略。
+// 没有任何时间标签的文本按普通歌词保留。
+func parseLyrics(text string) songLyrics {
+	text = strings.TrimPrefix(text, "\ufeff")
+	var synced, plain []lyricLine
+	var offset time.Duration
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		var stamps []time.Duration
+		tagsOnly := false
+		for strings.HasPrefix(line, "[") {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				break
+			}
+			tag := line[1:end]
+			if at, ok := parseLyricTime(tag); ok {
+				stamps = append(stamps, at)
+			} else if key, value, ok := strings.Cut(tag, ":"); ok && isLyricTagKey(key) {
+				if strings.EqualFold(key, "offset") {
+					if ms, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+						offset = time.Duration(ms) * time.Millisecond
+					}
+				}
+			} else {
+				// Text such as [Chorus] in plain lyrics.
+				// 普通歌词中的 [Chorus] 等文本。
+				break
+			}
+			line = strings.TrimSpace(line[end+1:])
+			tagsOnly = line == ""
+		}
+		line = stripWordTimes(line)
+
+		switch {
+		case len(stamps) > 0:
+			for _, at := range stamps {
+				synced = append(synced, lyricLine{at: at, text: line})
+			}
+		case !tagsOnly:
+			plain = append(plain, lyricLine{text: line})
+		}
+	}
+
+	if len(synced) > 0 {
+		// A positive offset shows the lines sooner.
+		// 正的偏移量使歌词更早显示。
+		for i := range synced {
+			synced[i].at -= offset
+			if synced[i].at < 0 {
+				synced[i].at = 0
+			}
+		}
+		sort.SliceStable(synced, func(i, j int) bool { return synced[i].at < synced[j].at })
+		return songLyrics{lines: synced, synced: true}
+	}
+
+	// Trim the blank lines around plain lyrics, keeping those between the verses.
+	// 去掉普通歌词首尾的空行，保留段落之间的空行。
+	for len(plain) > 0 && plain[0].text == "" {
+		plain = plain[1:]
+	}
+	for len(plain) > 0 && plain[len(plain)-1].text == "" {
+		plain = plain[:len(plain)-1]
+	}
+	return songLyrics{lines: plain}
+}
+
+// parseLyricTime parses an LRC time tag such as 01:02.34, 01:02:34 or 01:02.
+//
+// parseLyricTime 解析 01:02.34、01:02:34 或 01:02 这样的 LRC 时间标签。
+func parseLyricTime(s string) (time.Duration, bool) {
+	minutes, rest, ok := strings.Cut(s, ":")
+	if !ok || minutes == "" || strings.Trim(minutes, "0123456789") != "" {
+		return 0, false
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, false
+	}
+	seconds, fraction, _ := strings.Cut(strings.Replace(rest, ":", ".", 1), ".")
+	if len(seconds) == 0 || len(seconds) > 2 || strings.Trim(seconds, "0123456789") != "" ||
+		strings.Trim(fraction, "0123456789") != "" {
+		return 0, false
+	}
+	sec, err := strconv.ParseFloat(seconds+"."+fraction+"0", 64)
+	if err != nil || sec >= 60 {
+		return 0, false
+	}
+	return time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second)), true
+}
+
+// isLyricTagKey reports whether s is the key of an LRC metadata tag such as ar or offset.
+//
+// isLyricTagKey 判断 s 是否为 ar 或 offset 这样的 LRC 元数据标签的键。
+func isLyricTagKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// stripWordTimes removes the <mm:ss.xx> word tags of enhanced LRC from a line.
+//
+// stripWordTimes 去掉行中增强 LRC 的 <mm:ss.xx> 逐字时间标签。
+func stripWordTimes(line string) string {
+	if !strings.Contains(line, "<") {
+		return line
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(line, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(line[start:], '>')
+		if end < 0 {
+			break
+		}
+		if _, ok := parseLyricTime(line[start+1 : start+end]); !ok {
+			b.WriteString(line[:start+end+1])
+			line = line[start+end+1:]
+			continue
+		}
+		b.WriteString(line[:start])
+		line = line[start+end+1:]
+	}
+	b.WriteString(line)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// current returns the index of the synced line sung at a position, or -1 before the first.
+//
+// current 返回在给定位置演唱的同步歌词行的索引，在第一行之前返回 -1。
+func (l *songLyrics) current(pos time.Duration) int {
+	return sort.Search(len(l.lines), func(i int) bool { return l.lines[i].at > pos }) - 1
+}
+
+// drawLyrics draws the lyrics of the current song. Synced lyrics keep the line being sung in
+// the middle of the widget, highlighted; plain lyrics scroll along with the song.
+//
+// drawLyrics 绘制当前歌曲的歌词。同步歌词会将正在演唱的行高亮显示在部件中间；普通歌词随歌曲
+// 进度滚动。
+func (p *PlayerPage) drawLyrics(line templateLine, colorCode string) {
+	footerStyle := GlobalConfig.ActiveTheme.Footer.sgr()
+	blank := strings.Repeat(" ", line.width)
+	for i := range line.height {
+		fmt.Printf("\x1b[%d;%dH%s", line.row+i, line.col, blank)
+	}
+
+	song, player := p.app.song, p.app.player
+	if song == nil || player == nil {
+		return
+	}
+	lyrics := &song.lyrics
+	if len(lyrics.lines) == 0 {
+		text := runewidth.Truncate("No lyrics", line.width, "")
+		fmt.Printf("\x1b[%d;%dH%s%s\x1b[0m", line.row+(line.height-1)/2, line.col+(line.width-runewidth.StringWidth(text))/2, footerStyle, text)
+		return
+	}
+
+	pos := player.sampleRate.D(player.streamer.Position())
+	current, first := -1, 0
+	if lyrics.synced {
+		current = lyrics.current(pos)
+		first = max(current, 0) - (line.height-1)/2
+	} else if total := player.sampleRate.D(player.streamer.Len()); total > 0 {
+		scroll := max(len(lyrics.lines)-line.height, 0)
+		first = int(float64(scroll) * min(float64(pos)/float64(total), 1))
+	}
+
+	for i := range line.height {
+		index := first + i
+		if index < 0 || index >= len(lyrics.lines) {
+			continue
+		}
+		style := footerStyle
+		if index == current {
+			style = colorCode + "\x1b[1m"
+		}
+		text := runewidth.Truncate(lyrics.lines[index].text, line.width, "...")
+		col := line.col + (line.width-runewidth.StringWidth(text))/2
+		fmt.Printf("\x1b[%d;%dH%s%s\x1b[0m", line.row+i, col, style, text)
+	}
+}
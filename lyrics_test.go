@@ -0,0 +1,125 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLyricTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"00:00.00", 0, true},
+		{"01:02.34", time.Minute + 2340*time.Millisecond, true},
+		{"01:02:34", time.Minute + 2340*time.Millisecond, true},
+		{"01:02.5", time.Minute + 2500*time.Millisecond, true},
+		{"00:20.123", 20123 * time.Millisecond, true},
+		{"01:02", time.Minute + 2*time.Second, true},
+		{"123:00.00", 123 * time.Minute, true},
+		{"1:2", time.Minute + 2*time.Second, true},
+		{"00:60.00", 0, false},
+		{"00:100", 0, false},
+		{":02.00", 0, false},
+		{"00:.50", 0, false},
+		{"ar:Artist", 0, false},
+		{"-1:00.00", 0, false},
+		{"00:01.x", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLyricTime(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseLyricTime(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseLyrics(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want songLyrics
+	}{
+		{"empty", "", songLyrics{lines: []lyricLine{}}},
+		{
+			"sorted by time",
+			"[ti:Song]\n[ar:Artist]\n[00:12.00]Second\n[00:01.50]First\n",
+			songLyrics{synced: true, lines: []lyricLine{
+				{1500 * time.Millisecond, "First"},
+				{12 * time.Second, "Second"},
+			}},
+		},
+		{
+			"multiple timestamps",
+			"[00:10.00][01:00.50]Chorus\n[00:30.00]Verse\n[00:40.00]\n",
+			songLyrics{synced: true, lines: []lyricLine{
+				{10 * time.Second, "Chorus"},
+				{30 * time.Second, "Verse"},
+				{40 * time.Second, ""},
+				{60500 * time.Millisecond, "Chorus"},
+			}},
+		},
+		{
+			"positive offset",
+			"[offset:+500]\n[00:00.20]Clamped\n[00:10.00]Sooner\n",
+			songLyrics{synced: true, lines: []lyricLine{
+				{0, "Clamped"},
+				{9500 * time.Millisecond, "Sooner"},
+			}},
+		},
+		{
+			"negative offset after the lines",
+			"[00:10.00]Later\n[OFFSET: -250]\n",
+			songLyrics{synced: true, lines: []lyricLine{
+				{10250 * time.Millisecond, "Later"},
+			}},
+		},
+		{
+			"enhanced word times",
+			"\ufeff[00:05.00]<00:05.00>Hello <00:05.50>world <b>\r\n",
+			songLyrics{synced: true, lines: []lyricLine{
+				{5 * time.Second, "Hello world <b>"},
+			}},
+		},
+		{
+			"plain",
+			"\n[ar:Artist]\n[Chorus]\nLa la\n\nLa\n\n",
+			songLyrics{lines: []lyricLine{
+				{0, "[Chorus]"},
+				{0, "La la"},
+				{0, ""},
+				{0, "La"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseLyrics(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseLyrics(%q) = %+v; want %+v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLyricsCurrent(t *testing.T) {
+	lyrics := songLyrics{synced: true, lines: []lyricLine{
+		{5 * time.Second, "a"},
+		{10 * time.Second, "b"},
+		{10 * time.Second, "c"},
+	}}
+	tests := []struct {
+		pos  time.Duration
+		want int
+	}{
+		{0, -1},
+		{5 * time.Second, 0},
+		{9 * time.Second, 0},
+		{10 * time.Second, 2},
+		{time.Minute, 2},
+	}
+	for _, tt := range tests {
+		if got := lyrics.current(tt.pos); got != tt.want {
+			t.Errorf("current(%v) = %d; want %d", tt.pos, got, tt.want)
+		}
+	}
+}
@@ -2,15 +2,16 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"slices"
-	"strings"
 	"syscall"
 	"time"
+	"github.com/BurntSushi/toml"
 	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/speaker"
 	"golang.org/x/term"
@@ -68,23 +69,50 @@ func consumeCSISequence(keys chan rune, keyCh chan<- rune) {
 	}
 }
 
+// consumeStringSequence reads and discards the rest of an OSC, DCS or APC escape sequence,
+// which ends with ST (ESC \) or, for OSC, BEL. Terminals send these as replies to queries,
+// e.g. to those of the startup probe when they arrive after it gave up waiting. It returns
+// false if nothing follows the introducer within 25ms, meaning it was an Alt+key press.
+//
+// consumeStringSequence 读取并丢弃 OSC、DCS 或 APC 转义序列的剩余部分，这些序列以 ST（ESC \）
+// 结尾，OSC 也可以以 BEL 结尾。终端会以这些序列应答查询，例如启动探测放弃等待后才到达的应答。
+// 如果引导符之后 25 毫秒内没有后续字符，说明这是 Alt+键，返回 false。
+func consumeStringSequence(keys chan rune) bool {
+	escape := false
+	for read := false; ; read = true {
+		select {
+		case b, ok := <-keys:
+			if !ok || b == '\a' || (escape && b == '\\') {
+				return true
+			}
+			escape = b == '\x1b'
+		case <-time.After(25 * time.Millisecond):
+			return read
+		}
+	}
+}
+
 // App represents the main TUI application and holds shared state.
 //
 // App 代表主TUI应用程序并持有共享状态。
 type App struct {
 	player           *audioPlayer
 	mprisServer      *MPRISServer
+	notifier         *Notifier
 	pages            []Page
 	currentPageIndex int
 	Playlist         []string
 	LibraryPath      string      // Root path of the music library. / 音乐库的根路径。
 	currentSongPath  string      // Path of the currently playing song. / 当前播放歌曲的路径。
+	song             *songInfo   // Tags and cover of the last loaded song. / 最近加载的歌曲的标签和封面。
+	buffering        bool        // Whether playback waits for the read-ahead. / 播放是否正在等待预读。
 	playMode         int         // Play mode: 0=repeat one, 1=repeat all, 2=random. / 播放模式: 0=单曲循环, 1=列表循环, 2=随机播放。
 	volume           float64     // Saved volume setting. / 保存的音量设置。
 	linearVolume     float64     // 0.0 to 1.0 linear volume for display. / 用于显示的线性音量（0.0到1.0）。
 	playbackRate     float64     // Saved playback rate setting. / 保存的播放速度设置。
 	actionQueue      chan func() // Action queue for thread-safe UI updates. / 用于线程安全UI更新的操作队列。
 	sampleRate       beep.SampleRate
+	outputDevice     string // ID of the output device, "" for the backend's default. / 输出设备 ID，"" 表示后端默认设备。
 
 	// Play history. / 播放历史记录。
 	playHistory         []string // Stores up to 100 played songs. / 存储最多100首播放过的歌曲。
@@ -117,14 +145,17 @@ type Page interface {
 // switchToPage 将应用程序切换到给定索引的页面。
 func (a *App) switchToPage(index int) {
 	if index >= 0 && index < len(a.pages) && index != a.currentPageIndex {
+		if split, ok := a.splitView(); ok {
+			split.release()
+		}
 		a.currentPageIndex = index
-		if GlobalConfig.App.DefaultPage == 3 {
+		if GlobalConfig.App.DefaultPage == defaultPageMemory {
 			if err := SavePage(index); err != nil {
 				l.Warnf("failed to save page: %v\n\n警告: 保存页面失败: %v", err, err)
 			}
 		}
 		newPage := a.pages[a.currentPageIndex]
-		fmt.Print("\x1b[2J\x1b[3J\x1b[H") // Clear screen completely
+		clearScreen()
 		newPage.Init()
 		newPage.View()
 	}
@@ -148,6 +179,13 @@ func (a *App) PlaySongWithSwitch(songPath string, switchToPlayer bool) error {
 //
 // PlaySongWithSwitchAndRender 播放指定的歌曲文件，并可选择是否跳转到播放页面和是否强制重新渲染。
 func (a *App) PlaySongWithSwitchAndRender(songPath string, switchToPlayer bool, forceRender bool) error {
+	// The split view already shows the player, so it is redrawn instead.
+	// 分屏视图已经显示了播放器，因此改为重新绘制分屏视图。
+	split, inSplit := a.splitView()
+	if inSplit {
+		switchToPlayer = false
+	}
+
 	// Do nothing if it's the same song.
 	// 如果是同一首歌，则不执行任何操作。
 	if a.currentSongPath == songPath && a.player != nil {
@@ -157,18 +195,8 @@ func (a *App) PlaySongWithSwitchAndRender(songPath string, switchToPlayer bool,
 		return nil
 	}
 
-	// Stop current playback.
-	// 停止当前播放。
-	speaker.Lock()
-	if a.player != nil {
-		a.player.ctrl.Paused = true
-	}
-	speaker.Unlock()
-
-	streamer, format, err := decodeAudioFile(songPath)
-	if err != nil {
-		a.MarkFileAsCorrupted(songPath)
-		return fmt.Errorf("Failed to decode audio: %v\n\n解码音频失败: %v", err, err)
+	if err := a.startSong(songPath); err != nil {
+		return err
 	}
 
 	var playerPage *PlayerPage
@@ -176,41 +204,8 @@ func (a *App) PlaySongWithSwitchAndRender(songPath string, switchToPlayer bool,
 		playerPage = page
 	}
 
-	if err := speaker.ReInit(format.SampleRate, format.SampleRate.N(time.Second/30)); err != nil {
-		streamer.Close()
-		return fmt.Errorf("Failed to reinit speaker: %v\n\n重新初始化扬声器失败: %v", err, err)
-	}
-
-	audioStream := streamer
-
-	player, err := newAudioPlayer(audioStream, format, a.volume, a.playbackRate)
-	if err != nil {
-		streamer.Close()
-		return fmt.Errorf("Failed to create player: %v\n\n创建播放器失败: %v", err, err)
-	}
-
-	if a.mprisServer != nil {
-		a.mprisServer.StopService()
-	}
-	mprisServer, err := NewMPRISServer(a, player, songPath)
-	if err == nil {
-		if err := mprisServer.Start(); err == nil {
-			mprisServer.StartUpdateLoop()
-			mprisServer.UpdatePlaybackStatus(true)
-			mprisServer.UpdateMetadata()
-		}
-	}
-
-	speaker.Lock()
-	a.player = player
-	a.mprisServer = mprisServer
-	a.currentSongPath = songPath
-	speaker.Unlock()
-
 	a.addToPlayHistory(songPath)
 
-	speaker.Play(a.player.volume)
-
 	if switchToPlayer {
 		a.currentPageIndex = 0 // Directly set the page index
 		playerPage.UpdateSong(songPath)
@@ -218,7 +213,7 @@ func (a *App) PlaySongWithSwitchAndRender(songPath string, switchToPlayer bool,
 		if forceRender {
 			// This is for song changes during runtime.
 			// Clear the screen and redraw the page.
-			fmt.Print("\x1b[2J\x1b[3J\x1b[H")
+			clearScreen()
 			playerPage.Init()
 			playerPage.View()
 		}
@@ -227,6 +222,9 @@ func (a *App) PlaySongWithSwitchAndRender(songPath string, switchToPlayer bool,
 	} else {
 		// When not switching to player page, just update the song path without rendering
 		playerPage.UpdateSong(songPath)
+		if inSplit && forceRender {
+			split.View()
+		}
 	}
 
 	return nil
@@ -429,6 +427,32 @@ func sanitizePlayHistory(history []string, playlist []string) []string {
 	return cleaned
 }
 
+// callOnMainTimeout bounds how long callOnMain waits for the main loop.
+//
+// callOnMainTimeout 限制 callOnMain 等待主循环的最长时间。
+const callOnMainTimeout = 2 * time.Second
+
+// callOnMain runs fn on the main loop and waits for it, giving up after callOnMainTimeout. It
+// is for other goroutines, such as the web remote and D-Bus handlers, that need a result.
+//
+// callOnMain 在主循环中运行 fn 并等待其完成，超过 callOnMainTimeout 后放弃。它供需要结果的
+// 其他协程使用，例如网页遥控器和 D-Bus 处理函数。
+func (a *App) callOnMain(fn func()) error {
+	done := make(chan struct{})
+	timeout := time.After(callOnMainTimeout)
+	select {
+	case a.actionQueue <- func() { fn(); close(done) }:
+	case <-timeout:
+		return errors.New("player is busy")
+	}
+	select {
+	case <-done:
+		return nil
+	case <-timeout:
+		return errors.New("player is busy")
+	}
+}
+
 // NextSong switches to the next song.
 //
 // NextSong 切换到下一首歌曲。
@@ -451,6 +475,186 @@ func (a *App) PreviousSong() {
 	}
 }
 
+// SetPaused pauses or resumes the current song and notifies MPRIS clients and the
+// notification bubble.
+//
+// SetPaused 暂停或恢复当前歌曲，并通知 MPRIS 客户端和通知气泡。
+func (a *App) SetPaused(paused bool) {
+	if a.player == nil {
+		return
+	}
+	speaker.Lock()
+	a.player.ctrl.Paused = paused
+	speaker.Unlock()
+	if a.mprisServer != nil {
+		a.mprisServer.UpdatePlaybackStatus()
+	}
+	a.notifier.SetPaused(paused)
+}
+
+// SeekTo moves the current song to the given sample position, clamped to the
+// track, and notifies MPRIS clients of the jump.
+//
+// SeekTo 将当前歌曲移动到给定的采样位置（限制在曲目范围内），并通知 MPRIS 客户端发生了跳转。
+func (a *App) SeekTo(pos int) {
+	if a.player == nil {
+		return
+	}
+	speaker.Lock()
+	pos = min(pos, a.player.streamer.Len()-1)
+	pos = max(pos, 0)
+	if err := a.player.streamer.Seek(pos); err != nil {
+		// ignore seek errors
+	}
+	speaker.Unlock()
+	if a.mprisServer != nil {
+		a.mprisServer.EmitSeeked()
+	}
+}
+
+// SetLinearVolume sets the volume on a 0.0-1.0 scale, saves it and notifies MPRIS clients.
+//
+// SetLinearVolume 以 0.0-1.0 的刻度设置音量，保存并通知 MPRIS 客户端。
+func (a *App) SetLinearVolume(linearVolume float64) {
+	speaker.Lock()
+	a.linearVolume = min(max(linearVolume, 0.0), 1.0)
+	if a.linearVolume == 0 {
+		a.volume = -10
+	} else {
+		a.volume = math.Log2(a.linearVolume)
+	}
+	if a.player != nil {
+		a.player.volume.Volume = a.volume
+	}
+	speaker.Unlock()
+	a.SaveSettings()
+	if a.mprisServer != nil {
+		a.mprisServer.VolumeChanged()
+	}
+}
+
+// SetPlaybackRate sets the playback rate (0.1-4.0), saves it and notifies MPRIS clients.
+//
+// SetPlaybackRate 设置播放速度（0.1-4.0），保存并通知 MPRIS 客户端。
+func (a *App) SetPlaybackRate(rate float64) {
+	speaker.Lock()
+	a.playbackRate = min(max(rate, 0.1), 4.0)
+	if a.player != nil {
+		a.player.resampler.SetRatio(a.playbackRate)
+		a.playbackRate = a.player.resampler.Ratio()
+	}
+	speaker.Unlock()
+	a.SaveSettings()
+	if a.mprisServer != nil {
+		a.mprisServer.RateChanged()
+	}
+}
+
+// SetOutputDevice switches playback to another output device. The playing song keeps
+// its position and paused state.
+//
+// SetOutputDevice 将播放切换到另一个输出设备。正在播放的歌曲保持其位置和暂停状态。
+func (a *App) SetOutputDevice(id string) error {
+	if err := speaker.SetDevice(id); err != nil {
+		return fmt.Errorf("Failed to switch output device: %v\n\n切换输出设备失败: %v", err, err)
+	}
+	a.outputDevice = id
+	return nil
+}
+
+// SetPlayMode switches to the given play mode (0=repeat one, 1=repeat all, 2=random),
+// saves it and notifies MPRIS clients.
+//
+// SetPlayMode 切换到给定的播放模式（0=单曲循环, 1=列表循环, 2=随机播放），保存并通知 MPRIS 客户端。
+func (a *App) SetPlayMode(mode int) {
+	if mode < 0 || mode > 2 || mode == a.playMode {
+		return
+	}
+	oldMode := a.playMode
+	a.playMode = mode
+	a.switchedToRandom = oldMode != 2 && a.playMode == 2
+	if err := SavePlayMode(a.playMode); err != nil {
+		l.Warnf("failed to save play mode: %v\n\n警告: 保存播放模式失败: %v", err, err)
+	}
+	if a.mprisServer != nil {
+		a.mprisServer.PlayModeChanged()
+	}
+}
+
+// ReplacePlaylist replaces the whole playlist with the given songs, syncs the library
+// selection and play history, saves everything and starts playing the first song.
+// It must be called from the main loop, e.g. via actionQueue.
+//
+// ReplacePlaylist 用给定的歌曲替换整个播放列表，同步媒体库选择状态和播放历史，
+// 保存所有内容并开始播放第一首歌曲。必须在主循环中调用，例如通过 actionQueue。
+func (a *App) ReplacePlaylist(songs []string) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	a.Playlist = slices.Clone(songs)
+	if len(a.pages) > 2 {
+		if library, ok := a.pages[2].(*Library); ok {
+			library.selected = make(map[string]bool)
+			for _, songPath := range a.Playlist {
+				library.selected[songPath] = true
+			}
+			library.dirSelectionCache = make(map[string]bool)
+		}
+	}
+
+	if err := SavePlaylist(a.Playlist, a.LibraryPath); err != nil {
+		l.Warnf("failed to save playlist: %v\n\n警告: 保存播放列表失败: %v", err, err)
+	}
+
+	a.playHistory = sanitizePlayHistory(a.playHistory, a.Playlist)
+	a.historyIndex = len(a.playHistory) - 1
+	if err := SavePlayHistory(a.playHistory, a.LibraryPath); err != nil {
+		l.Warnf("failed to save play history: %v\n\n警告: 保存播放历史失败: %v", err, err)
+	}
+
+	if a.mprisServer != nil {
+		a.mprisServer.UpdateProperties()
+	}
+
+	return a.PlaySong(a.Playlist[0])
+}
+
+// AddSongsAndPlay appends the given songs to the playlist (skipping duplicates),
+// marks them as selected in the library, saves the playlist and starts playing the
+// first one. It must be called from the main loop, e.g. via actionQueue.
+//
+// AddSongsAndPlay 将给定的歌曲追加到播放列表（跳过重复项），在媒体库中将其标记为已选中，
+// 保存播放列表并开始播放第一首。必须在主循环中调用，例如通过 actionQueue。
+func (a *App) AddSongsAndPlay(songs []string) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	var library *Library
+	if len(a.pages) > 2 {
+		library, _ = a.pages[2].(*Library)
+	}
+
+	for _, songPath := range songs {
+		if !songExistsInPlaylist(songPath, a.Playlist) {
+			a.Playlist = append(a.Playlist, songPath)
+		}
+		if library != nil {
+			library.selected[songPath] = true
+		}
+	}
+	if library != nil {
+		library.dirSelectionCache = make(map[string]bool)
+	}
+
+	if err := SavePlaylist(a.Playlist, a.LibraryPath); err != nil {
+		l.Warnf("failed to save playlist: %v\n\n警告: 保存播放列表失败: %v", err, err)
+	}
+
+	return a.PlaySong(songs[0])
+}
+
 // SaveSettings saves the current volume and playback rate to the storage file.
 //
 // SaveSettings 将当前的音量和播放速度保存到存储文件。
@@ -536,6 +740,9 @@ func (a *App) Run() error {
 			case nextRune := <-keys:
 				if nextRune == '[' {
 					consumeCSISequence(keys, keyCh)
+				} else if (nextRune == ']' || nextRune == 'P' || nextRune == '_') && consumeStringSequence(keys) {
+					// A late reply to a terminal query, dropped so that it is not taken for keys.
+					// 迟到的终端查询应答，将其丢弃以免被当作按键。
 				} else {
 					// It's an Alt+key sequence. Set the high bit to encode the Alt modifier.
 					// 这是Alt+键序列。设置高位来编码Alt修饰符。
@@ -556,7 +763,15 @@ func (a *App) Run() error {
 	ticker := time.NewTicker(time.Second / 2)
 	defer ticker.Stop()
 
-	fmt.Print("\x1b[2J\x1b[3J\x1b[H")
+	// The visualizer is redrawn more often, and only if a layout template shows it.
+	// 可视化部件重绘得更频繁，并且只在布局模板显示它时进行。
+	frames := time.NewTicker(visualizerFrame)
+	defer frames.Stop()
+	if !layoutsUseWidget(GlobalConfig.Layouts, "visualizer") {
+		frames.Stop()
+	}
+
+	clearScreen()
 	a.pages[a.currentPageIndex].Init()
 	a.pages[a.currentPageIndex].View()
 
@@ -568,7 +783,7 @@ func (a *App) Run() error {
 
 		case key := <-keyCh:
 			if IsKey(key, GlobalConfig.Keymap.Global.Quit) {
-				if isInSearchMode(currentPage) {
+				if isInSearchMode(currentPage) || hasOpenOverlay(currentPage) {
 					_, needsRedraw, err := currentPage.HandleKey(key)
 					if err != nil {
 						return nil
@@ -582,7 +797,7 @@ func (a *App) Run() error {
 					}
 					return nil
 				}
-			} else if isActivelySearching(currentPage) {
+			} else if isActivelySearching(currentPage) || hasOpenOverlay(currentPage) {
 				// In search mode, pass all keys to the page's handler first.
 				// 在搜索模式下，优先将所有按键传递给页面的处理器。
 				_, needsRedraw, err := currentPage.HandleKey(key)
@@ -592,6 +807,8 @@ func (a *App) Run() error {
 				if needsRedraw {
 					currentPage.View()
 				}
+			} else if split, ok := currentPage.(*SplitView); ok && IsKey(key, GlobalConfig.Keymap.Split.FocusNext) {
+				split.focusNext()
 			} else if IsKey(key, GlobalConfig.Keymap.Global.CyclePages) {
 				a.switchToPage((a.currentPageIndex + 1) % len(a.pages))
 			} else if IsKey(key, GlobalConfig.Keymap.Global.SwitchToPlayer) {
@@ -600,6 +817,8 @@ func (a *App) Run() error {
 				a.switchToPage(1) // PlayListPage
 			} else if IsKey(key, GlobalConfig.Keymap.Global.SwitchToLibrary) {
 				a.switchToPage(2) // LibraryPage
+			} else if IsKey(key, GlobalConfig.Keymap.Global.SwitchToSplit) {
+				a.switchToPage(splitPageIndex)
 			} else {
 				_, needsRedraw, err := currentPage.HandleKey(key)
 				if err != nil {
@@ -622,7 +841,13 @@ func (a *App) Run() error {
 			}
 
 		case <-ticker.C:
+			a.checkBuffering()
 			currentPage.Tick()
+
+		case <-frames.C:
+			if player, ok := a.pages[0].(*PlayerPage); ok {
+				player.drawVisualizers()
+			}
 		}
 	}
 }
@@ -631,6 +856,9 @@ func (a *App) Run() error {
 //
 // isActivelySearching 检查用户当前是否正在输入搜索提示。
 func isActivelySearching(page Page) bool {
+	if split, ok := page.(*SplitView); ok {
+		return isActivelySearching(split.focused())
+	}
 	if lib, ok := page.(*Library); ok {
 		return lib.isSearching
 	}
@@ -640,10 +868,26 @@ func isActivelySearching(page Page) bool {
 	return false
 }
 
+// hasOpenOverlay checks if the current page shows an overlay that takes all keys.
+//
+// hasOpenOverlay 检查当前页面是否显示了接管所有按键的浮层。
+func hasOpenOverlay(page Page) bool {
+	if split, ok := page.(*SplitView); ok {
+		return hasOpenOverlay(split.focused())
+	}
+	if player, ok := page.(*PlayerPage); ok {
+		return player.devicePicker != nil
+	}
+	return false
+}
+
 // isInSearchMode checks if the current page is in search mode.
 //
 // isInSearchMode 检查当前页面是否处于搜索模式。
 func isInSearchMode(page Page) bool {
+	if split, ok := page.(*SplitView); ok {
+		return isInSearchMode(split.focused())
+	}
 	if lib, ok := page.(*Library); ok {
 		return lib.isSearching || lib.searchQuery != ""
 	}
@@ -654,9 +898,11 @@ func isInSearchMode(page Page) bool {
 }
 
 func main() {
-	if os.Getenv("TMUX") != "" || os.Getenv("ZELLIJ") != "" {
-		l.Fatalf("BM does not support running inside tmux or zellij\n\nBM 不支持在tmux或zellij里运行")
+	args, err := parseAudioFlags(os.Args)
+	if err != nil {
+		l.Fatalf("%v", err)
 	}
+	os.Args = args
 
 	if len(os.Args) >= 2 {
 		arg := os.Args[1]
@@ -667,11 +913,9 @@ func main() {
 
 		info, err := os.Stat(arg)
 		if err == nil && !info.IsDir() {
-			ext := filepath.Ext(arg)
-			ext = strings.ToLower(ext)
-			if ext == ".flac" || ext == ".mp3" || ext == ".wav" || ext == ".ogg" {
-				fmt.Print("\x1b[?1049h\x1b[?25l")
-				defer fmt.Print("\x1b[2J\x1b[?1049l\x1b[?25h")
+			if isAudioFile(arg) {
+				fmt.Print("\x1b[?1049h\x1b[22;0t\x1b[?25l")
+				defer fmt.Print("\x1b[2J\x1b[23;0t\x1b[?1049l\x1b[?25h")
 
 				oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
 				if err != nil {
@@ -692,8 +936,10 @@ func main() {
 		l.Fatalf("%v", err)
 	}
 
-	fmt.Print("\x1b[?1049h\x1b[?25l")
-	defer fmt.Print("\x1b[2J\x1b[?1049l\x1b[?25h")
+	// The window title is saved on the terminal's title stack for setWindowTitle.
+	// 窗口标题保存在终端的标题栈中，供 setWindowTitle 使用。
+	fmt.Print("\x1b[?1049h\x1b[22;0t\x1b[?25l")
+	defer fmt.Print("\x1b[2J\x1b[23;0t\x1b[?1049l\x1b[?25h")
 
 	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
 	if err != nil {
@@ -767,13 +1013,15 @@ func runApplication(dirPath string) error {
 		return fmt.Errorf("Error loading storage data: %v\n\n加载存储数据时出错: %v", err, err)
 	}
 
-	cellW, cellH, err := getCellSize()
-	if err != nil {
-		return fmt.Errorf("Unable to get terminal cell size: %v\n\n无法获取终端单元格尺寸: %v", err, err)
-	}
+	initTerminalCaps()
+	cellW, cellH := getCellSize()
 
 	sampleRate := beep.SampleRate(44100)
-	speaker.Init(sampleRate, sampleRate.N(time.Second/30))
+	outputDevice, err := initSpeaker(sampleRate)
+	if err != nil {
+		return err
+	}
+	configureDownmix()
 
 	playlist, err := LoadPlaylist(dirPath)
 	if err != nil {
@@ -804,6 +1052,7 @@ func runApplication(dirPath string) error {
 		playbackRate:        1.0,
 		actionQueue:         make(chan func(), 10),
 		sampleRate:          sampleRate,
+		outputDevice:        outputDevice,
 		playHistory:         playHistory,
 		historyIndex:        len(playHistory) - 1,
 		isNavigatingHistory: false,
@@ -812,17 +1061,7 @@ func runApplication(dirPath string) error {
 		switchedToRandom:    false,
 	}
 
-	if GlobalConfig.App.DefaultPage == 3 {
-		savedPage, err := LoadPage()
-		if err != nil {
-			l.Warnf("Could not load saved page: %v\n\n无法加载已保存的页面: %v", err, err)
-			app.currentPageIndex = 0
-		} else {
-			app.currentPageIndex = savedPage
-		}
-	} else {
-		app.currentPageIndex = GlobalConfig.App.DefaultPage
-	}
+	app.currentPageIndex = startPageIndex(GlobalConfig.App.DefaultPage, LoadPage)
 
 	if GlobalConfig.App.RememberVolume && storageData.Volume != nil {
 		app.linearVolume = *storageData.Volume
@@ -851,9 +1090,25 @@ func runApplication(dirPath string) error {
 	initialLayout := resolveInitialLayout(isWide)
 
 	playerPage := NewPlayerPage(app, "", cellW, cellH, initialLayout)
+	playerPage.layoutTemplate = resolveInitialTemplate()
 	playListPage := NewPlayList(app)
 	libraryPage := NewLibraryWithPath(app, dirPath)
-	app.pages = []Page{playerPage, playListPage, libraryPage}
+	splitPage := NewSplitView(app, playerPage, playListPage, libraryPage)
+	app.pages = []Page{playerPage, playListPage, libraryPage, splitPage}
+
+	app.mprisServer = startMPRISServer(app)
+	if app.mprisServer != nil {
+		defer app.mprisServer.StopService()
+	}
+
+	app.notifier = startNotifier(app)
+	defer app.notifier.Close()
+
+	webRemote := startWebRemote(app)
+	defer webRemote.Close()
+
+	streamServer := startStreamServer()
+	defer streamServer.Close()
 
 	if GlobalConfig.App.AutostartLastPlayed {
 		currentSong, err := LoadCurrentSong(dirPath)
@@ -909,13 +1164,15 @@ func runSingleSong(songPath string) error {
 		return fmt.Errorf("Failed to load minimal config: %v\n\n加载最小配置失败: %v", err, err)
 	}
 
-	cellW, cellH, err := getCellSize()
-	if err != nil {
-		return fmt.Errorf("Unable to get terminal cell size: %v\n\n无法获取终端单元格尺寸: %v", err, err)
-	}
+	initTerminalCaps()
+	cellW, cellH := getCellSize()
 
 	sampleRate := beep.SampleRate(44100)
-	speaker.Init(sampleRate, sampleRate.N(time.Second/30))
+	outputDevice, err := initSpeaker(sampleRate)
+	if err != nil {
+		return err
+	}
+	configureDownmix()
 
 	app := &App{
 		player:              nil,
@@ -929,6 +1186,7 @@ func runSingleSong(songPath string) error {
 		playbackRate:        1.0,
 		actionQueue:         make(chan func(), 10),
 		sampleRate:          sampleRate,
+		outputDevice:        outputDevice,
 		playHistory:         make([]string, 0),
 		historyIndex:        -1,
 		isNavigatingHistory: false,
@@ -939,6 +1197,14 @@ func runSingleSong(songPath string) error {
 	playerPage := NewPlayerPage(app, "", cellW, cellH, -1)
 	app.pages = []Page{playerPage}
 
+	app.mprisServer = startMPRISServer(app)
+	if app.mprisServer != nil {
+		defer app.mprisServer.StopService()
+	}
+
+	streamServer := startStreamServer()
+	defer streamServer.Close()
+
 	if err := app.PlaySongWithSwitchAndRender(absPath, true, false); err != nil {
 		return fmt.Errorf("Failed to play song: %v\n\n播放歌曲失败: %v", err, err)
 	}
@@ -958,6 +1224,7 @@ func loadMinimalConfig() error {
 				SwitchToPlayer:   Key{"1"},
 				SwitchToPlayList: Key{"2"},
 				SwitchToLibrary:  Key{"3"},
+				SwitchToSplit:    Key{"4"},
 			},
 			Player: PlayerKeymap{
 				TogglePause:     Key{"space"},
@@ -972,6 +1239,7 @@ func loadMinimalConfig() error {
 				TogglePlayMode:  Key{"r"},
 				ToggleTextColor: Key{"c"},
 				Reset:           Key{"backspace"},
+				SelectDevice:    Key{"v"},
 			},
 			Library: LibraryKeymap{
 				NavUp:           Key{"k", "w", "up"},
@@ -999,6 +1267,9 @@ func loadMinimalConfig() error {
 					SearchBackspace: Key{"backspace"},
 				},
 			},
+			Split: SplitKeymap{
+				FocusNext: Key{"tab"},
+			},
 		},
 		App: AppConfig{
 			MaxHistorySize:       100,
@@ -1020,12 +1291,32 @@ func loadMinimalConfig() error {
 			DefaultCoverPath:     "",
 			EnableFolderCovers:   true,
 			MaxSearchDirs:        15,
+			PlaylistsDir:         "",
+			NotificationTimeoutMs: -1,
+			NotificationUrgency:  "low",
+			WebRemoteAddr:        "",
+			WebRemoteToken:       "",
+			StreamAddr:           "",
+			StreamSampleRate:     44100,
+			StreamMuteLocal:      false,
+			AudioBackend:         "auto",
+			OutputDevice:         "",
+			OutputFormat:         "16",
+			Dither:               true,
+			BitPerfect:           false,
+			DownmixCenter:        0.707,
+			DownmixSurround:      0.707,
+			DownmixLFE:           0.0,
+			DownmixNormalize:     true,
+			MonoGain:             1.0,
+			CoverCacheMB:         64,
+			ReadAheadKB:          4096,
 		},
 	}
 
 	resolveIconSet(GlobalConfig)
 
-	return nil
+	return resolveTheme(GlobalConfig, toml.MetaData{})
 }
 
 // MarkFileAsCorrupted marks a file as corrupted.
@@ -1064,10 +1355,20 @@ func displayHelp() {
 	fmt.Println("  " + green + "bm <audio-file>" + reset + "             Play single audio file")
 	fmt.Println("  " + green + "bm help, -h, -help, --help" + reset + "  Show this help message")
 	fmt.Println()
+	fmt.Println(bold + "OPTIONS:" + reset)
+	fmt.Println("  " + green + "--backend <name>" + reset + "            Audio output: auto, pulse, alsa, file or null")
+	fmt.Println("  " + green + "--device <name>" + reset + "             Output device: PulseAudio sink, ALSA PCM or file path")
+	fmt.Println()
 	fmt.Println(bold + "SUPPORTED FORMATS:" + reset)
-	fmt.Println("  " + yellow + "FLAC, MP3, WAV, OGG" + reset)
+	fmt.Println("  " + yellow + "FLAC, MP3, WAV, OGG, AIFF" + reset)
+	fmt.Println("  " + yellow + "Opus, M4A (AAC/ALAC), AAC, WavPack, APE" + reset + " (requires ffmpeg)")
 	fmt.Println()
 	fmt.Println(bold + "CONFIGURATION:" + reset)
 	fmt.Println("  Configuration file: " + cyan + "~/.config/BM/config.toml" + reset)
 	fmt.Println()
+	fmt.Println(bold + "TERMINAL:" + reset)
+	for _, line := range describeTerminal() {
+		fmt.Println("  " + line[0] + ": " + cyan + line[1] + reset)
+	}
+	fmt.Println()
 }
@@ -3,95 +3,157 @@ package main
 import (
 	"encoding/base64"
 	"fmt"
-	"math"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"time"
+	"sync"
 
-	"github.com/dhowden/tag"
 	"github.com/godbus/dbus/v5"
+	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/speaker"
 )
 
+// mprisSupportedUriSchemes lists the URI schemes accepted by OpenUri.
+//
+// mprisSupportedUriSchemes 列出 OpenUri 接受的 URI 方案。
+var mprisSupportedUriSchemes = []string{"file"}
+
+// mprisSupportedMimeTypes lists the MIME types of every format BM can decode.
+//
+// mprisSupportedMimeTypes 列出 BM 能够解码的所有格式的 MIME 类型。
+var mprisSupportedMimeTypes = []string{
+	"audio/flac",
+	"audio/x-flac",
+	"audio/mpeg",
+	"audio/mp3",
+	"audio/wav",
+	"audio/x-wav",
+	"audio/wave",
+	"audio/ogg",
+	"audio/vorbis",
+	"application/ogg",
+	"audio/aiff",
+	"audio/x-aiff",
+	"audio/opus",
+	"audio/mp4",
+	"audio/x-m4a",
+	"audio/aac",
+	"audio/x-wavpack",
+	"audio/x-ape",
+	"audio/x-mpegurl",
+	"audio/mpegurl",
+	"audio/x-scpls",
+}
+
+// mprisObjectPath is the object path BM exports its MPRIS interfaces on.
+//
+// mprisObjectPath 是 BM 导出 MPRIS 接口的对象路径。
+const mprisObjectPath = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+
+// mprisNoTrack is the track ID reported when nothing is loaded.
+//
+// mprisNoTrack 是未加载任何歌曲时报告的曲目 ID。
+const mprisNoTrack = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+
 // MPRISServer implements the D-Bus MPRIS2 specification.
+// A single instance is created at startup and lives for the whole session;
+// track changes only update its metadata and emit signals.
 //
 // MPRISServer 实现了 D-Bus MPRIS2 规范。
+// 启动时只创建一个实例，并在整个会话期间存在；切换歌曲时只更新元数据并发送信号。
 type MPRISServer struct {
-	conn         *dbus.Conn
-	app          *App
-	player       *audioPlayer
-	flacPath     string
-	isPlaying    bool
-	position     int64
-	duration     int64
-	metadata     map[string]dbus.Variant
-	originalFile *os.File  // Keep a reference to the original file for duration calculation. / 保留对原始文件的引用以计算时长。
-	lastUpdate   time.Time // Time of the last update. / 上次更新的时间。
-	startTime    time.Time // Time when playback started. / 播放开始的时间。
-
-	stopChan chan struct{} // Channel to signal goroutines to stop. / 用于通知 goroutine 停止的通道。
-	stopped  bool          // Whether the server has been stopped. / 服务器是否已停止。
-}
-
-// NewMPRISServer creates a new MPRIS server instance.
-//
-// NewMPRISServer 创建一个新的 MPRIS 服务端实例。
-func NewMPRISServer(app *App, player *audioPlayer, flacPath string) (*MPRISServer, error) {
+	conn *dbus.Conn
+	app  *App
+
+	mu         sync.Mutex              // Guards the fields below. / 保护以下字段。
+	songPath   string                  // Path of the track described by metadata. / 元数据所描述的曲目路径。
+	trackID    dbus.ObjectPath         // Unique ID of the current track. / 当前曲目的唯一 ID。
+	trackCount uint64                  // Counter used to generate track IDs. / 用于生成曲目 ID 的计数器。
+	metadata   map[string]dbus.Variant // Cached metadata of the current track. / 当前曲目的缓存元数据。
+	stopped    bool                    // Whether the server has been stopped. / 服务器是否已停止。
+
+	// Copy of the app state that D-Bus calls read, as they run on goroutines of their own.
+	// The main loop refreshes it with syncState.
+	// D-Bus 调用所读取的应用状态副本，因为这些调用在各自的协程中运行。主循环通过 syncState 刷新它。
+	playMode    int
+	playlistLen int
+	volume      float64 // Linear volume. / 线性音量。
+	rate        float64 // Saved playback rate, reported while nothing is loaded. / 保存的播放速度，未加载歌曲时报告。
+	libraryPath string
+	history     []string // Replaced, never modified. / 只会被替换，不会被修改。
+	singleSong  bool
+
+	activePlaylist mprisMaybePlaylist // Playlist activated last via the Playlists interface. / 最近通过 Playlists 接口激活的播放列表。
+
+	named namedPlaylistCache // Playlist files of the playlists directory. / 播放列表目录中的播放列表文件。
+}
+
+// NewMPRISServer connects to the session bus and creates the MPRIS server.
+//
+// NewMPRISServer 连接到会话总线并创建 MPRIS 服务端。
+func NewMPRISServer(app *App) (*MPRISServer, error) {
 	conn, err := dbus.SessionBus()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to connect to D-Bus: %v\n\n连接 D-Bus 失败: %v", err, err)
 	}
 
-	f, err := os.Open(flacPath)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to open file: %v\n\n打开文件失败: %v", err, err)
-	}
-
 	server := &MPRISServer{
-		conn:         conn,
-		app:          app,
-		player:       player,
-		flacPath:     flacPath,
-		isPlaying:    false,
-		position:     0,
-		duration:     0,
-		metadata:     make(map[string]dbus.Variant),
-		originalFile: f,
-		lastUpdate:   time.Now(),
-		startTime:    time.Time{},
-		stopChan:     make(chan struct{}),
-		stopped:      false,
-	}
-
-	server.updateMetadata()
+		conn:     conn,
+		app:      app,
+		trackID:  mprisNoTrack,
+		metadata: map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(mprisNoTrack)},
 
-	if err := server.calculateDuration(); err != nil {
-		// Ignore duration calculation errors for now.
+		activePlaylist: mprisMaybePlaylist{Playlist: mprisPlaylist{ID: "/"}},
 	}
 
 	return server, nil
 }
 
-// Start starts the MPRIS service.
+// startMPRISServer creates and starts the session-wide MPRIS server.
+// It returns nil if no session bus is available or the name is taken.
+//
+// startMPRISServer 创建并启动整个会话使用的 MPRIS 服务端。
+// 如果会话总线不可用或服务名已被占用，则返回 nil。
+func startMPRISServer(app *App) *MPRISServer {
+	server, err := NewMPRISServer(app)
+	if err != nil {
+		return nil
+	}
+	if err := server.Start(); err != nil {
+		server.StopService()
+		return nil
+	}
+	return server
+}
+
+// Start exports the MPRIS interfaces and claims the bus name.
 //
-// Start 启动 MPRIS 服务。
+// Start 导出 MPRIS 接口并申请总线名称。
 func (m *MPRISServer) Start() error {
-	err := m.conn.Export(m, "/org/mpris/MediaPlayer2", "org.freedesktop.DBus.Properties")
+	m.syncState()
+
+	err := m.conn.Export(m, mprisObjectPath, "org.freedesktop.DBus.Properties")
 	if err != nil {
 		return fmt.Errorf("Failed to export Properties interface: %v\n\n导出 Properties 接口失败: %v", err, err)
 	}
 
-	err = m.conn.Export(m, "/org/mpris/MediaPlayer2", "org.mpris.MediaPlayer2")
+	err = m.conn.Export(m, mprisObjectPath, "org.mpris.MediaPlayer2")
 	if err != nil {
 		return fmt.Errorf("Failed to export MediaPlayer2 interface: %v\n\n导出 MediaPlayer2 接口失败: %v", err, err)
 	}
 
-	err = m.conn.Export(m, "/org/mpris/MediaPlayer2", "org.mpris.MediaPlayer2.Player")
+	err = m.conn.ExportWithMap(m, map[string]string{"SeekOffset": "Seek"}, mprisObjectPath, "org.mpris.MediaPlayer2.Player")
 	if err != nil {
 		return fmt.Errorf("Failed to export Player interface: %v\n\n导出 Player 接口失败: %v", err, err)
 	}
 
+	err = m.conn.Export(m, mprisObjectPath, mprisPlaylistsInterface)
+	if err != nil {
+		return fmt.Errorf("Failed to export Playlists interface: %v\n\n导出 Playlists 接口失败: %v", err, err)
+	}
+
 	reply, err := m.conn.RequestName("org.mpris.MediaPlayer2.bm", dbus.NameFlagDoNotQueue)
 	if err != nil {
 		return fmt.Errorf("Failed to request service name: %v\n\n请求服务名失败: %v", err, err)
@@ -103,118 +165,262 @@ func (m *MPRISServer) Start() error {
 	return nil
 }
 
-// StopService stops the MPRIS service.
+// StopService releases the bus name and closes the connection. It is called once on exit.
 //
-// StopService 停止 MPRIS 服务。
+// StopService 释放总线名称并关闭连接。仅在退出时调用一次。
 func (m *MPRISServer) StopService() {
+	m.mu.Lock()
 	if m.stopped {
+		m.mu.Unlock()
 		return
 	}
 	m.stopped = true
-
-	// Signal goroutines to stop
-	close(m.stopChan)
-
-	// Give goroutines a moment to exit
-	time.Sleep(50 * time.Millisecond)
+	m.mu.Unlock()
 
 	if m.conn != nil {
 		m.conn.ReleaseName("org.mpris.MediaPlayer2.bm")
 		m.conn.Close()
 	}
-	if m.originalFile != nil {
-		m.originalFile.Close()
-	}
 }
 
-// UpdatePlaybackStatus updates the playback status.
+// syncState copies the app state read by D-Bus calls. It must be called from the main loop.
 //
-// UpdatePlaybackStatus 更新播放状态。
-func (m *MPRISServer) UpdatePlaybackStatus(playing bool) {
-	if playing && !m.isPlaying {
-		m.startTime = time.Now().Add(-time.Duration(m.position) * time.Microsecond)
-	} else if !playing && m.isPlaying {
-		m.updatePositionFromTime()
-	}
-	m.isPlaying = playing
-	m.lastUpdate = time.Now()
+// syncState 复制 D-Bus 调用所读取的应用状态。必须从主循环中调用。
+func (m *MPRISServer) syncState() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.playMode = m.app.playMode
+	m.playlistLen = len(m.app.Playlist)
+	m.volume = m.app.linearVolume
+	m.rate = m.app.playbackRate
+	m.libraryPath = m.app.LibraryPath
+	m.history = slices.Clone(m.app.playHistory)
+	m.singleSong = m.app.isSingleSongMode
+}
+
+// TrackChanged refreshes the metadata for the app's current song and notifies clients.
+// It must be called from the main loop after a song has been started or playback stopped.
+//
+// TrackChanged 为应用的当前歌曲刷新元数据并通知客户端。
+// 必须在开始播放歌曲或停止播放后从主循环中调用。
+func (m *MPRISServer) TrackChanged() {
+	m.syncState()
+	m.UpdateMetadata()
+	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
+		"PlaybackStatus": m.getPlaybackStatus(),
+		"Rate":           m.getRate(),
+		"CanGoNext":      m.canGoNextOrPrevious(),
+		"CanGoPrevious":  m.canGoNextOrPrevious(),
+		"CanPlay":        m.hasTrack(),
+		"CanPause":       m.hasTrack(),
+		"CanSeek":        m.hasTrack(),
+	})
+	m.EmitSeeked()
+}
+
+// UpdatePlaybackStatus notifies clients of the live playback status.
+//
+// UpdatePlaybackStatus 通知客户端当前的实时播放状态。
+func (m *MPRISServer) UpdatePlaybackStatus() {
 	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
 		"PlaybackStatus": m.getPlaybackStatus(),
 	})
 }
 
-// UpdatePosition updates the playback position.
+// BufferingChanged tells clients that playback started or stopped waiting for the
+// read-ahead. MPRIS has no buffering status, so it is reported as a rate of 0, and the
+// position is resent because clients extrapolate it from the rate.
 //
-// UpdatePosition 更新播放位置。
-func (m *MPRISServer) UpdatePosition(pos int64) {
-	m.position = pos
-	m.lastUpdate = time.Now()
+// BufferingChanged 通知客户端播放开始或停止等待预读。MPRIS 没有缓冲状态，因此以速度 0 表示，
+// 并重新发送播放位置，因为客户端会根据速度推算位置。
+func (m *MPRISServer) BufferingChanged() {
 	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-		"Position": m.position,
+		"Rate": m.getRate(),
 	})
+	m.EmitSeeked()
 }
 
-// updatePositionFromTime updates the position based on elapsed time.
+// EmitSeeked emits the Seeked signal with the live position. Clients extrapolate
+// the position themselves, so it is only needed after a discontinuous jump.
 //
-// updatePositionFromTime 根据经过的时间更新位置。
-func (m *MPRISServer) updatePositionFromTime() {
-	if !m.isPlaying || m.startTime.IsZero() {
+// EmitSeeked 以实时位置发送 Seeked 信号。客户端会自行推算位置，
+// 因此只需在位置发生跳变后发送。
+func (m *MPRISServer) EmitSeeked() {
+	if m.isStopped() {
 		return
 	}
+	m.conn.Emit(mprisObjectPath, "org.mpris.MediaPlayer2.Player.Seeked", m.getCurrentPosition())
+}
 
-	elapsed := time.Since(m.startTime)
-	newPosition := int64(elapsed.Microseconds())
-
-	if m.duration > 0 && newPosition >= m.duration {
-		newPosition = newPosition % m.duration
-		m.startTime = time.Now().Add(-time.Duration(newPosition) * time.Microsecond)
+// getCurrentPosition returns the live playback position in microseconds.
+//
+// getCurrentPosition 返回实时播放位置（以微秒为单位）。
+func (m *MPRISServer) getCurrentPosition() int64 {
+	speaker.Lock()
+	defer speaker.Unlock()
+	player := m.app.player
+	if player == nil || player.streamer == nil {
+		return 0
 	}
+	return samplesToMicroseconds(player.streamer.Position(), player.sampleRate)
+}
 
-	if newPosition != m.position {
-		m.position = newPosition
-		m.lastUpdate = time.Now()
+// getDuration returns the length of the current track in microseconds.
+//
+// getDuration 返回当前曲目的时长（以微秒为单位）。
+func (m *MPRISServer) getDuration() int64 {
+	speaker.Lock()
+	defer speaker.Unlock()
+	player := m.app.player
+	if player == nil || player.streamer == nil {
+		return 0
 	}
+	return samplesToMicroseconds(player.streamer.Len(), player.sampleRate)
 }
 
-// getCurrentPosition gets the current position in microseconds.
+// getRate returns the live playback rate. It is 0 while buffering, as the position does
+// not move.
 //
-// getCurrentPosition 获取当前位置（以微秒为单位）。
-func (m *MPRISServer) getCurrentPosition() int64 {
-	if m.isPlaying && !m.startTime.IsZero() {
-		m.updatePositionFromTime()
+// getRate 返回实时播放速度。缓冲时为 0，因为播放位置不会前进。
+func (m *MPRISServer) getRate() float64 {
+	speaker.Lock()
+	defer speaker.Unlock()
+	if m.app.player == nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.rate
+	}
+	if m.app.player.output.buffering.Load() && !m.app.player.ctrl.Paused {
+		return 0
 	}
-	return m.position
+	return m.app.player.resampler.Ratio()
 }
 
-// getLoopStatus gets the loop status.
-// Accessing App.playMode here would cause a circular dependency, so it returns a default value.
+// getLoopStatus maps the app's play mode to an MPRIS LoopStatus.
+// Repeat one is "Track"; repeat all and random both loop over the playlist.
 //
-// getLoopStatus 获取循环状态。
-// 此处访问 App.playMode 会导致循环依赖，因此返回默认值。
+// getLoopStatus 将应用的播放模式映射为 MPRIS 的 LoopStatus。
+// 单曲循环对应 "Track"；列表循环和随机播放都在整个播放列表中循环。
 func (m *MPRISServer) getLoopStatus() string {
-	return "None"
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.playMode == 0 {
+		return "Track"
+	}
+	return "Playlist"
+}
+
+// getShuffle reports whether the app is in random play mode.
+//
+// getShuffle 报告应用是否处于随机播放模式。
+func (m *MPRISServer) getShuffle() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.playMode == 2
+}
+
+// getVolume returns the linear volume.
+//
+// getVolume 返回线性音量。
+func (m *MPRISServer) getVolume() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.volume
+}
+
+// PlayModeChanged notifies clients that LoopStatus and Shuffle may have changed.
+//
+// PlayModeChanged 通知客户端 LoopStatus 和 Shuffle 可能已改变。
+func (m *MPRISServer) PlayModeChanged() {
+	m.syncState()
+	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
+		"LoopStatus": m.getLoopStatus(),
+		"Shuffle":    m.getShuffle(),
+	})
 }
 
-// UpdateMetadata updates the metadata.
+// confirmPlayMode resends LoopStatus and Shuffle after a client set one of them but the play
+// mode stayed as it was, e.g. in single song mode or for a LoopStatus of "None", so that the
+// client shows the mode actually used. SetPlayMode already notifies clients of a change.
 //
-// UpdateMetadata 更新元数据。
+// confirmPlayMode 在客户端设置 LoopStatus 或 Shuffle 但播放模式未改变时（例如在单曲模式下，
+// 或 LoopStatus 为 "None" 时）重新发送这两个属性，使客户端显示实际使用的模式。
+// 播放模式改变时 SetPlayMode 已经会通知客户端。
+func (m *MPRISServer) confirmPlayMode(previous int) {
+	if m.app.playMode == previous {
+		m.PlayModeChanged()
+	}
+}
+
+// hasTrack reports whether a track is currently loaded.
+//
+// hasTrack 报告当前是否已加载曲目。
+func (m *MPRISServer) hasTrack() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.songPath != ""
+}
+
+// canGoNextOrPrevious reports whether there is another song to switch to.
+//
+// canGoNextOrPrevious 报告是否有其他歌曲可以切换。
+func (m *MPRISServer) canGoNextOrPrevious() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.playlistLen > 1
+}
+
+// isStopped reports whether the server has been stopped.
+//
+// isStopped 报告服务器是否已停止。
+func (m *MPRISServer) isStopped() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conn == nil || m.stopped
+}
+
+// UpdateMetadata rebuilds the metadata for the app's current song and notifies clients.
+//
+// UpdateMetadata 为应用的当前歌曲重建元数据并通知客户端。
 func (m *MPRISServer) UpdateMetadata() {
 	m.updateMetadata()
+	m.mu.Lock()
+	metadata := m.metadata
+	m.mu.Unlock()
+	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
+		"Metadata": metadata,
+	})
+}
+
+// VolumeChanged notifies clients of the volume.
+//
+// VolumeChanged 通知客户端当前音量。
+func (m *MPRISServer) VolumeChanged() {
+	m.syncState()
 	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-		"Metadata": m.metadata,
+		"Volume": m.getVolume(),
 	})
 }
 
-// UpdateProperties sends a PropertiesChanged signal for CanGoNext and CanGoPrevious.
+// RateChanged notifies clients of the playback rate.
 //
-// UpdateProperties 发送 CanGoNext 和 CanGoPrevious 的 PropertiesChanged 信号。
+// RateChanged 通知客户端当前播放速度。
+func (m *MPRISServer) RateChanged() {
+	m.syncState()
+	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
+		"Rate": m.getRate(),
+	})
+}
+
+// UpdateProperties sends a PropertiesChanged signal for CanGoNext and CanGoPrevious after
+// the playlist changed.
+//
+// UpdateProperties 在播放列表改变后发送 CanGoNext 和 CanGoPrevious 的 PropertiesChanged 信号。
 func (m *MPRISServer) UpdateProperties() {
-	if m.conn == nil || m.stopped {
-		return
-	}
+	m.syncState()
 	changedProperties := map[string]any{
-		"CanGoNext":     len(m.app.Playlist) > 1,
-		"CanGoPrevious": len(m.app.Playlist) > 1,
+		"CanGoNext":     m.canGoNextOrPrevious(),
+		"CanGoPrevious": m.canGoNextOrPrevious(),
 	}
 	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", changedProperties)
 }
@@ -276,14 +482,14 @@ func (m *MPRISServer) DesktopEntry() (string, *dbus.Error) {
 //
 // SupportedUriSchemes 获取支持的URI方案。
 func (m *MPRISServer) SupportedUriSchemes() ([]string, *dbus.Error) {
-	return []string{"file"}, nil
+	return mprisSupportedUriSchemes, nil
 }
 
 // SupportedMimeTypes gets the supported MIME types.
 //
 // SupportedMimeTypes 获取支持的MIME类型。
 func (m *MPRISServer) SupportedMimeTypes() ([]string, *dbus.Error) {
-	return []string{"audio/flac"}, nil
+	return mprisSupportedMimeTypes, nil
 }
 
 // --- org.mpris.MediaPlayer2.Player interface implementation ---
@@ -313,9 +519,8 @@ func (m *MPRISServer) Previous() *dbus.Error {
 //
 // Pause 暂停播放。
 func (m *MPRISServer) Pause() *dbus.Error {
-	if m.player != nil && !m.player.ctrl.Paused {
-		m.player.ctrl.Paused = true
-		m.UpdatePlaybackStatus(false)
+	m.app.actionQueue <- func() {
+		m.app.SetPaused(true)
 	}
 	return nil
 }
@@ -324,9 +529,10 @@ func (m *MPRISServer) Pause() *dbus.Error {
 //
 // PlayPause 切换播放和暂停。
 func (m *MPRISServer) PlayPause() *dbus.Error {
-	if m.player != nil {
-		m.player.ctrl.Paused = !m.player.ctrl.Paused
-		m.UpdatePlaybackStatus(!m.player.ctrl.Paused)
+	m.app.actionQueue <- func() {
+		if m.app.player != nil {
+			m.app.SetPaused(!m.app.player.ctrl.Paused)
+		}
 	}
 	return nil
 }
@@ -335,95 +541,101 @@ func (m *MPRISServer) PlayPause() *dbus.Error {
 //
 // Stop 停止播放。
 func (m *MPRISServer) Stop() *dbus.Error {
-	if m.player != nil {
-		m.player.ctrl.Paused = true
-		m.UpdatePlaybackStatus(false)
+	m.app.actionQueue <- func() {
+		m.app.SetPaused(true)
 	}
 	return nil
 }
 
 // Play starts or resumes the playback.
+// If nothing is loaded yet, the first song of the playlist is started.
 //
 // Play 开始或恢复播放。
+// 如果尚未加载任何歌曲，则开始播放播放列表中的第一首歌曲。
 func (m *MPRISServer) Play() *dbus.Error {
-	if m.player != nil && m.player.ctrl.Paused {
-		m.player.ctrl.Paused = false
-		m.UpdatePlaybackStatus(true)
+	m.app.actionQueue <- func() {
+		if m.app.player == nil {
+			if len(m.app.Playlist) > 0 {
+				if err := m.app.PlaySongWithSwitchAndRender(m.app.Playlist[0], false, false); err != nil {
+					l.Warnf("failed to start playback: %v\n\n警告: 开始播放失败: %v", err, err)
+				}
+			}
+			return
+		}
+		m.app.SetPaused(false)
 	}
 	return nil
 }
 
-// Seek seeks the track by the given offset in microseconds.
+// SeekOffset seeks the track by the given offset in microseconds.
+// It is exported on the bus as the MPRIS Seek method.
 //
-// Seek 按给定的偏移量（微秒）在曲目中跳转。
-func (m *MPRISServer) Seek(offset int64) (int64, *dbus.Error) {
-	currentPos := m.getCurrentPosition()
-	newPos := currentPos + offset
-	if newPos < 0 {
-		newPos = 0
-	}
-	if newPos >= m.duration {
-		newPos = m.duration - 1
-	}
-
-	m.position = newPos
-	if m.isPlaying {
-		m.startTime = time.Now().Add(-time.Duration(newPos) * time.Microsecond)
-	}
-	m.lastUpdate = time.Now()
-
-	if m.player != nil && m.player.streamer != nil {
-		samplePos := int(float64(newPos) / 1e6 * float64(m.player.sampleRate))
-		speaker.Lock()
-		if err := m.player.streamer.Seek(samplePos); err != nil {
-			// Ignore seek errors
+// SeekOffset 按给定的偏移量（微秒）在曲目中跳转。
+// 它在总线上导出为 MPRIS 的 Seek 方法。
+func (m *MPRISServer) SeekOffset(offset int64) *dbus.Error {
+	m.app.actionQueue <- func() {
+		player := m.app.player
+		if player == nil {
+			return
 		}
+		speaker.Lock()
+		newPos := player.streamer.Position() + microsecondsToSamples(offset, player.sampleRate)
 		speaker.Unlock()
+		m.app.SeekTo(newPos)
 	}
-
-	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-		"Position": m.position,
-	})
-	return newPos, nil
+	return nil
 }
 
 // SetPosition sets the track's position in microseconds.
+// The request is ignored if trackID is not the current track.
 //
 // SetPosition 设置曲目的位置（微秒）。
+// 如果 trackID 不是当前曲目，则忽略该请求。
 func (m *MPRISServer) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
-	if position < 0 {
-		position = 0
-	}
-	if position >= m.duration {
-		position = m.duration - 1
-	}
-
-	m.position = position
-	if m.isPlaying {
-		m.startTime = time.Now().Add(-time.Duration(position) * time.Microsecond)
+	m.mu.Lock()
+	currentTrackID := m.trackID
+	m.mu.Unlock()
+	if trackID != currentTrackID || position < 0 {
+		return nil
 	}
-	m.lastUpdate = time.Now()
 
-	if m.player != nil && m.player.streamer != nil {
-		samplePos := int(float64(position) / 1e6 * float64(m.player.sampleRate))
-		speaker.Lock()
-		if err := m.player.streamer.Seek(samplePos); err != nil {
-			// Ignore seek errors
+	m.app.actionQueue <- func() {
+		player := m.app.player
+		if player == nil {
+			return
 		}
-		speaker.Unlock()
+		m.app.SeekTo(microsecondsToSamples(position, player.sampleRate))
 	}
-
-	m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-		"Position": m.position,
-	})
 	return nil
 }
 
-// OpenUri opens a URI (not supported).
+// OpenUri opens a file:// URI. Audio files are played directly, directories are
+// scanned recursively and M3U/PLS playlists are expanded; the resulting songs are
+// appended to the playlist and the first one starts playing.
 //
-// OpenUri 打开一个URI（不支持）。
+// OpenUri 打开一个 file:// URI。音频文件会被直接播放，目录会被递归扫描，
+// M3U/PLS 播放列表会被展开；得到的歌曲会追加到播放列表并开始播放第一首。
 func (m *MPRISServer) OpenUri(uri string) *dbus.Error {
-	return dbus.MakeFailedError(fmt.Errorf("Opening URI is not supported\n\n不支持打开 URI"))
+	if m.app == nil {
+		return dbus.MakeFailedError(fmt.Errorf("Player is not available\n\n播放器不可用"))
+	}
+
+	localPath, err := fileURIToPath(uri)
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+
+	songs, err := resolveOpenTarget(localPath)
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+
+	m.app.actionQueue <- func() {
+		if err := m.app.AddSongsAndPlay(songs); err != nil {
+			l.Warnf("failed to open URI %s: %v\n\n警告: 打开 URI %s 失败: %v", uri, err, uri, err)
+		}
+	}
+	return nil
 }
 
 // --- D-Bus Properties interface implementation ---
@@ -447,51 +659,41 @@ func (m *MPRISServer) Get(interfaceName, propertyName string) (dbus.Variant, *db
 		case "DesktopEntry":
 			return dbus.MakeVariant(""), nil
 		case "SupportedUriSchemes":
-			return dbus.MakeVariant([]string{"file"}), nil
+			return dbus.MakeVariant(mprisSupportedUriSchemes), nil
 		case "SupportedMimeTypes":
-			return dbus.MakeVariant([]string{"audio/flac"}), nil
+			return dbus.MakeVariant(mprisSupportedMimeTypes), nil
 		}
 	case "org.mpris.MediaPlayer2.Player":
 		switch propertyName {
 		case "PlaybackStatus":
 			return dbus.MakeVariant(m.getPlaybackStatus()), nil
 		case "LoopStatus":
-			return dbus.MakeVariant("None"), nil
+			return dbus.MakeVariant(m.getLoopStatus()), nil
 		case "Rate":
-			if m.player != nil {
-				return dbus.MakeVariant(m.player.resampler.Ratio()), nil
-			}
-			return dbus.MakeVariant(1.0), nil
+			return dbus.MakeVariant(m.getRate()), nil
 		case "Shuffle":
-			return dbus.MakeVariant(false), nil
+			return dbus.MakeVariant(m.getShuffle()), nil
 		case "Metadata":
-			return dbus.MakeVariant(m.metadata), nil
+			return dbus.MakeVariant(m.getMetadata()), nil
 		case "Volume":
-			if m.app != nil {
-				return dbus.MakeVariant(m.app.linearVolume), nil
-			}
-			return dbus.MakeVariant(1.0), nil
+			return dbus.MakeVariant(m.getVolume()), nil
 		case "Position":
 			return dbus.MakeVariant(m.getCurrentPosition()), nil
 		case "MinimumRate":
 			return dbus.MakeVariant(0.1), nil
 		case "MaximumRate":
 			return dbus.MakeVariant(4.0), nil
-		case "CanGoNext":
-			canGoNext := m.app != nil && len(m.app.Playlist) > 1
-			return dbus.MakeVariant(canGoNext), nil
-		case "CanGoPrevious":
-			canGoPrevious := m.app != nil && len(m.app.Playlist) > 1
-			return dbus.MakeVariant(canGoPrevious), nil
-		case "CanPlay":
-			return dbus.MakeVariant(true), nil
-		case "CanPause":
-			return dbus.MakeVariant(true), nil
-		case "CanSeek":
-			return dbus.MakeVariant(true), nil
+		case "CanGoNext", "CanGoPrevious":
+			return dbus.MakeVariant(m.canGoNextOrPrevious()), nil
+		case "CanPlay", "CanPause", "CanSeek":
+			return dbus.MakeVariant(m.hasTrack()), nil
 		case "CanControl":
 			return dbus.MakeVariant(true), nil
 		}
+	case mprisPlaylistsInterface:
+		if value, ok := m.playlistsProperties()[propertyName]; ok {
+			return value, nil
+		}
 	}
 	return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("Unknown property: %s.%s\n\n未知属性: %s.%s", interfaceName, propertyName, interfaceName, propertyName))
 }
@@ -507,40 +709,31 @@ func (m *MPRISServer) GetAll(interfaceName string) (map[string]dbus.Variant, *db
 		props["HasTrackList"] = dbus.MakeVariant(false)
 		props["Identity"] = dbus.MakeVariant("BM")
 		props["DesktopEntry"] = dbus.MakeVariant("")
-		props["SupportedUriSchemes"] = dbus.MakeVariant([]string{"file"})
-		props["SupportedMimeTypes"] = dbus.MakeVariant([]string{"audio/flac"})
+		props["SupportedUriSchemes"] = dbus.MakeVariant(mprisSupportedUriSchemes)
+		props["SupportedMimeTypes"] = dbus.MakeVariant(mprisSupportedMimeTypes)
 		return props, nil
 	} else if interfaceName == "org.mpris.MediaPlayer2.Player" {
 		props := make(map[string]dbus.Variant)
 
 		props["PlaybackStatus"] = dbus.MakeVariant(m.getPlaybackStatus())
 		props["LoopStatus"] = dbus.MakeVariant(m.getLoopStatus())
-		if m.player != nil {
-			props["Rate"] = dbus.MakeVariant(m.player.resampler.Ratio())
-			if m.app != nil {
-				props["Volume"] = dbus.MakeVariant(m.app.linearVolume)
-			} else {
-				props["Volume"] = dbus.MakeVariant(1.0)
-			}
-			props["Position"] = dbus.MakeVariant(m.getCurrentPosition())
-		} else {
-			props["Rate"] = dbus.MakeVariant(1.0)
-			props["Volume"] = dbus.MakeVariant(1.0)
-			props["Position"] = dbus.MakeVariant(m.position)
-		}
-
-		props["Shuffle"] = dbus.MakeVariant(false)
-		props["Metadata"] = dbus.MakeVariant(m.metadata)
+		props["Rate"] = dbus.MakeVariant(m.getRate())
+		props["Volume"] = dbus.MakeVariant(m.getVolume())
+		props["Position"] = dbus.MakeVariant(m.getCurrentPosition())
+		props["Shuffle"] = dbus.MakeVariant(m.getShuffle())
+		props["Metadata"] = dbus.MakeVariant(m.getMetadata())
 		props["MinimumRate"] = dbus.MakeVariant(0.1)
 		props["MaximumRate"] = dbus.MakeVariant(4.0)
-		props["CanGoNext"] = dbus.MakeVariant(m.app != nil && len(m.app.Playlist) > 1)
-		props["CanGoPrevious"] = dbus.MakeVariant(m.app != nil && len(m.app.Playlist) > 1)
-		props["CanPlay"] = dbus.MakeVariant(true)
-		props["CanPause"] = dbus.MakeVariant(true)
-		props["CanSeek"] = dbus.MakeVariant(true)
+		props["CanGoNext"] = dbus.MakeVariant(m.canGoNextOrPrevious())
+		props["CanGoPrevious"] = dbus.MakeVariant(m.canGoNextOrPrevious())
+		props["CanPlay"] = dbus.MakeVariant(m.hasTrack())
+		props["CanPause"] = dbus.MakeVariant(m.hasTrack())
+		props["CanSeek"] = dbus.MakeVariant(m.hasTrack())
 		props["CanControl"] = dbus.MakeVariant(true)
 
 		return props, nil
+	} else if interfaceName == mprisPlaylistsInterface {
+		return m.playlistsProperties(), nil
 	}
 	return nil, dbus.MakeFailedError(fmt.Errorf("Unknown interface: %s\n\n未知接口: %s", interfaceName, interfaceName))
 }
@@ -553,31 +746,59 @@ func (m *MPRISServer) Set(interfaceName, propertyName string, value dbus.Variant
 	case "org.mpris.MediaPlayer2.Player":
 		switch propertyName {
 		case "Volume":
-			if m.player != nil && m.app != nil {
-				linearVol := value.Value().(float64)
-				m.app.linearVolume = min(max(linearVol, 0.0), 1.0)
-				if m.app.linearVolume == 0 {
-					m.app.volume = -10
-				} else {
-					m.app.volume = math.Log2(m.app.linearVolume)
-				}
-				m.player.volume.Volume = m.app.volume
-				m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-					"Volume": m.app.linearVolume,
-				})
+			linearVol, ok := value.Value().(float64)
+			if !ok {
+				return dbus.MakeFailedError(fmt.Errorf("Volume must be a double\n\n音量必须是 double 类型"))
+			}
+			m.app.actionQueue <- func() {
+				m.app.SetLinearVolume(linearVol)
 			}
 		case "Rate":
-			if m.player != nil {
-				rate := value.Value().(float64)
-				m.player.resampler.SetRatio(rate)
-				m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-					"Rate": rate,
-				})
+			rate, ok := value.Value().(float64)
+			if !ok {
+				return dbus.MakeFailedError(fmt.Errorf("Rate must be a double\n\n播放速度必须是 double 类型"))
+			}
+			m.app.actionQueue <- func() {
+				m.app.SetPlaybackRate(rate)
 			}
 		case "LoopStatus":
-			// Not supported
+			loopStatus, ok := value.Value().(string)
+			if !ok {
+				return dbus.MakeFailedError(fmt.Errorf("LoopStatus must be a string\n\nLoopStatus 必须是字符串"))
+			}
+			if loopStatus != "None" && loopStatus != "Track" && loopStatus != "Playlist" {
+				return dbus.MakeFailedError(fmt.Errorf("Invalid LoopStatus: %s\n\n无效的 LoopStatus: %s", loopStatus, loopStatus))
+			}
+			m.app.actionQueue <- func() {
+				defer m.confirmPlayMode(m.app.playMode)
+				if m.app.isSingleSongMode {
+					return
+				}
+				// BM always keeps playing, so "None" falls back to looping the playlist.
+				// BM 总是持续播放，因此 "None" 会退化为列表循环。
+				switch {
+				case loopStatus == "Track":
+					m.app.SetPlayMode(0)
+				case m.app.playMode == 0:
+					m.app.SetPlayMode(1)
+				}
+			}
 		case "Shuffle":
-			// Not supported
+			shuffle, ok := value.Value().(bool)
+			if !ok {
+				return dbus.MakeFailedError(fmt.Errorf("Shuffle must be a boolean\n\nShuffle 必须是布尔值"))
+			}
+			m.app.actionQueue <- func() {
+				defer m.confirmPlayMode(m.app.playMode)
+				if m.app.isSingleSongMode {
+					return
+				}
+				if shuffle {
+					m.app.SetPlayMode(2)
+				} else if m.app.playMode == 2 {
+					m.app.SetPlayMode(1)
+				}
+			}
 		default:
 			return dbus.MakeFailedError(fmt.Errorf("Property %s is not writable\n\n属性 %s 不可写", propertyName, propertyName))
 		}
@@ -593,54 +814,84 @@ func (m *MPRISServer) Set(interfaceName, propertyName string, value dbus.Variant
 //
 // getPlaybackStatus 以字符串形式获取播放状态。
 func (m *MPRISServer) getPlaybackStatus() string {
-	if m.player == nil {
+	speaker.Lock()
+	defer speaker.Unlock()
+	if m.app.player == nil {
 		return "Stopped"
 	}
-	if m.player.ctrl.Paused {
+	if m.app.player.ctrl.Paused {
 		return "Paused"
 	}
 	return "Playing"
 }
 
-// updateMetadata updates the track metadata.
+// getMetadata returns the cached metadata of the current track.
 //
-// updateMetadata 更新曲目元数据。
-func (m *MPRISServer) updateMetadata() {
-	title, artist, album := getSongMetadata(m.flacPath)
+// getMetadata 返回当前曲目的缓存元数据。
+func (m *MPRISServer) getMetadata() map[string]dbus.Variant {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.metadata
+}
 
-	m.metadata = map[string]dbus.Variant{
-		"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")),
-		"mpris:length":  dbus.MakeVariant(int64(m.duration)),
-		"xesam:title":   dbus.MakeVariant(title),
-		"xesam:artist":  dbus.MakeVariant([]string{artist}),
-		"xesam:album":   dbus.MakeVariant(album),
+// updateMetadata rebuilds the track metadata from the app's current song and player.
+// A new track ID is assigned whenever the song changes.
+//
+// updateMetadata 根据应用的当前歌曲和播放器重建曲目元数据。
+// 每当歌曲改变时都会分配一个新的曲目 ID。
+func (m *MPRISServer) updateMetadata() {
+	songPath := m.app.currentSongPath
+	if m.app.player == nil {
+		songPath = ""
 	}
 
-	if coverData := m.extractAlbumArt(); coverData != "" {
-		m.metadata["mpris:artUrl"] = dbus.MakeVariant(coverData)
+	if songPath == "" {
+		m.mu.Lock()
+		m.songPath = ""
+		m.trackID = mprisNoTrack
+		m.metadata = map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(mprisNoTrack)}
+		m.mu.Unlock()
+		return
 	}
-}
 
-// extractAlbumArt extracts the album art.
-// If the audio file has no cover, it uses the default cover image.
-//
-// extractAlbumArt 提取专辑封面。
-// 如果音频文件没有封面，则使用默认封面图片。
-func (m *MPRISServer) extractAlbumArt() string {
-	// Try to get cover from audio file
-	f, err := os.Open(m.flacPath)
-	if err != nil {
-		return m.getDefaultCoverArt()
+	title, artist, album := m.app.songMetadata(songPath)
+	metadata := map[string]dbus.Variant{
+		"mpris:length": dbus.MakeVariant(m.getDuration()),
+		"xesam:title":  dbus.MakeVariant(title),
+		"xesam:artist": dbus.MakeVariant([]string{artist}),
+		"xesam:album":  dbus.MakeVariant(album),
+		"xesam:url":    dbus.MakeVariant(songURL(songPath)),
+	}
+	if coverData := m.extractAlbumArt(songPath); coverData != "" {
+		metadata["mpris:artUrl"] = dbus.MakeVariant(coverData)
 	}
-	defer f.Close()
 
-	metadata, err := tag.ReadFrom(f)
-	if err != nil {
-		return m.getDefaultCoverArt()
+	m.mu.Lock()
+	if songPath != m.songPath {
+		m.trackCount++
+		m.trackID = dbus.ObjectPath(fmt.Sprintf("/org/mpris/MediaPlayer2/bm/track/%d", m.trackCount))
+		m.songPath = songPath
 	}
+	metadata["mpris:trackid"] = dbus.MakeVariant(m.trackID)
+	m.metadata = metadata
+	m.mu.Unlock()
+}
 
-	if pic := metadata.Picture(); pic != nil {
-		return m.encodePictureToBase64(pic)
+// extractAlbumArt returns the URL of the cached cover PNG of the song once its cover is
+// loaded. If there is none, it uses the default cover image.
+//
+// extractAlbumArt 在歌曲封面加载完成后返回其缓存封面 PNG 的 URL。
+// 如果没有，则使用默认封面图片。
+func (m *MPRISServer) extractAlbumArt(songPath string) string {
+	if song := m.app.song; song != nil && song.path == songPath {
+		if !song.coverReady() {
+			// coverLoaded updates the metadata when the cover is ready.
+			// 封面就绪时 coverLoaded 会更新元数据。
+			return ""
+		}
+		if song.coverFile != "" {
+			return (&url.URL{Scheme: "file", Path: song.coverFile}).String()
+		}
 	}
 
 	return m.getDefaultCoverArt()
@@ -675,49 +926,16 @@ func (m *MPRISServer) getDefaultCoverArt() string {
 	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
 }
 
-// encodePictureToBase64 saves the picture data to a temporary file and returns its file URL.
-//
-// encodePictureToBase64 将图片数据保存到临时文件并返回其文件URL。
-func (m *MPRISServer) encodePictureToBase64(pic *tag.Picture) string {
-	var fileExt string
-	switch pic.MIMEType {
-	case "image/jpeg", "image/jpg":
-		fileExt = "jpg"
-	case "image/png":
-		fileExt = "png"
-	default:
-		if len(pic.Data) > 8 && pic.Data[0] == 0x89 && pic.Data[1] == 0x50 && pic.Data[2] == 0x4E && pic.Data[3] == 0x47 {
-			fileExt = "png"
-		} else if len(pic.Data) > 2 && pic.Data[0] == 0xFF && pic.Data[1] == 0xD8 {
-			fileExt = "jpg"
-		} else {
-			fileExt = "jpg"
-		}
-	}
-
-	tempFile, err := os.CreateTemp("", "bm_cover_*."+fileExt)
-	if err != nil {
-		return ""
-	}
-	defer tempFile.Close()
-
-	if _, err := tempFile.Write(pic.Data); err != nil {
-		return ""
-	}
-
-	return "file://" + tempFile.Name()
-}
-
 // sendPropertiesChanged sends a PropertiesChanged signal.
 //
 // sendPropertiesChanged 发送 PropertiesChanged 信号。
 func (m *MPRISServer) sendPropertiesChanged(interfaceName string, changedProperties map[string]any) {
-	if m.conn == nil || m.stopped {
+	if m.isStopped() {
 		return
 	}
 
 	m.conn.Emit(
-		dbus.ObjectPath("/org/mpris/MediaPlayer2"),
+		mprisObjectPath,
 		"org.freedesktop.DBus.Properties.PropertiesChanged",
 		interfaceName,
 		changedProperties,
@@ -725,49 +943,19 @@ func (m *MPRISServer) sendPropertiesChanged(interfaceName string, changedPropert
 	)
 }
 
-// calculateDuration calculates the audio duration in microseconds.
+// samplesToMicroseconds converts a sample count at the given rate to microseconds.
 //
-// calculateDuration 计算音频时长（以微秒为单位）。
-func (m *MPRISServer) calculateDuration() error {
-	streamer, format, err := decodeAudioFile(m.flacPath)
-	if err != nil {
-		return fmt.Errorf("Failed to decode file: %v\n\n解码文件失败: %v", err, err)
+// samplesToMicroseconds 将给定采样率下的采样数转换为微秒。
+func samplesToMicroseconds(samples int, sampleRate beep.SampleRate) int64 {
+	if sampleRate <= 0 {
+		return 0
 	}
-	defer streamer.Close()
-
-	totalSamples := streamer.Len()
-	m.duration = int64(float64(totalSamples) / float64(format.SampleRate) * 1e6)
-
-	return nil
+	return int64(float64(samples) / float64(sampleRate) * 1e6)
 }
 
-// StartUpdateLoop starts the MPRIS update loop.
+// microsecondsToSamples converts microseconds to a sample count at the given rate.
 //
-// StartUpdateLoop 启动 MPRIS 更新循环。
-func (m *MPRISServer) StartUpdateLoop() {
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-m.stopChan:
-				return
-			case <-ticker.C:
-				if m.stopped {
-					return
-				}
-				if m.isPlaying && !m.startTime.IsZero() {
-					m.updatePositionFromTime()
-				} else if m.player != nil && m.player.streamer != nil {
-					samplePos := m.player.streamer.Position()
-					m.position = int64(float64(samplePos) / float64(m.player.sampleRate) * 1e6)
-				}
-
-				m.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-					"Position": m.position,
-				})
-			}
-		}
-	}()
+// microsecondsToSamples 将微秒转换为给定采样率下的采样数。
+func microsecondsToSamples(us int64, sampleRate beep.SampleRate) int {
+	return int(float64(us) / 1e6 * float64(sampleRate))
 }
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// mprisPlaylistsInterface is the name of the MPRIS Playlists interface.
+//
+// mprisPlaylistsInterface 是 MPRIS Playlists 接口的名称。
+const mprisPlaylistsInterface = "org.mpris.MediaPlayer2.Playlists"
+
+// mprisPlaylistPathPrefix is the object path prefix used for playlist IDs.
+//
+// mprisPlaylistPathPrefix 是播放列表 ID 使用的对象路径前缀。
+const mprisPlaylistPathPrefix = "/org/mpris/MediaPlayer2/bm/playlist/"
+
+// mprisPlaylistOrderings lists the orderings supported by GetPlaylists.
+//
+// mprisPlaylistOrderings 列出 GetPlaylists 支持的排序方式。
+var mprisPlaylistOrderings = []string{"UserDefined", "Alphabetical", "ModifiedDate"}
+
+// mprisPlaylist is the D-Bus (oss) structure describing a playlist.
+//
+// mprisPlaylist 是描述播放列表的 D-Bus (oss) 结构。
+type mprisPlaylist struct {
+	ID   dbus.ObjectPath
+	Name string
+	Icon string
+}
+
+// mprisMaybePlaylist is the D-Bus (b(oss)) structure used by ActivePlaylist.
+//
+// mprisMaybePlaylist 是 ActivePlaylist 使用的 D-Bus (b(oss)) 结构。
+type mprisMaybePlaylist struct {
+	Valid    bool
+	Playlist mprisPlaylist
+}
+
+// playlistSource is a playlist BM can offer: a smart playlist computed from app state,
+// or a named playlist file from the playlists directory.
+//
+// playlistSource 是 BM 可以提供的播放列表：根据应用状态计算的智能播放列表，
+// 或来自播放列表目录的命名播放列表文件。
+type playlistSource struct {
+	playlist mprisPlaylist
+	modTime  int64 // Modification time used by the ModifiedDate ordering. / 用于 ModifiedDate 排序的修改时间。
+	songs    func() []string
+}
+
+// namedPlaylistCache holds the playlist files of the playlists directory, listed again only
+// when the directory's modification time changes, i.e. when files are added, removed or
+// renamed.
+//
+// namedPlaylistCache 保存播放列表目录中的播放列表文件，只有在目录的修改时间改变时
+// （即添加、删除或重命名文件时）才会重新列出。
+type namedPlaylistCache struct {
+	mu      sync.Mutex
+	dir     string
+	modTime time.Time
+	sources []playlistSource
+}
+
+// smartPlaylists returns the built-in playlists derived from the library and history, from
+// the copy of the app state kept by syncState, as clients call this from D-Bus goroutines.
+// The songs of the library playlist are only collected when it is activated.
+//
+// smartPlaylists 根据 syncState 保存的应用状态副本返回由媒体库和播放历史生成的内置播放列表，
+// 因为客户端从 D-Bus 协程中调用此函数。媒体库播放列表的歌曲只在激活时才会收集。
+func (m *MPRISServer) smartPlaylists() []playlistSource {
+	m.mu.Lock()
+	libraryPath, history, singleSong := m.libraryPath, m.history, m.singleSong
+	m.mu.Unlock()
+
+	sources := []playlistSource{}
+	if libraryPath != "" && !singleSong {
+		sources = append(sources, playlistSource{
+			playlist: mprisPlaylist{ID: mprisPlaylistPathPrefix + "smart/library", Name: "Library"},
+			songs: func() []string {
+				return collectAudioFiles(libraryPath)
+			},
+		})
+	}
+	if len(history) > 0 {
+		sources = append(sources, playlistSource{
+			playlist: mprisPlaylist{ID: mprisPlaylistPathPrefix + "smart/recent", Name: "Recently Played"},
+			songs: func() []string {
+				recent := slices.Clone(history)
+				slices.Reverse(recent)
+				return recent
+			},
+		})
+	}
+	return sources
+}
+
+// namedPlaylists returns the playlist files found in the configured playlists directory,
+// from the cache while the directory is unchanged.
+//
+// namedPlaylists 返回在配置的播放列表目录中找到的播放列表文件，目录未改变时取自缓存。
+func (c *namedPlaylistCache) namedPlaylists() []playlistSource {
+	if GlobalConfig == nil || GlobalConfig.App.PlaylistsDir == "" {
+		return nil
+	}
+	dir, err := expandHomePath(GlobalConfig.App.PlaylistsDir)
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir != dir || !c.modTime.Equal(info.ModTime()) {
+		c.dir = dir
+		c.modTime = info.ModTime()
+		c.sources = readPlaylistsDir(dir)
+	}
+	return slices.Clone(c.sources)
+}
+
+// readPlaylistsDir lists the playlist files in dir, sorted by name.
+//
+// readPlaylistsDir 列出 dir 中的播放列表文件，按名称排序。
+func readPlaylistsDir(dir string) []playlistSource {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var sources []playlistSource
+	for _, entry := range entries {
+		if entry.IsDir() || !isPlaylistFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		filePath := filepath.Join(dir, entry.Name())
+		sources = append(sources, playlistSource{
+			playlist: mprisPlaylist{
+				ID:   dbus.ObjectPath(mprisPlaylistPathPrefix + "named/p" + hex.EncodeToString([]byte(entry.Name()))),
+				Name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			},
+			modTime: info.ModTime().Unix(),
+			songs: func() []string {
+				songs, err := parsePlaylistFile(filePath)
+				if err != nil {
+					return nil
+				}
+				return songs
+			},
+		})
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return strings.ToLower(sources[i].playlist.Name) < strings.ToLower(sources[j].playlist.Name)
+	})
+	return sources
+}
+
+// allPlaylists returns smart playlists followed by named playlists.
+//
+// allPlaylists 返回智能播放列表，后跟命名播放列表。
+func (m *MPRISServer) allPlaylists() []playlistSource {
+	return append(m.smartPlaylists(), m.named.namedPlaylists()...)
+}
+
+// ActivatePlaylist replaces the current playlist with the given one and starts playing it.
+// The songs are collected in the background, as the library playlist walks the whole
+// library, and the playlist is replaced on the main loop once they are.
+//
+// ActivatePlaylist 用给定的播放列表替换当前播放列表并开始播放。由于媒体库播放列表会遍历
+// 整个媒体库，歌曲在后台收集，收集完成后在主循环中替换播放列表。
+func (m *MPRISServer) ActivatePlaylist(playlistID dbus.ObjectPath) *dbus.Error {
+	for _, source := range m.allPlaylists() {
+		if source.playlist.ID != playlistID {
+			continue
+		}
+
+		go func() {
+			playlist := source.playlist
+			songs := source.songs()
+			if len(songs) == 0 {
+				l.Warnf("playlist %s is empty\n\n警告: 播放列表 %s 为空", playlist.Name, playlist.Name)
+				return
+			}
+			m.app.actionQueue <- func() {
+				if err := m.app.ReplacePlaylist(songs); err != nil {
+					l.Warnf("failed to activate playlist %s: %v\n\n警告: 激活播放列表 %s 失败: %v", playlist.Name, err, playlist.Name, err)
+					return
+				}
+				m.mu.Lock()
+				m.activePlaylist = mprisMaybePlaylist{Valid: true, Playlist: playlist}
+				m.mu.Unlock()
+				m.sendPropertiesChanged(mprisPlaylistsInterface, map[string]any{
+					"ActivePlaylist": m.getActivePlaylist(),
+				})
+			}
+		}()
+		return nil
+	}
+	return dbus.MakeFailedError(fmt.Errorf("Unknown playlist: %s\n\n未知的播放列表: %s", playlistID, playlistID))
+}
+
+// GetPlaylists returns a page of the available playlists in the requested order.
+//
+// GetPlaylists 按请求的顺序返回可用播放列表的一页。
+func (m *MPRISServer) GetPlaylists(index, maxCount uint32, order string, reverseOrder bool) ([]mprisPlaylist, *dbus.Error) {
+	sources := m.allPlaylists()
+	switch order {
+	case "Alphabetical":
+		sort.SliceStable(sources, func(i, j int) bool {
+			return strings.ToLower(sources[i].playlist.Name) < strings.ToLower(sources[j].playlist.Name)
+		})
+	case "ModifiedDate":
+		sort.SliceStable(sources, func(i, j int) bool {
+			return sources[i].modTime < sources[j].modTime
+		})
+	}
+	if reverseOrder {
+		slices.Reverse(sources)
+	}
+
+	playlists := []mprisPlaylist{}
+	for i := int(index); i < len(sources) && uint32(len(playlists)) < maxCount; i++ {
+		playlists = append(playlists, sources[i].playlist)
+	}
+	return playlists, nil
+}
+
+// getActivePlaylist returns the playlist activated last, if any.
+//
+// getActivePlaylist 返回最近激活的播放列表（如果有）。
+func (m *MPRISServer) getActivePlaylist() mprisMaybePlaylist {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.activePlaylist
+}
+
+// playlistsProperties returns all properties of the Playlists interface.
+//
+// playlistsProperties 返回 Playlists 接口的所有属性。
+func (m *MPRISServer) playlistsProperties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"PlaylistCount":  dbus.MakeVariant(uint32(len(m.allPlaylists()))),
+		"Orderings":      dbus.MakeVariant(mprisPlaylistOrderings),
+		"ActivePlaylist": dbus.MakeVariant(m.getActivePlaylist()),
+	}
+}
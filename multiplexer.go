@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// multiplexer is the terminal multiplexer BM runs in, if any.
+//
+// multiplexer 是 BM 所运行的终端复用器（如果有）。
+type multiplexer int
+
+const (
+	muxNone multiplexer = iota
+	muxTmux
+	muxZellij
+)
+
+// detectMultiplexer returns the terminal multiplexer BM runs in.
+//
+// detectMultiplexer 返回 BM 所运行的终端复用器。
+func detectMultiplexer() multiplexer {
+	if os.Getenv("TMUX") != "" {
+		return muxTmux
+	}
+	if os.Getenv("ZELLIJ") != "" {
+		return muxZellij
+	}
+	return muxNone
+}
+
+var (
+	tmuxPassthroughOnce    sync.Once
+	tmuxPassthroughAllowed bool
+)
+
+// tmuxPassthrough reports whether tmux forwards escape sequences wrapped in its passthrough
+// DCS to the outer terminal. tmux 3.3 and later only do so when allow-passthrough is on;
+// older versions have no such option and always do.
+//
+// tmuxPassthrough 判断 tmux 是否会将包装在其透传 DCS 中的转义序列转发给外层终端。
+// tmux 3.3 及更高版本仅在 allow-passthrough 开启时才会转发；旧版本没有该选项，总是转发。
+func tmuxPassthrough() bool {
+	tmuxPassthroughOnce.Do(func() {
+		args := []string{"show-options", "-Apv"}
+		if pane := os.Getenv("TMUX_PANE"); pane != "" {
+			args = append(args, "-t", pane)
+		}
+		out, err := exec.Command("tmux", append(args, "allow-passthrough")...).CombinedOutput()
+		value := strings.TrimSpace(string(out))
+		if err != nil {
+			tmuxPassthroughAllowed = strings.Contains(value, "invalid option") || strings.Contains(value, "unknown option")
+			return
+		}
+		tmuxPassthroughAllowed = value == "on" || value == "all"
+	})
+	return tmuxPassthroughAllowed
+}
+
+// wrapPassthrough wraps an escape sequence meant for the outer terminal in the tmux
+// passthrough DCS when running inside tmux.
+//
+// wrapPassthrough 在 tmux 中运行时，将发给外层终端的转义序列包装在 tmux 透传 DCS 中。
+func wrapPassthrough(seq string) string {
+	if detectMultiplexer() != muxTmux {
+		return seq
+	}
+	return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+}
+
+// muxProtocol returns the image protocol to use for covers given the protocol of the outer
+// terminal, or ProtocolBlocks if the multiplexer cannot show images with it. tmux can
+// forward all of them through passthrough; zellij has no passthrough but draws sixel images
+// itself.
+//
+// muxProtocol 根据外层终端的协议返回用于显示封面的图像协议；如果复用器无法用它显示图像，
+// 则返回 ProtocolBlocks。tmux 可以通过透传转发所有协议；zellij 没有透传，
+// 但会自行绘制 sixel 图像。
+func muxProtocol(protocol Protocol) Protocol {
+	switch detectMultiplexer() {
+	case muxTmux:
+		if !tmuxPassthrough() {
+			return ProtocolBlocks
+		}
+	case muxZellij:
+		if protocol != ProtocolSixel {
+			return ProtocolBlocks
+		}
+	}
+	return protocol
+}
+
+// tmuxPaneOffset returns the position of the top-left cell of BM's tmux pane in the outer
+// terminal, counted from zero. Images sent through passthrough are drawn at the cursor of
+// the outer terminal, so they must be positioned there explicitly.
+//
+// tmuxPaneOffset 返回 BM 所在 tmux 窗格左上角单元格在外层终端中的位置（从零开始）。
+// 通过透传发送的图像绘制在外层终端的光标处，因此必须显式定位。
+func tmuxPaneOffset() (row, col int, err error) {
+	args := []string{"display-message", "-p"}
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		args = append(args, "-t", pane)
+	}
+	out, err := exec.Command("tmux", append(args, "#{pane_top} #{pane_left} #{status} #{status-position}")...).Output()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) != 4 {
+		return 0, 0, fmt.Errorf("Unexpected tmux pane position: %q\n\n无法识别的 tmux 窗格位置: %q", out, out)
+	}
+	if row, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, err
+	}
+	if col, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, err
+	}
+
+	// A status line at the top pushes the panes down.
+	// 位于顶部的状态栏会将窗格下移。
+	if fields[3] == "top" {
+		switch fields[2] {
+		case "off":
+		case "on":
+			row++
+		default:
+			if lines, err := strconv.Atoi(fields[2]); err == nil {
+				row += lines
+			}
+		}
+	}
+	return row, col, nil
+}
+
+// tmuxCellSize returns the pixel size of a cell of the outer terminal as known to tmux.
+//
+// tmuxCellSize 返回 tmux 所知的外层终端单元格像素尺寸。
+func tmuxCellSize() (width, height int, err error) {
+	out, err := exec.Command("tmux", "display-message", "-p", "#{client_cell_width} #{client_cell_height}").Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("Unexpected tmux cell size: %q\n\n无法识别的 tmux 单元格尺寸: %q", out, out)
+	}
+	if width, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, err
+	}
+	if height, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, err
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("tmux does not know the cell size\n\ntmux 不知道单元格尺寸")
+	}
+	return width, height, nil
+}
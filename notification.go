@@ -1,74 +1,419 @@
 package main
 
 import (
+	"image"
+	"image/draw"
+	"image/png"
+	"net/url"
 	"os"
-	"os/exec"
+	"slices"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nfnt/resize"
+)
+
+const (
+	notificationsBusName    = "org.freedesktop.Notifications"
+	notificationsObjectPath = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notificationsInterface  = "org.freedesktop.Notifications"
+)
+
+// notificationImageSize is the largest width and height of the cover sent as image-data.
+//
+// notificationImageSize 是以 image-data 发送的封面的最大宽度和高度。
+const notificationImageSize = 256
+
+// Notification action keys. / 通知动作的键。
+const (
+	notificationActionNext  = "next"
+	notificationActionPause = "pause"
 )
 
-var notifySendAvailable bool
+// Notifier sends song-change notifications through org.freedesktop.Notifications.
+// All notifications reuse one bubble via replaces_id, and the Next/Pause action
+// buttons are forwarded to the app through actionQueue. The D-Bus calls are made one at a
+// time by a worker goroutine, so that the bubble always ends up on the latest song.
+//
+// Notifier 通过 org.freedesktop.Notifications 发送切歌通知。
+// 所有通知都通过 replaces_id 复用同一个气泡，Next/Pause 动作按钮通过 actionQueue 转发给应用。
+// D-Bus 调用由一个工作协程逐个执行，使气泡最终总是显示最新的歌曲。
+type Notifier struct {
+	app          *App
+	conn         *dbus.Conn
+	obj          dbus.BusObject
+	capabilities []string // Capabilities reported by the notification server. / 通知服务器报告的能力。
+
+	requests chan notification // Holds at most the latest request. / 最多保存最新的一个请求。
+	done     chan struct{}     // Closed when the worker has stopped. / 工作协程停止后关闭。
+	current  notification      // Latest request, only used from the main loop. / 最新的请求，只在主循环中使用。
+
+	// The cover last sent as image-data and its path, only used by the worker.
+	// 最近以 image-data 发送的封面及其路径，只由工作协程使用。
+	imagePath string
+	image     *notificationImage
+
+	mu     sync.Mutex // Guards lastID. / 保护 lastID。
+	lastID uint32     // ID of the bubble to replace, 0 if none. / 要替换的气泡 ID，没有则为 0。
+}
+
+// notificationImage is the (iiibiiay) structure of the image-data hint: the pixels of an
+// image as non-premultiplied RGBA rows.
+//
+// notificationImage 是 image-data 提示的 (iiibiiay) 结构：以非预乘 RGBA 行表示的图像像素。
+type notificationImage struct {
+	Width         int32
+	Height        int32
+	RowStride     int32
+	HasAlpha      bool
+	BitsPerSample int32
+	Channels      int32
+	Data          []byte
+}
+
+// notification is a request to show or update the song notification.
+//
+// notification 是显示或更新歌曲通知的请求。
+type notification struct {
+	artist, title, coverPath string
+	paused                   bool
+	refresh                  bool // Only update a bubble that is still open. / 只更新仍然打开的气泡。
+}
+
+// NewNotifier connects to the notification server on the session bus and starts
+// listening for action invocations.
+//
+// NewNotifier 连接到会话总线上的通知服务器，并开始监听动作调用。
+func NewNotifier(app *App) (*Notifier, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Notifier{
+		app:      app,
+		conn:     conn,
+		obj:      conn.Object(notificationsBusName, notificationsObjectPath),
+		requests: make(chan notification, 1),
+		done:     make(chan struct{}),
+	}
+
+	if err := n.obj.Call(notificationsInterface+".GetCapabilities", 0).Store(&n.capabilities); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(notificationsObjectPath),
+		dbus.WithMatchInterface(notificationsInterface),
+	); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	go n.handleSignals(signals)
+	go n.run()
+
+	return n, nil
+}
 
-func init() {
-	if _, err := exec.LookPath("notify-send"); err == nil {
-		notifySendAvailable = true
+// startNotifier creates the notifier if notifications are enabled.
+// It returns nil when they are disabled or no notification server is available.
+//
+// startNotifier 在启用通知时创建通知器。
+// 如果通知被禁用或没有可用的通知服务器，则返回 nil。
+func startNotifier(app *App) *Notifier {
+	if GlobalConfig == nil || !GlobalConfig.App.EnableNotifications {
+		return nil
+	}
+	n, err := NewNotifier(app)
+	if err != nil {
+		return nil
 	}
+	return n
 }
 
-func sendNotification(artist, title, coverPath string) {
-	if !notifySendAvailable {
+// Close stops the worker and closes the notification bubble and the D-Bus connection. It
+// must be called from the main loop.
+//
+// Close 停止工作协程，并关闭通知气泡和 D-Bus 连接。必须从主循环中调用。
+func (n *Notifier) Close() {
+	if n == nil {
 		return
 	}
+	close(n.requests)
+	select {
+	case <-n.done:
+	case <-time.After(time.Second):
+		// The notification server does not answer; do not hold up the exit.
+		// 通知服务器没有响应；不要拖延退出。
+	}
+	n.mu.Lock()
+	if n.lastID != 0 {
+		n.obj.Call(notificationsInterface+".CloseNotification", 0, n.lastID)
+		n.lastID = 0
+	}
+	n.mu.Unlock()
+	n.conn.Close()
+}
 
-	if GlobalConfig != nil && !GlobalConfig.App.EnableNotifications {
+// Notify shows or updates the song notification. It returns immediately; the D-Bus
+// call runs in the background. It must be called from the main loop. A nil Notifier does
+// nothing.
+//
+// Notify 显示或更新歌曲通知。它会立即返回，D-Bus 调用在后台执行。必须从主循环中调用。
+// nil 的 Notifier 不执行任何操作。
+func (n *Notifier) Notify(artist, title, coverPath string) {
+	if n == nil {
 		return
 	}
 
-	go func() {
-		safeArtist := sanitizeString(artist)
-		safeTitle := sanitizeString(title)
+	if artist == "" {
+		artist = "Unknown Artist"
+	}
+	if title == "" {
+		title = "Unknown Title"
+	}
 
-		if safeArtist == "" {
-			safeArtist = "Unknown Artist"
+	paused := false
+	if n.app.player != nil {
+		paused = n.app.player.ctrl.Paused
+	}
+	n.current = notification{artist: artist, title: title, coverPath: coverPath, paused: paused}
+	n.send(n.current)
+}
+
+// SetPaused updates the Pause/Play button of the open bubble. It must be called from the
+// main loop. A nil Notifier does nothing.
+//
+// SetPaused 更新打开的气泡上的 Pause/Play 按钮。必须从主循环中调用。nil 的 Notifier
+// 不执行任何操作。
+func (n *Notifier) SetPaused(paused bool) {
+	if n == nil || n.current.title == "" || n.current.paused == paused || !n.hasCapability("actions") {
+		return
+	}
+	n.current.paused = paused
+	req := n.current
+	req.refresh = true
+	n.send(req)
+}
+
+// send queues a request for the worker, replacing the one still waiting, if any. A waiting
+// song change stays a song change even if the new request only refreshes the bubble.
+//
+// send 将请求排入工作协程的队列，替换仍在等待的请求（如果有）。即使新请求只是刷新气泡，
+// 仍在等待的切歌请求也仍作为切歌请求处理。
+func (n *Notifier) send(req notification) {
+	for {
+		select {
+		case n.requests <- req:
+			return
+		default:
 		}
-		if safeTitle == "" {
-			safeTitle = "Unknown Title"
+		select {
+		case waiting := <-n.requests:
+			req.refresh = req.refresh && waiting.refresh
+		default:
 		}
+	}
+}
 
-		icon := ""
-		if isValidIconPath(coverPath) {
-			icon = coverPath
+// run shows the requests one at a time until Close.
+//
+// run 逐个显示请求，直到 Close。
+func (n *Notifier) run() {
+	defer close(n.done)
+	for req := range n.requests {
+		n.show(req)
+	}
+}
+
+// show makes the D-Bus call for a request.
+//
+// show 为请求执行 D-Bus 调用。
+func (n *Notifier) show(req notification) {
+	n.mu.Lock()
+	replaces := n.lastID
+	n.mu.Unlock()
+	if req.refresh && replaces == 0 {
+		// The bubble was closed; do not bring it back for a button label.
+		// 气泡已被关闭；不要为了按钮标签让它重新出现。
+		return
+	}
+
+	body := req.title
+	if n.hasCapability("body-markup") {
+		body = escapeNotificationMarkup(body)
+	}
+
+	var actions []string
+	if n.hasCapability("actions") {
+		pauseLabel := "Pause"
+		if req.paused {
+			pauseLabel = "Play"
 		}
+		actions = []string{notificationActionNext, "Next", notificationActionPause, pauseLabel}
+	}
+
+	hints := map[string]dbus.Variant{
+		"urgency":  dbus.MakeVariant(notificationUrgency()),
+		"category": dbus.MakeVariant("x-gnome.music"),
+	}
+	// The cover is sent both as pixels and as a file, as servers differ in which they show.
+	// image-data takes precedence for those that support both.
+	// 封面同时以像素和文件的形式发送，因为不同的服务器显示的方式不同。两者都支持的服务器优先使用 image-data。
+	if isValidIconPath(req.coverPath) {
+		hints["image-path"] = dbus.MakeVariant((&url.URL{Scheme: "file", Path: req.coverPath}).String())
+		if img := n.coverImage(req.coverPath); img != nil {
+			hints["image-data"] = dbus.MakeVariant(*img)
+		}
+	}
 
-		cmd := exec.Command(
-			"notify-send",
-			"-a", "BM",
-			"-i", icon,
-			safeArtist,
-			safeTitle,
-		)
+	var id uint32
+	call := n.obj.Call(notificationsInterface+".Notify", 0,
+		"BM",
+		replaces,
+		"",
+		req.artist,
+		body,
+		actions,
+		hints,
+		notificationTimeout(),
+	)
+	if err := call.Store(&id); err != nil {
+		return
+	}
+	n.mu.Lock()
+	n.lastID = id
+	n.mu.Unlock()
+}
+
+// coverImage returns the image-data of the cover PNG at path, decoding it only when the
+// cover changed. It returns nil if the file cannot be decoded.
+//
+// coverImage 返回 path 处封面 PNG 的 image-data，只在封面改变时才解码。无法解码时返回 nil。
+func (n *Notifier) coverImage(path string) *notificationImage {
+	if path != n.imagePath {
+		n.imagePath = path
+		n.image = loadNotificationImage(path)
+	}
+	return n.image
+}
+
+// loadNotificationImage decodes a PNG and scales it down to notificationImageSize for the
+// image-data hint. It returns nil if the file cannot be decoded.
+//
+// loadNotificationImage 解码 PNG 并将其缩小到 notificationImageSize，用于 image-data 提示。
+// 无法解码时返回 nil。
+func loadNotificationImage(path string) *notificationImage {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil
+	}
 
-		cmd.Run()
-	}()
+	img = resize.Thumbnail(notificationImageSize, notificationImageSize, img, resize.Lanczos3)
+	b := img.Bounds()
+	pixels := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(pixels, pixels.Bounds(), img, b.Min, draw.Src)
+	return &notificationImage{
+		Width:         int32(b.Dx()),
+		Height:        int32(b.Dy()),
+		RowStride:     int32(pixels.Stride),
+		HasAlpha:      true,
+		BitsPerSample: 8,
+		Channels:      4,
+		Data:          pixels.Pix,
+	}
+}
+
+// handleSignals dispatches ActionInvoked and NotificationClosed signals for BM's bubble.
+//
+// handleSignals 分发 BM 气泡的 ActionInvoked 和 NotificationClosed 信号。
+func (n *Notifier) handleSignals(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if len(sig.Body) < 2 {
+			continue
+		}
+		id, ok := sig.Body[0].(uint32)
+		if !ok {
+			continue
+		}
+
+		n.mu.Lock()
+		ours := id != 0 && id == n.lastID
+		if ours && sig.Name == notificationsInterface+".NotificationClosed" {
+			n.lastID = 0
+		}
+		n.mu.Unlock()
+		if !ours || sig.Name != notificationsInterface+".ActionInvoked" {
+			continue
+		}
+
+		switch action, _ := sig.Body[1].(string); action {
+		case notificationActionNext:
+			n.app.NextSong()
+		case notificationActionPause:
+			n.app.actionQueue <- func() {
+				if n.app.player != nil {
+					n.app.SetPaused(!n.app.player.ctrl.Paused)
+				}
+			}
+		}
+	}
+}
+
+// hasCapability reports whether the notification server supports the given capability.
+//
+// hasCapability 报告通知服务器是否支持给定的能力。
+func (n *Notifier) hasCapability(capability string) bool {
+	return slices.Contains(n.capabilities, capability)
+}
+
+// notificationUrgency converts the configured urgency to the D-Bus byte value.
+//
+// notificationUrgency 将配置的紧急程度转换为 D-Bus 字节值。
+func notificationUrgency() byte {
+	if GlobalConfig == nil {
+		return 0
+	}
+	switch strings.ToLower(GlobalConfig.App.NotificationUrgency) {
+	case "normal":
+		return 1
+	case "critical":
+		return 2
+	default:
+		return 0
+	}
+}
+
+// notificationTimeout returns the configured expiration timeout in milliseconds
+// (-1 = server default, 0 = never expire).
+//
+// notificationTimeout 返回配置的过期时间（毫秒）（-1 = 服务器默认，0 = 永不过期）。
+func notificationTimeout() int32 {
+	if GlobalConfig == nil {
+		return -1
+	}
+	return int32(max(GlobalConfig.App.NotificationTimeoutMs, -1))
 }
 
-func sanitizeString(s string) string {
+// escapeNotificationMarkup escapes text for servers that interpret body markup.
+//
+// escapeNotificationMarkup 为会解析正文标记的服务器转义文本。
+func escapeNotificationMarkup(s string) string {
 	replacer := strings.NewReplacer(
-		"&", "",
-		";", "",
-		"|", "",
-		"*", "",
-		"~", "",
-		"<", "",
-		">", "",
-		"^", "",
-		"(", "",
-		")", "",
-		"[", "",
-		"]", "",
-		"{", "",
-		"}", "",
-		"$", "",
-		"\"", "",
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
 	)
 	return replacer.Replace(s)
 }
@@ -78,7 +423,7 @@ func isValidIconPath(path string) bool {
 		return false
 	}
 	info, err := os.Stat(path)
-	if os.IsNotExist(err) {
+	if err != nil {
 		return false
 	}
 	return !info.IsDir()
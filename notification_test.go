@@ -0,0 +1,79 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func writeTestPNG(t *testing.T, w, h int, c color.NRGBA) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "cover.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadNotificationImage(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int32
+	}{
+		{16, 8, 16, 8},
+		{512, 512, notificationImageSize, notificationImageSize},
+		{512, 256, notificationImageSize, notificationImageSize / 2},
+	}
+	for _, tt := range tests {
+		c := color.NRGBA{R: 200, G: 100, B: 50, A: 255}
+		img := loadNotificationImage(writeTestPNG(t, tt.w, tt.h, c))
+		if img == nil {
+			t.Errorf("loadNotificationImage(%dx%d) = nil", tt.w, tt.h)
+			continue
+		}
+		if img.Width != tt.wantW || img.Height != tt.wantH {
+			t.Errorf("loadNotificationImage(%dx%d) size = %dx%d; want %dx%d", tt.w, tt.h, img.Width, img.Height, tt.wantW, tt.wantH)
+		}
+		if img.RowStride != img.Width*4 || len(img.Data) != int(img.RowStride*img.Height) {
+			t.Errorf("loadNotificationImage(%dx%d) stride %d, %d bytes", tt.w, tt.h, img.RowStride, len(img.Data))
+		}
+		if got := img.Data[:4]; got[0] != c.R || got[1] != c.G || got[2] != c.B || got[3] != c.A {
+			t.Errorf("loadNotificationImage(%dx%d) first pixel = %v; want %v", tt.w, tt.h, got, c)
+		}
+	}
+}
+
+func TestLoadNotificationImageInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.png")
+	if err := os.WriteFile(path, []byte("not a png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, filepath.Join(t.TempDir(), "missing.png")} {
+		if img := loadNotificationImage(p); img != nil {
+			t.Errorf("loadNotificationImage(%q) = %v; want nil", p, img)
+		}
+	}
+}
+
+func TestNotificationImageSignature(t *testing.T) {
+	v := dbus.MakeVariant(notificationImage{})
+	if got := v.Signature().String(); got != "(iiibiiay)" {
+		t.Errorf("image-data signature = %q; want %q", got, "(iiibiiay)")
+	}
+}
@@ -50,8 +50,40 @@ const (
 
 	//FormatSignedInt16LE is the format of 16 bits integers little endian.
 	FormatSignedInt16LE
+
+	// FormatSignedInt24LE is the format of packed 24 bits integers little endian.
+	FormatSignedInt24LE
+)
+
+// Backend selects the audio output driver.
+type Backend string
+
+const (
+	// BackendAuto uses PulseAudio and falls back to ALSA if no PulseAudio server is reachable.
+	BackendAuto Backend = ""
+
+	// BackendPulse plays through a PulseAudio server, including pipewire-pulse.
+	BackendPulse Backend = "pulse"
+
+	// BackendALSA plays through ALSA directly via libasound, loaded at runtime.
+	BackendALSA Backend = "alsa"
+
+	// BackendFile writes the output to a file, a FIFO or stdout at the pace of real-time playback.
+	BackendFile Backend = "file"
+
+	// BackendNull discards the output at the pace of real-time playback.
+	BackendNull Backend = "null"
 )
 
+// Device describes an output of the backend.
+type Device struct {
+	// ID is the value to pass to SetDevice or NewContextOptions.Device.
+	ID string
+
+	// Description is a human readable name of the device.
+	Description string
+}
+
 // NewContextOptions represents options for NewContext.
 type NewContextOptions struct {
 	// SampleRate specifies the number of samples that should be played during one second.
@@ -77,6 +109,16 @@ type NewContextOptions struct {
 	// ApplicationName specifies the name of the client application.
 	// It is used for PulseAudio's volume control UI and so on.
 	ApplicationName string
+
+	// Backend specifies the audio output driver. The zero value selects it automatically.
+	Backend Backend
+
+	// Device specifies the output of the backend. For BackendPulse it is the sink name
+	// (the default sink if empty). For BackendALSA it is the PCM name ("default" if
+	// empty). For BackendFile it is the path to write to ("-" or empty for stdout); a
+	// path ending in ".wav" gets a WAV header, anything else receives raw little-endian
+	// PCM, both in the sources' Format. Other backends ignore it.
+	Device string
 }
 
 // NewContext creates a new context with given options.
@@ -101,7 +143,7 @@ func NewContext(options *NewContextOptions) (*Context, chan struct{}, error) {
 		bufferSizeInBytes = int(int64(options.BufferSize) * int64(bytesPerSecond) / int64(time.Second))
 		bufferSizeInBytes = bufferSizeInBytes / bytesPerSample * bytesPerSample
 	}
-	ctx, ready, err := newContext(options.SampleRate, options.ChannelCount, mux.Format(options.Format), bufferSizeInBytes, options.ApplicationName)
+	ctx, ready, err := newContext(options.SampleRate, options.ChannelCount, mux.Format(options.Format), bufferSizeInBytes, options.ApplicationName, options.Backend, options.Device)
 	if err != nil {
 		return nil, nil, err
 	}
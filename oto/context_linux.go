@@ -19,3 +19,19 @@ package oto
 func (c *Context) SetSampleRate(sampleRate int) error {
 	return c.context.SetSampleRate(sampleRate)
 }
+
+// Devices lists the outputs of the backend the context plays to.
+//
+// Devices is concurrent-safe.
+func (c *Context) Devices() ([]Device, error) {
+	return c.context.Devices()
+}
+
+// SetDevice switches playback to the output with the given ID, as returned by Devices.
+// An empty id selects the backend's default output. Players keep their position; only
+// the samples already queued in the old device are lost.
+//
+// SetDevice is concurrent-safe.
+func (c *Context) SetDevice(id string) error {
+	return c.context.SetDevice(id)
+}
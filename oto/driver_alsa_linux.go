@@ -0,0 +1,342 @@
+// Copyright 2024 The Oto Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build !android
+
+package oto
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+
+	"github.com/ebitengine/oto/v3/internal/mux"
+)
+
+// ALSA constants from alsa/pcm.h.
+const (
+	sndPCMStreamPlayback      = 0
+	sndPCMAccessRWInterleaved = 3
+	sndPCMFormatS16LE         = 2
+	sndPCMFormatS32LE         = 10
+	sndPCMFormatFloatLE       = 14
+	alsaDefaultLatencyMicros  = 100000
+	alsaPeriodsPerBuffer      = 4
+	alsaDefaultDevice         = "default"
+	alsaLibraryName           = "libasound.so.2"
+	alsaSoftResample          = 1
+	alsaRecoverSilently       = 1
+	alsaMinimumPeriodFrames   = 64
+)
+
+// libasound functions, loaded at runtime so that BM builds and runs without ALSA headers.
+var (
+	alsaOnce    sync.Once
+	alsaLoadErr error
+
+	sndPCMOpen      func(pcm *uintptr, name string, stream int32, mode int32) int32
+	sndPCMSetParams func(pcm uintptr, format int32, access int32, channels uint32, rate uint32, softResample int32, latency uint32) int32
+	sndPCMWritei    func(pcm uintptr, buf unsafe.Pointer, frames uint64) int64
+	sndPCMRecover   func(pcm uintptr, err int32, silent int32) int32
+	sndPCMPrepare   func(pcm uintptr) int32
+	sndPCMDrop      func(pcm uintptr) int32
+	sndPCMClose     func(pcm uintptr) int32
+	sndStrerror     func(errnum int32) string
+)
+
+func loadALSA() error {
+	alsaOnce.Do(func() {
+		lib, err := purego.Dlopen(alsaLibraryName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+		if err != nil {
+			alsaLoadErr = fmt.Errorf("oto: ALSA library %s is not available: %w", alsaLibraryName, err)
+			return
+		}
+		purego.RegisterLibFunc(&sndPCMOpen, lib, "snd_pcm_open")
+		purego.RegisterLibFunc(&sndPCMSetParams, lib, "snd_pcm_set_params")
+		purego.RegisterLibFunc(&sndPCMWritei, lib, "snd_pcm_writei")
+		purego.RegisterLibFunc(&sndPCMRecover, lib, "snd_pcm_recover")
+		purego.RegisterLibFunc(&sndPCMPrepare, lib, "snd_pcm_prepare")
+		purego.RegisterLibFunc(&sndPCMDrop, lib, "snd_pcm_drop")
+		purego.RegisterLibFunc(&sndPCMClose, lib, "snd_pcm_close")
+		purego.RegisterLibFunc(&sndStrerror, lib, "snd_strerror")
+	})
+	return alsaLoadErr
+}
+
+func alsaError(what string, code int32) error {
+	return fmt.Errorf("oto: ALSA %s failed: %s", what, sndStrerror(code))
+}
+
+// alsaDriver plays audio through an ALSA PCM device. Blocking writes to the device
+// set the pace at which samples are pulled from the mux.
+type alsaDriver struct {
+	mux               *mux.Mux
+	device            string
+	channelCount      int
+	bufferSizeInBytes int
+
+	// cond.L is held while writing to the device, so the PCM handle can only be
+	// replaced between two writes.
+	cond       *sync.Cond
+	pcm        uintptr
+	format     int32
+	sampleRate int
+	suspended  bool
+	err        atomicError
+}
+
+func newALSADriver(m *mux.Mux, sampleRate int, channelCount int, bufferSizeInBytes int, device string) (*alsaDriver, error) {
+	if err := loadALSA(); err != nil {
+		return nil, err
+	}
+	if device == "" {
+		device = alsaDefaultDevice
+	}
+
+	d := &alsaDriver{
+		mux:               m,
+		device:            device,
+		channelCount:      channelCount,
+		bufferSizeInBytes: bufferSizeInBytes,
+		cond:              sync.NewCond(&sync.Mutex{}),
+	}
+	if err := d.open(sampleRate); err != nil {
+		return nil, err
+	}
+	go d.loop()
+	return d, nil
+}
+
+// alsaFormats are the sample formats tried when opening a device, best first. Hardware
+// devices opened without the plug layer (e.g. "hw:0,0" for exclusive output) often lack
+// float support; 32-bit integers still carry 24-bit sources unchanged.
+var alsaFormats = []int32{sndPCMFormatFloatLE, sndPCMFormatS32LE, sndPCMFormatS16LE}
+
+// open opens the PCM device at the given rate with the first supported format of alsaFormats.
+func (d *alsaDriver) open(sampleRate int) error {
+	var pcm uintptr
+	if code := sndPCMOpen(&pcm, d.device, sndPCMStreamPlayback, 0); code < 0 {
+		return alsaError(fmt.Sprintf("opening device %q", d.device), code)
+	}
+
+	latency := uint32(alsaDefaultLatencyMicros)
+	if d.bufferSizeInBytes > 0 {
+		latency = uint32(int64(d.bufferSizeInBytes) * 1000000 / int64(sampleRate*d.channelCount*4))
+	}
+
+	var format int32
+	var code int32
+	for _, format = range alsaFormats {
+		code = sndPCMSetParams(pcm, format, sndPCMAccessRWInterleaved, uint32(d.channelCount), uint32(sampleRate), alsaSoftResample, latency)
+		if code >= 0 {
+			break
+		}
+	}
+	if code < 0 {
+		sndPCMClose(pcm)
+		return alsaError("setting hardware parameters", code)
+	}
+
+	d.pcm = pcm
+	d.format = format
+	d.sampleRate = sampleRate
+	return nil
+}
+
+func (d *alsaDriver) loop() {
+	var (
+		in    []float32
+		out16 []int16
+		out32 []int32
+	)
+	for {
+		d.cond.L.Lock()
+		for d.suspended && d.err.Load() == nil {
+			d.cond.Wait()
+		}
+		if d.err.Load() != nil {
+			d.cond.L.Unlock()
+			return
+		}
+
+		latency := alsaDefaultLatencyMicros * d.sampleRate / 1000000
+		if d.bufferSizeInBytes > 0 {
+			latency = d.bufferSizeInBytes / (d.channelCount * 4)
+		}
+		frames := max(latency/alsaPeriodsPerBuffer, alsaMinimumPeriodFrames)
+		n := frames * d.channelCount
+		if cap(in) < n {
+			in = make([]float32, n)
+		}
+		in = in[:n]
+		d.mux.ReadFloat32s(in)
+
+		var buf unsafe.Pointer
+		switch d.format {
+		case sndPCMFormatFloatLE:
+			buf = unsafe.Pointer(&in[0])
+		case sndPCMFormatS32LE:
+			if cap(out32) < n {
+				out32 = make([]int32, n)
+			}
+			out32 = out32[:n]
+			for i, v := range in {
+				// Scale by 2^31 in float64 so that 24-bit samples map to exact integers.
+				out32[i] = int32(max(math.MinInt32, min(math.MaxInt32, float64(v)*(1<<31))))
+			}
+			buf = unsafe.Pointer(&out32[0])
+		default:
+			if cap(out16) < n {
+				out16 = make([]int16, n)
+			}
+			out16 = out16[:n]
+			for i, v := range in {
+				out16[i] = int16(max(-1, min(1, v)) * math.MaxInt16)
+			}
+			buf = unsafe.Pointer(&out16[0])
+		}
+
+		if err := d.write(buf, frames); err != nil {
+			d.err.TryStore(err)
+		}
+		d.cond.L.Unlock()
+	}
+}
+
+// write writes frames to the device, recovering from underruns. It must be called with cond.L held.
+func (d *alsaDriver) write(buf unsafe.Pointer, frames int) error {
+	frameSize := d.channelCount * 4
+	if d.format == sndPCMFormatS16LE {
+		frameSize = d.channelCount * 2
+	}
+	written := 0
+	for written < frames {
+		n := sndPCMWritei(d.pcm, unsafe.Add(buf, written*frameSize), uint64(frames-written))
+		if n < 0 {
+			if code := sndPCMRecover(d.pcm, int32(n), alsaRecoverSilently); code < 0 {
+				return alsaError("writing samples", code)
+			}
+			continue
+		}
+		written += int(n)
+	}
+	return nil
+}
+
+func (d *alsaDriver) Suspend() error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if err := d.err.Load(); err != nil {
+		return err
+	}
+	d.suspended = true
+	sndPCMDrop(d.pcm)
+	return nil
+}
+
+func (d *alsaDriver) Resume() error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if err := d.err.Load(); err != nil {
+		return err
+	}
+	if code := sndPCMPrepare(d.pcm); code < 0 {
+		return alsaError("resuming", code)
+	}
+	d.suspended = false
+	d.cond.Signal()
+	return nil
+}
+
+func (d *alsaDriver) Err() error {
+	return d.err.Load()
+}
+
+// SetSampleRate reopens the PCM device with the new sample rate.
+func (d *alsaDriver) SetSampleRate(sampleRate int) error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if sampleRate == d.sampleRate {
+		return d.err.Load()
+	}
+	return d.reopen(d.device, sampleRate)
+}
+
+// Devices lists the default device and the playback PCMs found in /proc/asound/pcm.
+// The PCMs are offered through the plug layer so that any sample rate and format works.
+func (d *alsaDriver) Devices() ([]Device, error) {
+	devices := []Device{{ID: alsaDefaultDevice, Description: "Default ALSA device"}}
+
+	data, err := os.ReadFile("/proc/asound/pcm")
+	if err != nil {
+		return devices, nil
+	}
+	// Each line looks like "00-03: HDMI 0 : HDMI 0 : playback 1".
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 || !strings.Contains(line, "playback") {
+			continue
+		}
+		card, dev, ok := strings.Cut(strings.TrimSpace(fields[0]), "-")
+		if !ok {
+			continue
+		}
+		cardNum, err1 := strconv.Atoi(card)
+		devNum, err2 := strconv.Atoi(dev)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		devices = append(devices, Device{
+			ID:          fmt.Sprintf("plughw:%d,%d", cardNum, devNum),
+			Description: strings.TrimSpace(fields[1]),
+		})
+	}
+	return devices, nil
+}
+
+// SetDevice reopens playback on another PCM device at the current sample rate.
+func (d *alsaDriver) SetDevice(id string) error {
+	if id == "" {
+		id = alsaDefaultDevice
+	}
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	return d.reopen(id, d.sampleRate)
+}
+
+// reopen closes the PCM device and opens the given one. If that fails, the previous
+// device is opened again so that playback continues. It must be called with cond.L held.
+func (d *alsaDriver) reopen(device string, sampleRate int) error {
+	if err := d.err.Load(); err != nil {
+		return err
+	}
+	if d.suspended {
+		return fmt.Errorf("oto: cannot change the ALSA device while suspended")
+	}
+
+	oldDevice, oldRate := d.device, d.sampleRate
+	sndPCMDrop(d.pcm)
+	sndPCMClose(d.pcm)
+
+	d.device = device
+	err := d.open(sampleRate)
+	if err == nil {
+		return nil
+	}
+	d.device = oldDevice
+	if restoreErr := d.open(oldRate); restoreErr != nil {
+		d.err.TryStore(restoreErr)
+	}
+	return err
+}
@@ -0,0 +1,21 @@
+// Copyright 2024 The Oto Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build !linux && !android && !darwin && !js && !windows && !nintendosdk && !playstation5
+
+package oto
+
+import (
+	"errors"
+
+	"github.com/ebitengine/oto/v3/internal/mux"
+)
+
+func newALSADriver(m *mux.Mux, sampleRate int, channelCount int, bufferSizeInBytes int, device string) (driver, error) {
+	return nil, errors.New("oto: the ALSA backend is only available on Linux")
+}
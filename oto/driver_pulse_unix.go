@@ -0,0 +1,255 @@
+// Copyright 2021 The Oto Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !android && !darwin && !js && !windows && !nintendosdk && !playstation5
+
+package oto
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jfreymuth/pulse"
+
+	"github.com/ebitengine/oto/v3/internal/mux"
+)
+
+// pulseDriver plays audio through a PulseAudio (or pipewire-pulse) server.
+type pulseDriver struct {
+	client *pulse.Client
+	stream *pulse.PlaybackStream
+	sink   *pulse.Sink // nil plays to the server's default sink.
+
+	suspended bool
+	cond      *sync.Cond
+
+	mux *mux.Mux
+	err atomicError
+
+	sampleRate        int
+	channelCount      int
+	bufferSizeInBytes int
+	applicationName   string
+}
+
+func newPulseDriver(m *mux.Mux, sampleRate int, channelCount int, bufferSizeInBytes int, applicationName string, device string) (*pulseDriver, error) {
+	if applicationName == "" {
+		if name, _ := os.Executable(); name != "" {
+			applicationName = filepath.Base(name)
+		} else {
+			applicationName = "Oto"
+		}
+	}
+	client := &pulseDriver{
+		cond:              sync.NewCond(&sync.Mutex{}),
+		mux:               m,
+		sampleRate:        sampleRate,
+		channelCount:      channelCount,
+		bufferSizeInBytes: bufferSizeInBytes,
+		applicationName:   applicationName,
+	}
+
+	var err error
+	client.client, err = pulse.NewClient(pulse.ClientApplicationName(applicationName))
+	if err != nil {
+		return nil, fmt.Errorf("oto: PulseAudio client initialization failed: %w", err)
+	}
+
+	if device != "" {
+		client.sink, err = client.client.SinkByID(device)
+		if err != nil {
+			client.client.Close()
+			return nil, fmt.Errorf("oto: PulseAudio sink %q not found: %w", device, err)
+		}
+	}
+
+	client.stream, err = client.newStream(sampleRate, client.sink)
+	if err != nil {
+		client.client.Close()
+		return nil, fmt.Errorf("oto: PulseAudio playback initialization failed: %w", err)
+	}
+	client.stream.Start()
+
+	return client, nil
+}
+
+// newStream creates a playback stream reading from the mux at the given rate on the given sink.
+func (c *pulseDriver) newStream(sampleRate int, sink *pulse.Sink) (*pulse.PlaybackStream, error) {
+	options := []pulse.PlaybackOption{
+		pulse.PlaybackMediaName(c.applicationName),
+	}
+	switch c.channelCount {
+	case 1:
+		options = append(options, pulse.PlaybackMono)
+	case 2:
+		options = append(options, pulse.PlaybackStereo)
+	default:
+		return nil, fmt.Errorf("oto: PulseAudio backend supports only mono or stereo output: %d", c.channelCount)
+	}
+	options = append(options, pulse.PlaybackSampleRate(sampleRate))
+	{
+		latency := float64(c.bufferSizeInBytes) / float64(sampleRate*c.channelCount*4)
+		if latency <= 0 {
+			// If no buffer size is specified, default to a 100ms latency.
+			// Without this, PulseAudio uses its own large default buffer (~2s),
+			// which causes a noticeable delay before audio starts playing.
+			latency = 0.1
+		}
+		options = append(options, pulse.PlaybackLatency(latency))
+	}
+	if sink != nil {
+		options = append(options, pulse.PlaybackSink(sink))
+	}
+	return c.client.NewPlayback(pulse.Float32Reader(c.read), options...)
+}
+
+func (c *pulseDriver) read(buf []float32) (int, error) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+
+	for c.suspended && c.err.Load() == nil {
+		c.cond.Wait()
+	}
+	if err := c.err.Load(); err != nil {
+		return 0, err
+	}
+
+	c.mux.ReadFloat32s(buf)
+	return len(buf), nil
+}
+
+func (c *pulseDriver) Suspend() error {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+
+	if err := c.err.Load(); err != nil {
+		return err
+	}
+	if err := c.stream.Error(); err != nil {
+		return fmt.Errorf("oto: PulseAudio error: %w", err)
+	}
+
+	c.suspended = true
+	c.stream.Pause()
+	return nil
+}
+
+func (c *pulseDriver) Resume() error {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+
+	if err := c.err.Load(); err != nil {
+		return err
+	}
+	if err := c.stream.Error(); err != nil {
+		return fmt.Errorf("oto: PulseAudio error: %w", err)
+	}
+
+	c.suspended = false
+	c.stream.Resume()
+	c.cond.Signal()
+	return nil
+}
+
+func (c *pulseDriver) Err() error {
+	if err := c.err.Load(); err != nil {
+		return err
+	}
+	if err := c.stream.Error(); err != nil {
+		return fmt.Errorf("oto: PulseAudio error: %w", err)
+	}
+	return nil
+}
+
+// SetSampleRate dynamically changes the sample rate of the underlying PulseAudio
+// playback stream. The PulseAudio stream is recreated with the new sample rate,
+// causing a brief gap in audio output (typically 5-50ms). Callers should hold
+// the appropriate lock and pause playback to avoid glitches.
+func (c *pulseDriver) SetSampleRate(sampleRate int) error {
+	c.cond.L.Lock()
+	sink := c.sink
+	c.cond.L.Unlock()
+	return c.replaceStream(sampleRate, sink)
+}
+
+// Devices lists the sinks of the PulseAudio server.
+func (c *pulseDriver) Devices() ([]Device, error) {
+	sinks, err := c.client.ListSinks()
+	if err != nil {
+		return nil, fmt.Errorf("oto: PulseAudio failed to list sinks: %w", err)
+	}
+	devices := make([]Device, 0, len(sinks))
+	for _, sink := range sinks {
+		devices = append(devices, Device{ID: sink.ID(), Description: sink.Name()})
+	}
+	return devices, nil
+}
+
+// SetDevice moves playback to the sink with the given ID, or to the default sink if
+// id is empty. Samples already sent to the old sink are dropped.
+func (c *pulseDriver) SetDevice(id string) error {
+	var sink *pulse.Sink
+	if id != "" {
+		var err error
+		sink, err = c.client.SinkByID(id)
+		if err != nil {
+			return fmt.Errorf("oto: PulseAudio sink %q not found: %w", id, err)
+		}
+	}
+	c.cond.L.Lock()
+	sampleRate := c.sampleRate
+	c.cond.L.Unlock()
+	return c.replaceStream(sampleRate, sink)
+}
+
+// replaceStream recreates the playback stream with the given rate and sink. The new stream
+// is created before the old one is closed, so that playback keeps going on the old stream
+// if it cannot be created.
+func (c *pulseDriver) replaceStream(sampleRate int, sink *pulse.Sink) error {
+	c.cond.L.Lock()
+	if err := c.err.Load(); err != nil {
+		c.cond.L.Unlock()
+		return err
+	}
+	if err := c.stream.Error(); err != nil {
+		c.cond.L.Unlock()
+		return fmt.Errorf("oto: PulseAudio error: %w", err)
+	}
+	if c.suspended {
+		c.cond.L.Unlock()
+		return fmt.Errorf("oto: cannot change the PulseAudio stream while suspended")
+	}
+	oldStream := c.stream
+	c.cond.L.Unlock()
+
+	newStream, err := c.newStream(sampleRate, sink)
+	if err != nil {
+		return fmt.Errorf("oto: PulseAudio playback reinitialization failed: %w", err)
+	}
+
+	oldStream.Stop()
+	oldStream.Drain()
+	oldStream.Close()
+	newStream.Start()
+
+	c.cond.L.Lock()
+	c.stream = newStream
+	c.sampleRate = sampleRate
+	c.sink = sink
+	c.cond.L.Unlock()
+
+	return nil
+}
@@ -17,205 +17,67 @@
 package oto
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"sync"
-
-	"github.com/jfreymuth/pulse"
 
 	"github.com/ebitengine/oto/v3/internal/mux"
 )
 
-type context struct {
-	client *pulse.Client
-	stream *pulse.PlaybackStream
-
-	suspended bool
-	cond      *sync.Cond
-
-	mux *mux.Mux
-	err atomicError
-
-	channelCount      int
-	bufferSizeInBytes int
-	applicationName   string
-}
-
-func newContext(sampleRate int, channelCount int, format mux.Format, bufferSizeInBytes int, applicationName string) (client *context, ready chan struct{}, err error) {
-	client = &context{
-		cond: sync.NewCond(&sync.Mutex{}),
-		mux:  mux.New(sampleRate, channelCount, format),
-	}
-	ready = make(chan struct{})
-	close(ready)
-	defer func() {
-		if client != nil && client.client != nil && err != nil {
-			client.client.Close()
-		}
-	}()
-
-	if applicationName == "" {
-		if name, _ := os.Executable(); name != "" {
-			applicationName = filepath.Base(name)
-		} else {
-			applicationName = "Oto"
-		}
-	}
-	client.channelCount = channelCount
-	client.bufferSizeInBytes = bufferSizeInBytes
-	client.applicationName = applicationName
-
-	client.client, err = pulse.NewClient(pulse.ClientApplicationName(applicationName))
-	if err != nil {
-		return nil, ready, fmt.Errorf("oto: PulseAudio client initialization failed: %w", err)
-	}
-
-	options := []pulse.PlaybackOption{
-		pulse.PlaybackMediaName(applicationName),
-	}
-	switch channelCount {
-	case 1:
-		options = append(options, pulse.PlaybackMono)
-	case 2:
-		options = append(options, pulse.PlaybackStereo)
-	default:
-		return nil, ready, fmt.Errorf("oto: PulseAudio backend supports only mono or stereo output: %d", channelCount)
-	}
-	options = append(options, pulse.PlaybackSampleRate(sampleRate))
-	{
-		latency := float64(bufferSizeInBytes) / float64(sampleRate*channelCount*4)
-		if latency <= 0 {
-			// If no buffer size is specified, default to a 100ms latency.
-			// Without this, PulseAudio uses its own large default buffer (~2s),
-			// which causes a noticeable delay before audio starts playing.
-			latency = 0.1
-		}
-		options = append(options, pulse.PlaybackLatency(latency))
-	}
-
-	client.stream, err = client.client.NewPlayback(pulse.Float32Reader(client.read), options...)
-	if err != nil {
-		return nil, ready, fmt.Errorf("oto: PulseAudio playback initialization failed: %w", err)
-	}
-	client.stream.Start()
-
-	return client, ready, nil
-}
-
-func (c *context) read(buf []float32) (int, error) {
-	c.cond.L.Lock()
-	defer c.cond.L.Unlock()
-
-	for c.suspended && c.err.Load() == nil {
-		c.cond.Wait()
-	}
-	if err := c.err.Load(); err != nil {
-		return 0, err
-	}
-
-	c.mux.ReadFloat32s(buf)
-	return len(buf), nil
-}
+// driver is an audio output backend. A driver pulls float32 samples from the
+// context's mux at the pace of its output and writes them to the device.
+type driver interface {
+	Suspend() error
+	Resume() error
+	Err() error
 
-func (c *context) Suspend() error {
-	c.cond.L.Lock()
-	defer c.cond.L.Unlock()
+	// SetSampleRate changes the rate at which samples are pulled from the mux.
+	SetSampleRate(sampleRate int) error
 
-	if err := c.err.Load(); err != nil {
-		return err
-	}
-	if err := c.stream.Error(); err != nil {
-		return fmt.Errorf("oto: PulseAudio error: %w", err)
-	}
+	// Devices lists the outputs the driver can play to.
+	Devices() ([]Device, error)
 
-	c.suspended = true
-	c.stream.Pause()
-	return nil
+	// SetDevice switches to another output without interrupting the mux. An empty id
+	// selects the driver's default output.
+	SetDevice(id string) error
 }
 
-func (c *context) Resume() error {
-	c.cond.L.Lock()
-	defer c.cond.L.Unlock()
-
-	if err := c.err.Load(); err != nil {
-		return err
-	}
-	if err := c.stream.Error(); err != nil {
-		return fmt.Errorf("oto: PulseAudio error: %w", err)
-	}
-
-	c.suspended = false
-	c.stream.Resume()
-	c.cond.Signal()
-	return nil
-}
-
-func (c *context) Err() error {
-	if err := c.err.Load(); err != nil {
-		return err
-	}
-	if err := c.stream.Error(); err != nil {
-		return fmt.Errorf("oto: PulseAudio error: %w", err)
-	}
-	return nil
+type context struct {
+	driver
+	mux *mux.Mux
 }
 
-// SetSampleRate dynamically changes the sample rate of the underlying PulseAudio
-// playback stream. The PulseAudio stream is recreated with the new sample rate,
-// causing a brief gap in audio output (typically 5-50ms). Callers should hold
-// the appropriate lock and pause playback to avoid glitches.
-func (c *context) SetSampleRate(sampleRate int) error {
-	c.cond.L.Lock()
-	if err := c.err.Load(); err != nil {
-		c.cond.L.Unlock()
-		return err
-	}
-	if err := c.stream.Error(); err != nil {
-		c.cond.L.Unlock()
-		return fmt.Errorf("oto: PulseAudio error: %w", err)
-	}
-	if c.suspended {
-		c.cond.L.Unlock()
-		return fmt.Errorf("oto: cannot set sample rate while suspended")
-	}
-	oldStream := c.stream
-	c.cond.L.Unlock()
-
-	oldStream.Stop()
-	oldStream.Drain()
-	oldStream.Close()
+func newContext(sampleRate int, channelCount int, format mux.Format, bufferSizeInBytes int, applicationName string, backend Backend, device string) (*context, chan struct{}, error) {
+	m := mux.New(sampleRate, channelCount, format)
+	ready := make(chan struct{})
+	close(ready)
 
-	options := []pulse.PlaybackOption{
-		pulse.PlaybackMediaName(c.applicationName),
-	}
-	switch c.channelCount {
-	case 1:
-		options = append(options, pulse.PlaybackMono)
-	case 2:
-		options = append(options, pulse.PlaybackStereo)
-	default:
-		return fmt.Errorf("oto: PulseAudio backend supports only mono or stereo output: %d", c.channelCount)
-	}
-	options = append(options, pulse.PlaybackSampleRate(sampleRate))
-	{
-		latency := float64(c.bufferSizeInBytes) / float64(sampleRate*c.channelCount*4)
-		if latency <= 0 {
-			latency = 0.1
+	var d driver
+	var err error
+	switch backend {
+	case BackendAuto:
+		d, err = newPulseDriver(m, sampleRate, channelCount, bufferSizeInBytes, applicationName, device)
+		if err != nil {
+			var alsaErr error
+			d, alsaErr = newALSADriver(m, sampleRate, channelCount, bufferSizeInBytes, device)
+			if alsaErr != nil {
+				err = errors.Join(err, alsaErr)
+			} else {
+				err = nil
+			}
 		}
-		options = append(options, pulse.PlaybackLatency(latency))
+	case BackendPulse:
+		d, err = newPulseDriver(m, sampleRate, channelCount, bufferSizeInBytes, applicationName, device)
+	case BackendALSA:
+		d, err = newALSADriver(m, sampleRate, channelCount, bufferSizeInBytes, device)
+	case BackendFile:
+		d, err = newFileDriver(m, sampleRate, channelCount, format, device)
+	case BackendNull:
+		d, err = newNullDriver(m, sampleRate, channelCount)
+	default:
+		err = fmt.Errorf("oto: unknown backend: %q", backend)
 	}
-
-	var err error
-	newStream, err := c.client.NewPlayback(pulse.Float32Reader(c.read), options...)
 	if err != nil {
-		return fmt.Errorf("oto: PulseAudio playback reinitialization failed: %w", err)
+		return nil, nil, err
 	}
-	newStream.Start()
-
-	c.cond.L.Lock()
-	c.stream = newStream
-	c.cond.L.Unlock()
-
-	return nil
+	return &context{driver: d, mux: m}, ready, nil
 }
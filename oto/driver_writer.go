@@ -0,0 +1,357 @@
+// Copyright 2024 The Oto Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build !android && !darwin && !js && !windows && !nintendosdk && !playstation5
+
+package oto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/oto/v3/internal/mux"
+)
+
+const (
+	// writerTick is how often a writer driver pulls samples from the mux.
+	writerTick = 10 * time.Millisecond
+
+	// writerMaxBehind caps how much audio is pulled at once when the writer has fallen
+	// behind, e.g. because a FIFO reader stalled. The missed time is skipped, not caught up.
+	writerMaxBehind = 200 * time.Millisecond
+
+	wavHeaderSize = 44
+)
+
+// writerDriver pulls samples from the mux at the pace of real-time playback and writes
+// them to w in the format of the sources (16-bit or 24-bit little-endian PCM, 32-bit
+// float or unsigned 8-bit), or discards them if w is nil.
+//
+// The output keeps the sample rate the context was created with. When SetSampleRate
+// changes the source rate, samples are resampled linearly so that the output format
+// never changes in the middle of a stream.
+type writerDriver struct {
+	mux          *mux.Mux
+	channelCount int
+	outRate      int
+	format       mux.Format
+
+	// cond.L guards the fields below and is held while writing, so the output can
+	// only be replaced between two writes.
+	cond      *sync.Cond
+	path      string
+	w         io.WriteCloser
+	wavFile   *os.File // Set when w is a seekable WAV file whose sizes can be patched.
+	written   int64    // Bytes of samples written to wavFile.
+	srcRate   int
+	suspended bool
+	err       atomicError
+
+	// Resampler state, only used by loop.
+	pos  float64
+	prev []float32
+}
+
+func newNullDriver(m *mux.Mux, sampleRate int, channelCount int) (*writerDriver, error) {
+	d := &writerDriver{
+		mux:          m,
+		channelCount: channelCount,
+		outRate:      sampleRate,
+		srcRate:      sampleRate,
+		cond:         sync.NewCond(&sync.Mutex{}),
+	}
+	go d.loop()
+	return d, nil
+}
+
+func newFileDriver(m *mux.Mux, sampleRate int, channelCount int, format mux.Format, path string) (*writerDriver, error) {
+	d := &writerDriver{
+		mux:          m,
+		channelCount: channelCount,
+		outRate:      sampleRate,
+		format:       format,
+		srcRate:      sampleRate,
+		cond:         sync.NewCond(&sync.Mutex{}),
+	}
+	if err := d.openOutput(path); err != nil {
+		return nil, err
+	}
+	go d.loop()
+	return d, nil
+}
+
+// openOutput opens path for writing, writing a WAV header if it ends in ".wav".
+// "-" or an empty path selects stdout.
+func (d *writerDriver) openOutput(path string) error {
+	var f *os.File
+	if path == "" || path == "-" {
+		f = os.Stdout
+	} else {
+		// Opening a FIFO blocks until a reader connects to it.
+		var err error
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fmt.Errorf("oto: file backend failed to open %s: %w", path, err)
+		}
+	}
+
+	var wavFile *os.File
+	if strings.EqualFold(filepath.Ext(path), ".wav") {
+		if _, err := f.Write(wavHeader(d.outRate, d.channelCount, d.format)); err != nil {
+			f.Close()
+			return fmt.Errorf("oto: file backend failed to write WAV header: %w", err)
+		}
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			wavFile = f
+		}
+	}
+
+	d.path = path
+	d.w = f
+	d.wavFile = wavFile
+	d.written = 0
+	return nil
+}
+
+func (d *writerDriver) loop() {
+	var (
+		rate   int
+		start  time.Time
+		pulled int64
+		in     []float32
+		out    []byte
+	)
+
+	for {
+		d.cond.L.Lock()
+		for d.suspended {
+			d.cond.Wait()
+			rate = 0 // Restart the clock after resuming.
+		}
+		srcRate := d.srcRate
+		d.cond.L.Unlock()
+
+		if srcRate != rate {
+			rate = srcRate
+			start = time.Now()
+			pulled = 0
+			d.pos = 0
+			d.prev = make([]float32, d.channelCount)
+		}
+
+		due := int64(time.Since(start).Seconds()*float64(rate)) - pulled
+		if maxDue := int64(writerMaxBehind.Seconds() * float64(rate)); due > maxDue {
+			start = time.Now()
+			pulled = -maxDue
+			due = maxDue
+		}
+		if due > 0 {
+			n := int(due) * d.channelCount
+			if cap(in) < n {
+				in = make([]float32, n)
+			}
+			in = in[:n]
+			d.mux.ReadFloat32s(in)
+			pulled += due
+
+			if err := d.write(in, rate, &out); err != nil {
+				d.err.TryStore(err)
+				return
+			}
+		}
+
+		time.Sleep(writerTick)
+	}
+}
+
+// write encodes in and writes it to the output, if any.
+func (d *writerDriver) write(in []float32, srcRate int, out *[]byte) error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if d.w == nil {
+		return nil
+	}
+
+	*out = d.encode((*out)[:0], in, srcRate)
+	if _, err := d.w.Write(*out); err != nil {
+		return fmt.Errorf("oto: file backend write failed: %w", err)
+	}
+	d.written += int64(len(*out))
+	if d.wavFile != nil {
+		patchWAVSizes(d.wavFile, d.written)
+	}
+	return nil
+}
+
+// encode resamples in from srcRate to the output rate and appends it to out in the
+// driver's format.
+func (d *writerDriver) encode(out []byte, in []float32, srcRate int) []byte {
+	ch := d.channelCount
+	frames := len(in) / ch
+	if frames == 0 {
+		return out
+	}
+	step := float64(srcRate) / float64(d.outRate)
+
+	// pos ranges over [-1, frames-1); frame -1 is the last frame of the previous call.
+	for ; d.pos < float64(frames-1); d.pos += step {
+		i := int(math.Floor(d.pos))
+		frac := float32(d.pos - float64(i))
+		for c := 0; c < ch; c++ {
+			a := d.prev[c]
+			if i >= 0 {
+				a = in[i*ch+c]
+			}
+			b := in[(i+1)*ch+c]
+			v := a + (b-a)*frac
+			out = appendSample(out, v, d.format)
+		}
+	}
+	d.pos -= float64(frames)
+	copy(d.prev, in[(frames-1)*ch:])
+	return out
+}
+
+func (d *writerDriver) Suspend() error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if err := d.err.Load(); err != nil {
+		return err
+	}
+	d.suspended = true
+	return nil
+}
+
+func (d *writerDriver) Resume() error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if err := d.err.Load(); err != nil {
+		return err
+	}
+	d.suspended = false
+	d.cond.Signal()
+	return nil
+}
+
+func (d *writerDriver) Err() error {
+	return d.err.Load()
+}
+
+// SetSampleRate changes the source rate. The output rate stays the same.
+func (d *writerDriver) SetSampleRate(sampleRate int) error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if err := d.err.Load(); err != nil {
+		return err
+	}
+	d.srcRate = sampleRate
+	return nil
+}
+
+// Devices returns the current output path of the file backend. The null backend has no devices.
+func (d *writerDriver) Devices() ([]Device, error) {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if d.w == nil {
+		return nil, nil
+	}
+	return []Device{{ID: d.path, Description: "File output"}}, nil
+}
+
+// SetDevice makes the file backend continue writing to another path.
+func (d *writerDriver) SetDevice(id string) error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+	if err := d.err.Load(); err != nil {
+		return err
+	}
+	if d.w == nil {
+		return fmt.Errorf("oto: the null backend has no devices")
+	}
+	if id == d.path {
+		return nil
+	}
+
+	old := d.w
+	if err := d.openOutput(id); err != nil {
+		return err
+	}
+	if old != os.Stdout {
+		old.Close()
+	}
+	return nil
+}
+
+// appendSample appends v, clamped to [-1, 1], to out in the given format. Integer
+// samples use the same scale as the mux, so samples read from a source of the same
+// format are written back unchanged.
+func appendSample(out []byte, v float32, format mux.Format) []byte {
+	switch format {
+	case mux.FormatFloat32LE:
+		return binary.LittleEndian.AppendUint32(out, math.Float32bits(max(-1, min(1, v))))
+	case mux.FormatUnsignedInt8:
+		return append(out, uint8(int(quantize(v, 1<<7))+1<<7))
+	case mux.FormatSignedInt24LE:
+		s := quantize(v, 1<<23)
+		return append(out, byte(s), byte(s>>8), byte(s>>16))
+	default:
+		return binary.LittleEndian.AppendUint16(out, uint16(int16(quantize(v, 1<<15))))
+	}
+}
+
+// quantize scales v to an integer sample in [-scale, scale-1].
+func quantize(v float32, scale int32) int32 {
+	s := int32(math.Round(float64(v) * float64(scale)))
+	return max(-scale, min(scale-1, s))
+}
+
+// wavHeader returns a WAV header for the format with the sizes set to their maximum,
+// which readers of a pipe treat as "read until the end of the stream". Float samples
+// use the IEEE float format tag.
+func wavHeader(sampleRate int, channelCount int, format mux.Format) []byte {
+	var formatTag uint16 = 1
+	if format == mux.FormatFloat32LE {
+		formatTag = 3
+	}
+	bytesPerSample := format.ByteLength()
+	blockAlign := channelCount * bytesPerSample
+	h := make([]byte, 0, wavHeaderSize)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, math.MaxUint32)
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, formatTag)
+	h = binary.LittleEndian.AppendUint16(h, uint16(channelCount))
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate*blockAlign))
+	h = binary.LittleEndian.AppendUint16(h, uint16(blockAlign))
+	h = binary.LittleEndian.AppendUint16(h, uint16(bytesPerSample*8))
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, math.MaxUint32)
+	return h
+}
+
+// patchWAVSizes updates the RIFF and data sizes of a WAV file so that it is valid
+// at any point, even if the process is killed.
+func patchWAVSizes(f *os.File, dataSize int64) {
+	if dataSize > math.MaxUint32-wavHeaderSize {
+		return
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(dataSize+wavHeaderSize-8))
+	f.WriteAt(b[:], 4)
+	binary.LittleEndian.PutUint32(b[:], uint32(dataSize))
+	f.WriteAt(b[:], wavHeaderSize-4)
+}
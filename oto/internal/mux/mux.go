@@ -33,6 +33,7 @@ const (
 	FormatFloat32LE Format = iota
 	FormatUnsignedInt8
 	FormatSignedInt16LE
+	FormatSignedInt24LE
 )
 
 func (f Format) ByteLength() int {
@@ -43,6 +44,8 @@ func (f Format) ByteLength() int {
 		return 1
 	case FormatSignedInt16LE:
 		return 2
+	case FormatSignedInt24LE:
+		return 3
 	}
 	panic(fmt.Sprintf("mux: unexpected format: %d", f))
 }
@@ -488,6 +491,9 @@ func (p *playerImpl) readBufferAndAdd(buf []float32) int {
 		case FormatSignedInt16LE:
 			v16 := int16(src[2*i]) | (int16(src[2*i+1]) << 8)
 			v = float32(v16) / (1 << 15)
+		case FormatSignedInt24LE:
+			v24 := int32(uint32(src[3*i])<<8|uint32(src[3*i+1])<<16|uint32(src[3*i+2])<<24) >> 8
+			v = float32(v24) / (1 << 23)
 		default:
 			panic(fmt.Sprintf("mux: unexpected format: %d", format))
 		}
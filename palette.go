@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/nfnt/resize"
+	"github.com/soniakeys/quant/median"
+)
+
+const (
+	// coverAnalysisSize is the size covers are reduced to before their palette is extracted;
+	// a hundred pixels a side find the same colors as the full image.
+	//
+	// coverAnalysisSize 是提取调色板之前封面缩小到的尺寸；边长一百像素即可得到与完整图片相同的颜色。
+	coverAnalysisSize = 100
+
+	// coverPaletteSize is how many colors median cut reduces a cover to.
+	//
+	// coverPaletteSize 是中位切分将封面缩减到的颜色数量。
+	coverPaletteSize = 8
+
+	// minAccentSaturation is how saturated a color must be to serve as an accent.
+	//
+	// minAccentSaturation 是颜色作为强调色所需的最低饱和度。
+	minAccentSaturation = 0.25
+
+	// minAccentHueDistance is how far, in degrees, the secondary accent's hue must be from the
+	// primary's.
+	//
+	// minAccentHueDistance 是次强调色与主强调色之间色相的最小距离（度）。
+	minAccentHueDistance = 30
+)
+
+// WCAG contrast ratios required against the terminal background: text must reach 4.5:1,
+// large or decorative elements such as the progress bar 3:1.
+//
+// 相对于终端背景所需的 WCAG 对比度：文字须达到 4.5:1，进度条等大型或装饰性元素须达到 3:1。
+const (
+	contrastText       = 4.5
+	contrastDecorative = 3.0
+)
+
+// coverPalette is the colors extracted from a cover. Primary and Secondary are accents for
+// text and are nil if the cover has no saturated colors; Background is its dominant color.
+// They are the cover's own colors, before they are adjusted to the terminal background.
+//
+// coverPalette 是从封面中提取的颜色。Primary 和 Secondary 是用于文字的强调色，封面没有饱和的
+// 颜色时为 nil；Background 是封面的主色。它们是封面本身的颜色，尚未根据终端背景调整。
+type coverPalette struct {
+	Primary    *[3]int `json:"primary"`
+	Secondary  *[3]int `json:"secondary"`
+	Background *[3]int `json:"background"`
+}
+
+// uiPalette is a cover palette adjusted to the terminal background, as the pages draw it.
+//
+// uiPalette 是根据终端背景调整后、供各页面绘制使用的封面调色板。
+type uiPalette struct {
+	Primary      [3]int // Titles, the playing song and the played part of the progress bar. / 标题、正在播放的歌曲和进度条已播放部分。
+	Secondary    [3]int // The unplayed part of the progress bar. / 进度条未播放部分。
+	Background   [3]int // Background of highlighted rows. / 高亮行的背景。
+	OnBackground [3]int // Text of highlighted rows. / 高亮行的文字。
+}
+
+// extractCoverPalette reduces a cover to a few colors with median cut and picks its accents
+// among them: the primary is the most common saturated color, the secondary the next one of
+// a clearly different hue.
+//
+// extractCoverPalette 使用中位切分将封面缩减为少量颜色，并从中选出强调色：主强调色是最常见的
+// 饱和颜色，次强调色是色相明显不同的下一个颜色。
+func extractCoverPalette(img image.Image) coverPalette {
+	small := resize.Thumbnail(coverAnalysisSize, coverAnalysisSize, img, resize.NearestNeighbor)
+	paletted := median.Quantizer(coverPaletteSize).Paletted(small)
+
+	counts := make([]int, len(paletted.Palette))
+	for _, index := range paletted.Pix {
+		counts[index]++
+	}
+
+	type candidate struct {
+		rgb   [3]int
+		hue   float64
+		score float64
+	}
+	var palette coverPalette
+	var accents []candidate
+	dominant := -1
+	for i, c := range paletted.Palette {
+		if counts[i] == 0 {
+			continue
+		}
+		r, g, b, _ := c.RGBA()
+		rgb := [3]int{int(r >> 8), int(g >> 8), int(b >> 8)}
+		if dominant < 0 || counts[i] > counts[dominant] {
+			dominant = i
+			palette.Background = &rgb
+		}
+
+		hue, saturation, lightness := rgbToHSL(rgb)
+		if saturation < minAccentSaturation || lightness < 0.15 || lightness > 0.9 {
+			continue
+		}
+		// Vivid colors of middle lightness make the best accents.
+		// 中等亮度的鲜艳颜色最适合作为强调色。
+		score := float64(counts[i]) * saturation * (1 - math.Abs(lightness-0.5))
+		accents = append(accents, candidate{rgb: rgb, hue: hue, score: score})
+	}
+
+	var primary *candidate
+	for i := range accents {
+		if primary == nil || accents[i].score > primary.score {
+			primary = &accents[i]
+		}
+	}
+	if primary == nil {
+		return palette
+	}
+	palette.Primary = &primary.rgb
+
+	var secondary *candidate
+	for i := range accents {
+		if hueDistance(accents[i].hue, primary.hue) < minAccentHueDistance {
+			continue
+		}
+		if secondary == nil || accents[i].score > secondary.score {
+			secondary = &accents[i]
+		}
+	}
+	if secondary != nil {
+		palette.Secondary = &secondary.rgb
+	}
+	return palette
+}
+
+// resolve adjusts the palette to the terminal background so that every color keeps its WCAG
+// contrast: text colors are lightened or darkened as needed, and the highlight background is
+// a tint of the cover's dominant color close to the terminal background. Missing accents
+// fall back to the configured default color.
+//
+// resolve 根据终端背景调整调色板，使每种颜色保持其 WCAG 对比度：文字颜色会按需变亮或变暗，
+// 高亮背景是接近终端背景的封面主色调。缺少的强调色回退到配置的默认颜色。
+func (p coverPalette) resolve(terminalBg [3]int) uiPalette {
+	primary := [3]int{255, 255, 255}
+	if p.Primary != nil {
+		primary = *p.Primary
+	} else if GlobalConfig != nil {
+		primary = [3]int{GlobalConfig.App.DefaultColorR, GlobalConfig.App.DefaultColorG, GlobalConfig.App.DefaultColorB}
+	}
+	secondary := primary
+	if p.Secondary != nil {
+		secondary = *p.Secondary
+	}
+	background := primary
+	if p.Background != nil {
+		background = *p.Background
+	}
+
+	// The highlight stands out from the terminal background without competing with the text.
+	// 高亮背景与终端背景有所区分，但不会喧宾夺主。
+	highlight := background
+	for t := 0.9; t > 0 && contrastRatio(highlight, terminalBg) > 1.8; t -= 0.1 {
+		highlight = mixColor(terminalBg, background, t)
+	}
+	if contrastRatio(highlight, terminalBg) < 1.2 {
+		highlight = ensureContrast(highlight, terminalBg, 1.2)
+	}
+
+	ui := uiPalette{
+		Primary:    ensureContrast(primary, terminalBg, contrastText),
+		Secondary:  ensureContrast(secondary, terminalBg, contrastDecorative),
+		Background: highlight,
+	}
+	ui.OnBackground = ensureContrast(primary, highlight, contrastText)
+	return ui
+}
+
+// plainPalette is the palette used without a cover: white text, adjusted to the terminal
+// background.
+//
+// plainPalette 是没有封面时使用的调色板：白色文字，并根据终端背景调整。
+func plainPalette() uiPalette {
+	white := [3]int{255, 255, 255}
+	return coverPalette{Primary: &white}.resolve(terminalBackground())
+}
+
+// coverColors returns the palette of the current song's cover for the pages to draw with,
+// or false if cover colors are turned off or the cover is not loaded. It runs on the main
+// loop.
+//
+// coverColors 返回当前歌曲封面的调色板供各页面绘制使用；如果封面颜色已关闭或封面尚未加载，
+// 则返回 false。它在主循环中运行。
+func (a *App) coverColors() (uiPalette, bool) {
+	if playerPage, ok := a.pages[0].(*PlayerPage); !ok || !playerPage.useCoverColor {
+		return uiPalette{}, false
+	}
+	song := a.song
+	if song == nil || song.path != a.currentSongPath || !song.coverReady() || song.cover == nil {
+		return uiPalette{}, false
+	}
+	return song.palette.resolve(terminalBackground()), true
+}
+
+// ensureContrast returns c, moved toward white or black (whichever contrasts more with bg)
+// just enough to reach the given contrast ratio against bg.
+//
+// ensureContrast 返回 c，将其向白色或黑色（取与 bg 对比更强者）移动到恰好达到与 bg 的给定对比度。
+func ensureContrast(c, bg [3]int, ratio float64) [3]int {
+	if contrastRatio(c, bg) >= ratio {
+		return c
+	}
+	target := [3]int{255, 255, 255}
+	if contrastRatio(target, bg) < contrastRatio([3]int{}, bg) {
+		target = [3]int{}
+	}
+	for t := 0.05; t < 1; t += 0.05 {
+		if mixed := mixColor(c, target, t); contrastRatio(mixed, bg) >= ratio {
+			return mixed
+		}
+	}
+	return target
+}
+
+// mixColor returns the color a fraction t of the way from a to b.
+//
+// mixColor 返回从 a 到 b 按比例 t 插值得到的颜色。
+func mixColor(a, b [3]int, t float64) [3]int {
+	var mixed [3]int
+	for i := range mixed {
+		mixed[i] = int(math.Round(float64(a[i]) + (float64(b[i])-float64(a[i]))*t))
+	}
+	return mixed
+}
+
+// contrastRatio returns the WCAG contrast ratio of two colors, from 1 to 21.
+//
+// contrastRatio 返回两种颜色的 WCAG 对比度，范围为 1 到 21。
+func contrastRatio(a, b [3]int) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// relativeLuminance returns the WCAG relative luminance of an sRGB color.
+//
+// relativeLuminance 返回 sRGB 颜色的 WCAG 相对亮度。
+func relativeLuminance(c [3]int) float64 {
+	var linear [3]float64
+	for i, v := range c {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			linear[i] = s / 12.92
+		} else {
+			linear[i] = math.Pow((s+0.055)/1.055, 2.4)
+		}
+	}
+	return 0.2126*linear[0] + 0.7152*linear[1] + 0.0722*linear[2]
+}
+
+// rgbToHSL returns the hue in degrees and the saturation and lightness from 0 to 1.
+//
+// rgbToHSL 返回以度为单位的色相，以及 0 到 1 之间的饱和度和亮度。
+func rgbToHSL(c [3]int) (hue, saturation, lightness float64) {
+	r, g, b := float64(c[0])/255, float64(c[1])/255, float64(c[2])/255
+	maxC, minC := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	lightness = (maxC + minC) / 2
+	delta := maxC - minC
+	if delta == 0 {
+		return 0, 0, lightness
+	}
+	saturation = delta / (1 - math.Abs(2*lightness-1))
+
+	switch maxC {
+	case r:
+		hue = math.Mod((g-b)/delta, 6)
+	case g:
+		hue = (b-r)/delta + 2
+	default:
+		hue = (r-g)/delta + 4
+	}
+	hue *= 60
+	if hue < 0 {
+		hue += 360
+	}
+	return hue, saturation, lightness
+}
+
+// hueDistance returns the distance between two hues in degrees, from 0 to 180.
+//
+// hueDistance 返回两个色相之间的距离（度），范围为 0 到 180。
+func hueDistance(a, b float64) float64 {
+	d := math.Abs(a - b)
+	if d > 180 {
+		d = 360 - d
+	}
+	return d
+}
+
+// fgColor returns the escape sequence setting the text color, in the terminal's color depth.
+//
+// fgColor 返回以终端色深设置文字颜色的转义序列。
+func fgColor(c [3]int) string {
+	return blocksColor(color.RGBA{uint8(c[0]), uint8(c[1]), uint8(c[2]), 255}, detectColorDepth(), false)
+}
+
+// terminalBackground returns the background color reported by the terminal, or black if it
+// is unknown.
+//
+// terminalBackground 返回终端报告的背景颜色；未知时返回黑色。
+func terminalBackground() [3]int {
+	if terminalCaps != nil {
+		if bg, ok := parseHexColor(terminalCaps.Background); ok {
+			return bg
+		}
+	}
+	return [3]int{}
+}
+
+// parseHexColor parses a color written as #rrggbb.
+//
+// parseHexColor 解析写作 #rrggbb 的颜色。
+func parseHexColor(s string) ([3]int, bool) {
+	var c [3]int
+	s, ok := strings.CutPrefix(s, "#")
+	if !ok || len(s) != 6 {
+		return c, false
+	}
+	for i := range c {
+		v, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return c, false
+		}
+		c[i] = int(v)
+	}
+	return c, true
+}
+
+// hexColor formats a color as #rrggbb.
+//
+// hexColor 将颜色格式化为 #rrggbb。
+func hexColor(c [3]int) string {
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		a, b [3]int
+		want float64
+	}{
+		{[3]int{255, 255, 255}, [3]int{0, 0, 0}, 21},
+		{[3]int{0, 0, 0}, [3]int{255, 255, 255}, 21},
+		{[3]int{120, 30, 200}, [3]int{120, 30, 200}, 1},
+		{[3]int{118, 118, 118}, [3]int{255, 255, 255}, 4.54},
+		{[3]int{255, 0, 0}, [3]int{0, 0, 0}, 5.25},
+		{[3]int{0, 0, 255}, [3]int{255, 255, 255}, 8.59},
+	}
+	for _, tt := range tests {
+		if got := contrastRatio(tt.a, tt.b); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("contrastRatio(%v, %v) = %.3f; want %.2f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureContrast(t *testing.T) {
+	backgrounds := [][3]int{{0, 0, 0}, {255, 255, 255}, {40, 44, 52}, {253, 246, 227}, {128, 128, 128}}
+	colors := [][3]int{{0, 0, 0}, {255, 255, 255}, {100, 149, 237}, {200, 30, 30}, {250, 240, 200}, {20, 20, 30}}
+	for _, bg := range backgrounds {
+		for _, c := range colors {
+			for _, ratio := range []float64{contrastDecorative, contrastText} {
+				got := ensureContrast(c, bg, ratio)
+				if contrastRatio(c, bg) >= ratio {
+					if got != c {
+						t.Errorf("ensureContrast(%v, %v, %v) = %v; want it unchanged", c, bg, ratio, got)
+					}
+					continue
+				}
+				// A mid grey background cannot reach 4.5:1 with anything but black.
+				if best := math.Max(contrastRatio([3]int{}, bg), contrastRatio([3]int{255, 255, 255}, bg)); best < ratio {
+					continue
+				}
+				if r := contrastRatio(got, bg); r < ratio {
+					t.Errorf("ensureContrast(%v, %v, %v) = %v with ratio %.2f", c, bg, ratio, got, r)
+				}
+			}
+		}
+	}
+}
+
+func TestRGBToHSL(t *testing.T) {
+	tests := []struct {
+		c       [3]int
+		h, s, l float64
+	}{
+		{[3]int{0, 0, 0}, 0, 0, 0},
+		{[3]int{255, 255, 255}, 0, 0, 1},
+		{[3]int{128, 128, 128}, 0, 0, 0.502},
+		{[3]int{255, 0, 0}, 0, 1, 0.5},
+		{[3]int{0, 255, 0}, 120, 1, 0.5},
+		{[3]int{0, 0, 255}, 240, 1, 0.5},
+		{[3]int{255, 0, 128}, 329.9, 1, 0.5},
+		{[3]int{100, 149, 237}, 218.5, 0.792, 0.661},
+	}
+	for _, tt := range tests {
+		h, s, l := rgbToHSL(tt.c)
+		if math.Abs(h-tt.h) > 0.1 || math.Abs(s-tt.s) > 0.001 || math.Abs(l-tt.l) > 0.001 {
+			t.Errorf("rgbToHSL(%v) = %.1f, %.3f, %.3f; want %.1f, %.3f, %.3f", tt.c, h, s, l, tt.h, tt.s, tt.l)
+		}
+	}
+}
+
+func TestHueDistance(t *testing.T) {
+	tests := []struct {
+		a, b, want float64
+	}{
+		{0, 0, 0},
+		{10, 50, 40},
+		{50, 10, 40},
+		{350, 10, 20},
+		{0, 180, 180},
+		{90, 300, 150},
+	}
+	for _, tt := range tests {
+		if got := hueDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("hueDistance(%v, %v) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [3]int
+		ok   bool
+	}{
+		{"#000000", [3]int{0, 0, 0}, true},
+		{"#6495ed", [3]int{100, 149, 237}, true},
+		{"#FFFFFF", [3]int{255, 255, 255}, true},
+		{"6495ed", [3]int{}, false},
+		{"#6495e", [3]int{}, false},
+		{"#6495edd", [3]int{}, false},
+		{"#gg0000", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseHexColor(tt.in)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseHexColor(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+		if ok {
+			if back, _ := parseHexColor(hexColor(got)); back != got {
+				t.Errorf("parseHexColor(hexColor(%v)) = %v", got, back)
+			}
+		}
+	}
+}
+
+// stripedImage returns an image whose rows are filled with colors in the given proportions.
+func stripedImage(stripes ...struct {
+	c    color.RGBA
+	rows int
+}) image.Image {
+	height := 0
+	for _, s := range stripes {
+		height += s.rows
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 100, height))
+	y := 0
+	for _, s := range stripes {
+		for ; s.rows > 0; s.rows-- {
+			for x := 0; x < 100; x++ {
+				img.SetRGBA(x, y, s.c)
+			}
+			y++
+		}
+	}
+	return img
+}
+
+func TestExtractCoverPalette(t *testing.T) {
+	type stripe = struct {
+		c    color.RGBA
+		rows int
+	}
+	red := color.RGBA{200, 30, 30, 255}
+	orange := color.RGBA{210, 60, 20, 255}
+	blue := color.RGBA{30, 60, 200, 255}
+	dark := color.RGBA{15, 15, 20, 255}
+	grey := color.RGBA{128, 128, 128, 255}
+	white := color.RGBA{250, 250, 250, 255}
+
+	rgb := func(c color.RGBA) *[3]int { return &[3]int{int(c.R), int(c.G), int(c.B)} }
+	tests := []struct {
+		name                           string
+		img                            image.Image
+		primary, secondary, background *[3]int
+	}{
+		{"two accents", stripedImage(stripe{dark, 60}, stripe{red, 30}, stripe{blue, 10}), rgb(red), rgb(blue), rgb(dark)},
+		{"close hues", stripedImage(stripe{red, 70}, stripe{orange, 30}), rgb(red), nil, rgb(red)},
+		{"greyscale", stripedImage(stripe{grey, 50}, stripe{white, 30}, stripe{dark, 20}), nil, nil, rgb(grey)},
+	}
+	for _, tt := range tests {
+		got := extractCoverPalette(tt.img)
+		check := func(field string, got, want *[3]int) {
+			if (got == nil) != (want == nil) || (got != nil && !nearColor(*got, *want)) {
+				t.Errorf("%s: %s = %v; want %v", tt.name, field, deref(got), deref(want))
+			}
+		}
+		check("Primary", got.Primary, tt.primary)
+		check("Secondary", got.Secondary, tt.secondary)
+		check("Background", got.Background, tt.background)
+	}
+}
+
+func nearColor(a, b [3]int) bool {
+	for i := range a {
+		if d := a[i] - b[i]; d < -4 || d > 4 {
+			return false
+		}
+	}
+	return true
+}
+
+func deref(c *[3]int) any {
+	if c == nil {
+		return nil
+	}
+	return *c
+}
+
+func TestCoverPaletteResolve(t *testing.T) {
+	old := GlobalConfig
+	t.Cleanup(func() { GlobalConfig = old })
+	GlobalConfig = &Config{App: AppConfig{DefaultColorR: 100, DefaultColorG: 149, DefaultColorB: 237}}
+
+	palettes := []coverPalette{
+		{},
+		{Primary: &[3]int{30, 30, 120}, Secondary: &[3]int{240, 240, 100}, Background: &[3]int{10, 10, 10}},
+		{Primary: &[3]int{250, 250, 200}, Background: &[3]int{250, 250, 250}},
+	}
+	for _, bg := range [][3]int{{0, 0, 0}, {255, 255, 255}, {40, 44, 52}, {253, 246, 227}} {
+		for _, p := range palettes {
+			ui := p.resolve(bg)
+			if r := contrastRatio(ui.Primary, bg); r < contrastText {
+				t.Errorf("resolve(%v) on %v: Primary %v has contrast %.2f", p, bg, ui.Primary, r)
+			}
+			if r := contrastRatio(ui.Secondary, bg); r < contrastDecorative {
+				t.Errorf("resolve(%v) on %v: Secondary %v has contrast %.2f", p, bg, ui.Secondary, r)
+			}
+			if r := contrastRatio(ui.OnBackground, ui.Background); r < contrastText {
+				t.Errorf("resolve(%v) on %v: OnBackground %v has contrast %.2f on %v", p, bg, ui.OnBackground, r, ui.Background)
+			}
+			if r := contrastRatio(ui.Background, bg); r < 1.2 || r > 1.81 {
+				t.Errorf("resolve(%v) on %v: Background %v has contrast %.2f", p, bg, ui.Background, r)
+			}
+		}
+	}
+}
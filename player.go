@@ -1,18 +1,16 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"image"
 	_ "image/jpeg"
-	"image/png"
 	_ "image/png"
+	"io"
 	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"slices"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -20,11 +18,7 @@ import (
 	"github.com/dhowden/tag"
 	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/effects"
-	"github.com/gopxl/beep/v2/flac"
-	"github.com/gopxl/beep/v2/mp3"
 	"github.com/gopxl/beep/v2/speaker"
-	"github.com/gopxl/beep/v2/vorbis"
-	"github.com/gopxl/beep/v2/wav"
 	"github.com/mattn/go-runewidth"
 	"golang.org/x/term"
 
@@ -57,24 +51,32 @@ func max[T ~int | ~float64](a, b T) T {
 //
 // PlayerPage 保存音乐播放器视图的状态。
 type PlayerPage struct {
+	pageArea
+
 	app      *App
 	flacPath string
 
 	// UI state / UI状态
 	cellW, cellH                          int
 	imageTop, imageHeight, imageRightEdge int
-	coverColorR, coverColorG, coverColorB int
+	palette                               uiPalette // Colors of the cover as last drawn. / 最近一次绘制时封面的颜色。
 	useCoverColor                         bool
 	volumeDisplayTimer                    int
 	rateDisplayTimer                      int
 	textTooLongForWide                    bool       // True if text is too long for wide terminal mode. / 如果文本太长不适合宽终端模式则为true。
 	showTextInWideMode                    bool       // True if text can be shown below image in wide mode. / 如果可以在宽终端模式下在图片下方显示文本则为true。
 	overrideLayout                        LayoutType // Override layout (-1=none). / 覆盖布局（-1=无）。
+	layoutTemplate                        string     // Chosen layout template, "" for automatic. / 选择的布局模板，"" 表示自动。
 	lastLayoutSwitchTime                  time.Time  // Debounce for layout switching. / 布局切换防抖。
 	layoutShift                           int        // Vertical shift for layout centering. / 布局居中的垂直偏移。
 
 	// Debounce mechanism for song switching. / 切歌防抖机制。
 	lastSwitchTime time.Time
+
+	devicePicker *devicePicker // Output device overlay, nil when closed. / 输出设备浮层，关闭时为 nil。
+	visualizer   visualizer    // Spectrum of the visualizer widget. / 可视化部件的频谱。
+
+	waitingForCover *songInfo // Song drawn before its cover was loaded. / 在封面加载完成前绘制的歌曲。
 }
 
 // NewPlayerPage creates a new instance of the player page.
@@ -84,7 +86,7 @@ func NewPlayerPage(app *App, flacPath string, cellW, cellH int, overrideLayout i
 	return &PlayerPage{
 		app:            app,
 		flacPath:       flacPath,
-		useCoverColor:  true,
+		useCoverColor:  GlobalConfig.ActiveTheme.CoverColors,
 		cellW:          cellW,
 		cellH:          cellH,
 		overrideLayout: LayoutType(overrideLayout),
@@ -129,8 +131,16 @@ func (p *PlayerPage) UpdateSongWithRender(songPath string) {
 //
 // HandleKey 处理播放器页面的用户按键。
 func (p *PlayerPage) HandleKey(key rune) (Page, bool, error) {
+	if p.devicePicker != nil {
+		p.handleDevicePickerKey(key)
+		return nil, false, nil
+	}
+	if IsKey(key, GlobalConfig.Keymap.Player.SelectDevice) {
+		p.openDevicePicker()
+		return nil, false, nil
+	}
+
 	player := p.app.player
-	mprisServer := p.app.mprisServer
 	needsRedraw := true
 
 	if player == nil {
@@ -138,89 +148,29 @@ func (p *PlayerPage) HandleKey(key rune) (Page, bool, error) {
 	}
 
 	if IsKey(key, GlobalConfig.Keymap.Player.TogglePause) {
-		speaker.Lock()
-		player.ctrl.Paused = !player.ctrl.Paused
-		speaker.Unlock()
-		if mprisServer != nil {
-			mprisServer.UpdatePlaybackStatus(!player.ctrl.Paused)
-		}
+		p.app.SetPaused(!player.ctrl.Paused)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.SeekBackward) {
 		speaker.Lock()
 		newPos := player.streamer.Position() - player.sampleRate.N(time.Second*5)
-		if newPos < 0 {
-			newPos = 0
-		}
-		if err := player.streamer.Seek(newPos); err != nil {
-			// ignore seek errors
-		}
 		speaker.Unlock()
-		if mprisServer != nil {
-			mprisServer.UpdatePosition(p.currentPositionInMicroseconds())
-		}
+		p.app.SeekTo(newPos)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.SeekForward) {
 		speaker.Lock()
 		newPos := player.streamer.Position() + player.sampleRate.N(time.Second*5)
-		if newPos >= player.streamer.Len() {
-			newPos = player.streamer.Len() - 1
-		}
-		if err := player.streamer.Seek(newPos); err != nil {
-			// ignore seek errors
-		}
 		speaker.Unlock()
-		if mprisServer != nil {
-			mprisServer.UpdatePosition(p.currentPositionInMicroseconds())
-		}
+		p.app.SeekTo(newPos)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.VolumeDown) {
 		p.volumeDisplayTimer = 10
-		speaker.Lock()
-		p.app.linearVolume = max(p.app.linearVolume-0.05, 0.0)
-		p.app.volume = math.Log2(p.app.linearVolume)
-		if p.app.linearVolume == 0 {
-			p.app.volume = -10
-		}
-		player.volume.Volume = p.app.volume
-		speaker.Unlock()
-		p.app.SaveSettings()
-		if mprisServer != nil {
-			volume, _ := mprisServer.Get("org.mpris.MediaPlayer2.Player", "Volume")
-			mprisServer.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{"Volume": volume.Value()})
-		}
+		p.app.SetLinearVolume(p.app.linearVolume - 0.05)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.VolumeUp) {
 		p.volumeDisplayTimer = 10
-		speaker.Lock()
-		p.app.linearVolume = min(p.app.linearVolume+0.05, 1.0)
-		p.app.volume = math.Log2(p.app.linearVolume)
-		player.volume.Volume = p.app.volume
-		speaker.Unlock()
-		p.app.SaveSettings()
-		if mprisServer != nil {
-			volume, _ := mprisServer.Get("org.mpris.MediaPlayer2.Player", "Volume")
-			mprisServer.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{"Volume": volume.Value()})
-		}
+		p.app.SetLinearVolume(p.app.linearVolume + 0.05)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.RateDown) {
 		p.rateDisplayTimer = 10
-		speaker.Lock()
-		ratio := player.resampler.Ratio() - 0.05
-		player.resampler.SetRatio(min(max(ratio, 0.1), 4.0))
-		p.app.playbackRate = player.resampler.Ratio()
-		speaker.Unlock()
-		p.app.SaveSettings()
-		if mprisServer != nil {
-			rate, _ := mprisServer.Get("org.mpris.MediaPlayer2.Player", "Rate")
-			mprisServer.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{"Rate": rate.Value()})
-		}
+		p.app.SetPlaybackRate(p.app.playbackRate - 0.05)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.RateUp) {
 		p.rateDisplayTimer = 10
-		speaker.Lock()
-		ratio := player.resampler.Ratio() + 0.05
-		player.resampler.SetRatio(min(max(ratio, 0.1), 4.0))
-		p.app.playbackRate = player.resampler.Ratio()
-		speaker.Unlock()
-		p.app.SaveSettings()
-		if mprisServer != nil {
-			rate, _ := mprisServer.Get("org.mpris.MediaPlayer2.Player", "Rate")
-			mprisServer.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{"Rate": rate.Value()})
-		}
+		p.app.SetPlaybackRate(p.app.playbackRate + 0.05)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.PrevSong) {
 		p.playPreviousSong()
 	} else if IsKey(key, GlobalConfig.Keymap.Player.NextSong) {
@@ -229,38 +179,15 @@ func (p *PlayerPage) HandleKey(key rune) (Page, bool, error) {
 		// Disable play mode toggle in single song mode
 		// 在单曲播放模式下禁用播放模式切换
 		if !p.app.isSingleSongMode {
-			oldMode := p.app.playMode
-			p.app.playMode = (p.app.playMode + 1) % 3
-			if oldMode != 2 && p.app.playMode == 2 {
-				p.app.switchedToRandom = true
-			} else {
-				p.app.switchedToRandom = false
-			}
-			if err := SavePlayMode(p.app.playMode); err != nil {
-				l.Warnf("failed to save play mode: %v\n\n警告: 保存播放模式失败: %v", err, err)
-			}
+			p.app.SetPlayMode((p.app.playMode + 1) % 3)
 		}
 	} else if IsKey(key, GlobalConfig.Keymap.Player.ToggleTextColor) {
 		p.useCoverColor = !p.useCoverColor
 	} else if IsKey(key, GlobalConfig.Keymap.Player.Reset) {
 		p.volumeDisplayTimer = 10
 		p.rateDisplayTimer = 10
-		speaker.Lock()
-		p.app.linearVolume = 1.0
-		p.app.volume = 0
-		player.volume.Volume = 0
-		player.resampler.SetRatio(1.0)
-		p.app.playbackRate = 1.0
-		speaker.Unlock()
-		p.app.SaveSettings()
-		if mprisServer != nil {
-			volume, _ := mprisServer.Get("org.mpris.MediaPlayer2.Player", "Volume")
-			rate, _ := mprisServer.Get("org.mpris.MediaPlayer2.Player", "Rate")
-			mprisServer.sendPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]any{
-				"Volume": volume.Value(),
-				"Rate":   rate.Value(),
-			})
-		}
+		p.app.SetLinearVolume(1.0)
+		p.app.SetPlaybackRate(1.0)
 	} else if IsKey(key, GlobalConfig.Keymap.Player.ToggleLayout) {
 		p.cycleLayout()
 	} else {
@@ -288,10 +215,26 @@ func (p *PlayerPage) HandleSignal(sig os.Signal) error {
 //
 // View 将播放器UI渲染到屏幕上。
 func (p *PlayerPage) View() {
+	if p.devicePicker != nil {
+		p.renderDevicePicker()
+		return
+	}
 	if p.flacPath == "" {
 		p.displayEmptyState()
 		return
 	}
+	if p.area != nil {
+		p.renderTemplate(&nowPlayingPanel, *p.area)
+		p.updateStatus()
+		return
+	}
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		if t := p.activeTemplate(w, h); t != nil {
+			p.renderTemplate(t, terminalRect())
+			p.updateStatus()
+			return
+		}
+	}
 	p.renderWithLayout()
 	p.updateStatus()
 }
@@ -300,45 +243,62 @@ func (p *PlayerPage) View() {
 //
 // displayEmptyState 在播放列表为空时显示空状态。
 func (p *PlayerPage) displayEmptyState() {
-	w, h, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil {
-		w, h = 80, 24
+	r := p.viewArea()
+	w, h := r.w, r.h
+
+	p.clearArea()
+	// In the split view the pane's header names it, and only the pane was cleared, which
+	// leaves the last cover behind.
+	// 在分屏视图中窗格的标题已显示其名称，并且只清除了该窗格，上一张封面仍会留下。
+	if p.area == nil {
+		title := "Player"
+		titleX := (w - len(title)) / 2
+		fmt.Printf("\x1b[1;%dH%s%s\x1b[0m", titleX, p.app.titleStyle(), title)
+	} else {
+		deleteKittyPlacement()
 	}
 
-	fmt.Print("\x1b[2J\x1b[3J\x1b[H")
-
-	title := "Player"
-	titleX := (w - len(title)) / 2
-	fmt.Printf("\x1b[1;%dH\x1b[1m%s\x1b[0m", titleX, title)
-
-	msg := "PlayList is empty"
-	msg2 := "Add songs from the Library tab"
-	msgX := (w - len(msg)) / 2
-	msg2X := (w - len(msg2)) / 2
+	msg := runewidth.Truncate("PlayList is empty", w, "...")
+	msg2 := runewidth.Truncate("Add songs from the Library tab", w, "...")
+	msgX := max(1, (w-len(msg))/2)
+	msg2X := max(1, (w-len(msg2))/2)
 	centerRow := h / 2
 
-	fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", centerRow-1, msgX, msg)
-	fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", centerRow+1, msg2X, msg2)
+	footerStyle := GlobalConfig.ActiveTheme.Footer.sgr()
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(centerRow-1, msgX), footerStyle, msg)
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(centerRow+1, msg2X), footerStyle, msg2)
 
 	footer := ""
 	footerX := (w - len(footer)) / 2
-	fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", h, footerX, footer)
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(h, footerX), footerStyle, footer)
 }
 
 // cycleLayout cycles through available layout overrides based on current layout.
 // Wide mode: switch-narrow -> switch-text -> switch-image -> auto
 // Narrow mode: switch-text -> switch-image -> auto
+// When layout templates fit the terminal, it cycles through them instead.
+// The compact player of the split view has a single layout.
 //
 // cycleLayout 根据当前布局循环切换可用的布局覆盖。
 // 宽模式：切换窄屏 -> 切换纯文本 -> 切换纯封面 -> 自动
 // 窄模式：切换纯文本 -> 切换纯封面 -> 自动
+// 有适合终端的布局模板时，改为在这些模板之间循环切换。
+// 分屏视图中的紧凑播放器只有一种布局。
 func (p *PlayerPage) cycleLayout() {
+	if p.area != nil {
+		return
+	}
 	if time.Since(p.lastLayoutSwitchTime) < time.Duration(GlobalConfig.App.LayoutDebounceMs)*time.Millisecond {
 		return
 	}
 	p.lastLayoutSwitchTime = time.Now()
 
 	w, h, _ := term.GetSize(int(os.Stdout.Fd()))
+	if p.cycleTemplate(w, h) {
+		p.showLayoutIndicator()
+		p.View()
+		return
+	}
 	isWideTerminal := w >= 100 && (float64(w)/float64(h) > 2.0 || h < 20)
 
 	var nextLayout LayoutType
@@ -382,7 +342,7 @@ func (p *PlayerPage) showLayoutIndicator() {
 		w, h = 80, 24
 	}
 
-	fmt.Print("\x1b[2J\x1b[3J\x1b[H")
+	clearScreen()
 
 	var layoutStr string
 	switch p.overrideLayout {
@@ -397,10 +357,16 @@ func (p *PlayerPage) showLayoutIndicator() {
 	default:
 		layoutStr = "auto"
 	}
+	if p.activeTemplate(w, h) != nil {
+		layoutStr = "auto"
+		if p.layoutTemplate != "" {
+			layoutStr = p.layoutTemplate
+		}
+	}
 
 	msgX := (w - len(layoutStr)) / 2
 	centerRow := h / 2
-	fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", centerRow, msgX, layoutStr)
+	fmt.Printf("\x1b[%d;%dH%s%s\x1b[0m", centerRow, msgX, GlobalConfig.ActiveTheme.Footer.sgr(), layoutStr)
 
 	time.Sleep(500 * time.Millisecond)
 	p.lastLayoutSwitchTime = time.Now()
@@ -423,21 +389,6 @@ func (p *PlayerPage) Tick() {
 
 	p.updateStatus()
 	p.checkSongEndAndHandleNext()
-
-	if p.app.mprisServer != nil {
-		p.app.mprisServer.UpdatePosition(p.currentPositionInMicroseconds())
-	}
-}
-
-// currentPositionInMicroseconds is a helper to get the player position for MPRIS.
-//
-// currentPositionInMicroseconds 是一个辅助函数，用于为MPRIS获取播放器位置。
-func (p *PlayerPage) currentPositionInMicroseconds() int64 {
-	if p.app.player == nil {
-		return 0
-	}
-	pos := p.app.player.streamer.Position()
-	return int64(float64(pos) / float64(p.app.player.sampleRate) * 1e6)
 }
 
 // checkSongEndAndHandleNext checks if the song has ended and handles the next song according to the play mode.
@@ -538,13 +489,13 @@ func (p *PlayerPage) tryPlayNextSong(currentIndex, nextIndex int) {
 		err := p.app.PlaySongWithSwitchAndRender(nextSong, true, true)
 		if err == nil {
 			if len(p.app.Playlist) > 1 {
-				title, artist, _ := getSongMetadata(nextSong)
-				coverPath := saveCoverArt(nextSong)
-				sendNotification(artist, title, coverPath)
+				p.app.notifySong()
 			}
 			return
 		}
-		p.app.MarkFileAsCorrupted(nextSong)
+		if isCorruptionError(err) {
+			p.app.MarkFileAsCorrupted(nextSong)
+		}
 
 		nextIndex = (nextIndex + 1) % len(p.app.Playlist)
 
@@ -635,13 +586,13 @@ func (p *PlayerPage) tryPlayPreviousSong(currentIndex, prevIndex int) {
 		err := p.app.PlaySongWithSwitchAndRender(prevSong, true, true)
 		if err == nil {
 			if len(p.app.Playlist) > 1 {
-				title, artist, _ := getSongMetadata(prevSong)
-				coverPath := saveCoverArt(prevSong)
-				sendNotification(artist, title, coverPath)
+				p.app.notifySong()
 			}
 			return
 		}
-		p.app.MarkFileAsCorrupted(prevSong)
+		if isCorruptionError(err) {
+			p.app.MarkFileAsCorrupted(prevSong)
+		}
 
 		if prevIndex == 0 {
 			prevIndex = len(p.app.Playlist) - 1
@@ -807,6 +758,13 @@ func (p *PlayerPage) isSongInPlaylist(songPath string) bool {
 //
 // playSongFromHistory 从历史记录中播放歌曲，而不添加新的历史记录条目。
 func (p *PlayerPage) playSongFromHistory(songPath string, switchToPlayer bool) error {
+	// The split view already shows the player, so it is redrawn instead.
+	// 分屏视图已经显示了播放器，因此改为重新绘制分屏视图。
+	split, inSplit := p.app.splitView()
+	if inSplit {
+		switchToPlayer = false
+	}
+
 	if p.app.currentSongPath == songPath && p.app.player != nil {
 		if switchToPlayer {
 			p.app.switchToPage(0)
@@ -814,52 +772,12 @@ func (p *PlayerPage) playSongFromHistory(songPath string, switchToPlayer bool) e
 		return nil
 	}
 
-	speaker.Lock()
-	if p.app.player != nil {
-		p.app.player.ctrl.Paused = true
+	if err := p.app.startSong(songPath); err != nil {
+		return err
 	}
-	speaker.Unlock()
-
-	streamer, format, err := decodeAudioFile(songPath)
-	if err != nil {
-		p.app.MarkFileAsCorrupted(songPath)
-		return fmt.Errorf("Failed to decode audio: %v\n\n解码音频失败: %v", err, err)
-	}
-
-	if err := speaker.ReInit(format.SampleRate, format.SampleRate.N(time.Second/30)); err != nil {
-		streamer.Close()
-		return fmt.Errorf("Failed to reinit speaker: %v\n\n重新初始化扬声器失败: %v", err, err)
-	}
-
-	audioStream := streamer
-
-	player, err := newAudioPlayer(audioStream, format, p.app.volume, p.app.playbackRate)
-	if err != nil {
-		streamer.Close()
-		return fmt.Errorf("Failed to create player: %v\n\n创建播放器失败: %v", err, err)
-	}
-
-	if p.app.mprisServer != nil {
-		p.app.mprisServer.StopService()
-	}
-	mprisServer, err := NewMPRISServer(p.app, player, songPath)
-	if err == nil {
-		if err := mprisServer.Start(); err == nil {
-			mprisServer.StartUpdateLoop()
-			mprisServer.UpdatePlaybackStatus(true)
-			mprisServer.UpdateMetadata()
-		}
-	}
-
-	speaker.Lock()
-	p.app.player = player
-	p.app.mprisServer = mprisServer
-	speaker.Unlock()
 
 	p.app.setCurrentSong(songPath)
 
-	speaker.Play(p.app.player.volume)
-
 	// Reset cover image position and dimensions
 	// 重置封面图片位置和尺寸
 	p.imageTop = 0
@@ -868,19 +786,20 @@ func (p *PlayerPage) playSongFromHistory(songPath string, switchToPlayer bool) e
 
 	if switchToPlayer {
 		p.UpdateSong(songPath)
-		fmt.Print("\x1b[2J\x1b[3J\x1b[H")
+		clearScreen()
 		p.app.currentPageIndex = 0
 		p.View()
 	} else {
 		// When not switching to player page, just update the song path without rendering
 		// 当不切换到播放器页面时，只更新歌曲路径而不渲染
 		p.UpdateSong(songPath)
+		if inSplit {
+			split.View()
+		}
 	}
 
 	if len(p.app.Playlist) > 1 {
-		title, artist, _ := getSongMetadata(songPath)
-		coverPath := saveCoverArt(songPath)
-		sendNotification(artist, title, coverPath)
+		p.app.notifySong()
 	}
 
 	return nil
@@ -913,15 +832,25 @@ func (p *PlayerPage) playNextInRandomMode() {
 
 type audioPlayer struct {
 	sampleRate beep.SampleRate
-	streamer   beep.StreamSeeker
+	streamer   beep.StreamSeekCloser
 	ctrl       *beep.Ctrl
 	resampler  *beep.Resampler
 	volume     *effects.Volume
 	position   int
 	initialVol float64
+	path       string
+	format     beep.Format
+	container  string // Format detected from the content of the file. / 根据文件内容检测到的格式。
+	layout     beep.ChannelMask
+	bitPerfect bool
+	output     *playerOutput // The streamer played by the speaker. / 扬声器播放的流。
+
+	source         *readAheadFile    // Read-ahead of the song file, nil if disabled. / 歌曲文件的预读，禁用时为 nil。
+	decoder        backgroundDecoder // Decoder running ffmpeg, nil otherwise. / 运行 ffmpeg 的解码器，否则为 nil。
+	bytesPerSample float64           // Average size of a sample in the file. / 文件中每个采样的平均大小。
 }
 
-func newAudioPlayer(streamer beep.StreamSeeker, format beep.Format, volumeLevel float64, playbackRate float64) (*audioPlayer, error) {
+func newAudioPlayer(path string, streamer beep.StreamSeekCloser, format beep.Format, volumeLevel float64, playbackRate float64) (*audioPlayer, error) {
 	loopStreamer, err := beep.Loop2(streamer)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create loop streamer: %v\n\n创建循环流失败: %v", err, err)
@@ -931,71 +860,39 @@ func newAudioPlayer(streamer beep.StreamSeeker, format beep.Format, volumeLevel
 	volume := &effects.Volume{Streamer: resampler, Base: 2}
 	volume.Volume = volumeLevel
 	resampler.SetRatio(playbackRate)
-	return &audioPlayer{format.SampleRate, streamer, ctrl, resampler, volume, 0, 0}, nil
-}
-
-// saveCoverArt extracts the cover art from an audio file and saves it to a temporary file.
-// If the audio file has no cover, it tries to find an image in the same folder (if enabled in config).
-// If no folder image is found, it uses the default cover image.
-// It returns the path to the temporary file.
-func saveCoverArt(audioPath string) string {
-	coverImg := getCoverFromAudioFile(audioPath)
-
-	if coverImg == nil && GlobalConfig != nil && GlobalConfig.App.EnableFolderCovers {
-		coverImg = getFolderCoverImage(audioPath)
-	}
-
-	if coverImg == nil {
-		defaultCoverPath := getDefaultCoverPath()
-		if defaultCoverPath != "" {
-			if img, err := loadImageFile(defaultCoverPath); err == nil {
-				coverImg = img
-			}
-		}
-	}
-
-	if coverImg == nil {
-		return ""
-	}
-
-	tempFile, err := os.CreateTemp("", "bm-cover-*.png")
-	if err != nil {
-		return ""
-	}
-	defer tempFile.Close()
-
-	err = png.Encode(tempFile, coverImg)
-	if err != nil {
-		return ""
-	}
-
-	return tempFile.Name()
+	player := &audioPlayer{
+		sampleRate: format.SampleRate,
+		streamer:   streamer,
+		ctrl:       ctrl,
+		resampler:  resampler,
+		volume:     volume,
+		path:       path,
+		format:     format,
+		layout:     sourceLayout(streamer, format),
+		bitPerfect: GlobalConfig != nil && GlobalConfig.App.BitPerfect,
+	}
+	player.output = &playerOutput{player: player}
+	return player, nil
 }
 
 // --- TUI / Drawing ---
 // All drawing functions are now methods on PlayerPage to access state.
 
 // refreshCellSize updates the player's cell pixel dimensions using the TIOCGWINSZ ioctl.
-// This accounts for DPI changes when the terminal is moved between displays.
+// This accounts for DPI changes when the terminal is moved between displays. Inside tmux
+// the ioctl describes the pane; if tmux does not report pixels, it is asked for the cell
+// size of the outer terminal instead.
 //
 // refreshCellSize 使用 TIOCGWINSZ ioctl 更新播放器的字符单元格像素尺寸。
-// 这可以应对终端在显示器之间移动时 DPI 变化的情况。
+// 这可以应对终端在显示器之间移动时 DPI 变化的情况。在 tmux 中该 ioctl 描述的是窗格；
+// 如果 tmux 不报告像素尺寸，则改为向 tmux 查询外层终端的单元格尺寸。
 func (p *PlayerPage) refreshCellSize() {
-	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
-	if err != nil {
-		return
-	}
-
-	w, h, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil {
-		return
-	}
-
-	if w > 0 && ws.Xpixel > 0 {
-		p.cellW = int(ws.Xpixel) / w
-	}
-	if h > 0 && ws.Ypixel > 0 {
-		p.cellH = int(ws.Ypixel) / h
+	if w, h, ok := winsizeCellSize(); ok {
+		p.cellW, p.cellH = w, h
+	} else if detectMultiplexer() == muxTmux {
+		if w, h, err := tmuxCellSize(); err == nil {
+			p.cellW, p.cellH = w, h
+		}
 	}
 	if p.cellW == 0 {
 		p.cellW = 1
@@ -1005,8 +902,27 @@ func (p *PlayerPage) refreshCellSize() {
 	}
 }
 
+// winsizeCellSize returns the cell size from the pixel and cell sizes of the terminal (or
+// multiplexer pane) reported by the TIOCGWINSZ ioctl, if it reports pixels.
+//
+// winsizeCellSize 根据 TIOCGWINSZ ioctl 报告的终端（或复用器窗格）像素尺寸和单元格数量
+// 计算单元格尺寸（如果报告了像素尺寸）。
+func winsizeCellSize() (width, height int, ok bool) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 0, 0, false
+	}
+	width = int(ws.Xpixel) / int(ws.Col)
+	height = int(ws.Ypixel) / int(ws.Row)
+	return width, height, width > 0 && height > 0
+}
+
 func (p *PlayerPage) updateStatus() {
-	if p.app.currentPageIndex != 0 || p.flacPath == "" {
+	if !p.app.pageShown(p) || p.flacPath == "" || p.devicePicker != nil {
+		return
+	}
+	if p.area != nil {
+		p.updateTemplateText(&nowPlayingPanel, *p.area)
 		return
 	}
 
@@ -1015,8 +931,15 @@ func (p *PlayerPage) updateStatus() {
 		return
 	}
 
-	title, artist, album := getSongMetadata(p.flacPath)
-	maxTextLength := max(max(len(title), len(artist)), len(album))
+	if t := p.activeTemplate(w, h); t != nil {
+		p.updateTemplateText(t, terminalRect())
+		return
+	}
+
+	maxTextLength := 0
+	for _, line := range p.statusLines() {
+		maxTextLength = max(maxTextLength, len(line))
+	}
 
 	showNothing := w < 23 || h < 5
 	if showNothing {
@@ -1078,12 +1001,6 @@ func (p *PlayerPage) updateRightPanel(w int) {
 		return
 	}
 
-	title, artist, album := getSongMetadata(p.flacPath)
-
-	titleWidth := runewidth.StringWidth(title)
-	artistWidth := runewidth.StringWidth(artist)
-	albumWidth := runewidth.StringWidth(album)
-
 	availableWidth := w - p.imageRightEdge
 	centerCol := p.imageRightEdge + availableWidth/2
 
@@ -1104,9 +1021,7 @@ func (p *PlayerPage) updateRightPanel(w int) {
 	}
 
 	colorCode := p.getColorCode()
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s\x1b[1m%s\x1b[0m", titleRow, centerCol-titleWidth/2, colorCode, title)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", artistRow, centerCol-artistWidth/2, colorCode, artist)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", albumRow, centerCol-albumWidth/2, colorCode, album)
+	p.drawStatusLines(titleRow, centerCol, colorCode)
 
 	progressBarStartCol := p.imageRightEdge + 5
 	progressBarWidth := w - progressBarStartCol - 2
@@ -1118,7 +1033,6 @@ func (p *PlayerPage) updateRightPanel(w int) {
 }
 
 func (p *PlayerPage) updateBottomStatus(startRow, w, h int) {
-	title, artist, album := getSongMetadata(p.flacPath)
 	availableRows := h - startRow
 	var infoRow, progressRow int
 	if p.layoutShift > 0 {
@@ -1131,13 +1045,8 @@ func (p *PlayerPage) updateBottomStatus(startRow, w, h int) {
 	centerCol := w / 2
 
 	colorCode := p.getColorCode()
-	titleWidth := runewidth.StringWidth(title)
-	artistWidth := runewidth.StringWidth(artist)
-	albumWidth := runewidth.StringWidth(album)
 
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s\x1b[1m%s\x1b[0m", infoRow, centerCol-titleWidth/2, colorCode, title)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", infoRow+1, centerCol-artistWidth/2, colorCode, artist)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", infoRow+2, centerCol-albumWidth/2, colorCode, album)
+	p.drawStatusLines(infoRow, centerCol, colorCode)
 
 	progressBarStartCol := 5
 	progressBarWidth := w - 10
@@ -1156,8 +1065,6 @@ func (p *PlayerPage) updateBottomStatus(startRow, w, h int) {
 // 限制图片和信息之间、专辑和进度条之间的最大间隔为5行。
 // 内容垂直居中。
 func (p *PlayerPage) updateSwitchNarrowMode(imageBottomRow, w, h int) {
-	title, artist, album := getSongMetadata(p.flacPath)
-
 	colorCode := p.getColorCode()
 
 	virtualWidth := 80
@@ -1177,13 +1084,8 @@ func (p *PlayerPage) updateSwitchNarrowMode(imageBottomRow, w, h int) {
 	}
 
 	centerCol := offset + virtualWidth/2
-	titleWidth := runewidth.StringWidth(title)
-	artistWidth := runewidth.StringWidth(artist)
-	albumWidth := runewidth.StringWidth(album)
 
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s\x1b[1m%s\x1b[0m", infoRow, centerCol-titleWidth/2, colorCode, title)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", infoRow+1, centerCol-artistWidth/2, colorCode, artist)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", infoRow+2, centerCol-albumWidth/2, colorCode, album)
+	p.drawStatusLines(infoRow, centerCol, colorCode)
 
 	progressBarStartCol := offset + 5
 	progressBarWidth := virtualWidth - 10
@@ -1195,17 +1097,11 @@ func (p *PlayerPage) updateSwitchNarrowMode(imageBottomRow, w, h int) {
 }
 
 func (p *PlayerPage) updateTextOnlyMode(w, h int) {
-	title, artist, album := getSongMetadata(p.flacPath)
 	centerRow, centerCol := h/2, w/2
 
 	colorCode := p.getColorCode()
-	titleWidth := runewidth.StringWidth(title)
-	artistWidth := runewidth.StringWidth(artist)
-	albumWidth := runewidth.StringWidth(album)
 
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s\x1b[1m%s\x1b[0m", centerRow-1, centerCol-titleWidth/2, colorCode, title)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", centerRow, centerCol-artistWidth/2, colorCode, artist)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", centerRow+1, centerCol-albumWidth/2, colorCode, album)
+	p.drawStatusLines(centerRow-1, centerCol, colorCode)
 
 	progressBarStartCol := 5
 	progressBarWidth := w - 10
@@ -1221,18 +1117,12 @@ func (p *PlayerPage) updateTextOnlyMode(w, h int) {
 //
 // updateSwitchTextMode 为切换布局渲染居中的文本和进度条。
 func (p *PlayerPage) updateSwitchTextMode(w, h int) {
-	title, artist, album := getSongMetadata(p.flacPath)
 	centerRow, centerCol := h/2, w/2
 
 	colorCode := p.getColorCode()
-	titleWidth := runewidth.StringWidth(title)
-	artistWidth := runewidth.StringWidth(artist)
-	albumWidth := runewidth.StringWidth(album)
 
 	infoRow := centerRow - 1
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s\x1b[1m%s\x1b[0m", infoRow, centerCol-titleWidth/2, colorCode, title)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", infoRow+1, centerCol-artistWidth/2, colorCode, artist)
-	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", infoRow+2, centerCol-albumWidth/2, colorCode, album)
+	p.drawStatusLines(infoRow, centerCol, colorCode)
 
 	isWideTerminal := w >= 100 && (float64(w)/float64(h) > 2.0 || h < 20)
 	var progressBarStartCol, progressBarWidth int
@@ -1251,6 +1141,27 @@ func (p *PlayerPage) updateSwitchTextMode(w, h int) {
 	p.drawProgressBar(progressRow, progressBarStartCol, progressBarWidth, colorCode)
 }
 
+// drawStatusLines draws the lines of the text area centered on centerCol, from row on. The
+// first line is bold, like the title shown by default.
+//
+// drawStatusLines 从 row 行开始，以 centerCol 为中心绘制文本区域的各行。
+// 第一行为粗体，与默认显示的标题一致。
+func (p *PlayerPage) drawStatusLines(row, centerCol int, colorCode string) {
+	lines := p.statusLines()
+	for i := range 3 {
+		line := ""
+		if i < len(lines) {
+			line = lines[i]
+		}
+		bold := ""
+		if i == 0 {
+			bold = "\x1b[1m"
+		}
+		width := runewidth.StringWidth(line)
+		fmt.Printf("\x1b[%d;%dH\x1b[K%s%s%s\x1b[0m", row+i, centerCol-width/2, colorCode, bold, line)
+	}
+}
+
 func (p *PlayerPage) drawProgressBar(row, startCol, width int, colorCode string) {
 	// 检查player是否可用
 	if p.app.player == nil {
@@ -1268,6 +1179,12 @@ func (p *PlayerPage) drawProgressBar(row, startCol, width int, colorCode string)
 			fmt.Printf("\x1b[%d;%dH%s%s\x1b[0m", indicatorRow, startCol, colorCode, volStr)
 		}
 
+		if p.app.isBuffering() {
+			bufStr := "buffering..."
+			bufCol := startCol + (width-runewidth.StringWidth(bufStr))/2
+			fmt.Printf("\x1b[%d;%dH%s%s\x1b[0m", indicatorRow, max(bufCol, startCol), GlobalConfig.ActiveTheme.Footer.sgr(), bufStr)
+		}
+
 		if p.rateDisplayTimer > 0 {
 			rateVal := p.app.player.resampler.Ratio()
 			rateStr := fmt.Sprintf("%.2fx", rateVal)
@@ -1280,18 +1197,6 @@ func (p *PlayerPage) drawProgressBar(row, startCol, width int, colorCode string)
 		}
 	}
 
-	currentPos := p.app.player.streamer.Position()
-	totalLen := p.app.player.streamer.Len()
-	progress := 0.0
-	if totalLen > 0 {
-		progress = float64(currentPos) / float64(totalLen)
-		if totalLen-currentPos <= p.app.player.sampleRate.N(time.Second) {
-			progress = 1.0
-		}
-	}
-
-	playedChars := int(float64(width) * progress)
-
 	icons := GlobalConfig.ActiveIcons
 
 	icon := icons.Pause
@@ -1309,25 +1214,51 @@ func (p *PlayerPage) drawProgressBar(row, startCol, width int, colorCode string)
 
 	fmt.Printf("\x1b[%d;%dH\x1b[K%s%s", row, startCol-2, colorCode, icon)
 
+	fmt.Printf("\x1b[0m\x1b[%d;%dH%s", row, startCol, p.progressBar(width))
+	fmt.Printf("\x1b[0m\x1b[%d;%dH\x1b[K%s%s\x1b[0m", row, startCol+width+1, colorCode, modeIcon)
+
+	// --- Source and output format ---
+	if _, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil && row+1 <= h {
+		formatStr := runewidth.Truncate(p.app.player.formatLine(), width, "...")
+		formatCol := startCol + (width-runewidth.StringWidth(formatStr))/2
+		fmt.Printf("\x1b[%d;%dH\x1b[K%s%s\x1b[0m", row+1, formatCol, GlobalConfig.ActiveTheme.Footer.sgr(), formatStr)
+	}
+}
+
+// progressBar returns the progress bar of the current song, the given number of cells wide.
+//
+// progressBar 返回当前歌曲的进度条，宽度为给定的单元格数。
+func (p *PlayerPage) progressBar(width int) string {
+	currentPos := p.app.player.streamer.Position()
+	totalLen := p.app.player.streamer.Len()
+	progress := 0.0
+	if totalLen > 0 {
+		progress = float64(currentPos) / float64(totalLen)
+		if totalLen-currentPos <= p.app.player.sampleRate.N(time.Second) {
+			progress = 1.0
+		}
+	}
+	playedChars := int(float64(width) * progress)
+
+	icons := GlobalConfig.ActiveIcons
+	playedStyle, unplayedStyle := p.app.progressStyles()
 	var bar strings.Builder
 	if playedChars > 0 {
-		bar.WriteString(colorCode)
+		bar.WriteString(playedStyle)
 		for range playedChars {
 			bar.WriteString(icons.ProgressFilled)
 		}
 	}
-	bar.WriteString(colorCode)
+	bar.WriteString("\x1b[0m" + unplayedStyle)
 	for i := playedChars; i < width; i++ {
 		bar.WriteString(icons.ProgressEmpty)
 	}
-
-	fmt.Printf("\x1b[0m\x1b[%d;%dH%s", row, startCol, bar.String())
-	fmt.Printf("\x1b[0m\x1b[%d;%dH\x1b[K%s%s\x1b[0m", row, startCol+width+1, colorCode, modeIcon)
+	return bar.String()
 }
 
 func (p *PlayerPage) getColorCode() string {
 	if p.useCoverColor {
-		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", p.coverColorR, p.coverColorG, p.coverColorB)
+		return fgColor(p.palette.Primary)
 	}
 	return "\x1b[37m"
 }
@@ -1365,58 +1296,85 @@ func parseMetadataFromFilename(filePath string) (title, artist, album string) {
 	return title, artist, ""
 }
 
-func getCellSize() (width, height int, err error) {
-	// This function remains unchanged.
-	fmt.Print("\x1b[16t")
-	var buf []byte
-	var b [1]byte
-	for {
-		n, err := os.Stdin.Read(b[:])
-		if err != nil {
-			return 0, 0, err
-		}
-		if n == 0 {
-			continue
+// getCellSize returns the pixel size of a terminal cell. Inside a multiplexer the pane's
+// window size or tmux itself is asked first; otherwise the terminal's answer to the startup
+// probe is used. Since not every terminal answers (e.g. the Linux console), a default size
+// is used if nothing is known.
+//
+// getCellSize 返回终端单元格的像素尺寸。在复用器中会先查询窗格的窗口尺寸或 tmux 本身；
+// 否则使用终端对启动探测的应答。由于并非所有终端都会应答（例如 Linux 控制台），
+// 一无所知时使用默认尺寸。
+func getCellSize() (width, height int) {
+	mux := detectMultiplexer()
+	if mux != muxNone {
+		if w, h, ok := winsizeCellSize(); ok {
+			return w, h
 		}
-		buf = append(buf, b[0])
-		if b[0] == 't' {
-			break
+		if mux == muxTmux {
+			if w, h, err := tmuxCellSize(); err == nil {
+				return w, h
+			}
 		}
 	}
-	if !(len(buf) > 2 && buf[0] == '\x1b' && buf[1] == '[' && buf[len(buf)-1] == 't') {
-		return 0, 0, fmt.Errorf("Unable to parse terminal response: %q\n\n无法解析的终端响应格式: %q", buf, buf)
-	}
-	content := buf[2 : len(buf)-1]
-	parts := bytes.Split(content, []byte(";"))
-	if len(parts) != 3 {
-		return 0, 0, fmt.Errorf("Expected 3 response segments, got %d: %q\n\n预期的响应分段为3, 实际为 %d: %q", len(parts), buf, len(parts), buf)
+	if terminalCaps != nil && terminalCaps.CellWidth > 0 && terminalCaps.CellHeight > 0 {
+		return terminalCaps.CellWidth, terminalCaps.CellHeight
 	}
-	if string(parts[0]) != "6" {
-		return 0, 0, fmt.Errorf("Expected response code 6, got %s\n\n预期的响应代码为 6, 实际为 %s", parts[0], parts[0])
-	}
-	h, err := strconv.Atoi(string(parts[1]))
-	if err != nil {
-		return 0, 0, err
+	if w, h, ok := winsizeCellSize(); ok {
+		return w, h
 	}
-	w, err := strconv.Atoi(string(parts[2]))
-	if err != nil {
-		return 0, 0, err
-	}
-	return w, h, nil
+	return 8, 16
 }
 
 func getSongMetadata(flacPath string) (title, artist, album string) {
-	f, err := os.Open(flacPath)
-	if err != nil {
+	var r io.ReadSeeker
+	if f, err := os.Open(cueAudioPath(flacPath)); err == nil {
+		defer f.Close()
+		r = f
+	}
+	title, artist, album, _, _ = readSongMetadata(r, flacPath)
+	return title, artist, album
+}
+
+// readSongMetadata reads the tags and the embedded cover of a song from r, its open audio
+// file, or nil if the file cannot be opened. Missing titles and artists are taken from the
+// file name.
+//
+// readSongMetadata 从 r（歌曲的已打开音频文件，无法打开时为 nil）读取歌曲的标签和内嵌封面。
+// 缺少的标题和艺术家从文件名中获取。
+func readSongMetadata(r io.ReadSeeker, flacPath string) (title, artist, album string, extra extraTags, pic *tag.Picture) {
+	// Cue tracks take their tags from the sheet and the rest from the audio file.
+	// cue 音轨的标签取自 cue 表，其余取自音频文件。
+	if track, ok := findCueTrack(flacPath); ok {
+		title, artist, album, extra, pic = readSongMetadata(r, cueAudioPath(flacPath))
+		title = track.Title
+		extra.track = track.Number
+		extra.lyrics = "" // They belong to the whole file. / 它们属于整个文件。
+		if track.Performer != "" {
+			artist = track.Performer
+		}
+		if track.Album != "" {
+			album = track.Album
+		}
+		return title, artist, album, extra, pic
+	}
+
+	if r == nil {
 		// Try to parse from filename as fallback
-		return parseMetadataFromFilename(flacPath)
+		title, artist, album = parseMetadataFromFilename(flacPath)
+		return title, artist, album, extra, nil
 	}
-	defer f.Close()
-	m, err := tag.ReadFrom(f)
+	m, err := tag.ReadFrom(r)
 	if err != nil {
-		return parseMetadataFromFilename(flacPath)
+		title, artist, album = parseMetadataFromFilename(flacPath)
+		return title, artist, album, extra, nil
+	}
+	title, artist, album, pic = m.Title(), m.Artist(), m.Album(), m.Picture()
+	extra.year, extra.genre = m.Year(), m.Genre()
+	extra.track, _ = m.Track()
+	extra.lyrics = m.Lyrics()
+	if extra.lyrics == "" {
+		extra.lyrics, _ = m.Raw()["unsyncedlyrics"].(string)
 	}
-	title, artist, album = m.Title(), m.Artist(), m.Album()
 
 	if title == "" || artist == "" {
 		filenameTitle, filenameArtist, filenameAlbum := parseMetadataFromFilename(flacPath)
@@ -1431,106 +1389,7 @@ func getSongMetadata(flacPath string) (title, artist, album string) {
 		}
 	}
 
-	if title == "" {
-		title = ""
-	}
-	if artist == "" {
-		artist = ""
-	}
-	if album == "" {
-		album = ""
-	}
-	return title, artist, album
-}
-
-func analyzeCoverColor(img image.Image) (r, g, b int) {
-	bounds := img.Bounds()
-	colorCount := make(map[[3]int]int)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			pr, pg, pb, _ := img.At(x, y).RGBA()
-			r8, g8, b8 := int(pr>>8), int(pg>>8), int(pb>>8)
-			brightness := 0.2126*float64(r8) + 0.7152*float64(g8) + 0.0722*float64(b8)
-			isBright := brightness > 160
-			isNotGray := math.Abs(float64(r8)-float64(g8)) > 25 || math.Abs(float64(g8)-float64(b8)) > 25
-			isNotWhite := !(r8 > 220 && g8 > 220 && b8 > 220)
-			if isBright && isNotGray && isNotWhite {
-				color := [3]int{r8, g8, b8}
-				colorCount[color]++
-			}
-		}
-	}
-
-	maxCount := 0
-	var dominantColor [3]int
-	for color, count := range colorCount {
-		if count > maxCount {
-			maxCount = count
-			dominantColor = color
-		}
-	}
-
-	if maxCount > 0 {
-		return dominantColor[0], dominantColor[1], dominantColor[2]
-	}
-
-	// Fallback to the configured default color when no suitable color is found
-	// 当没有找到合适的颜色时，回退到配置的默认颜色
-	return GlobalConfig.App.DefaultColorR, GlobalConfig.App.DefaultColorG, GlobalConfig.App.DefaultColorB
-}
-
-// decodeAudioFile decodes an audio file based on its extension.
-//
-// decodeAudioFile 根据文件扩展名解码音频文件。
-func decodeAudioFile(filePath string) (beep.StreamSeekCloser, beep.Format, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return nil, beep.Format{}, err
-	}
-
-	ext := strings.ToLower(filepath.Ext(filePath))
-	switch ext {
-	case ".flac":
-		return flac.Decode(f)
-	case ".mp3":
-		return mp3.Decode(f)
-	case ".wav":
-		return wav.Decode(f)
-	case ".ogg":
-		return vorbis.Decode(f)
-	default:
-		f.Close()
-		return nil, beep.Format{}, fmt.Errorf("unsupported audio format: %s\n\n不支持的音频格式: %s", ext, ext)
-	}
-}
-
-// getCoverFromAudioFile extracts cover art from an audio file.
-//
-// getCoverFromAudioFile 从音频文件中提取封面图片。
-func getCoverFromAudioFile(filePath string) image.Image {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return nil
-	}
-	defer f.Close()
-
-	m, err := tag.ReadFrom(f)
-	if err != nil {
-		return nil
-	}
-
-	pic := m.Picture()
-	if pic == nil {
-		return nil
-	}
-
-	img, _, err := image.Decode(bytes.NewReader(pic.Data))
-	if err != nil {
-		return nil
-	}
-
-	return img
+	return title, artist, album, extra, pic
 }
 
 // loadImageFile loads an image from a file path.
@@ -1551,19 +1410,21 @@ func loadImageFile(filePath string) (image.Image, error) {
 	return img, nil
 }
 
-// getFolderCoverImage searches for image files (jpg, jpeg, png) in the same directory as the audio file.
+// getFolderCoverPath searches for image files (jpg, jpeg, png) in the same directory as the audio file.
 // If multiple images are found, it randomly selects one.
 // It prioritizes files with names that suggest they are cover images (cover, folder, album, etc.)
+// It returns "" if there is none.
 //
-// getFolderCoverImage 在音频文件所在目录中搜索图片文件（jpg, jpeg, png）。
+// getFolderCoverPath 在音频文件所在目录中搜索图片文件（jpg, jpeg, png）。
 // 如果找到多个图片，则随机选择一个。
 // 优先选择文件名暗示为封面的图片（cover、folder、album等）。
-func getFolderCoverImage(audioPath string) image.Image {
+// 没有图片时返回 ""。
+func getFolderCoverPath(audioPath string) string {
 	dir := filepath.Dir(audioPath)
 
 	files, err := os.ReadDir(dir)
 	if err != nil {
-		return nil
+		return ""
 	}
 
 	var imageFiles []string
@@ -1600,16 +1461,9 @@ func getFolderCoverImage(audioPath string) image.Image {
 	} else if len(imageFiles) > 0 {
 		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 		selectedImage = imageFiles[rng.Intn(len(imageFiles))]
-	} else {
-		return nil
-	}
-
-	img, err := loadImageFile(selectedImage)
-	if err != nil {
-		return nil
 	}
 
-	return img
+	return selectedImage
 }
 
 // getDefaultCoverPath returns the path to the default cover image.
@@ -13,13 +13,14 @@ import (
 
 	"github.com/gopxl/beep/v2/speaker"
 	"github.com/mattn/go-runewidth"
-	"golang.org/x/term"
 )
 
 // PlayList displays the list of songs to be played.
 //
 // PlayList 显示要播放的歌曲列表。
 type PlayList struct {
+	pageArea
+
 	app    *App
 	cursor int // The UI cursor on the viewPlaylist. / UI在viewPlaylist上的光标。
 	offset int
@@ -77,10 +78,14 @@ func (p *PlayList) filterPlaylist() {
 		}
 		var scoredSongs []scoredSong
 
-		p.searchEngine.BuildFromPaths(p.app.Playlist)
+		targets := make([]string, len(p.app.Playlist))
+		for i, songPath := range p.app.Playlist {
+			targets[i] = songSearchTarget(songPath)
+		}
+		p.searchEngine.BuildFromPaths(targets)
 
 		for i, songPath := range p.app.Playlist {
-			songName := filepath.Base(songPath)
+			songName := filepath.Base(targets[i])
 			score := p.searchEngine.Match(p.searchQuery, songName)
 			if score > 0 {
 				scoredSongs = append(scoredSongs, scoredSong{
@@ -221,6 +226,9 @@ func (p *PlayList) removeCurrentSong() {
 	}
 
 	p.filterPlaylist()
+	if p.app.mprisServer != nil {
+		p.app.mprisServer.UpdateProperties()
+	}
 
 	if len(p.app.Playlist) == 0 {
 		p.stopPlaybackAndShowEmptyState()
@@ -237,16 +245,15 @@ func (p *PlayList) removeCurrentSong() {
 //
 // stopPlaybackAndShowEmptyState 停止播放并显示播放器页面的空状态。
 func (p *PlayList) stopPlaybackAndShowEmptyState() {
+	speaker.Lock()
 	if p.app.player != nil {
-		speaker.Lock()
 		p.app.player.ctrl.Paused = true
-		speaker.Unlock()
 	}
 	p.app.player = nil
+	speaker.Unlock()
 	p.app.setCurrentSong("")
 	if p.app.mprisServer != nil {
-		p.app.mprisServer.StopService()
-		p.app.mprisServer = nil
+		p.app.mprisServer.TrackChanged()
 	}
 	if playerPage, ok := p.app.pages[0].(*PlayerPage); ok {
 		playerPage.UpdateSong("")
@@ -267,16 +274,14 @@ func (p *PlayList) HandleSignal(sig os.Signal) error {
 //
 // View 渲染播放列表。
 func (p *PlayList) View() {
-	w, h, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil {
-		w, h = 80, 24
-	}
+	r := p.viewArea()
+	w, h := r.w, r.h
 
-	fmt.Print("\x1b[2J\x1b[3J\x1b[H")
+	p.clearArea()
 
 	title := "PlayList"
-	titleX := (w - len(title)) / 2
-	fmt.Printf("\x1b[1;%dH\x1b[1m%s\x1b[0m", titleX, title)
+	titleX := max(1, (w-len(title))/2)
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(1, titleX), p.app.titleStyle(), title)
 
 	listHeight := h - 4
 
@@ -293,11 +298,15 @@ func (p *PlayList) View() {
 	if footerX < 1 {
 		footerX = 1
 	}
-	fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", h, footerX, footer)
+	footerStyle := GlobalConfig.ActiveTheme.Footer.sgr()
+	if p.isSearching || p.searchQuery != "" {
+		footerStyle = GlobalConfig.ActiveTheme.Search.sgr()
+	}
+	fmt.Printf("%s%s%s\x1b[0m", r.moveTo(h, footerX), footerStyle, footer)
 	if p.isSearching {
 		cursorX := footerX + len("Search: ") + len(p.searchQuery)
 		if cursorX <= w {
-			fmt.Printf("\x1b[%d;%dH█", h, cursorX)
+			fmt.Printf("%s█", r.moveTo(h, cursorX))
 		}
 	}
 
@@ -307,13 +316,16 @@ func (p *PlayList) View() {
 			msg = "No songs match your search"
 		}
 		msg2 := "Add songs from the Library tab"
-		msgX := (w - len(msg)) / 2
-		msg2X := (w - len(msg2)) / 2
+		msg = runewidth.Truncate(msg, w, "...")
+		msg2 = runewidth.Truncate(msg2, w, "...")
+		msgX := max(1, (w-len(msg))/2)
+		msg2X := max(1, (w-len(msg2))/2)
 		centerRow := h / 2
 
-		fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", centerRow-1, msgX, msg)
+		hintStyle := GlobalConfig.ActiveTheme.Footer.sgr()
+		fmt.Printf("%s%s%s\x1b[0m", r.moveTo(centerRow-1, msgX), hintStyle, msg)
 		if p.searchQuery == "" {
-			fmt.Printf("\x1b[%d;%dH\x1b[90m%s\x1b[0m", centerRow+1, msg2X, msg2)
+			fmt.Printf("%s%s%s\x1b[0m", r.moveTo(centerRow+1, msg2X), hintStyle, msg2)
 		}
 		return
 	}
@@ -325,6 +337,7 @@ func (p *PlayList) View() {
 		p.offset = p.cursor - listHeight + 1
 	}
 
+	rowStyle, playingStyle, cursorStyle := GlobalConfig.ActiveTheme.Selected.sgr(), p.app.playingStyle(), p.app.cursorStyle()
 	for i := range listHeight {
 		trackIndex := p.offset + i
 		if trackIndex >= len(p.viewPlaylist) {
@@ -332,17 +345,17 @@ func (p *PlayList) View() {
 		}
 
 		trackPath := p.viewPlaylist[trackIndex]
-		trackName := filepath.Base(trackPath)
+		trackName := songDisplayName(trackPath)
 
-		style := "\x1b[32m"
+		style := rowStyle
 		if trackPath == p.app.currentSongPath {
-			style = "\x1b[31m"
+			style = playingStyle
 		}
 		if p.app.IsFileCorrupted(trackPath) {
 			style = "\x1b[33m"
 		}
 		if trackIndex == p.cursor {
-			style += "\x1b[7m"
+			style += cursorStyle
 		}
 
 		prefix := "✓"
@@ -355,7 +368,7 @@ func (p *PlayList) View() {
 				line = line[:len(line)-1]
 			}
 		}
-		fmt.Printf("\x1b[%d;1H\x1b[K%s%s\x1b[0m", i+3, style, line)
+		fmt.Printf("%s%s%s\x1b[0m", r.clearRow(i+3), style, line)
 	}
 
 	totalItems := len(p.viewPlaylist)
@@ -370,11 +383,12 @@ func (p *PlayList) View() {
 		if scrollRange > 0 {
 			thumbStart = p.offset * thumbRange / scrollRange
 		}
+		scrollbarStyle := GlobalConfig.ActiveTheme.Scrollbar.sgr()
 		for i := range listHeight {
 			if i >= thumbStart && i < thumbStart+thumbSize {
-				fmt.Printf("\x1b[%d;%dH┃", i+3, w)
+				fmt.Printf("%s%s┃\x1b[0m", r.moveTo(i+3, w), scrollbarStyle)
 			} else {
-				fmt.Printf("\x1b[%d;%dH│", i+3, w)
+				fmt.Printf("%s%s│\x1b[0m", r.moveTo(i+3, w), scrollbarStyle)
 			}
 		}
 	}
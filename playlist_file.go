@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// isPlaylistFile checks if a file has a supported playlist extension.
+//
+// isPlaylistFile 检查文件是否具有支持的播放列表扩展名。
+func isPlaylistFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".m3u" || ext == ".m3u8" || ext == ".pls"
+}
+
+// collectAudioFiles recursively collects all audio files under dir, sorted by path.
+//
+// collectAudioFiles 递归收集 dir 下的所有音频文件，并按路径排序。
+func collectAudioFiles(dir string) []string {
+	var songs []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && isAudioFile(d.Name()) {
+			songs = append(songs, songPaths(path)...)
+		}
+		return nil
+	})
+	sort.Strings(songs)
+	return songs
+}
+
+// parsePlaylistFile reads an M3U/M3U8 or PLS playlist and returns the absolute paths
+// of the audio files it references. Relative entries are resolved against the
+// playlist's own directory; missing and non-audio entries are skipped. PLS entries are
+// ordered by the number of their FileN key rather than by line.
+//
+// parsePlaylistFile 读取 M3U/M3U8 或 PLS 播放列表，并返回其引用的音频文件的绝对路径。
+// 相对路径相对于播放列表所在目录解析；不存在的条目和非音频条目会被跳过。
+// PLS 条目按 FileN 键中的编号排序，而不是按行的顺序。
+func parsePlaylistFile(playlistPath string) ([]string, error) {
+	f, err := os.Open(playlistPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open playlist: %v\n\n打开播放列表失败: %v", err, err)
+	}
+	defer f.Close()
+
+	isPLS := strings.ToLower(filepath.Ext(playlistPath)) == ".pls"
+	baseDir := filepath.Dir(playlistPath)
+
+	var songs []string
+	var numbers []int // Numbers of the PLS entries in songs. / songs 中 PLS 条目的编号。
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" {
+			continue
+		}
+
+		var entry string
+		number := 0
+		if isPLS {
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || !strings.HasPrefix(strings.ToLower(key), "file") {
+				continue
+			}
+			if number, err = strconv.Atoi(strings.TrimSpace(key[len("file"):])); err != nil {
+				continue
+			}
+			entry = strings.TrimSpace(value)
+		} else {
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			entry = line
+		}
+
+		if songPath := resolvePlaylistEntry(entry, baseDir); songPath != "" {
+			songs = append(songs, songPath)
+			numbers = append(numbers, number)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read playlist: %v\n\n读取播放列表失败: %v", err, err)
+	}
+	if isPLS {
+		sort.Stable(plsEntries{songs, numbers})
+	}
+	return songs, nil
+}
+
+// plsEntries sorts the songs of a PLS playlist by the numbers of their FileN keys.
+//
+// plsEntries 按 FileN 键中的编号对 PLS 播放列表的歌曲排序。
+type plsEntries struct {
+	songs   []string
+	numbers []int
+}
+
+func (e plsEntries) Len() int           { return len(e.songs) }
+func (e plsEntries) Less(i, j int) bool { return e.numbers[i] < e.numbers[j] }
+func (e plsEntries) Swap(i, j int) {
+	e.songs[i], e.songs[j] = e.songs[j], e.songs[i]
+	e.numbers[i], e.numbers[j] = e.numbers[j], e.numbers[i]
+}
+
+// resolvePlaylistEntry turns a playlist entry (plain path or file:// URI) into an absolute
+// audio file path, or returns "" if the entry cannot be played.
+//
+// resolvePlaylistEntry 将播放列表条目（普通路径或 file:// URI）转换为音频文件的绝对路径，
+// 如果条目无法播放则返回 ""。
+func resolvePlaylistEntry(entry, baseDir string) string {
+	if strings.Contains(entry, "://") {
+		localPath, err := fileURIToPath(entry)
+		if err != nil {
+			return ""
+		}
+		entry = localPath
+	}
+
+	entry = filepath.FromSlash(entry)
+	if !filepath.IsAbs(entry) {
+		entry = filepath.Join(baseDir, entry)
+	}
+	entry = filepath.Clean(entry)
+
+	info, err := os.Stat(cueAudioPath(entry))
+	if err != nil || info.IsDir() || !isAudioFile(entry) {
+		return ""
+	}
+	return entry
+}
+
+// fileURIToPath converts a file:// URI into a local filesystem path.
+//
+// fileURIToPath 将 file:// URI 转换为本地文件系统路径。
+func fileURIToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("Invalid URI: %v\n\n无效的 URI: %v", err, err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("Unsupported URI scheme: %s\n\n不支持的 URI 方案: %s", u.Scheme, u.Scheme)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("Remote file URIs are not supported: %s\n\n不支持远程文件 URI: %s", uri, uri)
+	}
+	if u.Path == "" {
+		return "", fmt.Errorf("Empty file URI path\n\nfile URI 路径为空")
+	}
+	if localPath := filepath.Clean(u.Path) + "#" + u.Fragment; u.Fragment != "" {
+		if _, _, ok := splitCueTrackPath(localPath); ok {
+			return localPath, nil
+		}
+	}
+	return filepath.Clean(u.Path), nil
+}
+
+// songURL returns the file:// URI of a song. Cue tracks keep their track number in the
+// fragment, which fileURIToPath understands.
+//
+// songURL 返回歌曲的 file:// URI。cue 音轨的编号保存在片段中，fileURIToPath 能够识别。
+func songURL(songPath string) string {
+	u := url.URL{Scheme: "file", Path: songPath}
+	if audioPath, _, ok := splitCueTrackPath(songPath); ok {
+		u.Path = audioPath
+		u.Fragment = strings.TrimPrefix(songPath[len(audioPath):], "#")
+	}
+	return u.String()
+}
+
+// resolveOpenTarget expands a path opened from outside the app into the audio files it
+// refers to: a single audio file, every audio file under a directory, or the entries of a
+// playlist file.
+//
+// resolveOpenTarget 将外部打开的路径展开为其对应的音频文件：单个音频文件、
+// 目录下的所有音频文件，或播放列表文件中的条目。
+func resolveOpenTarget(target string) ([]string, error) {
+	absPath, err := filepath.Abs(target)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve path: %v\n\n解析路径失败: %v", err, err)
+	}
+
+	info, err := os.Stat(cueAudioPath(absPath))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to access path: %v\n\n无法访问路径: %v", err, err)
+	}
+
+	var songs []string
+	switch {
+	case info.IsDir():
+		songs = collectAudioFiles(absPath)
+	case isPlaylistFile(absPath):
+		songs, err = parsePlaylistFile(absPath)
+		if err != nil {
+			return nil, err
+		}
+	case isAudioFile(absPath):
+		songs = songPaths(absPath)
+	default:
+		return nil, fmt.Errorf("Unsupported file type: %s\n\n不支持的文件类型: %s", absPath, absPath)
+	}
+
+	if len(songs) == 0 {
+		return nil, fmt.Errorf("No playable audio files found in %s\n\n在 %s 中未找到可播放的音频文件", absPath, absPath)
+	}
+	return songs, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileURIToPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"file:///music/song.mp3", "/music/song.mp3", true},
+		{"file://localhost/music/song.mp3", "/music/song.mp3", true},
+		{"file:///music/My%20Song%20%231.flac", "/music/My Song #1.flac", true},
+		{"file:///music/%E6%AD%8C.mp3", "/music/歌.mp3", true},
+		{"file:///music/a/../b/./song.mp3", "/music/b/song.mp3", true},
+		{"file:///music/disc.flac#track03", "/music/disc.flac#track03", true},
+		{"file:///music/song.mp3#notes", "/music/song.mp3", true},
+		{"file://server/music/song.mp3", "", false},
+		{"http://example.com/song.mp3", "", false},
+		{"file://", "", false},
+		{"file:///music/%zz.mp3", "", false},
+	}
+	for _, tt := range tests {
+		got, err := fileURIToPath(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("fileURIToPath(%q) = %q, %v; want %q, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePlaylistFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mp3", "b.flac", "My Song.mp3", "sub/c.ogg", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	song := func(name string) string { return filepath.Join(dir, name) }
+	uri := (&url.URL{Scheme: "file", Path: song("My Song.mp3")}).String()
+
+	tests := []struct {
+		name    string
+		ext     string
+		content string
+		want    []string
+	}{
+		{
+			"m3u relative paths",
+			".m3u",
+			"#EXTM3U\n#EXTINF:1,A\na.mp3\n\nsub/c.ogg\n./b.flac\nsub/../a.mp3\n",
+			[]string{song("a.mp3"), song("sub/c.ogg"), song("b.flac"), song("a.mp3")},
+		},
+		{
+			"m3u8 absolute paths and uris",
+			".m3u8",
+			"\ufeff" + song("b.flac") + "\n" + uri + "\nfile:///nonexistent/x.mp3\n",
+			[]string{song("b.flac"), song("My Song.mp3")},
+		},
+		{
+			"m3u skips missing and non-audio entries",
+			".m3u",
+			"missing.mp3\nnotes.txt\nsub\nhttp://example.com/stream.mp3\na.mp3\n",
+			[]string{song("a.mp3")},
+		},
+		{
+			"pls ordered by FileN",
+			".pls",
+			"[playlist]\nFile3=sub/c.ogg\nTitle3=C\nFile1=a.mp3\nfile10=" + uri + "\nFile2=b.flac\nNumberOfEntries=4\nVersion=2\n",
+			[]string{song("a.mp3"), song("b.flac"), song("sub/c.ogg"), song("My Song.mp3")},
+		},
+		{
+			"pls skips keys without a number",
+			".pls",
+			"[playlist]\nFile=b.flac\nFileX=b.flac\nFile1 = a.mp3\n",
+			[]string{song("a.mp3")},
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "list"+tt.ext)
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := parsePlaylistFile(path)
+		if err != nil {
+			t.Errorf("%s: parsePlaylistFile: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parsePlaylistFile = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := parsePlaylistFile(filepath.Join(dir, "missing.m3u")); err == nil {
+		t.Error("parsePlaylistFile(missing.m3u) succeeded; want an error")
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// readAheadChunk is how much the background goroutine reads from the file at a time.
+	//
+	// readAheadChunk 是后台协程每次从文件读取的字节数。
+	readAheadChunk = 64 << 10
+
+	// readAheadMargin is how much more than its estimated need the player wants buffered
+	// before it calls into the decoder, for frames that are larger than average.
+	//
+	// readAheadMargin 是播放器在调用解码器之前，除估计需求外额外要求缓冲的字节数，
+	// 用于应对大于平均大小的帧。
+	readAheadMargin = 64 << 10
+
+	// readAheadResume is how much must be buffered after an underrun before playback
+	// resumes, so that a slow mount does not stutter on and off.
+	//
+	// readAheadResume 是缓冲不足后恢复播放前必须缓冲的字节数，避免慢速挂载时播放断断续续。
+	readAheadResume = 512 << 10
+)
+
+// readAheadFile reads an audio file ahead of the decoder into a ring buffer from a
+// background goroutine, so that the decoder, which runs inside the audio callback, does not
+// wait for slow storage such as NFS or SMB mounts. Seeking within the buffered data keeps
+// it; seeking elsewhere restarts the read-ahead at the new offset.
+//
+// readAheadFile 由后台协程将音频文件预读到环形缓冲区中，使在音频回调中运行的解码器
+// 不必等待 NFS 或 SMB 挂载等慢速存储。在已缓冲的数据范围内跳转会保留缓冲；
+// 跳转到其他位置则从新的偏移量重新开始预读。
+type readAheadFile struct {
+	f    fileReaderAt
+	size int64
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	start  int   // Ring index of the byte at pos. / pos 处字节在环形缓冲区中的索引。
+	n      int   // Bytes buffered from pos. / 从 pos 开始已缓冲的字节数。
+	pos    int64 // File offset of the next byte read. / 下一个读取字节的文件偏移量。
+	err    error // Error reading the byte after the buffered ones, io.EOF at the end. / 读取缓冲数据之后字节时的错误，结尾处为 io.EOF。
+	gen    int   // Incremented when the buffer is discarded. / 缓冲区被丢弃时递增。
+	closed bool
+}
+
+// fileReaderAt is the file read by a readAheadFile: an *os.File, or a slow reader in tests.
+//
+// fileReaderAt 是 readAheadFile 读取的文件：*os.File，或测试中的慢速读取器。
+type fileReaderAt interface {
+	io.ReaderAt
+	io.Closer
+	Name() string
+}
+
+// newReadAheadFile starts reading f ahead into a buffer of the given size.
+//
+// newReadAheadFile 开始将 f 预读到给定大小的缓冲区中。
+func newReadAheadFile(f *os.File, size int) (*readAheadFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return startReadAhead(f, info.Size(), size), nil
+}
+
+// startReadAhead starts reading f, of the given file size, ahead into a buffer of
+// bufSize bytes.
+//
+// startReadAhead 开始将大小为 fileSize 的 f 预读到 bufSize 字节的缓冲区中。
+func startReadAhead(f fileReaderAt, fileSize int64, bufSize int) *readAheadFile {
+	r := &readAheadFile{
+		f:    f,
+		size: fileSize,
+		buf:  make([]byte, max(bufSize, readAheadChunk)),
+	}
+	r.cond = sync.NewCond(&r.mu)
+	go r.fill()
+	return r
+}
+
+// fill reads the file into the free part of the buffer until the reader is closed.
+//
+// fill 将文件读入缓冲区的空闲部分，直到读取器被关闭。
+func (r *readAheadFile) fill() {
+	chunk := make([]byte, readAheadChunk)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		for !r.closed && (r.n == len(r.buf) || r.err != nil) {
+			r.cond.Wait()
+		}
+		if r.closed {
+			return
+		}
+
+		gen := r.gen
+		offset := r.pos + int64(r.n)
+		want := min(len(chunk), len(r.buf)-r.n)
+		r.mu.Unlock()
+		m, err := r.f.ReadAt(chunk[:want], offset)
+		r.mu.Lock()
+
+		if r.gen != gen {
+			// The buffer was discarded by a seek while reading.
+			// 读取期间缓冲区被跳转操作丢弃。
+			continue
+		}
+		end := (r.start + r.n) % len(r.buf)
+		copied := copy(r.buf[end:], chunk[:m])
+		copy(r.buf, chunk[copied:m])
+		r.n += m
+		if err != nil {
+			r.err = err
+		}
+		r.cond.Broadcast()
+	}
+}
+
+// Read implements io.Reader. It waits for the read-ahead if the buffer is empty.
+//
+// Read 实现 io.Reader。如果缓冲区为空，它会等待预读。
+func (r *readAheadFile) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.n == 0 && r.err == nil && !r.closed {
+		r.cond.Wait()
+	}
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.n == 0 {
+		return 0, r.err
+	}
+
+	n := copy(p, r.buf[r.start:min(r.start+r.n, len(r.buf))])
+	if n < len(p) && n < r.n {
+		n += copy(p[n:], r.buf[:r.n-n])
+	}
+	r.start = (r.start + n) % len(r.buf)
+	r.n -= n
+	r.pos += int64(n)
+	r.cond.Broadcast()
+	return n, nil
+}
+
+// ReadAt implements io.ReaderAt by reading the file directly, leaving the buffer and the
+// read position alone, so that decoders can index the file in the background.
+//
+// ReadAt 通过直接读取文件实现 io.ReaderAt，不影响缓冲区和读取位置，使解码器可以在后台
+// 为文件建立索引。
+func (r *readAheadFile) ReadAt(p []byte, off int64) (int, error) {
+	return r.f.ReadAt(p, off)
+}
+
+// Seek implements io.Seeker. It never waits for the file.
+//
+// Seek 实现 io.Seeker。它从不等待文件。
+func (r *readAheadFile) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: r.f.Name(), Err: os.ErrInvalid}
+	}
+
+	if skip := offset - r.pos; skip >= 0 && skip <= int64(r.n) {
+		r.start = (r.start + int(skip)) % len(r.buf)
+		r.n -= int(skip)
+	} else {
+		r.start = 0
+		r.n = 0
+		r.err = nil
+		r.gen++
+	}
+	r.pos = offset
+	r.cond.Broadcast()
+	return offset, nil
+}
+
+// ready reports whether n bytes (at most the buffer size) can be read without waiting. A
+// reader at the end of the file or closed, e.g. after the decoder moved on to ffmpeg, is
+// always ready.
+//
+// ready 判断是否可以无需等待地读取 n 个字节（最多为缓冲区大小）。
+// 到达文件末尾或已关闭（例如解码器改用 ffmpeg 之后）的读取器总是就绪的。
+func (r *readAheadFile) ready(n int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed || r.err != nil || r.n >= min(n, len(r.buf))
+}
+
+// Name returns the name of the file.
+//
+// Name 返回文件名。
+func (r *readAheadFile) Name() string {
+	return r.f.Name()
+}
+
+// Close stops the read-ahead and closes the file.
+//
+// Close 停止预读并关闭文件。
+func (r *readAheadFile) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+	return r.f.Close()
+}
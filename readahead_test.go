@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowFile is a file that returns at most maxRead bytes per ReadAt, and blocks its reads while
+// it is held.
+type slowFile struct {
+	data    []byte
+	maxRead int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	held    bool
+	waiting int // ReadAt calls blocked by hold.
+	closed  bool
+}
+
+func newSlowFile(data []byte, maxRead int) *slowFile {
+	f := &slowFile{data: data, maxRead: maxRead}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *slowFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	f.waiting++
+	f.cond.Broadcast()
+	for f.held {
+		f.cond.Wait()
+	}
+	f.waiting--
+	f.mu.Unlock()
+
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), f.maxRead)], f.data[off:])
+	if off+int64(n) == int64(len(f.data)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *slowFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *slowFile) Name() string { return "slow.flac" }
+
+// hold makes the following reads block until release.
+func (f *slowFile) hold() {
+	f.mu.Lock()
+	f.held = true
+	f.mu.Unlock()
+}
+
+func (f *slowFile) release() {
+	f.mu.Lock()
+	f.held = false
+	f.cond.Broadcast()
+	f.mu.Unlock()
+}
+
+// waitBlocked waits for a read to be blocked by hold.
+func (f *slowFile) waitBlocked(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.waiting == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no read was blocked")
+		}
+		f.mu.Unlock()
+		time.Sleep(time.Millisecond)
+		f.mu.Lock()
+	}
+}
+
+// testData returns n bytes that differ at every offset within 256 KiB.
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i ^ i>>8 ^ i>>16)
+	}
+	return data
+}
+
+// waitReady waits until n bytes can be read from r without waiting.
+func waitReady(t *testing.T, r *readAheadFile, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !r.ready(n) {
+		if time.Now().After(deadline) {
+			t.Fatalf("ready(%d) is still false", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func readFull(t *testing.T, r io.Reader, n int) []byte {
+	t.Helper()
+	p := make([]byte, n)
+	if _, err := io.ReadFull(r, p); err != nil {
+		t.Fatalf("reading %d bytes: %v", n, err)
+	}
+	return p
+}
+
+func TestReadAheadFileRead(t *testing.T) {
+	// The file is several times the size of the buffer and is read in odd sizes, so that
+	// both the fill and the reads wrap around the end of the ring.
+	data := testData(5*readAheadChunk + 123)
+	r := startReadAhead(newSlowFile(data, 1000), int64(len(data)), readAheadChunk)
+	defer r.Close()
+
+	var got bytes.Buffer
+	p := make([]byte, 7777)
+	for {
+		n, err := r.Read(p)
+		got.Write(p[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Fatalf("read %d bytes that differ from the %d bytes of the file", got.Len(), len(data))
+	}
+}
+
+func TestReadAheadFileSeek(t *testing.T) {
+	data := testData(4 * readAheadChunk)
+	f := newSlowFile(data, 4096)
+	r := startReadAhead(f, int64(len(data)), readAheadChunk)
+	defer r.Close()
+
+	waitReady(t, r, readAheadChunk)
+	readFull(t, r, 1000)
+
+	// Within the buffered data, the buffer is kept.
+	r.mu.Lock()
+	gen := r.gen
+	r.mu.Unlock()
+	if pos, err := r.Seek(5000, io.SeekCurrent); err != nil || pos != 6000 {
+		t.Fatalf("Seek(5000, SeekCurrent) = %d, %v; want 6000", pos, err)
+	}
+	r.mu.Lock()
+	kept := r.gen == gen
+	r.mu.Unlock()
+	if !kept {
+		t.Error("a seek within the buffer discarded it")
+	}
+	if got := readFull(t, r, 100); !bytes.Equal(got, data[6000:6100]) {
+		t.Error("wrong data after a seek within the buffer")
+	}
+
+	// Backwards and past the buffered data, the read-ahead restarts at the new offset.
+	for _, offset := range []int64{10, 3 * readAheadChunk, int64(len(data)) - 50} {
+		if pos, err := r.Seek(offset, io.SeekStart); err != nil || pos != offset {
+			t.Fatalf("Seek(%d, SeekStart) = %d, %v", offset, pos, err)
+		}
+		n := min(100, len(data)-int(offset))
+		if got := readFull(t, r, n); !bytes.Equal(got, data[offset:offset+int64(n)]) {
+			t.Errorf("wrong data after seeking to %d", offset)
+		}
+	}
+
+	if pos, err := r.Seek(-10, io.SeekEnd); err != nil || pos != int64(len(data))-10 {
+		t.Fatalf("Seek(-10, SeekEnd) = %d, %v", pos, err)
+	}
+	if got, _ := io.ReadAll(r); !bytes.Equal(got, data[len(data)-10:]) {
+		t.Error("wrong data at the end of the file")
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seek(-1, SeekStart) succeeded")
+	}
+}
+
+func TestReadAheadFileStaleFill(t *testing.T) {
+	data := testData(4 * readAheadChunk)
+	f := newSlowFile(data, readAheadChunk)
+	f.hold()
+	r := startReadAhead(f, int64(len(data)), readAheadChunk)
+	defer r.Close()
+
+	// The fill is reading offset 0 when the reader seeks away; what it reads must be
+	// dropped instead of being taken for the data at the new offset.
+	f.waitBlocked(t)
+	if _, err := r.Seek(2*readAheadChunk+7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	f.release()
+
+	if got := readFull(t, r, 1000); !bytes.Equal(got, data[2*readAheadChunk+7:2*readAheadChunk+1007]) {
+		t.Error("data read before the seek was returned after it")
+	}
+}
+
+func TestReadAheadFileReadAt(t *testing.T) {
+	data := testData(3 * readAheadChunk)
+	r := startReadAhead(newSlowFile(data, len(data)), int64(len(data)), readAheadChunk)
+	defer r.Close()
+
+	readFull(t, r, 10)
+	p := make([]byte, 20)
+	if n, err := r.ReadAt(p, 2*readAheadChunk); n != len(p) || err != nil || !bytes.Equal(p, data[2*readAheadChunk:2*readAheadChunk+20]) {
+		t.Fatalf("ReadAt = %d, %v", n, err)
+	}
+	if pos, _ := r.Seek(0, io.SeekCurrent); pos != 10 {
+		t.Errorf("ReadAt moved the read position to %d", pos)
+	}
+	if got := readFull(t, r, 10); !bytes.Equal(got, data[10:20]) {
+		t.Error("ReadAt changed the buffered data")
+	}
+}
+
+func TestReadAheadFileReady(t *testing.T) {
+	data := testData(3 * readAheadChunk)
+	f := newSlowFile(data, 1000)
+	f.hold()
+	r := startReadAhead(f, int64(len(data)), readAheadChunk)
+
+	f.waitBlocked(t)
+	if r.ready(1) {
+		t.Error("ready before anything was read")
+	}
+	f.release()
+
+	// Requests larger than the buffer are capped to its size.
+	waitReady(t, r, 10*readAheadChunk)
+
+	// At the end of the file, the reader is ready even though less is left.
+	if _, err := r.Seek(int64(len(data))-5, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	waitReady(t, r, 1000)
+	if got := readFull(t, r, 5); !bytes.Equal(got, data[len(data)-5:]) {
+		t.Error("wrong data at the end of the file")
+	}
+
+	// A closed reader is ready and fails instead of waiting.
+	f.hold()
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	f.waitBlocked(t)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.release()
+	if !r.ready(1) {
+		t.Error("a closed reader is not ready")
+	}
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Read after Close = %v; want os.ErrClosed", err)
+	}
+	if !f.closed {
+		t.Error("Close did not close the file")
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+	"github.com/nfnt/resize"
+)
+
+// songInfo holds what the player shows about a song. The tags are read when the song is
+// loaded, from the same open file it is decoded from; the cover is decoded and analyzed in
+// the background.
+//
+// songInfo 存放播放器显示的歌曲信息。标签在加载歌曲时从解码所用的同一个已打开文件中读取；
+// 封面在后台解码和分析。
+type songInfo struct {
+	path    string
+	title   string
+	artist  string
+	album   string
+	extra   extraTags
+	bitrate int          // Average over the file in kbps, 0 if unknown. / 整个文件的平均值（kbps），未知时为 0。
+	picture *tag.Picture // Embedded cover, still encoded. / 内嵌封面，尚未解码。
+	lyrics  songLyrics
+
+	// cover, palette, coverKey and coverFile are set by loadCover before coverDone is closed.
+	// cover、palette、coverKey 和 coverFile 由 loadCover 在关闭 coverDone 之前设置。
+	coverDone chan struct{}
+	cover     image.Image // nil if the song has no cover. / 歌曲没有封面时为 nil。
+	palette   coverPalette
+	coverKey  string // Key of the cover in the cover cache. / 封面在封面缓存中的键。
+	coverFile string // PNG for notifications and MPRIS, "" if none. / 用于通知和 MPRIS 的 PNG，没有时为 ""。
+
+	// The cover as last drawn, kept by scaledCover. Only used from the main loop.
+	// 最近一次绘制的封面，由 scaledCover 保存。只在主循环中使用。
+	normalized       image.Image
+	scaled           image.Image
+	scaledW, scaledH int
+}
+
+// coverReady reports whether the cover has been loaded.
+//
+// coverReady 判断封面是否已加载。
+func (s *songInfo) coverReady() bool {
+	select {
+	case <-s.coverDone:
+		return true
+	default:
+		return false
+	}
+}
+
+// loadCover decodes the embedded cover, falling back to an image in the song's folder (if
+// enabled) and the default cover, and extracts its palette. The palette and the PNG for
+// notifications come from the cover cache when the same cover was seen before. It runs in
+// the background.
+//
+// loadCover 解码内嵌封面，并依次回退到歌曲所在文件夹中的图片（如果启用）和默认封面，
+// 然后提取其调色板。如果之前见过相同的封面，调色板和用于通知的 PNG 来自封面缓存。它在后台运行。
+func (s *songInfo) loadCover() {
+	defer close(s.coverDone)
+
+	var data []byte
+	var coverImg image.Image
+	if s.picture != nil {
+		if img, _, err := image.Decode(bytes.NewReader(s.picture.Data)); err == nil {
+			data, coverImg = s.picture.Data, img
+		}
+	}
+
+	if coverImg == nil && GlobalConfig != nil && GlobalConfig.App.EnableFolderCovers {
+		data, coverImg = readCoverFile(getFolderCoverPath(s.path))
+	}
+
+	if coverImg == nil {
+		data, coverImg = readCoverFile(getDefaultCoverPath())
+	}
+
+	if coverImg == nil {
+		return
+	}
+	s.cover = coverImg
+
+	cache := getCoverCache()
+	s.coverKey = coverKey(data)
+	palette, ok := cache.palette(s.coverKey)
+	if !ok {
+		palette = extractCoverPalette(coverImg)
+		cache.storePalette(s.coverKey, palette)
+	}
+	s.palette = palette
+
+	s.coverFile = cache.coverFile(s.coverKey, coverImg)
+}
+
+// readCoverFile reads and decodes an image file, returning nil if path is "" or the file
+// cannot be decoded.
+//
+// readCoverFile 读取并解码图片文件；path 为 "" 或文件无法解码时返回 nil。
+func readCoverFile(path string) ([]byte, image.Image) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil
+	}
+	return data, img
+}
+
+// scaledCover returns the loaded cover resized to fit the given pixel size, from memory if
+// it was last drawn at that size, else from the cover cache, else resizing it and caching
+// the result in the background. It runs on the main loop.
+//
+// scaledCover 返回缩放到给定像素尺寸的已加载封面：如果上次以该尺寸绘制则取自内存，
+// 否则取自封面缓存，再否则进行缩放并在后台缓存结果。它在主循环中运行。
+func (s *songInfo) scaledCover(pixelW, pixelH int) image.Image {
+	if s.scaled != nil && s.scaledW == pixelW && s.scaledH == pixelH {
+		return s.scaled
+	}
+
+	cache := getCoverCache()
+	scaled := cache.variant(s.coverKey, pixelW, pixelH)
+	if scaled == nil {
+		if s.normalized == nil {
+			s.normalized = resize.Resize(960, 960, s.cover, resize.Lanczos3)
+		}
+		scaled = resize.Thumbnail(uint(pixelW), uint(pixelH), s.normalized, resize.Lanczos3)
+		go cache.storeVariant(s.coverKey, pixelW, pixelH, scaled)
+	}
+
+	s.scaled, s.scaledW, s.scaledH = scaled, pixelW, pixelH
+	return scaled
+}
+
+// loadedSong is a song opened for playback by loadSong.
+//
+// loadedSong 是由 loadSong 打开以供播放的歌曲。
+type loadedSong struct {
+	streamer  beep.StreamSeekCloser
+	format    beep.Format
+	container string // Format detected from the content. / 根据内容检测到的格式。
+	info      *songInfo
+	source    *readAheadFile    // nil if read-ahead is disabled. / 禁用预读时为 nil。
+	decoder   backgroundDecoder // nil for the native decoders. / 使用原生解码器时为 nil。
+
+	// bytesPerSample is the average size of a sample in the file, to tell how much must
+	// be buffered for the decoder.
+	// bytesPerSample 是文件中每个采样的平均大小，用于判断需要为解码器缓冲多少数据。
+	bytesPerSample float64
+}
+
+// loadSong opens a song once, reads its tags and prepares its stream for playback. The
+// duration comes from the container header, so nothing else is read before playback can
+// start. The file is read through a read-ahead buffer if it is enabled.
+//
+// loadSong 只打开歌曲一次，读取其标签并准备好用于播放的流。时长来自容器头部，
+// 因此在开始播放前不会读取其他内容。如果启用了预读，文件会通过预读缓冲区读取。
+func loadSong(songPath string) (*loadedSong, error) {
+	f, err := os.Open(cueAudioPath(songPath))
+	if err != nil {
+		return nil, err
+	}
+
+	song := &loadedSong{info: &songInfo{path: songPath, coverDone: make(chan struct{})}}
+	var source audioSource = f
+	if GlobalConfig != nil && GlobalConfig.App.ReadAheadKB > 0 {
+		song.source, err = newReadAheadFile(f, GlobalConfig.App.ReadAheadKB<<10)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		source = song.source
+	}
+
+	info := song.info
+	info.title, info.artist, info.album, info.extra, info.picture = readSongMetadata(source, songPath)
+	info.lyrics = loadLyrics(songPath, info.extra.lyrics)
+
+	song.streamer, song.format, song.container, err = decodeAudioFile(source, songPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cue tracks are part of a longer file.
+	// cue 音轨只是较长文件的一部分。
+	fileStreamer := beep.StreamSeekCloser(song.streamer)
+	if track, ok := song.streamer.(*cueTrackStreamer); ok {
+		fileStreamer = track.StreamSeekCloser
+	}
+	fileLen := fileStreamer.Len()
+	song.decoder, _ = fileStreamer.(backgroundDecoder)
+	song.bytesPerSample = 16
+	if fileLen > 0 && song.source != nil {
+		song.bytesPerSample = float64(song.source.size) / float64(fileLen)
+	}
+	if stat, err := f.Stat(); err == nil && fileLen > 0 {
+		info.bitrate = int(float64(stat.Size()) * 8 / song.format.SampleRate.D(fileLen).Seconds() / 1000)
+	}
+	return song, nil
+}
+
+// startSong loads a song and starts playing it. The cover is loaded in the background and
+// the player page is redrawn when it is ready.
+//
+// startSong 加载歌曲并开始播放。封面在后台加载，加载完成后重绘播放器页面。
+func (a *App) startSong(songPath string) error {
+	// Stop current playback.
+	// 停止当前播放。
+	speaker.Lock()
+	if a.player != nil {
+		a.player.ctrl.Paused = true
+	}
+	speaker.Unlock()
+
+	song, err := loadSong(songPath)
+	if err != nil {
+		if isCorruptionError(err) {
+			a.MarkFileAsCorrupted(songPath)
+		}
+		return fmt.Errorf("Failed to decode audio: %w\n\n解码音频失败: %v", err, err)
+	}
+	streamer, format, info := song.streamer, song.format, song.info
+
+	if err := speaker.ReInit(format.SampleRate, format.SampleRate.N(time.Second/30)); err != nil {
+		streamer.Close()
+		return fmt.Errorf("Failed to reinit speaker: %v\n\n重新初始化扬声器失败: %v", err, err)
+	}
+
+	applyDither(format)
+
+	player, err := newAudioPlayer(songPath, streamer, format, a.volume, a.playbackRate)
+	if err != nil {
+		streamer.Close()
+		return fmt.Errorf("Failed to create player: %v\n\n创建播放器失败: %v", err, err)
+	}
+
+	player.container = song.container
+	player.source = song.source
+	player.decoder = song.decoder
+	player.bytesPerSample = song.bytesPerSample
+
+	speaker.Lock()
+	previous := a.player
+	a.player = player
+	a.currentSongPath = songPath
+	speaker.Unlock()
+	a.song = info
+	a.setWindowTitle()
+
+	// Close the previous song, which also stops its read-ahead.
+	// 关闭上一首歌曲，同时停止其预读。
+	speaker.Clear()
+	if previous != nil {
+		previous.streamer.Close()
+	}
+	speaker.Play(a.player.output)
+
+	go func() {
+		info.loadCover()
+		a.actionQueue <- func() { a.coverLoaded(info) }
+	}()
+
+	if a.mprisServer != nil {
+		a.mprisServer.TrackChanged()
+	}
+	return nil
+}
+
+// coverLoaded gives the cover of the current song to MPRIS clients and redraws the player
+// page if it was drawn before the cover was ready. It runs on the main loop.
+//
+// coverLoaded 将当前歌曲的封面提供给 MPRIS 客户端，并在播放器页面于封面就绪之前已绘制时
+// 重绘该页面。它在主循环中运行。
+func (a *App) coverLoaded(info *songInfo) {
+	if a.song != info {
+		return
+	}
+	if a.mprisServer != nil {
+		// The cover's PNG is only known now.
+		// 封面的 PNG 此时才可知。
+		a.mprisServer.UpdateMetadata()
+	}
+	if playerPage, ok := a.pages[0].(*PlayerPage); ok && playerPage.waitingForCover == info {
+		playerPage.waitingForCover = nil
+		if split, ok := a.splitView(); ok {
+			// The lists take their colors from the cover too.
+			// 列表也从封面获取颜色。
+			split.View()
+		} else if a.currentPageIndex == 0 {
+			playerPage.View()
+		}
+	}
+}
+
+// checkBuffering notices when playback starts or stops waiting for the read-ahead and
+// tells MPRIS clients. The player page shows it on its next update.
+//
+// checkBuffering 检测播放开始或停止等待预读的时刻，并通知 MPRIS 客户端。
+// 播放器页面会在下次更新时显示该状态。
+func (a *App) checkBuffering() {
+	buffering := a.isBuffering()
+	if buffering == a.buffering {
+		return
+	}
+	a.buffering = buffering
+	if a.mprisServer != nil {
+		a.mprisServer.BufferingChanged()
+	}
+}
+
+// isBuffering reports whether playback is waiting for the read-ahead.
+//
+// isBuffering 判断播放是否正在等待预读。
+func (a *App) isBuffering() bool {
+	return a.player != nil && !a.player.ctrl.Paused && a.player.output.buffering.Load()
+}
+
+// songMetadata returns the title, artist and album of a song, from the tags read when it
+// was loaded if it is the current song.
+//
+// songMetadata 返回歌曲的标题、艺术家和专辑；如果是当前歌曲，则使用加载时读取的标签。
+func (a *App) songMetadata(songPath string) (title, artist, album string) {
+	if a.song != nil && a.song.path == songPath {
+		return a.song.title, a.song.artist, a.song.album
+	}
+	return getSongMetadata(songPath)
+}
+
+// notifySong shows the song notification for the current song once its cover is loaded.
+//
+// notifySong 在当前歌曲的封面加载完成后为其显示歌曲通知。
+func (a *App) notifySong() {
+	info := a.song
+	if a.notifier == nil || info == nil {
+		return
+	}
+	go func() {
+		<-info.coverDone
+		a.actionQueue <- func() {
+			if a.song == info {
+				a.notifier.Notify(info.artist, info.title, info.coverFile)
+			}
+		}
+	}()
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// splitPageIndex is the index of the split view in App.pages.
+//
+// splitPageIndex 是分屏视图在 App.pages 中的索引。
+const splitPageIndex = 3
+
+// Values of default_page that are not page indexes. 3 meant "memory" before the split view
+// took page index 3 and keeps that meaning, so the split view is 4.
+//
+// default_page 中不是页面索引的取值。在分屏视图占用页面索引 3 之前，3 表示“记忆”，并保持
+// 这一含义，因此分屏视图为 4。
+const (
+	defaultPageMemory = 3
+	defaultPageSplit  = 4
+)
+
+// startPageIndex returns the index of the page shown at startup for a default_page value.
+// loadPage returns the page saved by the last session and is only called for "memory".
+//
+// startPageIndex 返回 default_page 取值对应的启动页面索引。loadPage 返回上次会话保存的页面，
+// 只在“记忆”时调用。
+func startPageIndex(defaultPage int, loadPage func() (int, error)) int {
+	switch defaultPage {
+	case defaultPageMemory:
+		savedPage, err := loadPage()
+		if err != nil {
+			l.Warnf("Could not load saved page: %v\n\n无法加载已保存的页面: %v", err, err)
+			return 0
+		}
+		if savedPage < 0 || savedPage > splitPageIndex {
+			return 0
+		}
+		return savedPage
+	case defaultPageSplit:
+		return splitPageIndex
+	default:
+		return defaultPage
+	}
+}
+
+// splitPane is a page that can be shown as a pane of the split view.
+//
+// splitPane 是可以作为分屏视图窗格显示的页面。
+type splitPane interface {
+	Page
+	setArea(r *viewRect)
+}
+
+// SplitView shows the Library, the PlayList and a compact player side by side. Each pane is
+// the page itself drawn into its own rect, and keys go to the focused pane's HandleKey.
+//
+// SplitView 并排显示媒体库、播放列表和紧凑播放器。每个窗格都是绘制到各自区域中的页面本身，
+// 按键会传给获得焦点的窗格的 HandleKey。
+type SplitView struct {
+	app      *App
+	library  *Library
+	playlist *PlayList
+	player   *PlayerPage
+
+	panes     [3]splitPane
+	names     [3]string
+	rects     [3]viewRect
+	focus     int    // Index of the focused pane. / 获得焦点的窗格索引。
+	shownSong string // Song the panes were last drawn with. / 窗格最近一次绘制时的歌曲。
+}
+
+// NewSplitView creates the split view over the existing pages.
+//
+// NewSplitView 基于现有页面创建分屏视图。
+func NewSplitView(app *App, player *PlayerPage, playlist *PlayList, library *Library) *SplitView {
+	return &SplitView{
+		app:      app,
+		library:  library,
+		playlist: playlist,
+		player:   player,
+		panes:    [3]splitPane{library, playlist, player},
+		names:    [3]string{"Library", "PlayList", "Now Playing"},
+	}
+}
+
+// layout splits the terminal into the rects of the panes and gives each pane its rect. The
+// player's rect starts below the header of its column, as it has no title of its own.
+//
+// layout 将终端划分为各窗格的区域并分配给每个窗格。播放器的区域从其所在列的标题下方开始，
+// 因为它没有自己的标题。
+func (s *SplitView) layout() {
+	s.rects = splitRects(terminalRect())
+	for i, pane := range s.panes {
+		pane.setArea(&s.rects[i])
+	}
+}
+
+// splitRects splits the rect of the terminal into the rects of the Library, the PlayList and
+// the player.
+//
+// splitRects 将终端区域划分为媒体库、播放列表和播放器的区域。
+func splitRects(r viewRect) [3]viewRect {
+	inner := r.w - 2 // Two columns of separators. / 两列分隔线。
+	libraryW := max(inner*3/8, 4)
+	playlistW := max(inner*3/8, 4)
+	playerW := max(inner-libraryW-playlistW, 1)
+
+	return [3]viewRect{
+		{col: 1, row: 1, w: libraryW, h: r.h},
+		{col: libraryW + 2, row: 1, w: playlistW, h: r.h},
+		{col: libraryW + playlistW + 3, row: 3, w: playerW, h: max(r.h-2, 1), toEdge: true},
+	}
+}
+
+// release gives the pages the whole terminal back when leaving the split view.
+//
+// release 在离开分屏视图时将整个终端交还给各页面。
+func (s *SplitView) release() {
+	for _, pane := range s.panes {
+		pane.setArea(nil)
+	}
+}
+
+// focused returns the page of the focused pane.
+//
+// focused 返回获得焦点的窗格的页面。
+func (s *SplitView) focused() Page {
+	return s.panes[s.focus]
+}
+
+// hasPane reports whether a page is one of the panes.
+//
+// hasPane 报告页面是否是其中一个窗格。
+func (s *SplitView) hasPane(page Page) bool {
+	for _, pane := range s.panes {
+		if pane == page {
+			return true
+		}
+	}
+	return false
+}
+
+// Init prepares the panes.
+//
+// Init 准备各个窗格。
+func (s *SplitView) Init() {
+	s.layout()
+	for _, pane := range s.panes {
+		pane.Init()
+	}
+}
+
+// HandleKey passes the key to the focused pane. Adding or removing songs in one list also
+// redraws the other.
+//
+// HandleKey 将按键传给获得焦点的窗格。在一个列表中添加或移除歌曲时也会重新绘制另一个列表。
+func (s *SplitView) HandleKey(key rune) (Page, bool, error) {
+	pane := s.focused()
+	songs := len(s.app.Playlist)
+
+	_, needsRedraw, err := pane.HandleKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if needsRedraw {
+		pane.View()
+	}
+
+	if len(s.app.Playlist) != songs {
+		if pane == Page(s.library) {
+			cursor := s.playlist.cursor
+			s.playlist.filterPlaylist()
+			s.playlist.cursor = min(cursor, max(len(s.playlist.viewPlaylist)-1, 0))
+			s.playlist.View()
+		} else if pane == Page(s.playlist) {
+			s.library.View()
+		}
+	}
+	s.drawFrame()
+	return nil, false, nil
+}
+
+// focusNext moves the focus to the next pane.
+//
+// focusNext 将焦点移到下一个窗格。
+func (s *SplitView) focusNext() {
+	s.focus = (s.focus + 1) % len(s.panes)
+	s.drawFrame()
+}
+
+// HandleSignal redraws the view on resize.
+//
+// HandleSignal 在调整大小时重绘视图。
+func (s *SplitView) HandleSignal(sig os.Signal) error {
+	if sig == syscall.SIGWINCH {
+		clearScreen()
+		s.View()
+	}
+	return nil
+}
+
+// View draws all panes and the frame around them.
+//
+// View 绘制所有窗格及其周围的框架。
+func (s *SplitView) View() {
+	s.layout()
+	s.shownSong = s.app.currentSongPath
+	for _, pane := range s.panes {
+		pane.View()
+	}
+	s.drawFrame()
+}
+
+// drawFrame draws the separators between the panes and their headers, the focused one
+// highlighted. It is drawn after the panes, over their own titles.
+//
+// drawFrame 绘制窗格之间的分隔线和各窗格的标题，获得焦点的窗格标题会高亮显示。它在窗格之后
+// 绘制，覆盖窗格自己的标题。
+func (s *SplitView) drawFrame() {
+	h := terminalRect().h
+	separatorStyle := GlobalConfig.ActiveTheme.Separator.sgr()
+	for _, r := range s.rects[:2] {
+		for row := 1; row <= h; row++ {
+			fmt.Printf("\x1b[%d;%dH%s│\x1b[0m", row, r.col+r.w, separatorStyle)
+		}
+	}
+
+	for i, r := range s.rects {
+		header := viewRect{col: r.col, row: 1, w: r.w, h: 1, toEdge: r.toEdge}
+		title := " " + s.names[i] + " "
+		style := GlobalConfig.ActiveTheme.Footer.sgr()
+		if i == s.focus {
+			style = s.app.titleStyle() + "\x1b[7m"
+		}
+		if len(title) > r.w {
+			title = runewidth.Truncate(s.names[i], r.w, "")
+		}
+		fmt.Printf("%s%s%s%s\x1b[0m", header.clearRow(1), header.moveTo(1, max(1, (r.w-len(title))/2)), style, title)
+	}
+}
+
+// Tick keeps the player going and redraws the panes when the song changed from elsewhere,
+// such as the Library or MPRIS.
+//
+// Tick 让播放器继续运行，并在歌曲从其他地方（例如媒体库或 MPRIS）切换时重新绘制各窗格。
+func (s *SplitView) Tick() {
+	s.player.Tick()
+	if s.app.currentSongPath != s.shownSong {
+		s.View()
+	}
+}
+
+// splitView returns the split view if it is the current page.
+//
+// splitView 在分屏视图是当前页面时返回它。
+func (a *App) splitView() (*SplitView, bool) {
+	split, ok := a.pages[a.currentPageIndex].(*SplitView)
+	return split, ok
+}
+
+// pageShown reports whether a page is on screen, as the current page or as a pane of the
+// split view.
+//
+// pageShown 报告页面是否显示在屏幕上，无论是作为当前页面还是作为分屏视图的窗格。
+func (a *App) pageShown(page Page) bool {
+	if split, ok := a.splitView(); ok {
+		return split.hasPane(page)
+	}
+	return a.pages[a.currentPageIndex] == page
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSplitRects(t *testing.T) {
+	tests := []struct {
+		w, h int
+		want [3]viewRect
+	}{
+		{80, 24, [3]viewRect{
+			{col: 1, row: 1, w: 29, h: 24},
+			{col: 31, row: 1, w: 29, h: 24},
+			{col: 61, row: 3, w: 20, h: 22, toEdge: true},
+		}},
+		{162, 40, [3]viewRect{
+			{col: 1, row: 1, w: 60, h: 40},
+			{col: 62, row: 1, w: 60, h: 40},
+			{col: 123, row: 3, w: 40, h: 38, toEdge: true},
+		}},
+		// Too narrow: the lists keep their minimum width and the player at least one column.
+		{6, 2, [3]viewRect{
+			{col: 1, row: 1, w: 4, h: 2},
+			{col: 6, row: 1, w: 4, h: 2},
+			{col: 11, row: 3, w: 1, h: 1, toEdge: true},
+		}},
+	}
+	for _, tt := range tests {
+		got := splitRects(viewRect{col: 1, row: 1, w: tt.w, h: tt.h, toEdge: true})
+		if got != tt.want {
+			t.Errorf("splitRects(%dx%d) = %+v; want %+v", tt.w, tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestSplitRectsCoverWidth(t *testing.T) {
+	for w := 20; w <= 300; w++ {
+		rects := splitRects(viewRect{col: 1, row: 1, w: w, h: 24, toEdge: true})
+		for i := 1; i < len(rects); i++ {
+			// One separator column between neighbouring panes.
+			if rects[i].col != rects[i-1].col+rects[i-1].w+1 {
+				t.Errorf("splitRects(%d): pane %d starts at %d; want %d", w, i, rects[i].col, rects[i-1].col+rects[i-1].w+1)
+			}
+		}
+		if last := rects[2]; last.col+last.w-1 != w {
+			t.Errorf("splitRects(%d): player ends at column %d; want %d", w, last.col+last.w-1, w)
+		}
+	}
+}
+
+func TestStartPageIndex(t *testing.T) {
+	errLoad := errors.New("no storage")
+	tests := []struct {
+		defaultPage int
+		saved       int
+		err         error
+		want        int
+	}{
+		{0, 2, nil, 0},
+		{1, 2, nil, 1},
+		{2, 0, nil, 2},
+		{defaultPageMemory, 2, nil, 2},
+		{defaultPageMemory, splitPageIndex, nil, splitPageIndex},
+		{defaultPageMemory, splitPageIndex + 1, nil, 0},
+		{defaultPageMemory, -1, nil, 0},
+		{defaultPageMemory, 2, errLoad, 0},
+		{defaultPageSplit, 0, nil, splitPageIndex},
+	}
+	for _, tt := range tests {
+		loaded := false
+		got := startPageIndex(tt.defaultPage, func() (int, error) {
+			loaded = true
+			return tt.saved, tt.err
+		})
+		if got != tt.want {
+			t.Errorf("startPageIndex(%d) with saved page %d, %v = %d; want %d", tt.defaultPage, tt.saved, tt.err, got, tt.want)
+		}
+		if loaded != (tt.defaultPage == defaultPageMemory) {
+			t.Errorf("startPageIndex(%d) loaded the saved page = %v", tt.defaultPage, loaded)
+		}
+	}
+}
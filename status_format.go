@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// extraTags are the tags of a song that are only shown through the status and window title
+// formats, and its embedded lyrics.
+//
+// extraTags 是只通过状态和窗口标题格式显示的歌曲标签，以及歌曲的内嵌歌词。
+type extraTags struct {
+	year   int
+	track  int
+	genre  string
+	lyrics string // USLT or LYRICS tag, read by loadLyrics. / USLT 或 LYRICS 标签，由 loadLyrics 读取。
+}
+
+// statusFields are the fields of the status and window title formats, written as {name}.
+//
+// statusFields 是状态和窗口标题格式中的字段，写作 {name}。
+var statusFields = []string{
+	"title", "artist", "album", "year", "track", "genre", "file",
+	"elapsed", "duration", "remaining",
+	"codec", "bitrate", "samplerate", "bits", "channels",
+	"volume", "rate", "mode", "state",
+}
+
+// validateFormat checks that a status or window title format only uses known fields.
+//
+// validateFormat 检查状态或窗口标题格式是否只使用已知字段。
+func validateFormat(key, format string) error {
+	var err error
+	expandFormat(format, func(name string) (string, bool) {
+		for _, field := range statusFields {
+			if name == field {
+				return "", true
+			}
+		}
+		if err == nil {
+			err = fmt.Errorf("unknown field {%s} in %s, available fields: %s\n\n%s 中的字段 {%s} 未知，可用字段: %s",
+				name, key, strings.Join(statusFields, ", "), key, name, strings.Join(statusFields, ", "))
+		}
+		return "", false
+	})
+	return err
+}
+
+// expandFormat replaces the {name} fields of a format with their values. Fields that lookup
+// does not know are kept as written.
+//
+// expandFormat 将格式中的 {name} 字段替换为其值。lookup 不认识的字段保持原样。
+func expandFormat(format string, lookup func(name string) (string, bool)) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(format, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(format[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(format[:start])
+		if value, ok := lookup(format[start+1 : end]); ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(format[start : end+1])
+		}
+		format = format[end+1:]
+	}
+	b.WriteString(format)
+	return b.String()
+}
+
+// formatSong expands a status or window title format for the current song.
+//
+// formatSong 为当前歌曲展开状态或窗口标题格式。
+func (a *App) formatSong(format string) string {
+	info := a.song
+	player := a.player
+	return expandFormat(format, func(name string) (string, bool) {
+		switch name {
+		case "volume":
+			return fmt.Sprintf("%d%%", int(math.Round(a.linearVolume*100))), true
+		case "mode":
+			return [3]string{"repeat one", "repeat all", "shuffle"}[a.playMode], true
+		}
+
+		if info == nil {
+			return "", true
+		}
+		switch name {
+		case "title":
+			return info.title, true
+		case "artist":
+			return info.artist, true
+		case "album":
+			return info.album, true
+		case "year":
+			return formatNonZero(info.extra.year), true
+		case "track":
+			return formatNonZero(info.extra.track), true
+		case "genre":
+			return info.extra.genre, true
+		case "file":
+			return songDisplayName(info.path), true
+		case "bitrate":
+			return formatNonZero(info.bitrate), true
+		}
+
+		if player == nil || player.path != info.path {
+			return "", true
+		}
+		switch name {
+		case "codec":
+			return player.sourceFormat(), true
+		case "elapsed":
+			return formatPlayTime(player.sampleRate.D(player.streamer.Position())), true
+		case "duration":
+			return formatPlayTime(player.sampleRate.D(player.streamer.Len())), true
+		case "remaining":
+			return formatPlayTime(player.sampleRate.D(max(player.streamer.Len()-player.streamer.Position(), 0))), true
+		case "samplerate":
+			return formatSampleRate(player.sampleRate), true
+		case "bits":
+			return formatNonZero(player.format.Precision * 8), true
+		case "channels":
+			if player.layout == 0 {
+				return fmt.Sprintf("%d ch", player.format.NumChannels), true
+			}
+			return player.layout.String(), true
+		case "rate":
+			return fmt.Sprintf("%.2fx", player.resampler.Ratio()), true
+		case "state":
+			if player.ctrl.Paused {
+				return "paused", true
+			}
+			return "playing", true
+		}
+		return "", false
+	})
+}
+
+// formatNonZero formats n, or returns "" for 0, which tags use for a missing value.
+//
+// formatNonZero 格式化 n；n 为 0 时返回 ""，标签用 0 表示缺失的值。
+func formatNonZero(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// statusLines returns the lines of the player's text area: the status format if one is set,
+// or else the title, artist and album.
+//
+// statusLines 返回播放器文本区域的各行：设置了状态格式时为其展开结果，否则为标题、艺术家和专辑。
+func (p *PlayerPage) statusLines() []string {
+	if GlobalConfig.App.StatusFormat != "" && p.app.song != nil && p.app.song.path == p.flacPath {
+		return strings.Split(p.app.formatSong(GlobalConfig.App.StatusFormat), "\n")
+	}
+	title, artist, album := p.app.songMetadata(p.flacPath)
+	return []string{title, artist, album}
+}
+
+// setWindowTitle sets the terminal's window title to the window title format for the current
+// song, with OSC 2.
+//
+// setWindowTitle 使用 OSC 2 将终端窗口标题设置为当前歌曲的窗口标题格式。
+func (a *App) setWindowTitle() {
+	if GlobalConfig.App.WindowTitleFormat == "" || a.song == nil {
+		return
+	}
+	// Tags must not end the sequence early or inject their own.
+	// 标签不得提前结束该序列或注入自己的序列。
+	title := strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, a.formatSong(GlobalConfig.App.WindowTitleFormat))
+	fmt.Printf("\x1b]2;%s\x1b\\", title)
+}
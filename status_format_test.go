@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandFormat(t *testing.T) {
+	values := map[string]string{"title": "Song", "artist": "Band", "empty": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+	tests := []struct {
+		format, want string
+	}{
+		{"", ""},
+		{"plain text", "plain text"},
+		{"{title}", "Song"},
+		{"{artist} - {title}", "Band - Song"},
+		{"[{empty}]", "[]"},
+		{"{unknown} {title}", "{unknown} Song"},
+		{"{}", "{}"},
+		{"{title", "{title"},
+		{"title}", "title}"},
+		{"{{title}}", "{{title}}"},
+		{"{title}{artist}\n{title}", "SongBand\nSong"},
+		{"歌曲：{title}", "歌曲：Song"},
+	}
+	for _, tt := range tests {
+		if got := expandFormat(tt.format, lookup); got != tt.want {
+			t.Errorf("expandFormat(%q) = %q; want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		bad    string // Unknown field named in the error, "" if valid. / 错误中指出的未知字段，有效时为 ""。
+	}{
+		{"", ""},
+		{"{artist} - {title}", ""},
+		{"{elapsed}/{duration} {codec} {bitrate} kbps {state}", ""},
+		{"{title} {titel}", "{titel}"},
+		{"{foo} {bar}", "{foo}"},
+		{"{title", ""},
+	}
+	for _, tt := range tests {
+		err := validateFormat("status_format", tt.format)
+		if tt.bad == "" {
+			if err != nil {
+				t.Errorf("validateFormat(%q) = %v; want nil", tt.format, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.bad) || !strings.Contains(err.Error(), "status_format") {
+			t.Errorf("validateFormat(%q) = %v; want an error naming %s", tt.format, err, tt.bad)
+		}
+	}
+}
+
+func TestFormatSong(t *testing.T) {
+	song := &songInfo{
+		path:    "/music/Band/01 Song.flac",
+		title:   "Song",
+		artist:  "Band",
+		album:   "Album",
+		extra:   extraTags{year: 1999, genre: "Rock"},
+		bitrate: 900,
+	}
+	tests := []struct {
+		song   *songInfo
+		format string
+		want   string
+	}{
+		{song, "{artist} - {title} ({album}, {year})", "Band - Song (Album, 1999)"},
+		{song, "{track}|{genre}|{file}", "|Rock|01 Song.flac"},
+		{song, "{bitrate} kbps", "900 kbps"},
+		// Without a playing stream the codec is unknown.
+		{song, "[{codec}]", "[]"},
+		{song, "{volume} {mode}", "50% shuffle"},
+		// Without a playing stream the playback fields are empty.
+		{song, "[{elapsed}/{duration}] {state}", "[/] "},
+		{nil, "{title}{bitrate} {volume}", " 50%"},
+	}
+	for _, tt := range tests {
+		a := &App{song: tt.song, linearVolume: 0.5, playMode: 2}
+		if got := a.formatSong(tt.format); got != tt.want {
+			t.Errorf("formatSong(%q) = %q; want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSongCodec(t *testing.T) {
+	tests := []struct {
+		path, container string
+		want            string
+	}{
+		{"/music/a.flac", formatFLAC, "FLAC"},
+		// The codec comes from the content, not from the extension.
+		{"/music/a.mp3", formatFLAC, "FLAC"},
+		{"/music/a.flac", formatMP3, "MP3"},
+		{"/music/a.m4a", formatAAC, "AAC"},
+		{"/music/a.m4a", formatMP4, "MP4"},
+		{"/music/a.ogg", formatOpus, "Opus"},
+		// Data after an ID3 tag is left to ffmpeg and named after the extension.
+		{"/music/a.mp2", formatTagged, "MP2"},
+	}
+	for _, tt := range tests {
+		a := &App{
+			song:   &songInfo{path: tt.path},
+			player: &audioPlayer{path: tt.path, container: tt.container},
+		}
+		if got := a.formatSong("{codec}"); got != tt.want {
+			t.Errorf("formatSong({codec}) for %s with %s content = %q; want %q", tt.path, tt.container, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNonZero(t *testing.T) {
+	tests := []struct {
+		in   int
+		want string
+	}{
+		{0, ""},
+		{1, "1"},
+		{2024, "2024"},
+		{-3, "-3"},
+	}
+	for _, tt := range tests {
+		if got := formatNonZero(tt.in); got != tt.want {
+			t.Errorf("formatNonZero(%d) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -22,21 +22,28 @@ type StorageData struct {
 	Page               *int     `json:"page,omitempty"`
 	OverrideLayoutNarrow *int   `json:"override_layout_narrow,omitempty"`
 	OverrideLayoutWide   *int   `json:"override_layout_wide,omitempty"`
+	LayoutTemplate       *string `json:"layout_template,omitempty"`
 }
 
 // getStoragePath returns the absolute path to the storage file.
 //
 // getStoragePath 返回存储文件的绝对路径。
 func getStoragePath() (string, error) {
-	storagePath := GlobalConfig.App.Storage
-	if strings.HasPrefix(storagePath, "~/") {
+	return expandHomePath(GlobalConfig.App.Storage)
+}
+
+// expandHomePath expands a leading ~/ in a configured path to the user's home directory.
+//
+// expandHomePath 将配置路径开头的 ~/ 展开为用户主目录。
+func expandHomePath(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
 		home, err := os.UserHomeDir()
 		if err != nil {
 			return "", fmt.Errorf("could not get user home directory: %v\n\n无法获取用户主目录: %v", err, err)
 		}
-		storagePath = filepath.Join(home, storagePath[2:])
+		path = filepath.Join(home, path[2:])
 	}
-	return storagePath, nil
+	return path, nil
 }
 
 // loadStorageData loads data from the storage.json file.
@@ -431,3 +438,37 @@ func LoadOverrideLayout(isWide bool) (int, error) {
 		return *storageData.OverrideLayoutNarrow, nil
 	}
 }
+
+// SaveLayoutTemplate saves the layout template chosen with ToggleLayout to the storage.json file.
+//
+// SaveLayoutTemplate 将通过 ToggleLayout 选择的布局模板保存到 storage.json 文件。
+func SaveLayoutTemplate(name string) error {
+	storageData, err := loadStorageData()
+	if err != nil {
+		return fmt.Errorf("could not load storage data for layout: %v\n\n无法加载布局的存储数据: %v", err, err)
+	}
+
+	storageData.LayoutTemplate = &name
+
+	if err := saveStorageData(storageData); err != nil {
+		return fmt.Errorf("could not save layout data: %v\n\n无法保存布局数据: %v", err, err)
+	}
+	return nil
+}
+
+// LoadLayoutTemplate loads the layout template chosen with ToggleLayout from the storage.json file.
+// Returns "" if no template is saved.
+//
+// LoadLayoutTemplate 从 storage.json 文件加载通过 ToggleLayout 选择的布局模板。
+// 如果没有保存的模板则返回 ""。
+func LoadLayoutTemplate() (string, error) {
+	storageData, err := loadStorageData()
+	if err != nil {
+		return "", fmt.Errorf("could not load storage data for layout: %v\n\n无法加载布局的存储数据: %v", err, err)
+	}
+
+	if storageData.LayoutTemplate == nil {
+		return "", nil
+	}
+	return *storageData.LayoutTemplate, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// streamListenerBuffer is the number of audio chunks buffered per listener. A listener
+// that falls further behind is disconnected instead of stalling the audio thread.
+//
+// streamListenerBuffer 是每个收听者缓冲的音频块数量。落后更多的收听者会被断开，
+// 以免阻塞音频线程。
+const streamListenerBuffer = 256
+
+// streamChannels and streamBitDepth describe the PCM format sent to listeners.
+//
+// streamChannels 和 streamBitDepth 描述发送给收听者的 PCM 格式。
+const (
+	streamChannels = 2
+	streamBitDepth = 16
+)
+
+// StreamServer serves the audio heard from the speaker as an endless 16-bit PCM WAV
+// stream over HTTP. It receives the final mixed samples through a speaker tap, resamples
+// them to a fixed rate (the speaker's rate changes from song to song) and fans them out
+// to every connected listener.
+//
+// StreamServer 通过 HTTP 将扬声器播放的音频作为无限长的 16 位 PCM WAV 流提供。
+// 它通过扬声器 tap 获取最终混音后的采样，重采样到固定的采样率（扬声器的采样率会随歌曲变化），
+// 然后分发给所有已连接的收听者。
+type StreamServer struct {
+	server     *http.Server
+	sampleRate beep.SampleRate
+	removeTap  func()
+
+	mu        sync.Mutex // Guards listeners and the resampler state. / 保护收听者和重采样器状态。
+	listeners map[chan []byte]struct{}
+	srcRate   beep.SampleRate // Rate of the previous tapped buffer. / 上一个 tap 缓冲区的采样率。
+	pos       float64         // Fractional read position, relative to the next buffer. / 相对于下一个缓冲区的小数读取位置。
+	prev      [2]float64      // Last sample of the previous buffer. / 上一个缓冲区的最后一个采样。
+}
+
+// startStreamServer starts the audio stream if stream_addr is configured.
+// It returns nil when the feature is disabled or the address cannot be bound.
+//
+// startStreamServer 在配置了 stream_addr 时启动音频流。
+// 如果功能被禁用或无法绑定地址，则返回 nil。
+func startStreamServer() *StreamServer {
+	if GlobalConfig == nil || GlobalConfig.App.StreamAddr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", GlobalConfig.App.StreamAddr)
+	if err != nil {
+		l.Warnf("failed to start audio stream on %s: %v\n\n警告: 在 %s 上启动音频流失败: %v", GlobalConfig.App.StreamAddr, err, GlobalConfig.App.StreamAddr, err)
+		return nil
+	}
+
+	sampleRate := beep.SampleRate(GlobalConfig.App.StreamSampleRate)
+	if sampleRate <= 0 {
+		sampleRate = 44100
+	}
+
+	s := &StreamServer{
+		sampleRate: sampleRate,
+		listeners:  make(map[chan []byte]struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleStream)
+	mux.HandleFunc("GET /stream.wav", s.handleStream)
+
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go s.server.Serve(listener)
+
+	s.removeTap = speaker.AddTap(s.tap)
+	speaker.SetMuted(GlobalConfig.App.StreamMuteLocal)
+	return s
+}
+
+// Close detaches the stream from the speaker, restores local output and disconnects
+// all listeners. A nil StreamServer does nothing.
+//
+// Close 将音频流从扬声器上移除，恢复本地输出并断开所有收听者。nil 的 StreamServer 不执行任何操作。
+func (s *StreamServer) Close() {
+	if s == nil {
+		return
+	}
+	s.removeTap()
+	speaker.SetMuted(false)
+
+	s.mu.Lock()
+	for ch := range s.listeners {
+		delete(s.listeners, ch)
+		close(ch)
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.server.Shutdown(ctx)
+}
+
+// handleStream writes a WAV header followed by PCM chunks until the client goes away.
+//
+// handleStream 写入 WAV 头，然后持续写入 PCM 数据块，直到客户端断开。
+func (s *StreamServer) handleStream(rw http.ResponseWriter, r *http.Request) {
+	ch := make(chan []byte, streamListenerBuffer)
+	s.mu.Lock()
+	s.listeners[ch] = struct{}{}
+	s.mu.Unlock()
+	defer s.removeListener(ch)
+
+	rw.Header().Set("Content-Type", "audio/wav")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("icy-name", "BM")
+	if _, err := rw.Write(streamWAVHeader(s.sampleRate)); err != nil {
+		return
+	}
+	flusher, _ := rw.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chunk, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := rw.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// removeListener unregisters and closes a listener channel if it is still registered.
+//
+// removeListener 注销并关闭仍处于注册状态的收听者通道。
+func (s *StreamServer) removeListener(ch chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.listeners[ch]; ok {
+		delete(s.listeners, ch)
+		close(ch)
+	}
+}
+
+// tap is the speaker tap. It runs on the audio thread with the speaker locked.
+//
+// tap 是扬声器的 tap 回调。它在音频线程中运行，此时扬声器处于锁定状态。
+func (s *StreamServer) tap(samples [][2]float64, sampleRate beep.SampleRate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.listeners) == 0 || sampleRate <= 0 {
+		s.srcRate = 0
+		return
+	}
+
+	chunk := s.encode(samples, sampleRate)
+	if len(chunk) == 0 {
+		return
+	}
+	for ch := range s.listeners {
+		select {
+		case ch <- chunk:
+		default:
+			delete(s.listeners, ch)
+			close(ch)
+		}
+	}
+}
+
+// encode linearly resamples samples to the stream rate and converts them to 16-bit
+// little-endian PCM. The resampler state carries over between calls so that buffer
+// boundaries are seamless; it is reset when the source rate changes.
+//
+// encode 将采样线性重采样到流的采样率，并转换为 16 位小端 PCM。
+// 重采样器的状态在调用之间保留，使缓冲区边界无缝衔接；源采样率变化时会重置。
+func (s *StreamServer) encode(samples [][2]float64, sampleRate beep.SampleRate) []byte {
+	if sampleRate != s.srcRate {
+		s.srcRate = sampleRate
+		s.pos = 0
+		s.prev = [2]float64{}
+	}
+
+	step := float64(sampleRate) / float64(s.sampleRate)
+	n := len(samples)
+	out := make([]byte, 0, int(float64(n)/step+2)*streamChannels*streamBitDepth/8)
+
+	// pos ranges over [-1, n-1); index -1 refers to the last sample of the previous buffer.
+	for ; s.pos < float64(n-1); s.pos += step {
+		i := int(math.Floor(s.pos))
+		frac := s.pos - float64(i)
+		a := s.prev
+		if i >= 0 {
+			a = samples[i]
+		}
+		b := samples[i+1]
+		for c := range streamChannels {
+			v := a[c] + (b[c]-a[c])*frac
+			v = math.Max(-1, math.Min(1, v))
+			out = binary.LittleEndian.AppendUint16(out, uint16(int16(v*math.MaxInt16)))
+		}
+	}
+	s.pos -= float64(n)
+	s.prev = samples[n-1]
+	return out
+}
+
+// streamWAVHeader returns a WAV header for an endless PCM stream. The RIFF and data sizes
+// are set to their maximum, which players treat as "read until the connection ends".
+//
+// streamWAVHeader 返回无限长 PCM 流的 WAV 头。RIFF 和 data 大小设为最大值，
+// 播放器会将其视为“读取直到连接结束”。
+func streamWAVHeader(sampleRate beep.SampleRate) []byte {
+	const blockAlign = streamChannels * streamBitDepth / 8
+	h := make([]byte, 0, 44)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, math.MaxUint32)
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, 1) // PCM
+	h = binary.LittleEndian.AppendUint16(h, streamChannels)
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate)*blockAlign)
+	h = binary.LittleEndian.AppendUint16(h, blockAlign)
+	h = binary.LittleEndian.AppendUint16(h, streamBitDepth)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, math.MaxUint32)
+	return h
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/gopxl/beep/v2"
+)
+
+func decodePCM(data []byte) [][2]int16 {
+	out := make([][2]int16, len(data)/4)
+	for i := range out {
+		out[i][0] = int16(binary.LittleEndian.Uint16(data[i*4:]))
+		out[i][1] = int16(binary.LittleEndian.Uint16(data[i*4+2:]))
+	}
+	return out
+}
+
+func rampSamples(n int) [][2]float64 {
+	samples := make([][2]float64, n)
+	for i := range samples {
+		v := float64(i) / float64(n)
+		samples[i] = [2]float64{v, -v}
+	}
+	return samples
+}
+
+func TestStreamWAVHeader(t *testing.T) {
+	h := streamWAVHeader(48000)
+	if len(h) != 44 {
+		t.Fatalf("streamWAVHeader(48000) is %d bytes; want 44", len(h))
+	}
+	tests := []struct {
+		offset int
+		want   any
+	}{
+		{0, "RIFF"},
+		{4, uint32(math.MaxUint32)},
+		{8, "WAVEfmt "},
+		{16, uint32(16)},
+		{20, uint16(1)},
+		{22, uint16(2)},
+		{24, uint32(48000)},
+		{28, uint32(48000 * 4)},
+		{32, uint16(4)},
+		{34, uint16(16)},
+		{36, "data"},
+		{40, uint32(math.MaxUint32)},
+	}
+	for _, tt := range tests {
+		var got any
+		switch want := tt.want.(type) {
+		case string:
+			got = string(h[tt.offset : tt.offset+len(want)])
+		case uint32:
+			got = binary.LittleEndian.Uint32(h[tt.offset:])
+		case uint16:
+			got = binary.LittleEndian.Uint16(h[tt.offset:])
+		}
+		if got != tt.want {
+			t.Errorf("streamWAVHeader(48000)[%d] = %v; want %v", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestStreamEncodeRate(t *testing.T) {
+	tests := []struct {
+		src, dst beep.SampleRate
+		n        int
+		want     int // Frames written for the first buffer. / 第一个缓冲区写入的帧数。
+	}{
+		{48000, 48000, 480, 479},
+		{96000, 48000, 960, 480},
+		{24000, 48000, 240, 478},
+		{44100, 48000, 441, 479},
+	}
+	for _, tt := range tests {
+		s := &StreamServer{sampleRate: tt.dst}
+		got := len(s.encode(rampSamples(tt.n), tt.src)) / 4
+		if got != tt.want {
+			t.Errorf("encode(%d frames, %d -> %d) = %d frames; want %d", tt.n, tt.src, tt.dst, got, tt.want)
+		}
+	}
+}
+
+func TestStreamEncodeValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst beep.SampleRate
+		in       [][2]float64
+		want     [][2]int16
+	}{
+		{"same rate", 48000, 48000,
+			[][2]float64{{0, 0}, {0.5, -0.5}, {1, -1}},
+			[][2]int16{{0, 0}, {16383, -16383}}},
+		{"upsample", 24000, 48000,
+			[][2]float64{{0, 0}, {0.5, -0.5}, {1, -1}},
+			[][2]int16{{0, 0}, {8191, -8191}, {16383, -16383}, {24575, -24575}}},
+		{"downsample", 96000, 48000,
+			[][2]float64{{0, 0}, {0.25, 0}, {0.5, 0}, {0.75, 0}, {1, 0}},
+			[][2]int16{{0, 0}, {16383, 0}}},
+		{"clipped", 48000, 48000,
+			[][2]float64{{2, -2}, {0, 0}},
+			[][2]int16{{math.MaxInt16, -math.MaxInt16}}},
+	}
+	for _, tt := range tests {
+		s := &StreamServer{sampleRate: tt.dst}
+		got := decodePCM(s.encode(tt.in, tt.src))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: encode = %v; want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			for c := range 2 {
+				if d := int(got[i][c]) - int(tt.want[i][c]); d < -1 || d > 1 {
+					t.Errorf("%s: encode = %v; want %v", tt.name, got, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamEncodeSplitBuffers(t *testing.T) {
+	samples := rampSamples(1000)
+	for _, rate := range []beep.SampleRate{22050, 44100, 48000, 96000} {
+		whole := decodePCM((&StreamServer{sampleRate: 48000}).encode(samples, rate))
+
+		s := &StreamServer{sampleRate: 48000}
+		var split []byte
+		rest := samples
+		for _, n := range []int{1, 7, 300, 192, 500} {
+			split = append(split, s.encode(rest[:n], rate)...)
+			rest = rest[n:]
+		}
+
+		got := decodePCM(split)
+		if len(got) != len(whole) {
+			t.Errorf("encode at %d in buffers = %d frames; want %d", rate, len(got), len(whole))
+			continue
+		}
+		for i := range got {
+			if d := int(got[i][0]) - int(whole[i][0]); d < -1 || d > 1 {
+				t.Errorf("encode at %d in buffers: frame %d = %v; want %v", rate, i, got[i], whole[i])
+				break
+			}
+		}
+	}
+}
+
+func TestStreamEncodeRateChange(t *testing.T) {
+	samples := rampSamples(100)
+	s := &StreamServer{sampleRate: 48000}
+	s.encode(samples, 44100)
+	got := s.encode(samples, 96000)
+	want := (&StreamServer{sampleRate: 48000}).encode(samples, 96000)
+	if !bytes.Equal(got, want) {
+		t.Errorf("encode after a rate change = %d bytes, differs from a new stream (%d bytes)", len(got), len(want))
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// TerminalCaps holds what the terminal reported about itself when probed.
+//
+// TerminalCaps 保存探测时终端报告的自身能力。
+type TerminalCaps struct {
+	Kitty     bool   `json:"kitty"`             // Kitty graphics protocol. / Kitty 图形协议。
+	Sixel     bool   `json:"sixel"`             // Sixel graphics. / Sixel 图形。
+	TrueColor bool   `json:"truecolor"`         // 24-bit color. / 24 位色。
+	Version   string `json:"version,omitempty"` // XTVERSION reply. / XTVERSION 应答。
+
+	// Background is the default background color as #rrggbb, "" if the terminal did not say.
+	// Background 是默认背景颜色，格式为 #rrggbb；终端未告知时为 ""。
+	Background string `json:"background,omitempty"`
+
+	// The cell size depends on the font and display, so it is not cached.
+	// 单元格尺寸取决于字体和显示器，因此不缓存。
+	CellWidth  int `json:"-"`
+	CellHeight int `json:"-"`
+
+	// Cached is set if the terminal did not answer in time and the capabilities come from
+	// an earlier probe of the same terminal.
+	// 如果终端未及时应答，能力来自之前对同一终端的探测，则设置 Cached。
+	Cached bool `json:"-"`
+}
+
+// terminalCaps is what the terminal reported at startup, or nil if it was not probed or
+// did not answer and nothing was cached for it.
+//
+// terminalCaps 是启动时终端报告的能力；如果未探测，或终端未应答且没有缓存，则为 nil。
+var terminalCaps *TerminalCaps
+
+// probeTimeout is how long the startup probe waits for the terminal to answer.
+//
+// probeTimeout 是启动探测等待终端应答的时长。
+const probeTimeout = time.Second
+
+// kittyProbeID is the image ID of the kitty graphics query.
+//
+// kittyProbeID 是 kitty 图形查询所用的图像 ID。
+const kittyProbeID = 31
+
+var errProbeTimeout = errors.New("terminal did not answer in time")
+
+var (
+	probeDA1Pattern      = regexp.MustCompile(`\x1b\[\?([0-9;]*)c`)
+	probeKittyPattern    = regexp.MustCompile(`\x1b_Gi=` + strconv.Itoa(kittyProbeID) + `;OK\x1b\\`)
+	probeVersionPattern  = regexp.MustCompile(`\x1bP>\|([^\x1b]*)\x1b\\`)
+	probeTermcapPattern  = regexp.MustCompile(`\x1bP1\+r([0-9A-Fa-f]+)`)
+	probeCellSizePattern = regexp.MustCompile(`\x1b\[6;([0-9]+);([0-9]+)t`)
+	probeColorPattern    = regexp.MustCompile(`\x1b\]11;rgb:([0-9A-Fa-f]{1,4})/([0-9A-Fa-f]{1,4})/([0-9A-Fa-f]{1,4})(?:\x07|\x1b\\)`)
+)
+
+// initTerminalCaps probes the terminal at startup, in raw mode and before input is read.
+// The answer is cached per terminal; if the terminal does not answer in time, e.g. over a
+// slow SSH connection, the cached capabilities are used.
+//
+// initTerminalCaps 在启动时、原始模式下且开始读取输入之前探测终端。应答按终端缓存；
+// 如果终端未及时应答（例如通过慢速 SSH 连接），则使用缓存的能力。
+func initTerminalCaps() {
+	caps, err := probeTerminal(probeTimeout)
+	key := terminalCacheKey()
+	if err == nil {
+		terminalCaps = caps
+		saveTerminalCaps(key, caps)
+		return
+	}
+	if cached, ok := loadTerminalCache()[key]; ok {
+		cached.Cached = true
+		if caps != nil {
+			cached.CellWidth, cached.CellHeight = caps.CellWidth, caps.CellHeight
+		}
+		terminalCaps = cached
+	}
+}
+
+// probeTerminal sends the kitty graphics query, XTVERSION, XTGETTCAP for truecolor, the cell
+// size query, the background color query and Primary Device Attributes, and parses the
+// answers. Every terminal answers Device Attributes and does so after the queries before
+// it, so its answer ends the probe. The terminal must be in raw mode.
+//
+// probeTerminal 发送 kitty 图形查询、XTVERSION、查询真彩色的 XTGETTCAP、单元格尺寸查询、
+// 背景颜色查询和主设备属性查询，并解析应答。每个终端都会应答设备属性查询，且在应答之前的
+// 查询之后应答，因此收到它的应答即结束探测。终端必须处于原始模式。
+func probeTerminal(timeout time.Duration) (*TerminalCaps, error) {
+	var query strings.Builder
+	// Inside a multiplexer the queries reach the multiplexer, which does not forward the
+	// kitty one.
+	// 在复用器中查询会发送给复用器，而复用器不会转发 kitty 查询。
+	if detectMultiplexer() == muxNone {
+		fmt.Fprintf(&query, "\x1b_Gi=%d,s=1,v=1,a=q,t=d,f=24;AAAA\x1b\\", kittyProbeID)
+	}
+	query.WriteString("\x1b[>0q")
+	fmt.Fprintf(&query, "\x1bP+q%s;%s\x1b\\", hex.EncodeToString([]byte("Tc")), hex.EncodeToString([]byte("RGB")))
+	query.WriteString("\x1b[16t")
+	query.WriteString("\x1b]11;?\x1b\\")
+	query.WriteString("\x1b[c")
+	if _, err := os.Stdout.WriteString(query.String()); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	var buf []byte
+	chunk := make([]byte, 256)
+	for !probeDA1Pattern.Match(buf) {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			// The replies still to come are dropped by the input reader, see
+			// consumeCSISequence and consumeStringSequence.
+			// 之后到达的应答会被输入读取器丢弃，见 consumeCSISequence 和 consumeStringSequence。
+			return parseProbeReplies(buf), errProbeTimeout
+		}
+		fds := []unix.PollFd{{Fd: int32(os.Stdin.Fd()), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(remaining.Milliseconds())+1)
+		if err == unix.EINTR || (err == nil && n == 0) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m, err := os.Stdin.Read(chunk)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, chunk[:m]...)
+	}
+	return parseProbeReplies(buf), nil
+}
+
+// parseProbeReplies parses the answers of the terminal to probeTerminal's queries.
+//
+// parseProbeReplies 解析终端对 probeTerminal 查询的应答。
+func parseProbeReplies(buf []byte) *TerminalCaps {
+	caps := &TerminalCaps{Kitty: probeKittyPattern.Match(buf)}
+
+	// The first attribute is the terminal class; 4 among the others means sixel.
+	// 第一个属性是终端类别；其余属性中的 4 表示支持 sixel。
+	if m := probeDA1Pattern.FindSubmatch(buf); m != nil {
+		attrs := strings.Split(string(m[1]), ";")
+		for _, attr := range attrs[1:] {
+			if attr == "4" {
+				caps.Sixel = true
+			}
+		}
+	}
+
+	if m := probeVersionPattern.FindSubmatch(buf); m != nil {
+		caps.Version = string(m[1])
+	}
+
+	for _, m := range probeTermcapPattern.FindAllSubmatch(buf, -1) {
+		name, err := hex.DecodeString(string(m[1]))
+		if err == nil && (string(name) == "Tc" || string(name) == "RGB") {
+			caps.TrueColor = true
+		}
+	}
+
+	if m := probeCellSizePattern.FindSubmatch(buf); m != nil {
+		caps.CellHeight, _ = strconv.Atoi(string(m[1]))
+		caps.CellWidth, _ = strconv.Atoi(string(m[2]))
+	}
+
+	// Each channel has one to four hex digits, scaled to its own maximum.
+	// 每个通道有一到四位十六进制数字，按其自身的最大值缩放。
+	if m := probeColorPattern.FindSubmatch(buf); m != nil {
+		var bg [3]int
+		for i := range bg {
+			v, _ := strconv.ParseUint(string(m[i+1]), 16, 16)
+			bg[i] = int(v * 255 / (1<<(4*len(m[i+1])) - 1))
+		}
+		caps.Background = hexColor(bg)
+	}
+	return caps
+}
+
+// terminalCacheKey identifies the terminal (and multiplexer) BM runs in for the cache.
+//
+// terminalCacheKey 为缓存标识 BM 所运行的终端（及复用器）。
+func terminalCacheKey() string {
+	mux := ""
+	switch detectMultiplexer() {
+	case muxTmux:
+		mux = "tmux"
+	case muxZellij:
+		mux = "zellij"
+	}
+	return strings.Join([]string{os.Getenv("TERM"), os.Getenv("TERM_PROGRAM"), os.Getenv("TERM_PROGRAM_VERSION"), mux}, "|")
+}
+
+// terminalCachePath returns the path of the file caching terminal capabilities.
+//
+// terminalCachePath 返回缓存终端能力的文件路径。
+func terminalCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "BM", "terminals.json"), nil
+}
+
+// loadTerminalCache returns the cached capabilities by terminal. A missing or unreadable
+// cache is empty.
+//
+// loadTerminalCache 返回按终端缓存的能力。缺失或无法读取的缓存视为空。
+func loadTerminalCache() map[string]*TerminalCaps {
+	cache := make(map[string]*TerminalCaps)
+	path, err := terminalCachePath()
+	if err != nil {
+		return cache
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return make(map[string]*TerminalCaps)
+	}
+	return cache
+}
+
+// saveTerminalCaps caches the capabilities of a terminal.
+//
+// saveTerminalCaps 缓存终端的能力。
+func saveTerminalCaps(key string, caps *TerminalCaps) {
+	stored := *caps
+	stored.CellWidth, stored.CellHeight = 0, 0
+	cache := loadTerminalCache()
+	if cached, ok := cache[key]; ok && *cached == stored {
+		return
+	}
+	cache[key] = &stored
+
+	path, err := terminalCachePath()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		l.Warnf("could not create cache directory: %v\n\n无法创建缓存目录: %v", err, err)
+		return
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		l.Warnf("could not write terminal cache: %v\n\n无法写入终端缓存: %v", err, err)
+	}
+}
+
+// describeTerminal probes the terminal, if BM runs in one, and returns what was detected
+// as label and value pairs for `bm help`.
+//
+// describeTerminal 探测终端（如果 BM 运行在终端中），并以标签和值对的形式返回检测结果，
+// 供 `bm help` 使用。
+func describeTerminal() [][2]string {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) && term.IsTerminal(int(os.Stdout.Fd())) {
+		if oldState, err := term.MakeRaw(fd); err == nil {
+			initTerminalCaps()
+			term.Restore(fd, oldState)
+		}
+	}
+
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	mux := "none"
+	switch detectMultiplexer() {
+	case muxTmux:
+		mux = "tmux (passthrough " + yesNo(tmuxPassthrough()) + ")"
+	case muxZellij:
+		mux = "zellij"
+	}
+
+	if terminalCaps == nil {
+		return [][2]string{
+			{"Multiplexer", mux},
+			{"Capabilities", "not detected"},
+			{"Cover protocol", DetectTerminalProtocol().String()},
+		}
+	}
+
+	caps := terminalCaps
+	version := caps.Version
+	if version == "" {
+		version = "unknown"
+	}
+	if caps.Cached {
+		version += " (from cache)"
+	}
+	background := caps.Background
+	if background == "" {
+		background = "unknown"
+	}
+	cellW, cellH := getCellSize()
+	return [][2]string{
+		{"Terminal", version},
+		{"Multiplexer", mux},
+		{"Kitty graphics", yesNo(caps.Kitty)},
+		{"Sixel", yesNo(caps.Sixel)},
+		{"Truecolor", yesNo(caps.TrueColor)},
+		{"Cell size", fmt.Sprintf("%dx%d px", cellW, cellH)},
+		{"Background", background},
+		{"Cover protocol", DetectTerminalProtocol().String()},
+	}
+}
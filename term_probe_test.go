@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProbeReplies(t *testing.T) {
+	const da1 = "\x1b[?62;22c"
+	tests := []struct {
+		name  string
+		reply string
+		want  TerminalCaps
+	}{
+		{"nothing", "", TerminalCaps{}},
+		{"da1 without sixel", da1, TerminalCaps{}},
+		{"da1 class only", "\x1b[?4c", TerminalCaps{}},
+		{"da1 with sixel", "\x1b[?62;4;22c", TerminalCaps{Sixel: true}},
+		{"da1 with attribute 44", "\x1b[?62;44c", TerminalCaps{}},
+		{
+			"kitty, version and truecolor",
+			"\x1b_Gi=31;OK\x1b\\\x1bP>|kitty(0.35.2)\x1b\\\x1bP1+r5463\x1b\\" + da1,
+			TerminalCaps{Kitty: true, Version: "kitty(0.35.2)", TrueColor: true},
+		},
+		{"kitty error", "\x1b_Gi=31;ENOTSUPPORTED\x1b\\" + da1, TerminalCaps{}},
+		{"truecolor from RGB", "\x1bP1+r524742=38\x1b\\" + da1, TerminalCaps{TrueColor: true}},
+		{"unknown capability", "\x1bP0+r5463\x1b\\" + da1, TerminalCaps{}},
+		{"cell size", "\x1b[6;17;8t" + da1, TerminalCaps{CellWidth: 8, CellHeight: 17}},
+		{"background 1 digit", "\x1b]11;rgb:f/0/8\x1b\\" + da1, TerminalCaps{Background: "#ff0088"}},
+		{"background 2 digits", "\x1b]11;rgb:1e/1e/2e\x07" + da1, TerminalCaps{Background: "#1e1e2e"}},
+		{"background 3 digits", "\x1b]11;rgb:fff/800/000\x1b\\" + da1, TerminalCaps{Background: "#ff7f00"}},
+		{"background 4 digits", "\x1b]11;rgb:1e1e/1e1e/2e2e\x1b\\" + da1, TerminalCaps{Background: "#1e1e2e"}},
+		{"background 4 digits full", "\x1b]11;rgb:ffff/0000/8080\x07" + da1, TerminalCaps{Background: "#ff0080"}},
+		{"background 5 digits", "\x1b]11;rgb:fffff/0/0\x07" + da1, TerminalCaps{}},
+		{"background unterminated", "\x1b]11;rgb:ff/ff/ff" + da1, TerminalCaps{}},
+	}
+	for _, tt := range tests {
+		if got := parseProbeReplies([]byte(tt.reply)); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: parseProbeReplies(%q) = %+v; want %+v", tt.name, tt.reply, *got, tt.want)
+		}
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"os"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -23,8 +22,26 @@ const (
 	ProtocolSixel
 	ProtocolKitty
 	ProtocolITerm2
+	ProtocolBlocks
 )
 
+// String returns the name of the protocol as used in the configuration.
+//
+// String 返回协议在配置中使用的名称。
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolSixel:
+		return "sixel"
+	case ProtocolKitty:
+		return "kitty"
+	case ProtocolITerm2:
+		return "iterm2"
+	case ProtocolBlocks:
+		return "blocks"
+	}
+	return "auto"
+}
+
 var kittyImageID uint32 = uint32(os.Getpid()<<16) + uint32(time.Now().UnixMicro()&0xFFFF)
 
 var kittyZlibPool = sync.Pool{
@@ -47,7 +64,17 @@ var kittyBase64Pool = sync.Pool{
 	},
 }
 
+// DetectTerminalProtocol returns the image protocol to draw covers with. Covers are drawn
+// with text blocks if the terminal has no known graphics support, or runs in a multiplexer
+// that does not forward its graphics.
+//
+// DetectTerminalProtocol 返回用于绘制封面的图像协议。如果终端没有已知的图形支持，
+// 或运行在不转发其图形的复用器中，则使用文本色块绘制封面。
 func DetectTerminalProtocol() Protocol {
+	return muxProtocol(detectOuterProtocol())
+}
+
+func detectOuterProtocol() Protocol {
 	if GlobalConfig != nil && GlobalConfig.App.ImageProtocol != "" {
 		switch strings.ToLower(GlobalConfig.App.ImageProtocol) {
 		case "auto":
@@ -57,7 +84,24 @@ func DetectTerminalProtocol() Protocol {
 			return ProtocolSixel
 		case "iterm2":
 			return ProtocolITerm2
+		case "blocks":
+			return ProtocolBlocks
+		}
+	}
+
+	// Inside a multiplexer the probe only reaches the multiplexer, so the outer terminal is
+	// guessed from the environment.
+	// 在复用器中探测只能到达复用器，因此根据环境变量猜测外层终端。
+	if terminalCaps != nil && detectMultiplexer() == muxNone {
+		switch {
+		case os.Getenv("TERM_PROGRAM") == "iTerm.app":
+			return ProtocolITerm2
+		case terminalCaps.Kitty:
+			return ProtocolKitty
+		case terminalCaps.Sixel:
+			return ProtocolSixel
 		}
+		return ProtocolBlocks
 	}
 
 	termProgram := os.Getenv("TERM_PROGRAM")
@@ -90,31 +134,84 @@ func DetectTerminalProtocol() Protocol {
 		return ProtocolSixel
 	}
 
-	return ProtocolSixel
+	return ProtocolBlocks
+}
+
+// clearScreen clears the screen and removes the cover from it. It erases from the top-left
+// corner instead of using ED 2 or 3, which in kitty would also free the covers kept in the
+// terminal for reuse.
+//
+// clearScreen 清除屏幕并从中移除封面。它从左上角开始擦除，而不使用 ED 2 或 3，
+// 因为在 kitty 中后者还会释放保留在终端中以供复用的封面。
+func clearScreen() {
+	deleteKittyPlacement()
+	fmt.Print("\x1b[H\x1b[J")
 }
 
-func RenderImage(img image.Image, widthChars, heightChars int) error {
+// RenderImage draws a cover with its top-left corner at the given cell, counted from one.
+// img is the cover scaled to its size on screen; kitty instead keeps the original cover in
+// the terminal under its cover cache key and scales it there, so that redraws only place it
+// again.
+//
+// RenderImage 以给定单元格（从一开始计数）为左上角绘制封面。img 是缩放到屏幕尺寸的封面；
+// kitty 则将原始封面按其封面缓存键保存在终端中并在终端内缩放，使重绘时只需重新放置。
+func RenderImage(cover image.Image, key string, img image.Image, row, col, widthChars, heightChars int) error {
 	protocol := DetectTerminalProtocol()
+	inTmux := detectMultiplexer() == muxTmux
 
+	fmt.Printf("\x1b[%d;%dH", row, col)
 	switch protocol {
+	case ProtocolBlocks:
+		return renderBlocksImage(img, row, col, widthChars, heightChars)
 	case ProtocolKitty:
-		return renderKittyImage(img, widthChars, heightChars)
+		return renderKittyCover(cover, key, row, col, widthChars, heightChars)
 	case ProtocolITerm2:
-		return renderITerm2Image(img, widthChars, heightChars)
-	case ProtocolSixel:
-		return renderSixelImage(img)
+		if inTmux {
+			var buf bytes.Buffer
+			if err := writeITerm2Image(&buf, img, widthChars, heightChars); err != nil {
+				return err
+			}
+			return writePassthroughAt(row, col, buf.String())
+		}
+		return writeITerm2Image(os.Stdout, img, widthChars, heightChars)
 	default:
+		if inTmux {
+			var buf bytes.Buffer
+			if err := NewEncoder(&buf).Encode(img); err != nil {
+				return err
+			}
+			return writePassthroughAt(row, col, buf.String())
+		}
 		return renderSixelImage(img)
 	}
 }
 
-func renderKittyImage(img image.Image, widthChars, heightChars int) error {
+// writePassthroughAt sends an image sequence through tmux passthrough, moving the cursor of
+// the outer terminal to the given cell of BM's pane first.
+//
+// writePassthroughAt 通过 tmux 透传发送图像序列，并先将外层终端的光标移动到 BM 窗格的给定单元格。
+func writePassthroughAt(row, col int, seq string) error {
+	top, left, err := tmuxPaneOffset()
+	if err != nil {
+		return fmt.Errorf("failed to get tmux pane position: %v\n\n无法获取 tmux 窗格位置: %v", err, err)
+	}
+	seq = fmt.Sprintf("\x1b7\x1b[%d;%dH%s\x1b8", top+row, left+col, seq)
+	if _, err := io.WriteString(os.Stdout, wrapPassthrough(seq)); err != nil {
+		return fmt.Errorf("failed to write image data: %v\n\n无法写入图像数据: %v", err, err)
+	}
+	return nil
+}
+
+// transmitKittyImage sends an image to the terminal with the kitty graphics protocol. The
+// action and its keys come first in control; the pixel format and size are added here.
+//
+// transmitKittyImage 使用 kitty 图形协议将图像发送到终端。control 以动作及其参数开头；
+// 像素格式和尺寸在此处添加。
+func transmitKittyImage(img image.Image, control string) error {
 	bounds := img.Bounds()
 	pixelWidth := bounds.Dx()
 	pixelHeight := bounds.Dy()
 
-	imageID := atomic.AddUint32(&kittyImageID, 1)
-
 	rgbaImg := image.NewRGBA(bounds)
 	draw.Draw(rgbaImg, rgbaImg.Bounds(), img, bounds.Min, draw.Src)
 
@@ -148,17 +245,11 @@ func renderKittyImage(img image.Image, widthChars, heightChars int) error {
 	base64Buf := base64Raw[:encLen]
 	base64.StdEncoding.Encode(base64Buf, compressedData)
 
-	var control string
+	control = fmt.Sprintf("%s,f=32,s=%d,v=%d,q=2", control, pixelWidth, pixelHeight)
 	if compressed {
-		control = fmt.Sprintf("a=T,f=32,i=%d,s=%d,v=%d,c=%d,r=%d,q=2,o=z",
-			imageID, pixelWidth, pixelHeight, widthChars, heightChars)
-	} else {
-		control = fmt.Sprintf("a=T,f=32,i=%d,s=%d,v=%d,c=%d,r=%d,q=2",
-			imageID, pixelWidth, pixelHeight, widthChars, heightChars)
+		control += ",o=z"
 	}
 
-	inTmux := os.Getenv("TMUX") != ""
-
 	chunkSize := 4096
 	first := true
 
@@ -186,11 +277,7 @@ func renderKittyImage(img image.Image, widthChars, heightChars int) error {
 			}
 		}
 
-		if inTmux {
-			chunkSequence = "\x1bPtmux;" + strings.ReplaceAll(chunkSequence, "\x1b", "\x1b\x1b") + "\x1b\\"
-		}
-
-		if _, err := io.WriteString(os.Stdout, chunkSequence); err != nil {
+		if _, err := io.WriteString(os.Stdout, wrapPassthrough(chunkSequence)); err != nil {
 			kittyBase64Pool.Put(base64RawPtr)
 			return fmt.Errorf("failed to write kitty image data: %v\n\n无法写入 kitty 图像数据: %v", err, err)
 		}
@@ -200,7 +287,7 @@ func renderKittyImage(img image.Image, widthChars, heightChars int) error {
 	return nil
 }
 
-func renderITerm2Image(img image.Image, widthChars, heightChars int) error {
+func writeITerm2Image(w io.Writer, img image.Image, widthChars, heightChars int) error {
 	var buf bytes.Buffer
 	if err := png.Encode(&buf, img); err != nil {
 		return fmt.Errorf("failed to encode PNG: %v\n\n无法编码 PNG: %v", err, err)
@@ -211,11 +298,7 @@ func renderITerm2Image(img image.Image, widthChars, heightChars int) error {
 	sequence := fmt.Sprintf("\x1b]1337;File=inline=1;width=%d;height=%d:%s\x07",
 		widthChars, heightChars, encoded)
 
-	if os.Getenv("TMUX") != "" {
-		sequence = "\x1bPtmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + "\x1b\\"
-	}
-
-	if _, err := io.WriteString(os.Stdout, sequence); err != nil {
+	if _, err := io.WriteString(w, sequence); err != nil {
 		return fmt.Errorf("failed to write iTerm2 image data: %v\n\n无法写入 iTerm2 图像数据: %v", err, err)
 	}
 
@@ -229,43 +312,18 @@ func renderSixelImage(img image.Image) error {
 func ClearKittyImages() error {
 	sequence := "\x1b_Ga=d\x1b\\"
 
-	if os.Getenv("TMUX") != "" {
-		sequence = "\x1bPtmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + "\x1b\\"
-	}
-
-	if _, err := io.WriteString(os.Stdout, sequence); err != nil {
+	if _, err := io.WriteString(os.Stdout, wrapPassthrough(sequence)); err != nil {
 		return fmt.Errorf("failed to clear kitty images: %v\n\n无法清除 kitty 图像: %v", err, err)
 	}
 
 	return nil
 }
 
-func GetTerminalFontSize() (width, height int, err error) {
-	fmt.Print("\x1b[16t")
-
-	var buf [32]byte
-	n, err := os.Stdin.Read(buf[:])
-	if err != nil {
-		return 8, 16, nil
-	}
-
-	response := string(buf[:n])
-	if strings.HasPrefix(response, "\x1b[8;") && strings.HasSuffix(response, "t") {
-		parts := strings.Split(response[4:len(response)-1], ";")
-		if len(parts) >= 2 {
-			var h, w int
-			if _, err := fmt.Sscanf(parts[0], "%d", &h); err == nil {
-				if _, err := fmt.Sscanf(parts[1], "%d", &w); err == nil {
-					return w, h, nil
-				}
-			}
-		}
+func SupportsTrueColor() bool {
+	if terminalCaps != nil && terminalCaps.TrueColor {
+		return true
 	}
 
-	return 8, 16, nil
-}
-
-func SupportsTrueColor() bool {
 	colorterm := os.Getenv("COLORTERM")
 	if colorterm == "truecolor" || colorterm == "24bit" {
 		return true
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+//go:embed themes/*.toml
+var builtinThemes embed.FS
+
+// ThemeStyle is how one element of the pages is drawn. Colors are "#rrggbb", a number of the
+// 256-color palette, one of the 16 basic color names ("red", "bright-blue", ...) or "" for
+// the terminal's default.
+//
+// ThemeStyle 是页面中某个元素的绘制方式。颜色可以是 "#rrggbb"、256 色调色板中的编号、
+// 16 种基本颜色名称之一（"red"、"bright-blue" 等），或 "" 表示终端默认颜色。
+type ThemeStyle struct {
+	Fg      string `toml:"fg"`
+	Bg      string `toml:"bg"`
+	Bold    bool   `toml:"bold"`
+	Reverse bool   `toml:"reverse"`
+}
+
+// Theme holds the colors of all pages. It is read from a theme file and the [theme] section
+// of the config can override any of its elements.
+//
+// Theme 保存所有页面的颜色。它从主题文件读取，配置中的 [theme] 节可以覆盖其中任意元素。
+type Theme struct {
+	CoverColors   bool       `toml:"cover_colors"`   // Accent colors from the cover. / 从封面获取强调色。
+	Title         ThemeStyle `toml:"title"`          // Page titles. / 页面标题。
+	Cursor        ThemeStyle `toml:"cursor"`         // Row under the cursor. / 光标所在行。
+	Selected      ThemeStyle `toml:"selected"`       // Selected songs and playlist rows. / 已选歌曲和播放列表行。
+	Playing       ThemeStyle `toml:"playing"`        // The playing song. / 正在播放的歌曲。
+	Directory     ThemeStyle `toml:"directory"`      // Directories in the library. / 媒体库中的目录。
+	Separator     ThemeStyle `toml:"separator"`      // Separators in search results. / 搜索结果中的分隔符。
+	Scrollbar     ThemeStyle `toml:"scrollbar"`      // List scrollbars. / 列表滚动条。
+	Footer        ThemeStyle `toml:"footer"`         // Footers and hints. / 页脚和提示。
+	Search        ThemeStyle `toml:"search"`         // The search prompt. / 搜索提示符。
+	Progress      ThemeStyle `toml:"progress"`       // Played part of the progress bar. / 进度条已播放部分。
+	ProgressEmpty ThemeStyle `toml:"progress_empty"` // Rest of the progress bar. / 进度条其余部分。
+}
+
+// themeColorNames are the names of the 16 basic colors, by their ANSI index.
+//
+// themeColorNames 是 16 种基本颜色的名称，按其 ANSI 索引排列。
+var themeColorNames = [16]string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright-black", "bright-red", "bright-green", "bright-yellow",
+	"bright-blue", "bright-magenta", "bright-cyan", "bright-white",
+}
+
+// resolveTheme loads the theme named in the [theme] section, from ~/.config/BM/themes/<name>.toml
+// or the built-in themes, applies the section's overrides and checks its colors.
+//
+// resolveTheme 加载 [theme] 节中指定的主题（来自 ~/.config/BM/themes/<name>.toml 或内置主题），
+// 应用该节中的覆盖设置并检查其颜色。
+func resolveTheme(config *Config, md toml.MetaData) error {
+	var section struct {
+		Name string `toml:"name"`
+	}
+	if md.IsDefined("theme") {
+		if err := md.PrimitiveDecode(config.Theme, &section); err != nil {
+			return fmt.Errorf("could not decode theme section: %v\n\n无法解析主题配置: %v", err, err)
+		}
+	}
+	if section.Name == "" {
+		section.Name = "default"
+	}
+
+	data, err := readThemeFile(section.Name)
+	if err != nil {
+		return err
+	}
+	var theme Theme
+	if _, err := toml.Decode(string(data), &theme); err != nil {
+		return fmt.Errorf("could not decode theme %q: %v\n\n无法解析主题 %q: %v", section.Name, err, section.Name, err)
+	}
+	if md.IsDefined("theme") {
+		if err := md.PrimitiveDecode(config.Theme, &theme); err != nil {
+			return fmt.Errorf("could not decode theme section: %v\n\n无法解析主题配置: %v", err, err)
+		}
+	}
+
+	styles := map[string]ThemeStyle{
+		"title": theme.Title, "cursor": theme.Cursor, "selected": theme.Selected,
+		"playing": theme.Playing, "directory": theme.Directory, "separator": theme.Separator,
+		"scrollbar": theme.Scrollbar, "footer": theme.Footer, "search": theme.Search,
+		"progress": theme.Progress, "progress_empty": theme.ProgressEmpty,
+	}
+	for element, style := range styles {
+		for _, c := range []string{style.Fg, style.Bg} {
+			if _, ok := parseThemeColor(c); !ok {
+				return fmt.Errorf("invalid color %q for %s in theme %q\n\n主题 %q 中 %s 的颜色 %q 无效", c, element, section.Name, section.Name, element, c)
+			}
+		}
+	}
+
+	config.ActiveTheme = &theme
+	return nil
+}
+
+// readThemeFile returns the theme file of a theme, preferring the user's own over a built-in
+// theme of the same name.
+//
+// readThemeFile 返回主题的主题文件，用户自己的主题优先于同名的内置主题。
+func readThemeFile(name string) ([]byte, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid theme name %q\n\n无效的主题名称 %q", name, name)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		data, err := os.ReadFile(filepath.Join(home, ".config", "BM", "themes", name+".toml"))
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("could not read theme %q: %v\n\n无法读取主题 %q: %v", name, err, name, err)
+		}
+	}
+	data, err := builtinThemes.ReadFile("themes/" + name + ".toml")
+	if err != nil {
+		return nil, fmt.Errorf("theme %q not found in ~/.config/BM/themes\n\n在 ~/.config/BM/themes 中找不到主题 %q", name, name)
+	}
+	return data, nil
+}
+
+// themeColor is a parsed theme color: an index of the 256-color palette (the first 16 being
+// the basic colors) or an RGB color.
+//
+// themeColor 是解析后的主题颜色：256 色调色板中的索引（前 16 个为基本颜色）或 RGB 颜色。
+type themeColor struct {
+	index int // -1 for an RGB color. / RGB 颜色时为 -1。
+	rgb   [3]int
+}
+
+// parseThemeColor parses a theme color. "" is the terminal's default color and parses to nil.
+//
+// parseThemeColor 解析主题颜色。"" 表示终端默认颜色，解析结果为 nil。
+func parseThemeColor(s string) (*themeColor, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "default" {
+		return nil, true
+	}
+	if rgb, ok := parseHexColor(s); ok {
+		return &themeColor{index: -1, rgb: rgb}, true
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 255 {
+			return nil, false
+		}
+		return &themeColor{index: n}, true
+	}
+	switch s {
+	case "gray", "grey":
+		s = "bright-black"
+	}
+	for i, name := range themeColorNames {
+		if s == name {
+			return &themeColor{index: i}, true
+		}
+	}
+	return nil, false
+}
+
+// sgr returns the escape sequence setting the color, degraded to what the terminal shows.
+//
+// sgr 返回设置该颜色的转义序列，并降级为终端能显示的颜色。
+func (c *themeColor) sgr(depth colorDepth, background bool) string {
+	index := c.index
+	if index < 0 {
+		if depth == colorDepthTrue {
+			return blocksColor(color.RGBA{uint8(c.rgb[0]), uint8(c.rgb[1]), uint8(c.rgb[2]), 255}, depth, background)
+		}
+		if depth == colorDepth256 {
+			index = nearest256(c.rgb[0], c.rgb[1], c.rgb[2])
+		} else {
+			index = nearest16(c.rgb[0], c.rgb[1], c.rgb[2])
+		}
+	}
+	if index >= 16 && depth == colorDepth16 {
+		rgb := color256RGB(index)
+		index = nearest16(rgb[0], rgb[1], rgb[2])
+	}
+
+	if index >= 16 {
+		if background {
+			return fmt.Sprintf("\x1b[48;5;%dm", index)
+		}
+		return fmt.Sprintf("\x1b[38;5;%dm", index)
+	}
+	code := 30 + index
+	if index >= 8 {
+		code = 90 + index - 8
+	}
+	if background {
+		code += 10
+	}
+	return fmt.Sprintf("\x1b[%dm", code)
+}
+
+// sgr returns the escape sequences that start drawing with the style.
+//
+// sgr 返回以该样式开始绘制的转义序列。
+func (s ThemeStyle) sgr() string {
+	var b strings.Builder
+	if s.Bold {
+		b.WriteString("\x1b[1m")
+	}
+	if s.Reverse {
+		b.WriteString("\x1b[7m")
+	}
+	depth := detectColorDepth()
+	if c, _ := parseThemeColor(s.Fg); c != nil {
+		b.WriteString(c.sgr(depth, false))
+	}
+	if c, _ := parseThemeColor(s.Bg); c != nil {
+		b.WriteString(c.sgr(depth, true))
+	}
+	return b.String()
+}
+
+// color256RGB returns the usual xterm RGB value of a color of the 256-color palette.
+//
+// color256RGB 返回 256 色调色板中某个颜色的常用 xterm RGB 值。
+func color256RGB(index int) [3]int {
+	switch {
+	case index < 16:
+		return ansi16Palette[index]
+	case index < 232:
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		index -= 16
+		return [3]int{levels[index/36], levels[index/6%6], levels[index%6]}
+	default:
+		gray := 8 + 10*(index-232)
+		return [3]int{gray, gray, gray}
+	}
+}
+
+// titleStyle returns the style of page titles, in the cover's primary color when the pages
+// take their colors from the cover.
+//
+// titleStyle 返回页面标题的样式；页面从封面获取颜色时使用封面的主强调色。
+func (a *App) titleStyle() string {
+	style := GlobalConfig.ActiveTheme.Title
+	if palette, ok := a.coverColors(); ok {
+		style.Fg = hexColor(palette.Primary)
+	}
+	return style.sgr()
+}
+
+// cursorStyle returns the style of the row under the cursor in lists, highlighted with the
+// cover's colors when the pages take their colors from the cover.
+//
+// cursorStyle 返回列表中光标所在行的样式；页面从封面获取颜色时使用封面的颜色高亮。
+func (a *App) cursorStyle() string {
+	style := GlobalConfig.ActiveTheme.Cursor
+	if palette, ok := a.coverColors(); ok {
+		style.Fg, style.Bg = hexColor(palette.OnBackground), hexColor(palette.Background)
+		style.Reverse = false
+	}
+	return style.sgr()
+}
+
+// playingStyle returns the style of the playing song in lists, in the cover's primary color
+// when the pages take their colors from the cover.
+//
+// playingStyle 返回列表中正在播放的歌曲的样式；页面从封面获取颜色时使用封面的主强调色。
+func (a *App) playingStyle() string {
+	style := GlobalConfig.ActiveTheme.Playing
+	if palette, ok := a.coverColors(); ok {
+		style.Fg = hexColor(palette.Primary)
+	}
+	return style.sgr()
+}
+
+// progressStyles returns the styles of the played and unplayed parts of the progress bar, in
+// the cover's accent colors when the pages take their colors from the cover.
+//
+// progressStyles 返回进度条已播放和未播放部分的样式；页面从封面获取颜色时使用封面的强调色。
+func (a *App) progressStyles() (played, unplayed string) {
+	playedStyle, unplayedStyle := GlobalConfig.ActiveTheme.Progress, GlobalConfig.ActiveTheme.ProgressEmpty
+	if palette, ok := a.coverColors(); ok {
+		playedStyle.Fg, unplayedStyle.Fg = hexColor(palette.Primary), hexColor(palette.Secondary)
+	}
+	return playedStyle.sgr(), unplayedStyle.sgr()
+}
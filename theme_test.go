@@ -0,0 +1,105 @@
+package main
+
+import "testing"
+
+func TestParseThemeColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *themeColor
+		ok   bool
+	}{
+		{"", nil, true},
+		{"default", nil, true},
+		{"red", &themeColor{index: 1}, true},
+		{" Bright-White ", &themeColor{index: 15}, true},
+		{"grey", &themeColor{index: 8}, true},
+		{"gray", &themeColor{index: 8}, true},
+		{"208", &themeColor{index: 208}, true},
+		{"0", &themeColor{index: 0}, true},
+		{"#FF8800", &themeColor{index: -1, rgb: [3]int{255, 136, 0}}, true},
+		{"256", nil, false},
+		{"-1", nil, false},
+		{"purple", nil, false},
+		{"#ff88", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseThemeColor(tt.in)
+		if ok != tt.ok || (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseThemeColor(%q) = %+v, %v; want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestThemeColorSGR(t *testing.T) {
+	tests := []struct {
+		color      string
+		depth      colorDepth
+		background bool
+		want       string
+	}{
+		// Basic colors are the same at every depth.
+		{"blue", colorDepthTrue, false, "\x1b[34m"},
+		{"blue", colorDepth16, true, "\x1b[44m"},
+		{"bright-white", colorDepth256, false, "\x1b[97m"},
+		{"bright-red", colorDepth16, true, "\x1b[101m"},
+
+		// Palette colors above 16 degrade to the nearest basic color.
+		{"208", colorDepthTrue, false, "\x1b[38;5;208m"},
+		{"208", colorDepth256, true, "\x1b[48;5;208m"},
+		{"196", colorDepth16, false, "\x1b[91m"},
+		{"232", colorDepth16, false, "\x1b[30m"},
+		{"255", colorDepth16, true, "\x1b[47m"},
+
+		// RGB colors degrade to the 256-color cube or gray ramp, then to the basic colors.
+		{"#ff0000", colorDepthTrue, false, "\x1b[38;2;255;0;0m"},
+		{"#ff0000", colorDepth256, false, "\x1b[38;5;196m"},
+		{"#ff0000", colorDepth16, false, "\x1b[91m"},
+		{"#808080", colorDepth256, true, "\x1b[48;5;244m"},
+		{"#808080", colorDepth16, true, "\x1b[100m"},
+		{"#00005f", colorDepth256, false, "\x1b[38;5;17m"},
+		{"#00005f", colorDepth16, false, "\x1b[30m"},
+	}
+	for _, tt := range tests {
+		c, ok := parseThemeColor(tt.color)
+		if !ok || c == nil {
+			t.Fatalf("parseThemeColor(%q) failed", tt.color)
+		}
+		if got := c.sgr(tt.depth, tt.background); got != tt.want {
+			t.Errorf("%q.sgr(%v, %v) = %q; want %q", tt.color, tt.depth, tt.background, got, tt.want)
+		}
+	}
+}
+
+func TestColor256RGB(t *testing.T) {
+	tests := []struct {
+		index int
+		want  [3]int
+	}{
+		{0, [3]int{0, 0, 0}},
+		{9, [3]int{255, 0, 0}},
+		{16, [3]int{0, 0, 0}},
+		{21, [3]int{0, 0, 255}},
+		{110, [3]int{135, 175, 215}},
+		{196, [3]int{255, 0, 0}},
+		{231, [3]int{255, 255, 255}},
+		{232, [3]int{8, 8, 8}},
+		{255, [3]int{238, 238, 238}},
+	}
+	for _, tt := range tests {
+		if got := color256RGB(tt.index); got != tt.want {
+			t.Errorf("color256RGB(%d) = %v; want %v", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestColor256RGBRoundTrip(t *testing.T) {
+	// Every cube and gray ramp color maps back to itself, except the ramp grays that
+	// coincide with a cube color.
+	for index := 16; index < 256; index++ {
+		rgb := color256RGB(index)
+		got := nearest256(rgb[0], rgb[1], rgb[2])
+		if got != index && color256RGB(got) != rgb {
+			t.Errorf("nearest256(color256RGB(%d) = %v) = %d", index, rgb, got)
+		}
+	}
+}
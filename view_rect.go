@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// viewRect is the rectangle of the terminal a page draws into, in cells counted from 1.
+//
+// viewRect 是页面绘制所在的终端矩形区域，以从 1 开始计数的单元格表示。
+type viewRect struct {
+	col, row int  // Top left cell. / 左上角的单元格。
+	w, h     int  // Size in cells. / 以单元格计的尺寸。
+	toEdge   bool // True if the rect reaches the right edge of the terminal. / 如果区域到达终端右边缘则为true。
+}
+
+// terminalRect returns the rect of the whole terminal, 80x24 if its size is unknown.
+//
+// terminalRect 返回整个终端的区域，无法获取尺寸时为 80x24。
+func terminalRect() viewRect {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		w, h = 80, 24
+	}
+	return viewRect{col: 1, row: 1, w: w, h: h, toEdge: true}
+}
+
+// moveTo returns the escape sequence moving the cursor to a cell of the rect, with 1,1 being
+// its top left cell.
+//
+// moveTo 返回将光标移动到区域中某个单元格的转义序列，1,1 为其左上角单元格。
+func (r viewRect) moveTo(row, col int) string {
+	return fmt.Sprintf("\x1b[%d;%dH", r.row+row-1, r.col+col-1)
+}
+
+// clearRow returns the escape sequence blanking a row of the rect and leaving the cursor at
+// its start. Rows reaching the right edge are cleared with \x1b[K, others with spaces.
+//
+// clearRow 返回清空区域中某一行并将光标留在行首的转义序列。到达右边缘的行使用 \x1b[K 清除，
+// 其他行使用空格清除。
+func (r viewRect) clearRow(row int) string {
+	if r.toEdge {
+		return r.moveTo(row, 1) + "\x1b[K"
+	}
+	return r.moveTo(row, 1) + strings.Repeat(" ", r.w) + r.moveTo(row, 1)
+}
+
+// pageArea is embedded by the pages that can be drawn into a part of the terminal, as the
+// panes of the split view do.
+//
+// pageArea 嵌入在可以绘制到终端一部分区域的页面中，分屏视图的各个窗格就是如此。
+type pageArea struct {
+	area *viewRect // Rect in the split view, nil for the whole terminal. / 在分屏视图中的区域，nil 表示整个终端。
+}
+
+// setArea sets the rect the page draws into, nil for the whole terminal.
+//
+// setArea 设置页面绘制所在的区域，nil 表示整个终端。
+func (a *pageArea) setArea(r *viewRect) {
+	a.area = r
+}
+
+// viewArea returns the rect the page draws into.
+//
+// viewArea 返回页面绘制所在的区域。
+func (a *pageArea) viewArea() viewRect {
+	if a.area != nil {
+		return *a.area
+	}
+	return terminalRect()
+}
+
+// clearArea clears the rect of the page. The whole terminal is cleared with clearScreen, which
+// also removes the cover; a pane of the split view is blanked, leaving the other panes alone.
+//
+// clearArea 清除页面的区域。整个终端使用 clearScreen 清除，同时会移除封面；分屏视图中的窗格
+// 则被清空，不影响其他窗格。
+func (a *pageArea) clearArea() {
+	if a.area == nil {
+		clearScreen()
+		return
+	}
+	for row := 1; row <= a.area.h; row++ {
+		fmt.Print(a.area.clearRow(row))
+	}
+}
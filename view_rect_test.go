@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestViewRectMoveTo(t *testing.T) {
+	tests := []struct {
+		r        viewRect
+		row, col int
+		want     string
+	}{
+		{viewRect{col: 1, row: 1, w: 80, h: 24}, 1, 1, "\x1b[1;1H"},
+		{viewRect{col: 1, row: 1, w: 80, h: 24}, 5, 10, "\x1b[5;10H"},
+		{viewRect{col: 31, row: 3, w: 20, h: 10}, 1, 1, "\x1b[3;31H"},
+		{viewRect{col: 31, row: 3, w: 20, h: 10}, 4, 7, "\x1b[6;37H"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.moveTo(tt.row, tt.col); got != tt.want {
+			t.Errorf("%+v.moveTo(%d, %d) = %q; want %q", tt.r, tt.row, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestViewRectClearRow(t *testing.T) {
+	tests := []struct {
+		r    viewRect
+		row  int
+		want string
+	}{
+		{viewRect{col: 1, row: 1, w: 80, h: 24, toEdge: true}, 3, "\x1b[3;1H\x1b[K"},
+		{viewRect{col: 50, row: 3, w: 30, h: 20, toEdge: true}, 1, "\x1b[3;50H\x1b[K"},
+		{viewRect{col: 1, row: 1, w: 4, h: 24}, 2, "\x1b[2;1H    \x1b[2;1H"},
+		{viewRect{col: 10, row: 2, w: 3, h: 5}, 5, "\x1b[6;10H   \x1b[6;10H"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.clearRow(tt.row); got != tt.want {
+			t.Errorf("%+v.clearRow(%d) = %q; want %q", tt.r, tt.row, got, tt.want)
+		}
+	}
+}
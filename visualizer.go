@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+const (
+	// visualizerFFTSize is the number of samples the spectrum is computed from, a power of
+	// two.
+	//
+	// visualizerFFTSize 是计算频谱所用的采样数，为 2 的幂。
+	visualizerFFTSize = 1024
+
+	// visualizerFrame is how often the visualizer is redrawn, faster than the rest of the
+	// player.
+	//
+	// visualizerFrame 是可视化部件的重绘间隔，比播放器其他部分更快。
+	visualizerFrame = time.Second / 20
+
+	// The bands of the visualizer are spread logarithmically between these frequencies in Hz.
+	// 可视化部件的频段在这两个频率（Hz）之间按对数分布。
+	visualizerMinFreq = 40
+	visualizerMaxFreq = 16000
+
+	// visualizerRange is the range of levels in dB shown by the bars, below full scale.
+	//
+	// visualizerRange 是柱条所显示的电平范围（dB，低于满刻度）。
+	visualizerRange = 60
+
+	// visualizerFall is how much of the full height a bar falls per frame, so that peaks
+	// stay visible.
+	//
+	// visualizerFall 是柱条每帧下降的高度占满高的比例，使峰值保持可见。
+	visualizerFall = 0.06
+)
+
+// visualizerBlocks are the eighths of a cell drawn at the top of a bar.
+//
+// visualizerBlocks 是柱条顶端绘制的八分之一单元格块。
+var visualizerBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// visualizer keeps the latest samples played through a speaker tap and turns them into the
+// bars of the visualizer widget. The tap is added when the widget is drawn and removed when
+// it is no longer on screen.
+//
+// visualizer 通过扬声器 tap 保存最近播放的采样，并将其转换为可视化部件的柱条。
+// tap 在绘制部件时添加，在部件不再显示时移除。
+type visualizer struct {
+	mu      sync.Mutex // Guards samples, pos and rate. / 保护 samples、pos 和 rate。
+	samples [visualizerFFTSize]float64
+	pos     int // Index of the oldest sample. / 最早采样的索引。
+	rate    beep.SampleRate
+
+	// Only used from the main loop. / 只在主循环中使用。
+	removeTap func()    // Removes the tap, nil if it is not added. / 移除 tap，未添加时为 nil。
+	bars      []float64 // Heights of the bars as last drawn, 0 to 1. / 最近一次绘制的柱条高度，0 到 1。
+}
+
+// stop removes the tap, so that the audio thread no longer copies the samples.
+//
+// stop 移除 tap，使音频线程不再复制采样。
+func (v *visualizer) stop() {
+	if v.removeTap == nil {
+		return
+	}
+	v.removeTap()
+	v.removeTap = nil
+	v.bars = nil
+}
+
+// tap is the speaker tap. It runs on the audio thread with the speaker locked.
+//
+// tap 是扬声器的 tap 回调。它在音频线程中运行，此时扬声器处于锁定状态。
+func (v *visualizer) tap(samples [][2]float64, sampleRate beep.SampleRate) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, s := range samples[max(len(samples)-visualizerFFTSize, 0):] {
+		v.samples[v.pos] = (s[0] + s[1]) / 2
+		v.pos = (v.pos + 1) % visualizerFFTSize
+	}
+	v.rate = sampleRate
+}
+
+// levels returns the level of each band of the latest samples, from 0 to 1.
+//
+// levels 返回最近采样中每个频段的电平，范围为 0 到 1。
+func (v *visualizer) levels(bands int) []float64 {
+	var re, im [visualizerFFTSize]float64
+	v.mu.Lock()
+	rate := v.rate
+	for i := range re {
+		// Hann window. / 汉宁窗。
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/visualizerFFTSize)
+		re[i] = v.samples[(v.pos+i)%visualizerFFTSize] * window
+	}
+	v.mu.Unlock()
+
+	levels := make([]float64, bands)
+	if rate <= 0 {
+		return levels
+	}
+	fft(re[:], im[:])
+
+	binHz := float64(rate) / visualizerFFTSize
+	maxFreq := min(visualizerMaxFreq, float64(rate)/2)
+	for b := range levels {
+		lo := visualizerMinFreq * math.Pow(maxFreq/visualizerMinFreq, float64(b)/float64(bands))
+		hi := visualizerMinFreq * math.Pow(maxFreq/visualizerMinFreq, float64(b+1)/float64(bands))
+		first := min(int(lo/binHz), visualizerFFTSize/2-1)
+		last := min(max(int(hi/binHz), first+1), visualizerFFTSize/2)
+		var peak float64
+		for k := first; k < last; k++ {
+			peak = max(peak, math.Hypot(re[k], im[k]))
+		}
+		// A full scale sine peaks at a quarter of the size with the Hann window.
+		// 使用汉宁窗时，满刻度正弦波的峰值为大小的四分之一。
+		db := 20 * math.Log10(peak/(visualizerFFTSize/4)+1e-9)
+		levels[b] = min(max((db+visualizerRange)/visualizerRange, 0), 1)
+	}
+	return levels
+}
+
+// fft computes the discrete Fourier transform of re and im in place. Their length must be a
+// power of two.
+//
+// fft 原地计算 re 和 im 的离散傅里叶变换。它们的长度必须是 2 的幂。
+func fft(re, im []float64) {
+	n := len(re)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := range size / 2 {
+				wr, wi := math.Cos(step*float64(k)), math.Sin(step*float64(k))
+				a, b := start+k, start+k+size/2
+				tr := re[b]*wr - im[b]*wi
+				ti := re[b]*wi + im[b]*wr
+				re[b], im[b] = re[a]-tr, im[a]-ti
+				re[a], im[a] = re[a]+tr, im[a]+ti
+			}
+		}
+	}
+}
+
+// drawVisualizer draws the spectrum of the audio being played as one bar per column.
+//
+// drawVisualizer 将正在播放的音频的频谱绘制为每列一根柱条。
+func (p *PlayerPage) drawVisualizer(line templateLine, colorCode string) {
+	v := &p.visualizer
+	if v.removeTap == nil {
+		v.removeTap = speaker.AddTap(v.tap)
+	}
+
+	levels := v.levels(line.width)
+	if len(v.bars) != len(levels) {
+		v.bars = make([]float64, len(levels))
+	}
+	paused := p.app.player == nil || p.app.player.ctrl.Paused
+	for i, level := range levels {
+		if paused {
+			level = 0
+		}
+		v.bars[i] = max(level, v.bars[i]-visualizerFall)
+	}
+
+	eighths := make([]int, len(v.bars))
+	for i, bar := range v.bars {
+		eighths[i] = int(math.Round(bar * float64(line.height*8)))
+	}
+	var b strings.Builder
+	for row := range line.height {
+		b.Reset()
+		floor := (line.height - 1 - row) * 8
+		for _, e := range eighths {
+			b.WriteRune(visualizerBlocks[min(max(e-floor, 0), 8)])
+		}
+		fmt.Printf("\x1b[%d;%dH%s%s\x1b[0m", line.row+row, line.col, colorCode, b.String())
+	}
+}
+
+// drawVisualizers redraws the visualizer widgets of the layout template on screen, every
+// visualizerFrame. It removes the tap when the page or its template does not show the
+// visualizer.
+//
+// drawVisualizers 每隔 visualizerFrame 重绘屏幕上布局模板中的可视化部件。
+// 当页面或其模板不显示可视化部件时，它会移除 tap。
+func (p *PlayerPage) drawVisualizers() {
+	var lines []templateLine
+	if p.app.pageShown(p) && p.area == nil {
+		r := terminalRect()
+		if t := p.activeTemplate(r.w, r.h); t != nil {
+			for _, line := range t.place(r) {
+				if line.widgets[0] == "visualizer" {
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+	if len(lines) == 0 {
+		p.visualizer.stop()
+		return
+	}
+
+	if p.flacPath == "" || p.devicePicker != nil {
+		return
+	}
+	colorCode := p.getColorCode()
+	for _, line := range lines {
+		p.drawVisualizer(line, colorCode)
+	}
+}
+
+// layoutsUseWidget reports whether any layout template shows a widget.
+//
+// layoutsUseWidget 判断是否有布局模板显示某个部件。
+func layoutsUseWidget(layouts []LayoutTemplate, widget string) bool {
+	for _, t := range layouts {
+		for _, pane := range t.Panes {
+			for _, line := range pane.Widgets {
+				if slices.Contains(strings.Fields(line), widget) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
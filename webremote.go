@@ -0,0 +1,613 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+//go:embed web/index.html
+var webRemoteIndex []byte
+
+// webRemoteMaxBody bounds the size of the JSON body of an action request.
+//
+// webRemoteMaxBody 限制操作请求的 JSON 请求体大小。
+const webRemoteMaxBody = 64 << 10
+
+// webRemoteCookie is the cookie handleIndex stores web_remote_token in, so that the
+// EventSource and the cover image of the page, which cannot send headers, carry it too.
+//
+// webRemoteCookie 是 handleIndex 存放 web_remote_token 的 cookie，使网页中无法发送请求头的
+// EventSource 和封面图片也能携带它。
+const webRemoteCookie = "bm_token"
+
+// WebRemote is the optional embedded HTTP server that serves the web UI and its
+// REST/SSE API. Every read or change of player state runs on the main loop via callOnMain.
+//
+// WebRemote 是可选的内置 HTTP 服务器，提供网页界面及其 REST/SSE API。
+// 所有对播放器状态的读取或修改都通过 callOnMain 在主循环中执行。
+type WebRemote struct {
+	app    *App
+	server *http.Server
+}
+
+// webStatus is the now-playing snapshot returned by /api/status and /api/events.
+//
+// webStatus 是 /api/status 和 /api/events 返回的正在播放快照。
+type webStatus struct {
+	Path      string  `json:"path"`
+	Title     string  `json:"title"`
+	Artist    string  `json:"artist"`
+	Album     string  `json:"album"`
+	Position  float64 `json:"position"` // Seconds. / 秒。
+	Duration  float64 `json:"duration"` // Seconds. / 秒。
+	Playing   bool    `json:"playing"`
+	Paused    bool    `json:"paused"`
+	Buffering bool    `json:"buffering"` // Waiting for the read-ahead. / 正在等待预读。
+	Volume    float64 `json:"volume"`
+	Rate      float64 `json:"rate"`
+	PlayMode  int     `json:"play_mode"`
+	Index     int     `json:"index"` // Index of the song in the playlist, -1 if absent. / 歌曲在播放列表中的索引，不存在则为 -1。
+}
+
+// webSong is one entry of the playlist or of search results.
+//
+// webSong 是播放列表或搜索结果中的一个条目。
+type webSong struct {
+	Index int    `json:"index"`
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+}
+
+// startWebRemote starts the web remote if web_remote_addr is configured.
+// It returns nil when the feature is disabled or the address cannot be bound.
+//
+// startWebRemote 在配置了 web_remote_addr 时启动网页遥控器。
+// 如果功能被禁用或无法绑定地址，则返回 nil。
+func startWebRemote(app *App) *WebRemote {
+	if GlobalConfig == nil || GlobalConfig.App.WebRemoteAddr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", GlobalConfig.App.WebRemoteAddr)
+	if err != nil {
+		l.Warnf("failed to start web remote on %s: %v\n\n警告: 在 %s 上启动网页遥控器失败: %v", GlobalConfig.App.WebRemoteAddr, err, GlobalConfig.App.WebRemoteAddr, err)
+		return nil
+	}
+
+	w := &WebRemote{app: app}
+	w.server = &http.Server{Handler: w.handler(), ReadHeaderTimeout: 5 * time.Second}
+	go w.server.Serve(listener)
+	return w
+}
+
+// handler returns the routes of the web UI and its API.
+//
+// handler 返回网页界面及其 API 的路由。
+func (w *WebRemote) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", w.handleIndex)
+	mux.HandleFunc("GET /api/status", w.protect(w.handleStatus))
+	mux.HandleFunc("GET /api/events", w.protect(w.handleEvents))
+	mux.HandleFunc("GET /api/cover", w.protect(w.handleCover))
+	mux.HandleFunc("GET /api/playlist", w.protect(w.handlePlaylist))
+	mux.HandleFunc("GET /api/search", w.protect(w.handleSearch))
+	mux.HandleFunc("POST /api/toggle", w.guard(w.handleToggle))
+	mux.HandleFunc("POST /api/next", w.guard(w.handleNext))
+	mux.HandleFunc("POST /api/previous", w.guard(w.handlePrevious))
+	mux.HandleFunc("POST /api/seek", w.guard(w.handleSeek))
+	mux.HandleFunc("POST /api/volume", w.guard(w.handleVolume))
+	mux.HandleFunc("POST /api/mode", w.guard(w.handleMode))
+	mux.HandleFunc("POST /api/play", w.guard(w.handlePlay))
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r) {
+			http.Error(rw, "unknown host", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+// Close shuts the server down. A nil WebRemote does nothing.
+//
+// Close 关闭服务器。nil 的 WebRemote 不执行任何操作。
+func (w *WebRemote) Close() {
+	if w == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	w.server.Shutdown(ctx)
+}
+
+// authorized reports whether a request carries web_remote_token, as a bearer token, a token
+// query parameter or the cookie set by handleIndex. Every request is authorized when no
+// token is configured.
+//
+// authorized 判断请求是否携带 web_remote_token，可以是 bearer 令牌、token 查询参数或
+// handleIndex 设置的 cookie。未配置令牌时所有请求均被授权。
+func authorized(r *http.Request) bool {
+	token := GlobalConfig.App.WebRemoteToken
+	if token == "" {
+		return true
+	}
+	given := []string{r.URL.Query().Get("token")}
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		given = append(given, bearer)
+	}
+	if cookie, err := r.Cookie(webRemoteCookie); err == nil {
+		given = append(given, cookie.Value)
+	}
+	for _, g := range given {
+		if g != "" && subtle.ConstantTimeCompare([]byte(g), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHost reports whether the Host header of a request names this server. Without
+// web_remote_token it must be a loopback name or address, the host of web_remote_addr, or an
+// address of this machine when listening on all interfaces, so that a site that rebinds its
+// own domain name to this address cannot use the API from a browser.
+//
+// allowedHost 判断请求的 Host 头是否指向本服务器。未设置 web_remote_token 时，它必须是回环
+// 名称或地址、web_remote_addr 中的主机，或在监听所有网络接口时本机的某个地址，使将自己的域名
+// 重新绑定到该地址的网站无法通过浏览器使用 API。
+func allowedHost(r *http.Request) bool {
+	if GlobalConfig.App.WebRemoteToken != "" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+	}
+	ip := net.ParseIP(host)
+	if strings.EqualFold(host, "localhost") || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+
+	listen, _, err := net.SplitHostPort(GlobalConfig.App.WebRemoteAddr)
+	if err != nil {
+		return false
+	}
+	if listen != "" && strings.EqualFold(host, listen) {
+		return true
+	}
+	if ip == nil || (listen != "" && !net.ParseIP(listen).IsUnspecified()) {
+		return false
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// protect lets only requests carrying web_remote_token through, if it is set, so that the
+// library and the player state are not readable by anyone on the network.
+//
+// protect 在设置了 web_remote_token 时只放行携带它的请求，使网络上的任何人都无法读取媒体库
+// 和播放器状态。
+func (w *WebRemote) protect(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(rw, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		next(rw, r)
+	}
+}
+
+// guard protects an action endpoint. Requests from other sites are rejected, so that a page
+// open in the same browser cannot control playback, and the body must be JSON, which a
+// cross-site form cannot send. If web_remote_token is set, the request must also carry it.
+//
+// guard 保护操作端点。来自其他站点的请求会被拒绝，使同一浏览器中打开的网页无法控制播放；
+// 请求体必须为 JSON，跨站表单无法发送这种请求。如果设置了 web_remote_token，请求还必须
+// 携带它。
+func (w *WebRemote) guard(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+			http.Error(rw, "cross-site request", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(rw, "cross-site request", http.StatusForbidden)
+				return
+			}
+		}
+
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			http.Error(rw, "the body must be JSON", http.StatusUnsupportedMediaType)
+			return
+		}
+		r.Body = http.MaxBytesReader(rw, r.Body, webRemoteMaxBody)
+		w.protect(next)(rw, r)
+	}
+}
+
+// readJSON decodes the JSON body of an action request into v, replying with 400 if it is
+// invalid.
+//
+// readJSON 将操作请求的 JSON 请求体解码到 v 中，无效时以 400 响应。
+func readJSON(rw http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(rw, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// status collects a now-playing snapshot, including the tags read when the song was
+// loaded, on the main loop.
+//
+// status 在主循环中收集正在播放的快照，包括加载歌曲时读取的标签。
+func (w *WebRemote) status() (webStatus, error) {
+	var st webStatus
+	err := w.app.callOnMain(func() {
+		a := w.app
+		st = webStatus{
+			Path:     a.currentSongPath,
+			Volume:   a.linearVolume,
+			Rate:     a.playbackRate,
+			PlayMode: a.playMode,
+			Index:    -1,
+		}
+		for i, song := range a.Playlist {
+			if song == a.currentSongPath {
+				st.Index = i
+				break
+			}
+		}
+		if st.Path != "" {
+			st.Title, st.Artist, st.Album = a.songMetadata(st.Path)
+		}
+		if a.player == nil {
+			return
+		}
+		speaker.Lock()
+		rate := float64(a.player.sampleRate)
+		st.Position = float64(a.player.streamer.Position()) / rate
+		st.Duration = float64(a.player.streamer.Len()) / rate
+		st.Paused = a.player.ctrl.Paused
+		speaker.Unlock()
+		st.Buffering = a.isBuffering()
+		st.Playing = !st.Paused
+	})
+	return st, err
+}
+
+// handleIndex serves the embedded web UI. Opened with a valid ?token=, it also stores the
+// token in a cookie for the requests of the page.
+//
+// handleIndex 提供内置的网页界面。使用有效的 ?token= 打开时，它还会将令牌存入 cookie，
+// 供网页的请求使用。
+func (w *WebRemote) handleIndex(rw http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" && GlobalConfig.App.WebRemoteToken != "" && authorized(r) {
+		http.SetCookie(rw, &http.Cookie{
+			Name:     webRemoteCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.Write(webRemoteIndex)
+}
+
+// handleStatus returns the current now-playing snapshot.
+//
+// handleStatus 返回当前的正在播放快照。
+func (w *WebRemote) handleStatus(rw http.ResponseWriter, r *http.Request) {
+	st, err := w.status()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(rw, st)
+}
+
+// handleEvents streams the now-playing snapshot as Server-Sent Events once per second.
+//
+// handleEvents 以 Server-Sent Events 的形式每秒推送一次正在播放快照。
+func (w *WebRemote) handleEvents(rw http.ResponseWriter, r *http.Request) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if st, err := w.status(); err == nil {
+			data, _ := json.Marshal(st)
+			if _, err := fmt.Fprintf(rw, "event: status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleCover serves the cover art of the current song as PNG.
+//
+// handleCover 以 PNG 格式提供当前歌曲的封面。
+func (w *WebRemote) handleCover(rw http.ResponseWriter, r *http.Request) {
+	var info *songInfo
+	err := w.app.callOnMain(func() {
+		if song := w.app.song; song != nil && song.path == w.app.currentSongPath {
+			info = song
+		}
+	})
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if info == nil {
+		http.NotFound(rw, r)
+		return
+	}
+
+	// The cover is loaded in the background when the song starts.
+	// 封面在歌曲开始时于后台加载。
+	select {
+	case <-info.coverDone:
+	case <-r.Context().Done():
+		return
+	}
+
+	if info.coverFile == "" {
+		http.NotFound(rw, r)
+		return
+	}
+	rw.Header().Set("Cache-Control", "no-cache")
+	http.ServeFile(rw, r, info.coverFile)
+}
+
+// handlePlaylist returns the current playlist.
+//
+// handlePlaylist 返回当前播放列表。
+func (w *WebRemote) handlePlaylist(rw http.ResponseWriter, r *http.Request) {
+	var playlist []string
+	if err := w.app.callOnMain(func() { playlist = append([]string(nil), w.app.Playlist...) }); err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	songs := make([]webSong, len(playlist))
+	for i, songPath := range playlist {
+		songs[i] = webSong{Index: i, Path: songPath, Name: songDisplayName(songPath)}
+	}
+	writeJSON(rw, songs)
+}
+
+// handleSearch searches the library for songs matching ?q=.
+//
+// handleSearch 在媒体库中搜索与 ?q= 匹配的歌曲。
+func (w *WebRemote) handleSearch(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	var results []string
+	err := w.app.callOnMain(func() {
+		if len(w.app.pages) > 2 {
+			if library, ok := w.app.pages[2].(*Library); ok {
+				results = library.searchSongs(query, 50)
+			}
+		}
+	})
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	songs := make([]webSong, len(results))
+	for i, songPath := range results {
+		songs[i] = webSong{Index: -1, Path: songPath, Name: songDisplayName(songPath)}
+	}
+	writeJSON(rw, songs)
+}
+
+// handleToggle toggles between play and pause.
+//
+// handleToggle 切换播放和暂停。
+func (w *WebRemote) handleToggle(rw http.ResponseWriter, r *http.Request) {
+	w.runAction(rw, func() error {
+		if w.app.player != nil {
+			w.app.SetPaused(!w.app.player.ctrl.Paused)
+		}
+		return nil
+	})
+}
+
+// handleNext switches to the next song.
+//
+// handleNext 切换到下一首歌曲。
+func (w *WebRemote) handleNext(rw http.ResponseWriter, r *http.Request) {
+	w.runAction(rw, func() error {
+		if playerPage, ok := w.app.pages[0].(*PlayerPage); ok {
+			playerPage.playNextSong()
+		}
+		return nil
+	})
+}
+
+// handlePrevious switches to the previous song.
+//
+// handlePrevious 切换到上一首歌曲。
+func (w *WebRemote) handlePrevious(rw http.ResponseWriter, r *http.Request) {
+	w.runAction(rw, func() error {
+		if playerPage, ok := w.app.pages[0].(*PlayerPage); ok {
+			playerPage.playPreviousSong()
+		}
+		return nil
+	})
+}
+
+// handleSeek seeks to {"position": seconds}.
+//
+// handleSeek 跳转到 {"position": 秒数} 指定的位置。
+func (w *WebRemote) handleSeek(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Position *float64 `json:"position"`
+	}
+	if !readJSON(rw, r, &req) {
+		return
+	}
+	if req.Position == nil {
+		http.Error(rw, "invalid position", http.StatusBadRequest)
+		return
+	}
+	w.runAction(rw, func() error {
+		if w.app.player != nil {
+			w.app.SeekTo(int(*req.Position * float64(w.app.player.sampleRate)))
+		}
+		return nil
+	})
+}
+
+// handleVolume sets the linear volume to {"value": 0.0-1.0}.
+//
+// handleVolume 将线性音量设置为 {"value": 0.0-1.0}。
+func (w *WebRemote) handleVolume(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Value *float64 `json:"value"`
+	}
+	if !readJSON(rw, r, &req) {
+		return
+	}
+	if req.Value == nil {
+		http.Error(rw, "invalid volume", http.StatusBadRequest)
+		return
+	}
+	w.runAction(rw, func() error {
+		w.app.SetLinearVolume(*req.Value)
+		return nil
+	})
+}
+
+// handleMode sets the play mode to {"value": 0=repeat one, 1=repeat all, 2=random}.
+//
+// handleMode 将播放模式设置为 {"value": 0=单曲循环, 1=列表循环, 2=随机播放}。
+func (w *WebRemote) handleMode(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Value *int `json:"value"`
+	}
+	if !readJSON(rw, r, &req) {
+		return
+	}
+	if req.Value == nil || *req.Value < 0 || *req.Value > 2 {
+		http.Error(rw, "invalid play mode", http.StatusBadRequest)
+		return
+	}
+	w.runAction(rw, func() error {
+		if !w.app.isSingleSongMode {
+			w.app.SetPlayMode(*req.Value)
+		}
+		return nil
+	})
+}
+
+// handlePlay plays the playlist entry {"index": n}, or adds and plays the library file
+// {"path": "..."}.
+//
+// handlePlay 播放播放列表中的 {"index": n} 条目，或添加并播放媒体库中的
+// {"path": "..."} 文件。
+func (w *WebRemote) handlePlay(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Index *int   `json:"index"`
+		Path  string `json:"path"`
+	}
+	if !readJSON(rw, r, &req) {
+		return
+	}
+	if songPath := req.Path; songPath != "" {
+		w.runAction(rw, func() error {
+			if !w.isInLibrary(songPath) {
+				return errors.New("path is outside the library")
+			}
+			// Checked before the song is added, so that the playlist never keeps a missing file.
+			// 在添加歌曲之前检查，使播放列表不会保留不存在的文件。
+			if info, err := os.Stat(cueAudioPath(songPath)); err != nil || !info.Mode().IsRegular() {
+				return errors.New("no such file")
+			}
+			return w.app.AddSongsAndPlay([]string{songPath})
+		})
+		return
+	}
+
+	if req.Index == nil {
+		http.Error(rw, "invalid index", http.StatusBadRequest)
+		return
+	}
+	index := *req.Index
+	w.runAction(rw, func() error {
+		if index < 0 || index >= len(w.app.Playlist) {
+			return errors.New("index out of range")
+		}
+		return w.app.PlaySongWithSwitchAndRender(w.app.Playlist[index], w.app.currentPageIndex == 0, true)
+	})
+}
+
+// isInLibrary reports whether songPath is an audio file inside the library root.
+//
+// isInLibrary 报告 songPath 是否为媒体库根目录内的音频文件。
+func (w *WebRemote) isInLibrary(songPath string) bool {
+	rel, err := filepath.Rel(w.app.LibraryPath, songPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return isAudioFile(songPath)
+}
+
+// runAction runs fn on the main loop and replies with 204 or the error.
+//
+// runAction 在主循环中运行 fn，并以 204 或错误进行响应。
+func (w *WebRemote) runAction(rw http.ResponseWriter, fn func() error) {
+	var actionErr error
+	if err := w.app.callOnMain(func() { actionErr = fn() }); err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if actionErr != nil {
+		http.Error(rw, actionErr.Error(), http.StatusBadRequest)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// writeJSON writes v as a JSON response.
+//
+// writeJSON 将 v 作为 JSON 响应写出。
+func writeJSON(rw http.ResponseWriter, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(v)
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestWebRemote returns the handler of a web remote for an app whose main loop runs until
+// the test ends, with web_remote_token set to token.
+func newTestWebRemote(t *testing.T, token string) (*WebRemote, http.Handler) {
+	saved := GlobalConfig
+	GlobalConfig = &Config{App: AppConfig{WebRemoteToken: token}}
+	t.Cleanup(func() { GlobalConfig = saved })
+
+	app := &App{
+		actionQueue: make(chan func()),
+		LibraryPath: filepath.Join(t.TempDir(), "music"),
+		Playlist:    []string{"/music/a.mp3"},
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case fn := <-app.actionQueue:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	w := &WebRemote{app: app}
+	return w, w.handler()
+}
+
+func TestWebRemoteToken(t *testing.T) {
+	_, h := newTestWebRemote(t, "secret")
+
+	tests := []struct {
+		name   string
+		target string
+		header string // "Name: value"
+		want   int
+	}{
+		{"missing token", "/api/playlist", "", http.StatusUnauthorized},
+		{"wrong query token", "/api/playlist?token=guess", "", http.StatusUnauthorized},
+		{"wrong bearer token", "/api/status", "Authorization: Bearer guess", http.StatusUnauthorized},
+		{"wrong cookie", "/api/search?q=a", "Cookie: bm_token=guess", http.StatusUnauthorized},
+		{"token without bearer scheme", "/api/playlist", "Authorization: secret", http.StatusUnauthorized},
+		{"prefix of the token", "/api/playlist?token=secre", "", http.StatusUnauthorized},
+		{"query token", "/api/playlist?token=secret", "", http.StatusOK},
+		{"bearer token", "/api/playlist", "Authorization: Bearer secret", http.StatusOK},
+		{"cookie", "/api/playlist", "Cookie: bm_token=secret", http.StatusOK},
+		{"index without token", "/", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if name, value, ok := strings.Cut(tt.header, ": "); ok {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %d; want %d", tt.target, rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebRemoteIndexCookie(t *testing.T) {
+	_, h := newTestWebRemote(t, "secret")
+
+	for token, want := range map[string]bool{"secret": true, "guess": false} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token="+token, nil))
+		cookies := rec.Result().Cookies()
+		if got := len(cookies) == 1 && cookies[0].Name == webRemoteCookie && cookies[0].Value == token; got != want {
+			t.Errorf("/?token=%s set cookies %v; want the token cookie: %v", token, cookies, want)
+		}
+	}
+}
+
+func TestWebRemoteGuard(t *testing.T) {
+	_, h := newTestWebRemote(t, "secret")
+
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{
+			"cross-site fetch",
+			"/api/volume",
+			map[string]string{"Sec-Fetch-Site": "cross-site", "Content-Type": "application/json", "Authorization": "Bearer secret"},
+			`{"value": 0.5}`,
+			http.StatusForbidden,
+		},
+		{
+			"same-site fetch from another port",
+			"/api/volume",
+			map[string]string{"Sec-Fetch-Site": "same-site", "Content-Type": "application/json", "Authorization": "Bearer secret"},
+			`{"value": 0.5}`,
+			http.StatusForbidden,
+		},
+		{
+			"foreign origin",
+			"/api/volume",
+			map[string]string{"Origin": "http://evil.example", "Content-Type": "application/json", "Authorization": "Bearer secret"},
+			`{"value": 0.5}`,
+			http.StatusForbidden,
+		},
+		{
+			"form post",
+			"/api/volume",
+			map[string]string{"Content-Type": "application/x-www-form-urlencoded", "Authorization": "Bearer secret"},
+			`value=0.5`,
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"text body",
+			"/api/volume",
+			map[string]string{"Content-Type": "text/plain", "Authorization": "Bearer secret"},
+			`{"value": 0.5}`,
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"missing content type",
+			"/api/volume",
+			map[string]string{"Authorization": "Bearer secret"},
+			`{"value": 0.5}`,
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"missing token",
+			"/api/volume",
+			map[string]string{"Content-Type": "application/json"},
+			`{"value": 0.5}`,
+			http.StatusUnauthorized,
+		},
+		{
+			"oversized body",
+			"/api/volume",
+			map[string]string{"Content-Type": "application/json", "Authorization": "Bearer secret"},
+			`{"value": 0.5, "padding": "` + strings.Repeat("x", webRemoteMaxBody) + `"}`,
+			http.StatusBadRequest,
+		},
+		{
+			"invalid mode",
+			"/api/mode",
+			map[string]string{"Content-Type": "application/json; charset=utf-8", "Authorization": "Bearer secret"},
+			`{"value": 7}`,
+			http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("POST %s = %d (%s); want %d", tt.target, rec.Code, strings.TrimSpace(rec.Body.String()), tt.want)
+			}
+		})
+	}
+}
+
+func TestWebRemoteSameOriginAction(t *testing.T) {
+	w, h := newTestWebRemote(t, "")
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/api/mode", strings.NewReader(`{"value": 2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	req.Header.Set("Origin", "http://localhost:8080")
+	w.app.isSingleSongMode = true // SetPlayMode needs the pages, which the test app has none of.
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("same-origin POST /api/mode = %d (%s); want %d", rec.Code, strings.TrimSpace(rec.Body.String()), http.StatusNoContent)
+	}
+}
+
+func TestWebRemotePlayOutsideLibrary(t *testing.T) {
+	w, h := newTestWebRemote(t, "")
+	library := w.app.LibraryPath
+
+	for _, songPath := range []string{
+		filepath.Join(library, "..", "secret.mp3"),
+		library + "/../../etc/secret.mp3",
+		library + "-other/song.mp3",
+		"/etc/passwd",
+	} {
+		body := `{"path": "` + songPath + `"}`
+		req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/api/play", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "outside the library") {
+			t.Errorf("playing %s = %d (%s); want it rejected as outside the library", songPath, rec.Code, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestWebRemotePlayMissingFile(t *testing.T) {
+	w, h := newTestWebRemote(t, "")
+	library := w.app.LibraryPath
+	if err := os.MkdirAll(filepath.Join(library, "album.flac"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, songPath := range []string{
+		filepath.Join(library, "missing.mp3"),
+		filepath.Join(library, "album.flac"),
+		cueTrackPath(filepath.Join(library, "missing.flac"), 1),
+	} {
+		body := `{"path": "` + songPath + `"}`
+		req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/api/play", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "no such file") {
+			t.Errorf("playing %s = %d (%s); want it rejected as missing", songPath, rec.Code, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+	if want := []string{"/music/a.mp3"}; !slices.Equal(w.app.Playlist, want) {
+		t.Errorf("playlist = %v; want %v", w.app.Playlist, want)
+	}
+}
+
+func TestAllowedHost(t *testing.T) {
+	saved := GlobalConfig
+	t.Cleanup(func() { GlobalConfig = saved })
+
+	tests := []struct {
+		token, addr string
+		host        string
+		want        bool
+	}{
+		{"", ":8080", "localhost:8080", true},
+		{"", ":8080", "LOCALHOST", true},
+		{"", ":8080", "127.0.0.1:8080", true},
+		{"", ":8080", "127.0.0.2", true},
+		{"", ":8080", "[::1]:8080", true},
+		{"", ":8080", "evil.example:8080", false},
+		{"", ":8080", "203.0.113.9:8080", false},
+		{"", "127.0.0.1:8080", "evil.example:8080", false},
+		{"", "bm.lan:8080", "bm.lan:8080", true},
+		{"", "bm.lan:8080", "BM.lan", true},
+		{"", "bm.lan:8080", "bm.lan.evil.example:8080", false},
+		{"", "192.168.1.5:8080", "192.168.1.5:8080", true},
+		{"", "192.168.1.5:8080", "192.168.1.6:8080", false},
+		{"secret", ":8080", "evil.example:8080", true},
+	}
+	for _, tt := range tests {
+		GlobalConfig = &Config{App: AppConfig{WebRemoteToken: tt.token, WebRemoteAddr: tt.addr}}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = tt.host
+		if got := allowedHost(req); got != tt.want {
+			t.Errorf("allowedHost(%q) with addr %q, token %q = %v; want %v", tt.host, tt.addr, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedHostInterfaces(t *testing.T) {
+	saved := GlobalConfig
+	t.Cleanup(func() { GlobalConfig = saved })
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Skip(err)
+	}
+	var ip net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+			ip = ipNet.IP
+			break
+		}
+	}
+	if ip == nil {
+		t.Skip("no network interface besides loopback")
+	}
+
+	host := net.JoinHostPort(ip.String(), "8080")
+	for addr, want := range map[string]bool{":8080": true, "0.0.0.0:8080": true, "127.0.0.1:8080": false} {
+		GlobalConfig = &Config{App: AppConfig{WebRemoteAddr: addr}}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		if got := allowedHost(req); got != want {
+			t.Errorf("allowedHost(%q) with addr %q = %v; want %v", host, addr, got, want)
+		}
+	}
+}
+
+func TestWebRemoteRebinding(t *testing.T) {
+	_, h := newTestWebRemote(t, "")
+
+	for host, want := range map[string]int{"localhost:8080": http.StatusOK, "evil.example:8080": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/playlist", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("GET /api/playlist with Host %s = %d; want %d", host, rec.Code, want)
+		}
+	}
+}
+
+func TestIsInLibrary(t *testing.T) {
+	w := &WebRemote{app: &App{LibraryPath: "/music"}}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/music/song.mp3", true},
+		{"/music/Artist/Album/01.flac", true},
+		{"/music/Album/../song.mp3", true},
+		{"/music/cover.jpg", false},
+		{"/music", false},
+		{"/music/../song.mp3", false},
+		{"/music/../../etc/song.mp3", false},
+		{"/music-other/song.mp3", false},
+		{"/song.mp3", false},
+		{"music/song.mp3", false},
+	}
+	for _, tt := range tests {
+		if got := w.isInLibrary(tt.path); got != tt.want {
+			t.Errorf("isInLibrary(%q) = %v; want %v", tt.path, got, tt.want)
+		}
+	}
+}